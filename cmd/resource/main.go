@@ -48,7 +48,7 @@ func detectConfigPath() string {
 	return "configs"
 }
 
-func newApp(logger log.Logger, httpServer *http.Server, grpcServer *grpc.Server, mqServer *server.MQServer) *kratos.App {
+func newApp(logger log.Logger, httpServer *http.Server, grpcServer *grpc.Server, mqServer *server.MQServer, reconcileServer *server.NetworkReconcileServer, statusServer *server.InstanceStatusServer) *kratos.App {
 	return kratos.New(
 		kratos.ID(id),
 		kratos.Name(Name),
@@ -59,6 +59,8 @@ func newApp(logger log.Logger, httpServer *http.Server, grpcServer *grpc.Server,
 			httpServer,
 			grpcServer,
 			mqServer,
+			reconcileServer,
+			statusServer,
 		),
 	)
 }
@@ -90,7 +92,7 @@ func main() {
 		panic(err)
 	}
 
-	app, cleanup, err := wireApp(bc.Server, bc.Data, logger)
+	app, cleanup, err := wireApp(bc.Server, bc.Data, bc.Auth, logger)
 	if err != nil {
 		panic(err)
 	}
@@ -23,7 +23,7 @@ import (
 // Injectors from wire.go:
 
 // wireApp init kratos application.
-func wireApp(confServer *conf.Server, confData *conf.Data, logger log.Logger) (*kratos.App, func(), error) {
+func wireApp(confServer *conf.Server, confData *conf.Data, auth *conf.Auth, logger log.Logger) (*kratos.App, func(), error) {
 	dataData, cleanup, err := data.NewData(confData, logger)
 	if err != nil {
 		return nil, nil, err
@@ -42,17 +42,32 @@ func wireApp(confServer *conf.Server, confData *conf.Data, logger log.Logger) (*
 	}
 	networkRepo := data.NewNetworkRepo(dataData, logger)
 	execRepo := data.NewExecRepo(k8sClient, logger)
-	resourceUsecase := biz.NewResourceUsecase(instanceRepo, auditRepo, k8sRepo, networkRepo, execRepo, logger)
-	resourceService := service.NewResourceService(resourceUsecase)
-	httpServer := server.NewHTTPServer(confServer, resourceService, logger)
-	grpcServer := server.NewGRPCServer(confServer, resourceService, logger)
+	execSessionRepo := data.NewExecSessionRepo(dataData, logger)
+	execRecordingStore, err := data.NewExecRecordingStore(confData, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	domainResolver := data.NewDomainResolver(confData)
+	resourceUsecase := biz.NewResourceUsecase(instanceRepo, auditRepo, k8sRepo, networkRepo, execRepo, execSessionRepo, execRecordingStore, domainResolver, logger)
+	authzUsecase, err := biz.NewAuthzUsecase(auth, instanceRepo, auditRepo, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	execLimiter := biz.NewExecLimiter(confServer)
+	resourceService := service.NewResourceService(resourceUsecase, authzUsecase, execLimiter)
+	httpServer := server.NewHTTPServer(confServer, resourceService, authzUsecase, logger)
+	grpcServer := server.NewGRPCServer(confServer, resourceService, authzUsecase, logger)
 	connection, cleanup2, err := data.NewRabbitMQ(confData, logger)
 	if err != nil {
 		cleanup()
 		return nil, nil, err
 	}
 	mqServer := server.NewMQServer(confData, connection, resourceService, logger)
-	app := newApp(logger, httpServer, grpcServer, mqServer)
+	networkReconcileServer := server.NewNetworkReconcileServer(confServer, resourceUsecase, logger)
+	instanceStatusServer := server.NewInstanceStatusServer(confServer, resourceUsecase, logger)
+	app := newApp(logger, httpServer, grpcServer, mqServer, networkReconcileServer, instanceStatusServer)
 	return app, func() {
 		cleanup2()
 		cleanup()
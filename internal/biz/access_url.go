@@ -0,0 +1,112 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// tcpUDPAccessURL TCP/UDP 端口的访问地址：{公开地址}:{外部端口}
+func tcpUDPAccessURL(address string, externalPort uint32) string {
+	return fmt.Sprintf("%s:%d", address, externalPort)
+}
+
+// isPlaceholderAccessURL 早期版本在地址未分配时保存的占位符，如 <ingress-lb-ip>:30000、<pending>:8080
+func isPlaceholderAccessURL(url string) bool {
+	return strings.HasPrefix(url, "<")
+}
+
+// refreshAccessURLs 按当前地址重新计算 TCP/UDP 与 LOADBALANCER 端口的访问地址，变化时写回记录：
+//   - TCP/UDP 使用配置的公开主机名或 ingress-nginx LoadBalancer 地址，配置或地址变化后随之更新
+//   - LOADBALANCER 在开放时尚未分配外部地址的，查询 Service 补全
+//
+// 地址仍不可用时访问地址为空，不保存占位符。bindings 原地更新，写回失败只记录日志并保留原值。
+func (uc *ResourceUsecase) refreshAccessURLs(ctx context.Context, namespace string, bindings []NetworkBinding) {
+	var publicAddress string
+	var publicErr error
+	resolved := false
+
+	for i := range bindings {
+		b := &bindings[i]
+		url, externalIP := b.AccessURL, b.ExternalIP
+
+		switch {
+		case b.ExternalPort != nil && (b.Protocol == "TCP" || b.Protocol == "UDP"):
+			if !resolved {
+				publicAddress, publicErr = uc.K8sRepo.GetTCPUDPPublicAddress(ctx)
+				resolved = true
+				if publicErr != nil {
+					uc.log.WithContext(ctx).Warnf("failed to get TCP/UDP public address: %v", publicErr)
+				}
+			}
+			if publicErr == nil {
+				url = tcpUDPAccessURL(publicAddress, *b.ExternalPort)
+			}
+
+		case b.Protocol == "LOADBALANCER" && b.ExternalIP == "":
+			address, err := uc.K8sRepo.GetLoadBalancerAddress(ctx, namespace, b.ServiceName)
+			if err != nil {
+				uc.log.WithContext(ctx).Warnf("failed to get LoadBalancer address of %s: %v", b.ServiceName, err)
+			} else if address != "" {
+				externalIP = address
+				url = fmt.Sprintf("%s:%d", address, b.ServicePort)
+			}
+
+		default:
+			continue
+		}
+
+		if isPlaceholderAccessURL(url) {
+			url = ""
+		}
+		if url == b.AccessURL && externalIP == b.ExternalIP {
+			continue
+		}
+
+		// 只写回地址字段，不覆盖并发的启用、禁用或访问控制修改
+		if err := uc.NetworkRepo.UpdateAccessURL(ctx, *b, url, externalIP); err != nil {
+			uc.log.WithContext(ctx).Warnf("failed to update access URL of port %d of instance %d: %v", b.Port, b.InstanceID, err)
+			continue
+		}
+		b.AccessURL, b.ExternalIP = url, externalIP
+		uc.log.WithContext(ctx).Infof("access URL of port %d of instance %d updated to %q", b.Port, b.InstanceID, url)
+	}
+}
+
+// RefreshAccessURLs 重新计算全部端口的访问地址并写回变化，使开放时尚未分配地址的 LOADBALANCER、
+// TCP/UDP 端口无需等待查询也能补全。返回写回的端口数。
+func (uc *ResourceUsecase) RefreshAccessURLs(ctx context.Context) (int, error) {
+	resources, err := uc.InstanceSpec.ListResources(ctx, ListResourcesFilter{})
+	if err != nil {
+		return 0, err
+	}
+	bindings, err := uc.NetworkRepo.ListAllNetworkBindings(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	byInstance := make(map[int64][]NetworkBinding)
+	for _, b := range bindings {
+		byInstance[b.InstanceID] = append(byInstance[b.InstanceID], b)
+	}
+
+	updated := 0
+	for _, r := range resources {
+		if ctx.Err() != nil {
+			return updated, ctx.Err()
+		}
+		instanceBindings := byInstance[r.InstanceID]
+		if len(instanceBindings) == 0 {
+			continue
+		}
+		before := make([]NetworkBinding, len(instanceBindings))
+		copy(before, instanceBindings)
+		uc.refreshAccessURLs(ctx, r.UserID, instanceBindings)
+		for i := range instanceBindings {
+			if instanceBindings[i].AccessURL != before[i].AccessURL || instanceBindings[i].ExternalIP != before[i].ExternalIP {
+				updated++
+			}
+		}
+	}
+	return updated, nil
+}
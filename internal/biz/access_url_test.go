@@ -0,0 +1,139 @@
+package biz
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeOpenK8sRepo struct {
+	fakeEnsureK8sRepo
+}
+
+func (f *fakeOpenK8sRepo) CreateServiceForTCPUDP(_ context.Context, _, instanceID string, port uint32, _ string) (string, uint32, error) {
+	return "instance-" + instanceID + "-22", 30005, nil
+}
+
+type fakeCreateBindingRepo struct {
+	fakeBindingRepo
+}
+
+func (f *fakeCreateBindingRepo) CreateNetworkBinding(_ context.Context, binding NetworkBinding) error {
+	f.bindings[binding.Port] = binding
+	return nil
+}
+
+func TestResourceUsecase_RefreshAccessURLs(t *testing.T) {
+	externalPort := uint32(30002)
+	network := &fakeBindingRepo{bindings: map[uint32]NetworkBinding{}}
+	k8s := &fakeEnsureK8sRepo{lbAddresses: map[string]string{}}
+	uc := NewResourceUsecase(&fakeInstanceRepo{}, &fakeAuditRepo{}, k8s, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	bindings := []NetworkBinding{
+		{InstanceID: 1, Port: 22, ExternalPort: &externalPort, Protocol: "TCP", AccessURL: "<ingress-lb-ip>:30002"},
+		{InstanceID: 1, Port: 8080, ServiceName: "instance-1-8080", ServicePort: 8080, Protocol: "LOADBALANCER", AccessURL: "<pending>:8080"},
+		{InstanceID: 1, Port: 80, Protocol: "HTTP", AccessURL: "http://demo.localtest.me/alice/1/80"},
+	}
+
+	for _, b := range bindings {
+		network.bindings[b.Port] = b
+	}
+
+	// 地址仍不可用：清除旧版本保存的占位符
+	uc.refreshAccessURLs(ctx, "alice", bindings)
+	if bindings[0].AccessURL != "" || bindings[1].AccessURL != "" || network.bindings[22].AccessURL != "" || network.bindings[8080].AccessURL != "" {
+		t.Fatalf("bindings=%+v stored=%+v", bindings, network.bindings)
+	}
+	if network.bindings[80].AccessURL != bindings[2].AccessURL {
+		t.Fatal("HTTP binding must not be rewritten")
+	}
+
+	// 读取后端口被并发禁用：写回地址不得覆盖 enabled
+	stored := network.bindings[22]
+	stored.Enabled = false
+	network.bindings[22] = stored
+	bindings[0].Enabled = true
+
+	// 地址分配后补全并写回
+	k8s.publicAddress = "tcp.example.com"
+	k8s.lbAddresses["instance-1-8080"] = "198.51.100.7"
+	uc.refreshAccessURLs(ctx, "alice", bindings)
+	if bindings[0].AccessURL != "tcp.example.com:30002" || network.bindings[22].AccessURL != "tcp.example.com:30002" {
+		t.Fatalf("tcp=%+v stored=%+v", bindings[0], network.bindings[22])
+	}
+	if network.bindings[22].Enabled {
+		t.Fatal("refresh must not overwrite concurrent enabled change")
+	}
+	if bindings[1].AccessURL != "198.51.100.7:8080" || network.bindings[8080].ExternalIP != "198.51.100.7" {
+		t.Fatalf("lb=%+v stored=%+v", bindings[1], network.bindings[8080])
+	}
+}
+
+func TestResourceUsecase_OpenTCPPortWithoutPublicAddress(t *testing.T) {
+	network := &fakeCreateBindingRepo{fakeBindingRepo{bindings: map[uint32]NetworkBinding{}}}
+	k8s := &fakeOpenK8sRepo{}
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	url, err := uc.SetInstancePort(ctx, 1, 22, "TCP", "", true, IngressOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "" || network.bindings[22].AccessURL != "" || *network.bindings[22].ExternalPort != 30005 {
+		t.Fatalf("url=%q binding=%+v", url, network.bindings[22])
+	}
+
+	// 再次打开时按当前地址计算
+	k8s.publicAddress = "203.0.113.10"
+	url, err = uc.SetInstancePort(ctx, 1, 22, "TCP", "", true, IngressOptions{})
+	if err != nil || url != "203.0.113.10:30005" || network.bindings[22].AccessURL != url {
+		t.Fatalf("url=%q err=%v binding=%+v", url, err, network.bindings[22])
+	}
+}
+
+func (f *fakeOpenK8sRepo) CreateDirectService(_ context.Context, _, instanceID string, port uint32, _, _ string) (string, DirectEndpoint, error) {
+	return "instance-" + instanceID + "-" + strconv.FormatUint(uint64(port), 10), DirectEndpoint{NodePort: 31000}, nil
+}
+
+func (f *fakeCreateBindingRepo) ListAllNetworkBindings(context.Context) ([]NetworkBinding, error) {
+	out := make([]NetworkBinding, 0, len(f.bindings))
+	for _, b := range f.bindings {
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func TestResourceUsecase_RefreshAccessURLsPendingLoadBalancer(t *testing.T) {
+	network := &fakeCreateBindingRepo{fakeBindingRepo{bindings: map[uint32]NetworkBinding{}}}
+	k8s := &fakeOpenK8sRepo{fakeEnsureK8sRepo{lbAddresses: map[string]string{}}}
+	repo := &listInstanceRepo{fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	url, err := uc.SetInstancePort(ctx, 1, 8080, "LOADBALANCER", "", true, IngressOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "" || network.bindings[8080].AccessURL != "" || network.bindings[8080].ExternalIP != "" {
+		t.Fatalf("url=%q binding=%+v", url, network.bindings[8080])
+	}
+
+	// 地址未分配时不写回
+	if updated, err := uc.RefreshAccessURLs(ctx); err != nil || updated != 0 {
+		t.Fatalf("updated=%d err=%v", updated, err)
+	}
+
+	// 分配后周期刷新补全，无需查询端口
+	k8s.lbAddresses["instance-1-8080"] = "198.51.100.7"
+	if updated, err := uc.RefreshAccessURLs(ctx); err != nil || updated != 1 {
+		t.Fatalf("updated=%d err=%v", updated, err)
+	}
+	if b := network.bindings[8080]; b.AccessURL != "198.51.100.7:8080" || b.ExternalIP != "198.51.100.7" || !b.Enabled {
+		t.Fatalf("binding=%+v", b)
+	}
+}
@@ -0,0 +1,104 @@
+package biz
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// asciicastHeader asciicast v2 头部
+// 格式说明：https://docs.asciinema.org/manual/asciicast/v2/
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     uint32            `json:"width"`
+	Height    uint32            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// asciicast v2 事件类型
+const (
+	asciicastOutput = "o"
+	asciicastInput  = "i"
+	asciicastResize = "r"
+)
+
+// asciicastRecorder 以 asciicast v2 格式记录 exec 会话，每行一个 JSON 事件
+type asciicastRecorder struct {
+	mu     sync.Mutex
+	w      io.WriteCloser
+	start  time.Time
+	err    error
+	closed bool
+}
+
+func newAsciicastRecorder(w io.WriteCloser, width, height uint32, command []string, start time.Time) (*asciicastRecorder, error) {
+	r := &asciicastRecorder{w: w, start: start}
+	header := asciicastHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: start.Unix(),
+		Command:   strings.Join(command, " "),
+	}
+	if err := r.writeLine(header); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Output 记录标准输出/错误
+func (r *asciicastRecorder) Output(data []byte) {
+	r.event(asciicastOutput, string(data))
+}
+
+// Input 记录标准输入
+func (r *asciicastRecorder) Input(data []byte) {
+	r.event(asciicastInput, string(data))
+}
+
+// Resize 记录终端大小调整
+func (r *asciicastRecorder) Resize(cols, rows uint32) {
+	r.event(asciicastResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close 结束录像，返回录像过程中的首个写入错误
+func (r *asciicastRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+	if err := r.w.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+func (r *asciicastRecorder) event(code, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || r.err != nil {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	if err := r.writeLine([]interface{}{elapsed, code, data}); err != nil {
+		r.err = err
+	}
+}
+
+func (r *asciicastRecorder) writeLine(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = r.w.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,312 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"resource/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// ErrPermissionDenied 调用方无权执行该操作
+var ErrPermissionDenied = errors.New("permission denied")
+
+// InstanceScope 角色可访问的实例范围
+type InstanceScope string
+
+const (
+	// InstanceScopeAll 可访问全部实例
+	InstanceScopeAll InstanceScope = "ALL"
+	// InstanceScopeOwn 仅可访问本人（namespace = user_id）的实例
+	InstanceScopeOwn InstanceScope = "OWN"
+)
+
+// 内置角色名称
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleReadOnly = "readonly"
+	RoleUser     = "user"
+)
+
+// Principal 调用方身份，由网关通过请求头传入
+type Principal struct {
+	UserID string
+	Role   string
+}
+
+// RolePolicy 角色到 RPC 与实例范围的映射
+type RolePolicy struct {
+	Name        string
+	Operations  map[string]bool // 允许的 RPC 方法名，"*" 表示全部
+	Scope       InstanceScope
+	InstanceIDs map[int64]bool // 额外允许访问的实例
+	Exec        *ExecPolicy    // exec 命令策略，nil 表示不限制
+}
+
+// allows 判断角色是否允许调用该 RPC
+func (p RolePolicy) allows(operation string) bool {
+	return p.Operations["*"] || p.Operations[operation]
+}
+
+// defaultRolePolicies 内置角色：
+//   - admin: 全部 RPC，全部实例
+//   - operator: 查询（含容器日志）、启动、停止任意实例，不允许 exec
+//   - readonly: 仅查询（含容器日志与 exec 会话录像）
+//   - user: 全部 RPC，仅本人实例
+func defaultRolePolicies() map[string]RolePolicy {
+	return map[string]RolePolicy{
+		RoleAdmin: {
+			Name:       RoleAdmin,
+			Operations: operationSet("*"),
+			Scope:      InstanceScopeAll,
+		},
+		RoleOperator: {
+			Name:       RoleOperator,
+			Operations: operationSet("ListResources", "ListInstancePods", "ListInstancePorts", "ListCustomDomains", "GetInstanceLogs", "GetInstanceEvents", "ListNetworkRules", "StopInstance", "StartInstance"),
+			Scope:      InstanceScopeAll,
+		},
+		RoleReadOnly: {
+			Name:       RoleReadOnly,
+			Operations: operationSet("ListResources", "ListInstancePods", "ListInstancePorts", "ListCustomDomains", "GetInstanceLogs", "GetInstanceEvents", "ListNetworkRules", "ListExecSessions", "GetExecSession"),
+			Scope:      InstanceScopeAll,
+		},
+		RoleUser: {
+			Name:       RoleUser,
+			Operations: operationSet("*"),
+			Scope:      InstanceScopeOwn,
+		},
+	}
+}
+
+func operationSet(ops ...string) map[string]bool {
+	set := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		set[op] = true
+	}
+	return set
+}
+
+// AuthzUsecase 基于角色的访问控制
+type AuthzUsecase struct {
+	enabled         bool
+	userHeader      string
+	roleHeader      string
+	trustRoleHeader bool
+	defaultRole     string
+	roles           map[string]RolePolicy
+	bindings        map[string]string // user_id -> role
+	instanceExec    map[int64][]*ExecPolicy
+
+	instances InstanceRepo
+	audit     AuditRepo
+	log       *log.Helper
+}
+
+// NewAuthzUsecase 根据配置加载角色策略，未配置时不启用访问控制
+func NewAuthzUsecase(c *conf.Auth, repo InstanceRepo, audit AuditRepo, logger log.Logger) (*AuthzUsecase, error) {
+	uc := &AuthzUsecase{
+		userHeader:   "x-user-id",
+		roleHeader:   "x-user-role",
+		defaultRole:  RoleUser,
+		roles:        defaultRolePolicies(),
+		bindings:     map[string]string{},
+		instanceExec: map[int64][]*ExecPolicy{},
+		instances:    repo,
+		audit:        audit,
+		log:          log.NewHelper(logger),
+	}
+	if c == nil {
+		return uc, nil
+	}
+
+	uc.enabled = c.GetEnabled()
+	uc.trustRoleHeader = c.GetTrustRoleHeader()
+	if c.GetUserHeader() != "" {
+		uc.userHeader = c.GetUserHeader()
+	}
+	if c.GetRoleHeader() != "" {
+		uc.roleHeader = c.GetRoleHeader()
+	}
+	if c.GetDefaultRole() != "" {
+		uc.defaultRole = c.GetDefaultRole()
+	}
+
+	for _, r := range c.GetRoles() {
+		if r.GetName() == "" {
+			continue
+		}
+		scope := InstanceScope(r.GetInstanceScope())
+		if scope != InstanceScopeAll {
+			scope = InstanceScopeOwn
+		}
+		policy := RolePolicy{
+			Name:        r.GetName(),
+			Operations:  operationSet(r.GetOperations()...),
+			Scope:       scope,
+			InstanceIDs: make(map[int64]bool, len(r.GetInstanceIds())),
+		}
+		for _, id := range r.GetInstanceIds() {
+			policy.InstanceIDs[id] = true
+		}
+		exec, err := newExecPolicy(r.GetExec())
+		if err != nil {
+			return nil, fmt.Errorf("role %s: %w", r.GetName(), err)
+		}
+		policy.Exec = exec
+		uc.roles[policy.Name] = policy
+	}
+	for _, b := range c.GetBindings() {
+		uc.bindings[b.GetUserId()] = b.GetRole()
+	}
+	for _, ip := range c.GetInstanceExecPolicies() {
+		exec, err := newExecPolicy(ip.GetPolicy())
+		if err != nil {
+			return nil, fmt.Errorf("instance exec policy %v: %w", ip.GetInstanceIds(), err)
+		}
+		if exec == nil {
+			continue
+		}
+		for _, id := range ip.GetInstanceIds() {
+			uc.instanceExec[id] = append(uc.instanceExec[id], exec)
+		}
+	}
+
+	return uc, nil
+}
+
+// Enabled 是否启用访问控制
+func (uc *AuthzUsecase) Enabled() bool {
+	return uc.enabled
+}
+
+// UserHeader 携带用户 ID 的请求头名称
+func (uc *AuthzUsecase) UserHeader() string {
+	return uc.userHeader
+}
+
+// RoleHeader 携带角色的请求头名称
+func (uc *AuthzUsecase) RoleHeader() string {
+	return uc.roleHeader
+}
+
+// ResolvePrincipal 根据请求头中的用户 ID 与角色解析调用方身份。
+// 优先使用配置中的绑定关系，其次在信任网关时使用请求头角色，最后使用默认角色。
+func (uc *AuthzUsecase) ResolvePrincipal(userID, headerRole string) Principal {
+	role := uc.defaultRole
+	if r, ok := uc.bindings[userID]; ok {
+		role = r
+	} else if uc.trustRoleHeader && headerRole != "" {
+		role = headerRole
+	}
+	return Principal{UserID: userID, Role: role}
+}
+
+// Authorize 检查调用方是否可以对指定实例执行 operation。
+// operation 可以是完整的 gRPC 方法名（/resource.v1.resourceService/StopInstance），也可以是方法名本身；
+// instanceID 为 0 表示与具体实例无关的操作。拒绝时写入审计日志并返回 ErrPermissionDenied。
+func (uc *AuthzUsecase) Authorize(ctx context.Context, p Principal, operation string, instanceID int64) error {
+	if !uc.enabled {
+		return nil
+	}
+	method := path.Base(operation)
+
+	policy, ok := uc.roles[p.Role]
+	if !ok {
+		return uc.deny(ctx, p, method, instanceID, "unknown role "+p.Role)
+	}
+	if p.UserID == "" && policy.Scope != InstanceScopeAll {
+		return uc.deny(ctx, p, method, instanceID, "missing user identity")
+	}
+	if !policy.allows(method) {
+		return uc.deny(ctx, p, method, instanceID, "operation not allowed for role "+p.Role)
+	}
+	if instanceID == 0 || policy.Scope == InstanceScopeAll || policy.InstanceIDs[instanceID] {
+		return nil
+	}
+
+	resource, err := uc.instances.GetResource(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if resource == nil {
+		// 实例不存在，交由后续处理返回 NOT_FOUND
+		return nil
+	}
+	if resource.UserID != p.UserID {
+		return uc.deny(ctx, p, method, instanceID, "instance is out of scope for role "+p.Role)
+	}
+	return nil
+}
+
+// AuthorizeExec 按角色与实例的 exec 策略校验命令，需在 Authorize 通过后、启动 exec 之前调用。
+// 违反任一策略时写入审计日志并返回 ErrPermissionDenied。
+func (uc *AuthzUsecase) AuthorizeExec(ctx context.Context, p Principal, instanceID int64, command []string, tty bool) error {
+	if !uc.enabled {
+		return nil
+	}
+
+	policies := uc.instanceExec[instanceID]
+	if role, ok := uc.roles[p.Role]; ok && role.Exec != nil {
+		policies = append([]*ExecPolicy{role.Exec}, policies...)
+	}
+	for _, policy := range policies {
+		if reason, ok := policy.check(command, tty); !ok {
+			return uc.deny(ctx, p, "ExecContainer", instanceID, reason)
+		}
+	}
+	return nil
+}
+
+// ScopedUserID 返回列表查询需要限定的用户 ID；角色可访问全部实例时返回 false。
+func (uc *AuthzUsecase) ScopedUserID(p Principal) (string, bool) {
+	if !uc.enabled {
+		return "", false
+	}
+	policy, ok := uc.roles[p.Role]
+	if ok && policy.Scope == InstanceScopeAll {
+		return "", false
+	}
+	return p.UserID, true
+}
+
+// deny 记录拒绝的审计日志
+func (uc *AuthzUsecase) deny(ctx context.Context, p Principal, method string, instanceID int64, reason string) error {
+	uc.log.WithContext(ctx).Warnf("access denied: user=%s role=%s operation=%s instance=%d reason=%s", p.UserID, p.Role, method, instanceID, reason)
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"user_id":   p.UserID,
+		"role":      p.Role,
+		"operation": method,
+		"reason":    reason,
+	})
+	if err := uc.audit.CreateAudit(ctx, AuditInformation{
+		InstanceID: instanceID,
+		LogType:    "ACCESS_DENIED",
+		Message:    "Access denied for " + method,
+		DataJson:   data,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		uc.log.WithContext(ctx).Errorf("failed to record access denial: %v", err)
+	}
+
+	return ErrPermissionDenied
+}
+
+type principalKey struct{}
+
+// NewPrincipalContext 将调用方身份放入 context
+func NewPrincipalContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext 从 context 中取出调用方身份
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,180 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"resource/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeInstanceRepo struct {
+	InstanceRepo
+	resources map[int64]*Resource
+}
+
+func (f *fakeInstanceRepo) GetResource(_ context.Context, instanceID int64) (*Resource, error) {
+	return f.resources[instanceID], nil
+}
+
+type fakeAuditRepo struct {
+	records []AuditInformation
+}
+
+func (f *fakeAuditRepo) CreateAudit(_ context.Context, info AuditInformation) error {
+	f.records = append(f.records, info)
+	return nil
+}
+
+func TestAuthzUsecase_Authorize(t *testing.T) {
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{
+		1: {InstanceID: 1, UserID: "alice"},
+		2: {InstanceID: 2, UserID: "bob"},
+	}}
+	c := &conf.Auth{
+		Enabled: true,
+		Roles: []*conf.Auth_Role{
+			{Name: "support", Operations: []string{"ExecContainer"}, InstanceScope: "OWN", InstanceIds: []int64{2}},
+		},
+		Bindings: []*conf.Auth_Binding{
+			{UserId: "ops", Role: RoleOperator},
+			{UserId: "auditor", Role: RoleReadOnly},
+			{UserId: "helper", Role: "support"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		userID     string
+		operation  string
+		instanceID int64
+		wantDenied bool
+	}{
+		{name: "user_own_instance", userID: "alice", operation: "/resource.v1.resourceService/StopInstance", instanceID: 1},
+		{name: "user_other_instance", userID: "alice", operation: "/resource.v1.resourceService/StopInstance", instanceID: 2, wantDenied: true},
+		{name: "operator_stop_any", userID: "ops", operation: "StopInstance", instanceID: 2},
+		{name: "operator_no_exec", userID: "ops", operation: "ExecContainer", instanceID: 2, wantDenied: true},
+		{name: "readonly_list", userID: "auditor", operation: "ListResources"},
+		{name: "readonly_no_delete", userID: "auditor", operation: "DeleteInstance", instanceID: 1, wantDenied: true},
+		{name: "custom_role_extra_instance", userID: "helper", operation: "ExecContainer", instanceID: 2},
+		{name: "custom_role_other_instance", userID: "helper", operation: "ExecContainer", instanceID: 1, wantDenied: true},
+		{name: "anonymous_denied", userID: "", operation: "ListResources", wantDenied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &fakeAuditRepo{}
+			uc, err := NewAuthzUsecase(c, repo, audit, log.NewStdLogger(io.Discard))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p := uc.ResolvePrincipal(tt.userID, "")
+			err = uc.Authorize(context.Background(), p, tt.operation, tt.instanceID)
+			if tt.wantDenied {
+				if !errors.Is(err, ErrPermissionDenied) {
+					t.Fatalf("err=%v want ErrPermissionDenied", err)
+				}
+				if len(audit.records) != 1 || audit.records[0].LogType != "ACCESS_DENIED" {
+					t.Fatalf("audit records=%v want one ACCESS_DENIED", audit.records)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err=%v want nil", err)
+			}
+			if len(audit.records) != 0 {
+				t.Fatalf("audit records=%v want none", audit.records)
+			}
+		})
+	}
+}
+
+func TestAuthzUsecase_Disabled(t *testing.T) {
+	uc, err := NewAuthzUsecase(nil, &fakeInstanceRepo{}, &fakeAuditRepo{}, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.Authorize(context.Background(), Principal{}, "DeleteInstance", 1); err != nil {
+		t.Fatalf("err=%v want nil when disabled", err)
+	}
+	if _, scoped := uc.ScopedUserID(Principal{}); scoped {
+		t.Fatal("ScopedUserID should not scope when disabled")
+	}
+}
+
+func TestAuthzUsecase_AuthorizeExec(t *testing.T) {
+	c := &conf.Auth{
+		Enabled: true,
+		Roles: []*conf.Auth_Role{
+			{
+				Name:          "support",
+				Operations:    []string{"ExecContainer"},
+				InstanceScope: "ALL",
+				Exec: &conf.Auth_ExecPolicy{
+					Allow:   []*conf.Auth_ExecRule{{Prefix: []string{"cat"}}, {Regex: `^ls( -[a-z]+)*( /\S*)?$`}},
+					Deny:    []*conf.Auth_ExecRule{{Prefix: []string{"cat", "/etc/shadow"}}},
+					DenyTty: true,
+				},
+			},
+		},
+		Bindings: []*conf.Auth_Binding{
+			{UserId: "helper", Role: "support"},
+			{UserId: "root", Role: RoleAdmin},
+		},
+		InstanceExecPolicies: []*conf.Auth_InstanceExecPolicy{
+			{InstanceIds: []int64{9}, Policy: &conf.Auth_ExecPolicy{Deny: []*conf.Auth_ExecRule{{Regex: `\brm\b`}}}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		userID     string
+		instanceID int64
+		command    []string
+		tty        bool
+		wantDenied bool
+	}{
+		{name: "allow_prefix", userID: "helper", instanceID: 1, command: []string{"cat", "/var/log/app.log"}},
+		{name: "allow_regex", userID: "helper", instanceID: 1, command: []string{"ls", "-la", "/tmp"}},
+		{name: "not_in_allow_list", userID: "helper", instanceID: 1, command: []string{"sh", "-c", "id"}, wantDenied: true},
+		{name: "deny_overrides_allow", userID: "helper", instanceID: 1, command: []string{"cat", "/etc/shadow"}, wantDenied: true},
+		{name: "deny_tty", userID: "helper", instanceID: 1, command: []string{"cat"}, tty: true, wantDenied: true},
+		{name: "admin_unrestricted", userID: "root", instanceID: 1, command: []string{"sh"}, tty: true},
+		{name: "instance_policy_applies_to_admin", userID: "root", instanceID: 9, command: []string{"sh", "-c", "rm -rf /data"}, wantDenied: true},
+		{name: "instance_policy_other_command", userID: "root", instanceID: 9, command: []string{"sh"}, tty: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &fakeAuditRepo{}
+			uc, err := NewAuthzUsecase(c, &fakeInstanceRepo{}, audit, log.NewStdLogger(io.Discard))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p := uc.ResolvePrincipal(tt.userID, "")
+			err = uc.AuthorizeExec(context.Background(), p, tt.instanceID, tt.command, tt.tty)
+			if tt.wantDenied != errors.Is(err, ErrPermissionDenied) {
+				t.Fatalf("err=%v wantDenied=%v", err, tt.wantDenied)
+			}
+			if tt.wantDenied && len(audit.records) != 1 {
+				t.Fatalf("audit records=%v want one ACCESS_DENIED", audit.records)
+			}
+		})
+	}
+}
+
+func TestNewAuthzUsecase_InvalidExecRule(t *testing.T) {
+	c := &conf.Auth{
+		Roles: []*conf.Auth_Role{
+			{Name: "broken", Exec: &conf.Auth_ExecPolicy{Allow: []*conf.Auth_ExecRule{{Regex: "("}}}},
+		},
+	}
+	if _, err := NewAuthzUsecase(c, &fakeInstanceRepo{}, &fakeAuditRepo{}, log.NewStdLogger(io.Discard)); err == nil {
+		t.Fatal("expected error for invalid regex")
+	}
+}
@@ -0,0 +1,379 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCustomDomainNotFound 自定义域名不存在或不属于该实例
+	ErrCustomDomainNotFound = errors.New("custom domain not found")
+	// ErrInvalidDomain 域名格式不合法或属于平台域名
+	ErrInvalidDomain = errors.New("invalid domain")
+	// ErrDomainTaken 域名已绑定到其他端口
+	ErrDomainTaken = errors.New("domain is already bound")
+	// ErrCustomDomainNotSupported 自定义域名只支持 HTTP 端口
+	ErrCustomDomainNotSupported = errors.New("custom domains are only supported for HTTP ports")
+	// ErrDomainVerificationFailed 未查询到匹配的 DNS TXT 记录
+	ErrDomainVerificationFailed = errors.New("domain verification failed")
+)
+
+// 自定义域名状态
+const (
+	CustomDomainPending = "PENDING" // 等待 DNS TXT 验证
+	CustomDomainActive  = "ACTIVE"  // 已验证，Ingress 已创建
+)
+
+const (
+	// customDomainTXTPrefix TXT 记录名前缀：{prefix}.{domain}
+	customDomainTXTPrefix = "_resource-challenge"
+	// customDomainTXTValuePrefix TXT 记录值前缀：{prefix}{token}
+	customDomainTXTValuePrefix = "resource-verification="
+	// customDomainPendingExpiry 未验证的域名超过该时间后可被其他实例重新添加，避免抢注
+	customDomainPendingExpiry = 7 * 24 * time.Hour
+)
+
+// CustomDomain 绑定到实例 HTTP 端口的用户域名。
+// 添加后为 PENDING，用户在域名下添加 TXT 记录并验证通过后为 ACTIVE，
+// 此时创建 Host 路由、cert-manager 签发证书的 HTTPS Ingress。
+type CustomDomain struct {
+	Domain     string // 规范化后的小写域名，全局唯一
+	InstanceID int64
+	Port       uint32
+	Token      string // 所有权验证令牌
+	Status     string // PENDING / ACTIVE
+	CreatedAt  time.Time
+	VerifiedAt *time.Time
+}
+
+// TXTRecordName 验证所有权需要添加的 TXT 记录名
+func (d CustomDomain) TXTRecordName() string {
+	return customDomainTXTPrefix + "." + d.Domain
+}
+
+// TXTRecordValue 验证所有权需要添加的 TXT 记录值
+func (d CustomDomain) TXTRecordValue() string {
+	return customDomainTXTValuePrefix + d.Token
+}
+
+// AccessURL 域名生效后的访问地址
+func (d CustomDomain) AccessURL() string {
+	return "https://" + d.Domain + "/"
+}
+
+// domainPattern 至少两级的小写 DNS 名称，每级 1-63 个字符
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?\.)+[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// DomainResolver 查询 DNS TXT 记录，测试中可替换为假实现
+type DomainResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// normalizeDomain 转为小写并去掉末尾的点，校验为合法的 DNS 名称：至少两级、不含通配符、不是 IP 地址
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" {
+		return "", fmt.Errorf("%w: domain is required", ErrInvalidDomain)
+	}
+	if strings.Contains(domain, "*") {
+		return "", fmt.Errorf("%w: wildcard domains are not supported", ErrInvalidDomain)
+	}
+	if len(domain) > 253 || !domainPattern.MatchString(domain) || net.ParseIP(domain) != nil {
+		return "", fmt.Errorf("%w: %q is not a valid domain name", ErrInvalidDomain, domain)
+	}
+	return domain, nil
+}
+
+// checkIngressDomain 校验 HTTP 端口的 ingress_domain，只允许配置的平台域名或该实例已验证的自定义域名，
+// 避免用任意主机名创建 Ingress 绕过 TXT 验证。返回规范化后的域名
+func (uc *ResourceUsecase) checkIngressDomain(ctx context.Context, instanceID int64, domain string) (string, error) {
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return "", err
+	}
+	if domain == strings.ToLower(uc.K8sRepo.GetIngressDomain()) {
+		return domain, nil
+	}
+
+	domains, err := uc.NetworkRepo.ListCustomDomains(ctx, instanceID)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range domains {
+		if d.Domain == domain && d.Status == CustomDomainActive {
+			return domain, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s is neither the platform domain nor a verified custom domain of the instance", ErrInvalidDomain, domain)
+}
+
+// AddCustomDomain 为已开放的 HTTP 端口添加自定义域名，返回待验证的记录。
+// 同一实例端口重复添加时返回已有记录；域名已被其他端口使用时返回 ErrDomainTaken，
+// 其他实例超过 7 天未验证的记录会被替换。
+func (uc *ResourceUsecase) AddCustomDomain(ctx context.Context, instanceID int64, port uint32, domain string) (*CustomDomain, error) {
+	uc.log.WithContext(ctx).Infof("AddCustomDomain: instanceID=%d port=%d domain=%s", instanceID, port, domain)
+
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if resource == nil {
+		return nil, ErrInstanceNotFound
+	}
+
+	binding, err := uc.NetworkRepo.GetNetworkBinding(ctx, instanceID, port)
+	if err != nil {
+		return nil, err
+	}
+	if binding == nil {
+		return nil, ErrPortNotOpen
+	}
+	if binding.IngressName == nil {
+		return nil, ErrCustomDomainNotSupported
+	}
+	// 平台域名下的主机名由 HOST 路由分配，不允许作为自定义域名
+	if platform := strings.ToLower(uc.K8sRepo.GetIngressDomain()); platform != "" && (domain == platform || strings.HasSuffix(domain, "."+platform)) {
+		return nil, fmt.Errorf("%w: %s is under the platform domain %s", ErrInvalidDomain, domain, platform)
+	}
+
+	existing, err := uc.NetworkRepo.GetCustomDomain(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.InstanceID == instanceID && existing.Port == port {
+			return existing, nil
+		}
+		if existing.Status != CustomDomainPending || time.Since(existing.CreatedAt) < customDomainPendingExpiry {
+			return nil, ErrDomainTaken
+		}
+		if err := uc.NetworkRepo.DeleteCustomDomain(ctx, domain); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := newDomainToken()
+	if err != nil {
+		return nil, err
+	}
+	record := &CustomDomain{
+		Domain:     domain,
+		InstanceID: instanceID,
+		Port:       port,
+		Token:      token,
+		Status:     CustomDomainPending,
+	}
+	if err := uc.NetworkRepo.CreateCustomDomain(ctx, record); err != nil {
+		return nil, err
+	}
+
+	uc.auditCustomDomain(ctx, "CUSTOM_DOMAIN_ADDED", *record)
+	return record, nil
+}
+
+// VerifyCustomDomain 查询 TXT 记录验证域名所有权，通过后创建 HTTPS Ingress 并标记为 ACTIVE。
+// 已生效的域名再次调用时按记录修复 Ingress。端口被禁用时只更新状态，重新启用端口后创建 Ingress。
+func (uc *ResourceUsecase) VerifyCustomDomain(ctx context.Context, instanceID int64, domain string) (*CustomDomain, error) {
+	uc.log.WithContext(ctx).Infof("VerifyCustomDomain: instanceID=%d domain=%s", instanceID, domain)
+
+	resource, record, err := uc.getCustomDomain(ctx, instanceID, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Status != CustomDomainActive {
+		values, err := uc.DomainResolver.LookupTXT(ctx, record.TXTRecordName())
+		if err != nil {
+			return nil, fmt.Errorf("%w: lookup %s: %v", ErrDomainVerificationFailed, record.TXTRecordName(), err)
+		}
+		if !containsTXT(values, record.TXTRecordValue()) {
+			return nil, fmt.Errorf("%w: TXT record %s does not contain %s", ErrDomainVerificationFailed, record.TXTRecordName(), record.TXTRecordValue())
+		}
+	}
+
+	binding, err := uc.NetworkRepo.GetNetworkBinding(ctx, instanceID, record.Port)
+	if err != nil {
+		return nil, err
+	}
+	if binding == nil {
+		return nil, ErrPortNotOpen
+	}
+	if binding.Enabled {
+		if err := uc.K8sRepo.EnsureCustomDomain(ctx, resource.UserID, *record, *binding); err != nil {
+			return nil, err
+		}
+	}
+
+	if record.Status == CustomDomainActive {
+		return record, nil
+	}
+
+	now := time.Now()
+	record.Status = CustomDomainActive
+	record.VerifiedAt = &now
+	if err := uc.NetworkRepo.UpdateCustomDomain(ctx, *record); err != nil {
+		if binding.Enabled {
+			_ = uc.K8sRepo.DeleteCustomDomain(ctx, resource.UserID, *record)
+		}
+		return nil, err
+	}
+
+	uc.auditCustomDomain(ctx, "CUSTOM_DOMAIN_VERIFIED", *record)
+	return record, nil
+}
+
+// ListCustomDomains 列出实例的自定义域名
+func (uc *ResourceUsecase) ListCustomDomains(ctx context.Context, instanceID int64) ([]CustomDomain, error) {
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if resource == nil {
+		return nil, ErrInstanceNotFound
+	}
+	return uc.NetworkRepo.ListCustomDomains(ctx, instanceID)
+}
+
+// DeleteCustomDomain 删除自定义域名及其 Ingress
+func (uc *ResourceUsecase) DeleteCustomDomain(ctx context.Context, instanceID int64, domain string) error {
+	uc.log.WithContext(ctx).Infof("DeleteCustomDomain: instanceID=%d domain=%s", instanceID, domain)
+
+	resource, record, err := uc.getCustomDomain(ctx, instanceID, domain)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.K8sRepo.DeleteCustomDomain(ctx, resource.UserID, *record); err != nil {
+		return err
+	}
+	if err := uc.NetworkRepo.DeleteCustomDomain(ctx, record.Domain); err != nil {
+		return err
+	}
+
+	uc.auditCustomDomain(ctx, "CUSTOM_DOMAIN_DELETED", *record)
+	return nil
+}
+
+// syncCustomDomains 按端口当前配置更新已生效域名的 Ingress：端口启用时创建或更新（同步访问控制），
+// 禁用时删除。bindings 为同一实例的端口，单个域名失败只记录日志。
+func (uc *ResourceUsecase) syncCustomDomains(ctx context.Context, namespace string, bindings []NetworkBinding) {
+	if len(bindings) == 0 {
+		return
+	}
+	domains, err := uc.NetworkRepo.ListCustomDomains(ctx, bindings[0].InstanceID)
+	if err != nil {
+		uc.log.WithContext(ctx).Warnf("failed to list custom domains of instance %d: %v", bindings[0].InstanceID, err)
+		return
+	}
+
+	byPort := make(map[uint32]NetworkBinding, len(bindings))
+	for _, b := range bindings {
+		byPort[b.Port] = b
+	}
+	for _, d := range domains {
+		b, ok := byPort[d.Port]
+		if !ok || d.Status != CustomDomainActive {
+			continue
+		}
+		if b.Enabled {
+			err = uc.K8sRepo.EnsureCustomDomain(ctx, namespace, d, b)
+		} else {
+			err = uc.K8sRepo.DeleteCustomDomain(ctx, namespace, d)
+		}
+		if err != nil {
+			uc.log.WithContext(ctx).Warnf("failed to sync custom domain %s of port %d: %v", d.Domain, d.Port, err)
+		}
+	}
+}
+
+// releaseCustomDomains 删除实例端口的自定义域名及其 Ingress，port 为 0 时删除实例的全部域名
+func (uc *ResourceUsecase) releaseCustomDomains(ctx context.Context, namespace string, instanceID int64, port uint32) {
+	domains, err := uc.NetworkRepo.ListCustomDomains(ctx, instanceID)
+	if err != nil {
+		uc.log.WithContext(ctx).Warnf("failed to list custom domains of instance %d: %v", instanceID, err)
+		return
+	}
+	for _, d := range domains {
+		if port != 0 && d.Port != port {
+			continue
+		}
+		if err := uc.K8sRepo.DeleteCustomDomain(ctx, namespace, d); err != nil {
+			uc.log.Errorf("failed to delete custom domain ingress of %s: %v", d.Domain, err)
+		}
+		if err := uc.NetworkRepo.DeleteCustomDomain(ctx, d.Domain); err != nil {
+			uc.log.Errorf("failed to delete custom domain %s: %v", d.Domain, err)
+		}
+	}
+}
+
+// getCustomDomain 返回实例与域名记录，域名不属于该实例时返回 ErrCustomDomainNotFound
+func (uc *ResourceUsecase) getCustomDomain(ctx context.Context, instanceID int64, domain string) (*Resource, *CustomDomain, error) {
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resource == nil {
+		return nil, nil, ErrInstanceNotFound
+	}
+
+	record, err := uc.NetworkRepo.GetCustomDomain(ctx, domain)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record == nil || record.InstanceID != instanceID {
+		return nil, nil, ErrCustomDomainNotFound
+	}
+	return resource, record, nil
+}
+
+func (uc *ResourceUsecase) auditCustomDomain(ctx context.Context, logType string, d CustomDomain) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"domain": d.Domain,
+		"port":   d.Port,
+		"status": d.Status,
+	})
+	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
+		InstanceID: d.InstanceID,
+		LogType:    logType,
+		Message:    "Custom domain " + d.Domain + " of port " + strconv.Itoa(int(d.Port)) + " " + strings.ToLower(strings.TrimPrefix(logType, "CUSTOM_DOMAIN_")),
+		DataJson:   data,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// containsTXT 逐条比较 TXT 记录值，忽略首尾空白与引号
+func containsTXT(values []string, want string) bool {
+	for _, v := range values {
+		if strings.Trim(strings.TrimSpace(v), `"`) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func newDomainToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
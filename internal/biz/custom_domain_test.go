@@ -0,0 +1,255 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeDomainRepo struct {
+	fakeBindingRepo
+	domains map[string]CustomDomain
+}
+
+func (f *fakeDomainRepo) CreateCustomDomain(_ context.Context, d *CustomDomain) error {
+	d.CreatedAt = time.Now()
+	f.domains[d.Domain] = *d
+	return nil
+}
+
+func (f *fakeDomainRepo) GetCustomDomain(_ context.Context, domain string) (*CustomDomain, error) {
+	d, ok := f.domains[domain]
+	if !ok {
+		return nil, nil
+	}
+	return &d, nil
+}
+
+func (f *fakeDomainRepo) ListCustomDomains(_ context.Context, instanceID int64) ([]CustomDomain, error) {
+	var out []CustomDomain
+	for _, d := range f.domains {
+		if d.InstanceID == instanceID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeDomainRepo) UpdateCustomDomain(_ context.Context, d CustomDomain) error {
+	f.domains[d.Domain] = d
+	return nil
+}
+
+func (f *fakeDomainRepo) DeleteCustomDomain(_ context.Context, domain string) error {
+	delete(f.domains, domain)
+	return nil
+}
+
+type fakeDomainK8sRepo struct {
+	fakeEnsureK8sRepo
+	exposed   []string
+	unexposed []string
+}
+
+func (f *fakeDomainK8sRepo) EnsureCustomDomain(_ context.Context, _ string, d CustomDomain, _ NetworkBinding) error {
+	f.exposed = append(f.exposed, d.Domain)
+	return nil
+}
+
+func (f *fakeDomainK8sRepo) DeleteCustomDomain(_ context.Context, _ string, d CustomDomain) error {
+	f.unexposed = append(f.unexposed, d.Domain)
+	return nil
+}
+
+type fakeResolver struct {
+	records map[string][]string
+}
+
+func (f *fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	values, ok := f.records[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return values, nil
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	if d, err := normalizeDomain(" App.Example.COM. "); err != nil || d != "app.example.com" {
+		t.Fatalf("domain=%q err=%v", d, err)
+	}
+	for _, in := range []string{"", "localhost", "*.example.com", "203.0.113.10", "-app.example.com", "app_1.example.com"} {
+		if _, err := normalizeDomain(in); !errors.Is(err, ErrInvalidDomain) {
+			t.Fatalf("%q: err=%v want ErrInvalidDomain", in, err)
+		}
+	}
+}
+
+func TestResourceUsecase_CustomDomain(t *testing.T) {
+	ingress := "ingress-1-80"
+	network := &fakeDomainRepo{
+		fakeBindingRepo: fakeBindingRepo{bindings: map[uint32]NetworkBinding{
+			80: {InstanceID: 1, Port: 80, ServiceName: "instance-1-80", ServicePort: 80, IngressName: &ingress, Protocol: "HTTP", Enabled: true,
+				Ingress: IngressOptions{Domain: "apps.example.net"}},
+			22: {InstanceID: 1, Port: 22, ServiceName: "instance-1-22", ServicePort: 22, Protocol: "TCP", Enabled: true},
+		}},
+		domains: map[string]CustomDomain{},
+	}
+	k8s := &fakeDomainK8sRepo{fakeEnsureK8sRepo: fakeEnsureK8sRepo{ingressDomain: "apps.example.net"}}
+	resolver := &fakeResolver{records: map[string][]string{}}
+	audit := &fakeAuditRepo{}
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{
+		1: {InstanceID: 1, UserID: "alice"},
+		2: {InstanceID: 2, UserID: "bob"},
+	}}
+	uc := NewResourceUsecase(repo, audit, k8s, network, nil, nil, nil, resolver, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	if _, err := uc.AddCustomDomain(ctx, 1, 22, "app.example.com"); !errors.Is(err, ErrCustomDomainNotSupported) {
+		t.Fatalf("err=%v want ErrCustomDomainNotSupported", err)
+	}
+	if _, err := uc.AddCustomDomain(ctx, 1, 80, "app.apps.example.net"); !errors.Is(err, ErrInvalidDomain) {
+		t.Fatalf("err=%v want ErrInvalidDomain for platform domain", err)
+	}
+
+	d, err := uc.AddCustomDomain(ctx, 1, 80, "App.Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Domain != "app.example.com" || d.Status != CustomDomainPending || d.TXTRecordName() != "_resource-challenge.app.example.com" || len(d.Token) != 32 {
+		t.Fatalf("domain=%+v", d)
+	}
+
+	// 同一端口重复添加返回原令牌，其他实例不能抢占
+	again, err := uc.AddCustomDomain(ctx, 1, 80, "app.example.com")
+	if err != nil || again.Token != d.Token {
+		t.Fatalf("again=%+v err=%v", again, err)
+	}
+	if _, err := uc.AddCustomDomain(ctx, 2, 80, "app.example.com"); !errors.Is(err, ErrDomainTaken) {
+		t.Fatalf("err=%v want ErrDomainTaken", err)
+	}
+
+	// 未添加 TXT 记录时验证失败，不创建 Ingress
+	if _, err := uc.VerifyCustomDomain(ctx, 1, "app.example.com"); !errors.Is(err, ErrDomainVerificationFailed) {
+		t.Fatalf("err=%v want ErrDomainVerificationFailed", err)
+	}
+	resolver.records[d.TXTRecordName()] = []string{"v=spf1 -all", d.TXTRecordValue()}
+	if _, err := uc.VerifyCustomDomain(ctx, 2, "app.example.com"); !errors.Is(err, ErrCustomDomainNotFound) {
+		t.Fatalf("err=%v want ErrCustomDomainNotFound", err)
+	}
+	verified, err := uc.VerifyCustomDomain(ctx, 1, "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Status != CustomDomainActive || verified.VerifiedAt == nil || len(k8s.exposed) != 1 {
+		t.Fatalf("verified=%+v exposed=%v", verified, k8s.exposed)
+	}
+	if last := audit.records[len(audit.records)-1]; last.LogType != "CUSTOM_DOMAIN_VERIFIED" {
+		t.Fatalf("audit=%+v", last)
+	}
+
+	// 禁用端口时删除域名 Ingress，重新启用后恢复
+	if _, err := uc.SetPortEnabled(ctx, 1, 80, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(k8s.unexposed) != 1 {
+		t.Fatalf("unexposed=%v", k8s.unexposed)
+	}
+	if _, err := uc.SetPortEnabled(ctx, 1, 80, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(k8s.exposed) != 2 {
+		t.Fatalf("exposed=%v", k8s.exposed)
+	}
+
+	if err := uc.DeleteCustomDomain(ctx, 1, "app.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(network.domains) != 0 || len(k8s.unexposed) != 2 {
+		t.Fatalf("domains=%v unexposed=%v", network.domains, k8s.unexposed)
+	}
+}
+
+func TestResourceUsecase_AddCustomDomainReplacesExpiredClaim(t *testing.T) {
+	ingress := "ingress-2-80"
+	network := &fakeDomainRepo{
+		fakeBindingRepo: fakeBindingRepo{bindings: map[uint32]NetworkBinding{
+			80: {InstanceID: 2, Port: 80, ServiceName: "instance-2-80", IngressName: &ingress, Protocol: "HTTP", Enabled: true},
+		}},
+		domains: map[string]CustomDomain{
+			"app.example.com": {Domain: "app.example.com", InstanceID: 1, Port: 80, Token: "old", Status: CustomDomainPending, CreatedAt: time.Now().Add(-8 * 24 * time.Hour)},
+		},
+	}
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{2: {InstanceID: 2, UserID: "bob"}}}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, &fakeDomainK8sRepo{}, network, nil, nil, nil, &fakeResolver{}, log.NewStdLogger(io.Discard))
+
+	d, err := uc.AddCustomDomain(context.Background(), 2, 80, "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.InstanceID != 2 || d.Token == "old" {
+		t.Fatalf("domain=%+v", d)
+	}
+}
+
+type fakeHTTPK8sRepo struct {
+	fakeDomainK8sRepo
+	hosts []string
+}
+
+func (f *fakeHTTPK8sRepo) CreateServiceForHTTP(_ context.Context, _, instanceID string, port uint32) (string, error) {
+	return "instance-" + instanceID + "-" + strconv.FormatUint(uint64(port), 10), nil
+}
+
+func (f *fakeHTTPK8sRepo) CreateIngress(_ context.Context, _, _ string, _ uint32, serviceName string, opts IngressOptions) (string, string, error) {
+	f.hosts = append(f.hosts, opts.Domain)
+	return serviceName, "http://" + opts.Domain, nil
+}
+
+type fakeHTTPDomainRepo struct {
+	fakeDomainRepo
+}
+
+func (f *fakeHTTPDomainRepo) CreateNetworkBinding(_ context.Context, binding NetworkBinding) error {
+	f.bindings[binding.Port] = binding
+	return nil
+}
+
+func TestResourceUsecase_SetInstancePortIngressDomain(t *testing.T) {
+	network := &fakeHTTPDomainRepo{fakeDomainRepo{
+		fakeBindingRepo: fakeBindingRepo{bindings: map[uint32]NetworkBinding{}},
+		domains: map[string]CustomDomain{
+			"app.example.com":     {Domain: "app.example.com", InstanceID: 1, Port: 80, Status: CustomDomainActive},
+			"pending.example.com": {Domain: "pending.example.com", InstanceID: 1, Port: 80, Status: CustomDomainPending},
+			"bob.example.com":     {Domain: "bob.example.com", InstanceID: 2, Port: 80, Status: CustomDomainActive},
+		},
+	}}
+	k8s := &fakeHTTPK8sRepo{fakeDomainK8sRepo: fakeDomainK8sRepo{fakeEnsureK8sRepo: fakeEnsureK8sRepo{ingressDomain: "apps.example.net"}}}
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, network, nil, nil, nil, &fakeResolver{}, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	// 未验证、其他实例的域名以及任意主机名都不能作为 Ingress 主机
+	for _, domain := range []string{"victim.example.org", "pending.example.com", "bob.example.com", "x.apps.example.net"} {
+		if _, err := uc.SetInstancePort(ctx, 1, 8080, "HTTP", "", true, IngressOptions{Domain: domain}); !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("domain=%s err=%v want ErrInvalidDomain", domain, err)
+		}
+	}
+	if len(k8s.hosts) != 0 {
+		t.Fatalf("ingress created for %v", k8s.hosts)
+	}
+
+	if _, err := uc.SetInstancePort(ctx, 1, 8080, "HTTP", "", true, IngressOptions{Domain: "Apps.Example.NET"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.SetInstancePort(ctx, 1, 8081, "HTTP", "", true, IngressOptions{Domain: "app.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(k8s.hosts) != 2 || k8s.hosts[0] != "apps.example.net" || network.bindings[8081].Ingress.Domain != "app.example.com" {
+		t.Fatalf("hosts=%v bindings=%+v", k8s.hosts, network.bindings)
+	}
+}
@@ -0,0 +1,261 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"resource/internal/conf"
+)
+
+// ErrExecSessionLimit 并发 exec 会话数超过上限
+var ErrExecSessionLimit = errors.New("exec session limit exceeded")
+
+// RunCommand 默认限制
+const (
+	defaultRunTimeout        = 30 * time.Second
+	defaultRunMaxTimeout     = 10 * time.Minute
+	defaultRunMaxOutputBytes = 1 << 20
+
+	defaultFileChunkSize    = 32 * 1024
+	defaultMaxTransferBytes = 1 << 30
+)
+
+// ExecLimiter 限制 exec 会话的空闲时间、最长持续时间和并发数
+type ExecLimiter struct {
+	idleTimeout    time.Duration
+	maxDuration    time.Duration
+	maxPerUser     int
+	maxPerInstance int
+
+	runTimeout        time.Duration
+	runMaxTimeout     time.Duration
+	runMaxOutputBytes int
+
+	maxUploadBytes   int64
+	maxDownloadBytes int64
+	fileChunkSize    int
+
+	// newTimer 创建空闲与最长持续时间计时器，测试时替换
+	newTimer func(time.Duration) execTimer
+
+	mu        sync.Mutex
+	users     map[string]int
+	instances map[int64]int
+}
+
+// execTimer 会话计时器
+type execTimer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// realTimer 基于 time.Timer 的计时器
+type realTimer struct{ t *time.Timer }
+
+func newRealTimer(d time.Duration) execTimer { return realTimer{t: time.NewTimer(d)} }
+
+func (r realTimer) C() <-chan time.Time { return r.t.C }
+
+func (r realTimer) Reset(d time.Duration) {
+	if !r.t.Stop() {
+		select {
+		case <-r.t.C:
+		default:
+		}
+	}
+	r.t.Reset(d)
+}
+
+func (r realTimer) Stop() { r.t.Stop() }
+
+// NewExecLimiter 根据配置创建 exec 会话限制。会话限制未配置时不限制，RunCommand 与文件传输未配置时使用默认值
+func NewExecLimiter(c *conf.Server) *ExecLimiter {
+	e := c.GetExec()
+	l := &ExecLimiter{
+		idleTimeout:       e.GetIdleTimeout().AsDuration(),
+		maxDuration:       e.GetMaxDuration().AsDuration(),
+		maxPerUser:        int(e.GetMaxSessionsPerUser()),
+		maxPerInstance:    int(e.GetMaxSessionsPerInstance()),
+		runTimeout:        defaultRunTimeout,
+		runMaxTimeout:     defaultRunMaxTimeout,
+		runMaxOutputBytes: defaultRunMaxOutputBytes,
+		maxUploadBytes:    defaultMaxTransferBytes,
+		maxDownloadBytes:  defaultMaxTransferBytes,
+		fileChunkSize:     defaultFileChunkSize,
+		newTimer:          newRealTimer,
+		users:             map[string]int{},
+		instances:         map[int64]int{},
+	}
+	if e.GetRunTimeout() != nil {
+		l.runTimeout = e.GetRunTimeout().AsDuration()
+	}
+	if e.GetRunMaxTimeout() != nil {
+		l.runMaxTimeout = e.GetRunMaxTimeout().AsDuration()
+	}
+	if e.GetRunMaxOutputBytes() > 0 {
+		l.runMaxOutputBytes = int(e.GetRunMaxOutputBytes())
+	}
+	if c.GetFileTransfer().GetMaxUploadBytes() > 0 {
+		l.maxUploadBytes = c.GetFileTransfer().GetMaxUploadBytes()
+	}
+	if c.GetFileTransfer().GetMaxDownloadBytes() > 0 {
+		l.maxDownloadBytes = c.GetFileTransfer().GetMaxDownloadBytes()
+	}
+	if c.GetFileTransfer().GetChunkSize() > 0 {
+		l.fileChunkSize = int(c.GetFileTransfer().GetChunkSize())
+	}
+	return l
+}
+
+// RunTimeout 返回 RunCommand 的实际超时：未指定时使用默认值，超过上限时取上限
+func (l *ExecLimiter) RunTimeout(requested time.Duration) time.Duration {
+	if requested <= 0 {
+		requested = l.runTimeout
+	}
+	if l.runMaxTimeout > 0 && requested > l.runMaxTimeout {
+		return l.runMaxTimeout
+	}
+	return requested
+}
+
+// RunMaxOutputBytes RunCommand stdout/stderr 各自的缓冲上限
+func (l *ExecLimiter) RunMaxOutputBytes() int {
+	return l.runMaxOutputBytes
+}
+
+// MaxUploadBytes 单次上传的大小上限
+func (l *ExecLimiter) MaxUploadBytes() int64 {
+	return l.maxUploadBytes
+}
+
+// MaxDownloadBytes 单次下载的大小上限
+func (l *ExecLimiter) MaxDownloadBytes() int64 {
+	return l.maxDownloadBytes
+}
+
+// FileChunkSize 下载时每条消息携带的最大字节数
+func (l *ExecLimiter) FileChunkSize() int {
+	return l.fileChunkSize
+}
+
+// ExecLease 一个已占用名额的 exec 会话
+type ExecLease struct {
+	limiter    *ExecLimiter
+	userID     string
+	instanceID int64
+
+	cancel   context.CancelFunc
+	activity chan struct{}
+	done     chan struct{}
+	once     sync.Once
+
+	mu     sync.Mutex
+	reason string
+}
+
+// Acquire 占用会话名额并开始计时。返回的 context 在空闲超时或超过最长持续时间时取消，
+// 取消原因通过 Reason 获取；会话结束后必须调用 Release。
+func (l *ExecLimiter) Acquire(ctx context.Context, userID string, instanceID int64) (*ExecLease, context.Context, error) {
+	l.mu.Lock()
+	if l.maxPerUser > 0 && l.users[userID] >= l.maxPerUser {
+		l.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w: user %s already has %d sessions", ErrExecSessionLimit, userID, l.maxPerUser)
+	}
+	if l.maxPerInstance > 0 && l.instances[instanceID] >= l.maxPerInstance {
+		l.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w: instance %d already has %d sessions", ErrExecSessionLimit, instanceID, l.maxPerInstance)
+	}
+	l.users[userID]++
+	l.instances[instanceID]++
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	lease := &ExecLease{
+		limiter:    l,
+		userID:     userID,
+		instanceID: instanceID,
+		cancel:     cancel,
+		activity:   make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	go lease.watch()
+
+	return lease, ctx, nil
+}
+
+// watch 空闲与最长持续时间计时
+func (e *ExecLease) watch() {
+	var idle, deadline <-chan time.Time
+	var idleTimer execTimer
+	if e.limiter.idleTimeout > 0 {
+		idleTimer = e.limiter.newTimer(e.limiter.idleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C()
+	}
+	if e.limiter.maxDuration > 0 {
+		maxTimer := e.limiter.newTimer(e.limiter.maxDuration)
+		defer maxTimer.Stop()
+		deadline = maxTimer.C()
+	}
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-e.activity:
+			if idleTimer != nil {
+				idleTimer.Reset(e.limiter.idleTimeout)
+			}
+		case <-idle:
+			e.terminate(fmt.Sprintf("session closed after %s without input", e.limiter.idleTimeout))
+			return
+		case <-deadline:
+			e.terminate(fmt.Sprintf("session exceeded max duration %s", e.limiter.maxDuration))
+			return
+		}
+	}
+}
+
+func (e *ExecLease) terminate(reason string) {
+	e.mu.Lock()
+	e.reason = reason
+	e.mu.Unlock()
+	e.cancel()
+}
+
+// Touch 记录一次客户端输入，重置空闲计时
+func (e *ExecLease) Touch() {
+	select {
+	case e.activity <- struct{}{}:
+	default:
+	}
+}
+
+// Reason 会话被限制终止的原因，未被终止时返回空字符串
+func (e *ExecLease) Reason() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.reason
+}
+
+// Release 释放会话名额，可重复调用
+func (e *ExecLease) Release() {
+	e.once.Do(func() {
+		close(e.done)
+		e.cancel()
+
+		l := e.limiter
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.users[e.userID]--; l.users[e.userID] <= 0 {
+			delete(l.users, e.userID)
+		}
+		if l.instances[e.instanceID]--; l.instances[e.instanceID] <= 0 {
+			delete(l.instances, e.instanceID)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"resource/internal/conf"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestExecLimiter_ConcurrentLimits(t *testing.T) {
+	l := NewExecLimiter(&conf.Server{Exec: &conf.Server_Exec{
+		MaxSessionsPerUser:     2,
+		MaxSessionsPerInstance: 1,
+	}})
+	ctx := context.Background()
+
+	a, _, err := l.Acquire(ctx, "alice", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := l.Acquire(ctx, "bob", 1); !errors.Is(err, ErrExecSessionLimit) {
+		t.Fatalf("err=%v want per-instance limit", err)
+	}
+	b, _, err := l.Acquire(ctx, "alice", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := l.Acquire(ctx, "alice", 3); !errors.Is(err, ErrExecSessionLimit) {
+		t.Fatalf("err=%v want per-user limit", err)
+	}
+
+	a.Release()
+	a.Release() // 重复释放不应重复计数
+	c, _, err := l.Acquire(ctx, "alice", 1)
+	if err != nil {
+		t.Fatalf("err=%v want nil after release", err)
+	}
+	b.Release()
+	c.Release()
+	if len(l.users) != 0 || len(l.instances) != 0 {
+		t.Fatalf("users=%v instances=%v want empty", l.users, l.instances)
+	}
+}
+
+func TestNewExecLimiter_FileTransferDefaults(t *testing.T) {
+	l := NewExecLimiter(&conf.Server{})
+	if l.MaxUploadBytes() != 1<<30 || l.MaxDownloadBytes() != 1<<30 {
+		t.Fatalf("upload=%d download=%d want 1GiB", l.MaxUploadBytes(), l.MaxDownloadBytes())
+	}
+
+	l = NewExecLimiter(&conf.Server{FileTransfer: &conf.Server_FileTransfer{MaxUploadBytes: 1024}})
+	if l.MaxUploadBytes() != 1024 || l.MaxDownloadBytes() != 1<<30 {
+		t.Fatalf("upload=%d download=%d", l.MaxUploadBytes(), l.MaxDownloadBytes())
+	}
+}
+
+// fakeTimer 由测试手动触发的计时器
+type fakeTimer struct {
+	d      time.Duration
+	c      chan time.Time
+	resets chan time.Duration
+}
+
+func (f *fakeTimer) C() <-chan time.Time   { return f.c }
+func (f *fakeTimer) Reset(d time.Duration) { f.resets <- d }
+func (f *fakeTimer) Stop()                 {}
+
+// fakeClock 按创建顺序交出计时器
+type fakeClock struct {
+	timers chan *fakeTimer
+}
+
+func newFakeClock(l *ExecLimiter) *fakeClock {
+	clock := &fakeClock{timers: make(chan *fakeTimer, 2)}
+	l.newTimer = func(d time.Duration) execTimer {
+		t := &fakeTimer{d: d, c: make(chan time.Time, 1), resets: make(chan time.Duration, 4)}
+		clock.timers <- t
+		return t
+	}
+	return clock
+}
+
+func (c *fakeClock) next(t *testing.T) *fakeTimer {
+	t.Helper()
+	select {
+	case timer := <-c.timers:
+		return timer
+	case <-time.After(5 * time.Second):
+		t.Fatal("timer was not created")
+		return nil
+	}
+}
+
+func waitDone(t *testing.T, ctx context.Context) {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("session was not closed")
+	}
+}
+
+func TestExecLimiter_IdleTimeout(t *testing.T) {
+	l := NewExecLimiter(&conf.Server{Exec: &conf.Server_Exec{
+		IdleTimeout: durationpb.New(time.Minute),
+		MaxDuration: durationpb.New(time.Hour),
+	}})
+	clock := newFakeClock(l)
+
+	lease, ctx, err := l.Acquire(context.Background(), "alice", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+
+	idle, deadline := clock.next(t), clock.next(t)
+	if idle.d != time.Minute || deadline.d != time.Hour {
+		t.Fatalf("idle=%s deadline=%s", idle.d, deadline.d)
+	}
+
+	// 输入重置空闲计时
+	lease.Touch()
+	select {
+	case d := <-idle.resets:
+		if d != time.Minute {
+			t.Fatalf("reset=%s want 1m", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle timer was not reset on input")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("session should stay open while receiving input")
+	}
+
+	idle.c <- time.Now()
+	waitDone(t, ctx)
+	if !strings.Contains(lease.Reason(), "without input") {
+		t.Fatalf("reason=%q want idle timeout", lease.Reason())
+	}
+}
+
+func TestExecLimiter_MaxDuration(t *testing.T) {
+	l := NewExecLimiter(&conf.Server{Exec: &conf.Server_Exec{
+		MaxDuration: durationpb.New(time.Hour),
+	}})
+	clock := newFakeClock(l)
+
+	lease, ctx, err := l.Acquire(context.Background(), "alice", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+
+	clock.next(t).c <- time.Now()
+	waitDone(t, ctx)
+	if !strings.Contains(lease.Reason(), "max duration") {
+		t.Fatalf("reason=%q want max duration", lease.Reason())
+	}
+}
@@ -0,0 +1,104 @@
+package biz
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"resource/internal/conf"
+)
+
+// ExecRule 命令匹配规则，Prefix 与 Regex 同时配置时需同时满足
+type ExecRule struct {
+	Prefix []string       // argv 前缀，逐个参数完全匹配
+	Regex  *regexp.Regexp // 匹配以空格拼接的完整命令
+}
+
+// matches 判断命令是否命中规则
+func (r ExecRule) matches(command []string) bool {
+	if len(r.Prefix) > len(command) {
+		return false
+	}
+	for i, arg := range r.Prefix {
+		if command[i] != arg {
+			return false
+		}
+	}
+	if r.Regex != nil && !r.Regex.MatchString(strings.Join(command, " ")) {
+		return false
+	}
+	return true
+}
+
+func (r ExecRule) String() string {
+	if r.Regex != nil {
+		return fmt.Sprintf("prefix=%q regex=%q", r.Prefix, r.Regex.String())
+	}
+	return fmt.Sprintf("prefix=%q", r.Prefix)
+}
+
+// ExecPolicy exec 命令策略
+type ExecPolicy struct {
+	Allow   []ExecRule // 非空时命令必须命中其中一条
+	Deny    []ExecRule // 命中任意一条即拒绝，优先于 Allow
+	DenyTTY bool       // 禁止交互式 TTY
+}
+
+// check 校验命令，违反策略时返回拒绝原因
+func (p *ExecPolicy) check(command []string, tty bool) (string, bool) {
+	if p == nil {
+		return "", true
+	}
+	if tty && p.DenyTTY {
+		return "interactive tty is not allowed", false
+	}
+	for _, rule := range p.Deny {
+		if rule.matches(command) {
+			return "command matches deny rule " + rule.String(), false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return "", true
+	}
+	for _, rule := range p.Allow {
+		if rule.matches(command) {
+			return "", true
+		}
+	}
+	return "command is not in allow list", false
+}
+
+// newExecPolicy 解析配置中的 exec 策略，未配置时返回 nil
+func newExecPolicy(c *conf.Auth_ExecPolicy) (*ExecPolicy, error) {
+	if c == nil {
+		return nil, nil
+	}
+	allow, err := newExecRules(c.GetAllow())
+	if err != nil {
+		return nil, err
+	}
+	deny, err := newExecRules(c.GetDeny())
+	if err != nil {
+		return nil, err
+	}
+	return &ExecPolicy{Allow: allow, Deny: deny, DenyTTY: c.GetDenyTty()}, nil
+}
+
+func newExecRules(rules []*conf.Auth_ExecRule) ([]ExecRule, error) {
+	out := make([]ExecRule, 0, len(rules))
+	for _, r := range rules {
+		rule := ExecRule{Prefix: r.GetPrefix()}
+		if r.GetRegex() != "" {
+			re, err := regexp.Compile(r.GetRegex())
+			if err != nil {
+				return nil, fmt.Errorf("invalid exec rule regex %q: %w", r.GetRegex(), err)
+			}
+			rule.Regex = re
+		}
+		if len(rule.Prefix) == 0 && rule.Regex == nil {
+			return nil, fmt.Errorf("exec rule requires prefix or regex")
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
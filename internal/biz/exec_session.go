@@ -0,0 +1,182 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrExecSessionNotFound exec 会话不存在
+var ErrExecSessionNotFound = errors.New("exec session not found")
+
+// 录像默认终端大小，实际大小以 resize 事件为准
+const (
+	defaultTerminalWidth  = 80
+	defaultTerminalHeight = 24
+)
+
+// ExecSession exec 会话元数据
+type ExecSession struct {
+	SessionID     string
+	InstanceID    int64
+	UserID        string // 发起 exec 的用户
+	ContainerName string
+	Command       []string
+	TTY           bool
+	ExitCode      *int32 // 会话结束后写入
+	StartedAt     time.Time
+	EndedAt       *time.Time // 会话结束后写入
+}
+
+// ExecSessionRepo exec 会话元数据仓储接口
+type ExecSessionRepo interface {
+	CreateExecSession(ctx context.Context, session ExecSession) error
+	// FinishExecSession 记录会话结束时间与退出码
+	FinishExecSession(ctx context.Context, sessionID string, exitCode int32, endedAt time.Time) error
+	// GetExecSession 返回 nil 表示未找到
+	GetExecSession(ctx context.Context, sessionID string) (*ExecSession, error)
+	ListExecSessions(ctx context.Context, instanceID int64) ([]ExecSession, error)
+}
+
+// ExecRecordingStore exec 会话录像存储（asciicast v2），可替换为对象存储等实现
+type ExecRecordingStore interface {
+	// Create 创建录像，返回写入器
+	Create(ctx context.Context, sessionID string) (io.WriteCloser, error)
+	// Open 打开录像
+	Open(ctx context.Context, sessionID string) (io.ReadCloser, error)
+	// Delete 删除录像，录像不存在时不返回错误
+	Delete(ctx context.Context, sessionID string) error
+}
+
+// recordExec 为 exec 会话创建元数据、审计记录与录像，并在输入输出通道之间插入录像。
+// 返回的通道交给 ExecRepo 使用，finish 在 ExecRepo 返回后调用，等待录像写完并落库退出码。
+func (uc *ResourceUsecase) recordExec(ctx context.Context, opts ExecOptions, instanceID int64, input <-chan ExecInput, output chan<- ExecOutput) (<-chan ExecInput, chan<- ExecOutput, func(), error) {
+	p, _ := PrincipalFromContext(ctx)
+	session := ExecSession{
+		SessionID:     uuid.NewString(),
+		InstanceID:    instanceID,
+		UserID:        p.UserID,
+		ContainerName: opts.ContainerName,
+		Command:       opts.Command,
+		TTY:           opts.TTY,
+		StartedAt:     time.Now(),
+	}
+
+	w, err := uc.RecordingStore.Create(ctx, session.SessionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rec, err := newAsciicastRecorder(w, defaultTerminalWidth, defaultTerminalHeight, opts.Command, session.StartedAt)
+	if err != nil {
+		uc.deleteRecording(ctx, session.SessionID)
+		return nil, nil, nil, err
+	}
+	if err := uc.ExecSessionRepo.CreateExecSession(ctx, session); err != nil {
+		_ = rec.Close()
+		uc.deleteRecording(ctx, session.SessionID)
+		return nil, nil, nil, err
+	}
+
+	// 记录审计日志，关联录像会话
+	data, _ := json.Marshal(map[string]interface{}{
+		"session_id": session.SessionID,
+		"user_id":    session.UserID,
+		"command":    session.Command,
+		"tty":        session.TTY,
+	})
+	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
+		InstanceID: instanceID,
+		LogType:    "EXEC",
+		Message:    "Exec session " + session.SessionID + " started",
+		DataJson:   data,
+		CreatedAt:  session.StartedAt,
+	})
+
+	recInput := make(chan ExecInput, cap(input))
+	recOutput := make(chan ExecOutput, cap(output))
+
+	go func() {
+		defer close(recInput)
+		for in := range input {
+			switch in.Type {
+			case ExecInputStdin:
+				rec.Input(in.Data)
+			case ExecInputResize:
+				rec.Resize(in.Cols, in.Rows)
+			}
+			select {
+			case recInput <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	exitCode := ExecExitCodeUnknown
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for out := range recOutput {
+			switch out.Type {
+			case ExecOutputData:
+				rec.Output(out.Data)
+			case ExecOutputExit:
+				exitCode = out.ExitCode
+			}
+			output <- out
+		}
+	}()
+
+	finish := func() {
+		close(recOutput)
+		<-done
+		if err := rec.Close(); err != nil {
+			uc.log.WithContext(ctx).Errorf("failed to write exec recording %s: %v", session.SessionID, err)
+		}
+		// 客户端可能已断开，使用独立 context 落库
+		if err := uc.ExecSessionRepo.FinishExecSession(context.WithoutCancel(ctx), session.SessionID, exitCode, time.Now()); err != nil {
+			uc.log.WithContext(ctx).Errorf("failed to finish exec session %s: %v", session.SessionID, err)
+		}
+	}
+
+	return recInput, recOutput, finish, nil
+}
+
+// deleteRecording 删除没有对应会话记录的录像
+func (uc *ResourceUsecase) deleteRecording(ctx context.Context, sessionID string) {
+	if err := uc.RecordingStore.Delete(context.WithoutCancel(ctx), sessionID); err != nil {
+		uc.log.WithContext(ctx).Warnf("failed to delete exec recording %s: %v", sessionID, err)
+	}
+}
+
+// ListExecSessions 列出实例的 exec 会话
+func (uc *ResourceUsecase) ListExecSessions(ctx context.Context, instanceID int64) ([]ExecSession, error) {
+	return uc.ExecSessionRepo.ListExecSessions(ctx, instanceID)
+}
+
+// GetExecSession 返回 exec 会话元数据及其 asciicast v2 录像
+func (uc *ResourceUsecase) GetExecSession(ctx context.Context, sessionID string) (*ExecSession, []byte, error) {
+	session, err := uc.ExecSessionRepo.GetExecSession(ctx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session == nil {
+		return nil, nil, ErrExecSessionNotFound
+	}
+
+	r, err := uc.RecordingStore.Open(ctx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+
+	recording, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, recording, nil
+}
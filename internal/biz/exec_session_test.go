@@ -0,0 +1,228 @@
+package biz
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// memRecordingStore 在内存中保存录像
+type memRecordingStore struct {
+	recordings map[string]*nopWriteCloser
+	deleted    []string
+}
+
+func (s *memRecordingStore) Create(_ context.Context, sessionID string) (io.WriteCloser, error) {
+	w := &nopWriteCloser{}
+	s.recordings[sessionID] = w
+	return w, nil
+}
+
+func (s *memRecordingStore) Open(_ context.Context, sessionID string) (io.ReadCloser, error) {
+	w, ok := s.recordings[sessionID]
+	if !ok {
+		return nil, errors.New("recording not found")
+	}
+	return io.NopCloser(bytes.NewReader(w.Bytes())), nil
+}
+
+func (s *memRecordingStore) Delete(_ context.Context, sessionID string) error {
+	delete(s.recordings, sessionID)
+	s.deleted = append(s.deleted, sessionID)
+	return nil
+}
+
+// memExecSessionRepo 记录会话的创建与结束
+type memExecSessionRepo struct {
+	ExecSessionRepo
+	sessions  map[string]*ExecSession
+	createErr error
+}
+
+func (r *memExecSessionRepo) CreateExecSession(_ context.Context, session ExecSession) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	r.sessions[session.SessionID] = &session
+	return nil
+}
+
+func (r *memExecSessionRepo) FinishExecSession(_ context.Context, sessionID string, exitCode int32, endedAt time.Time) error {
+	s, ok := r.sessions[sessionID]
+	if !ok {
+		return errors.New("session not found")
+	}
+	s.ExitCode = &exitCode
+	s.EndedAt = &endedAt
+	return nil
+}
+
+func (r *memExecSessionRepo) GetExecSession(_ context.Context, sessionID string) (*ExecSession, error) {
+	return r.sessions[sessionID], nil
+}
+
+func TestAsciicastRecorder(t *testing.T) {
+	var buf nopWriteCloser
+	start := time.Unix(1700000000, 0)
+	rec, err := newAsciicastRecorder(&buf, 80, 24, []string{"sh", "-c", "ls"}, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Input([]byte("ls\r"))
+	rec.Output([]byte("a.txt\r\n"))
+	rec.Resize(120, 40)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 关闭后的事件被忽略
+	rec.Output([]byte("ignored"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("lines=%q", lines)
+	}
+
+	var header asciicastHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 || header.Timestamp != start.Unix() || header.Command != "sh -c ls" {
+		t.Fatalf("header=%+v", header)
+	}
+
+	want := [][2]string{{"i", "ls\r"}, {"o", "a.txt\r\n"}, {"r", "120x40"}}
+	for i, line := range lines[1:] {
+		var event []interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		if len(event) != 3 {
+			t.Fatalf("event=%v", event)
+		}
+		if _, ok := event[0].(float64); !ok {
+			t.Fatalf("event time=%v", event[0])
+		}
+		if event[1] != want[i][0] || event[2] != want[i][1] {
+			t.Fatalf("event %d=%v want %v", i, event, want[i])
+		}
+	}
+}
+
+// failingWriteCloser 写入总是失败
+type failingWriteCloser struct{ closed bool }
+
+func (*failingWriteCloser) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (w *failingWriteCloser) Close() error            { w.closed = true; return nil }
+
+func TestAsciicastRecorder_HeaderWriteError(t *testing.T) {
+	w := &failingWriteCloser{}
+	if _, err := newAsciicastRecorder(w, 80, 24, nil, time.Now()); err == nil {
+		t.Fatal("expected header write error")
+	}
+	if !w.closed {
+		t.Fatal("writer should be closed")
+	}
+}
+
+func TestResourceUsecase_RecordExec(t *testing.T) {
+	store := &memRecordingStore{recordings: map[string]*nopWriteCloser{}}
+	sessions := &memExecSessionRepo{sessions: map[string]*ExecSession{}}
+	audit := &fakeAuditRepo{}
+	uc := &ResourceUsecase{
+		AuditRepo:       audit,
+		ExecRepo:        &fakeExecRepo{exitCode: 2},
+		ExecSessionRepo: sessions,
+		RecordingStore:  store,
+		log:             log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+	ctx := NewPrincipalContext(context.Background(), Principal{UserID: "alice"})
+
+	input := make(chan ExecInput, 2)
+	output := make(chan ExecOutput, 8)
+	input <- ExecInput{Type: ExecInputStdin, Data: []byte("hello")}
+	input <- ExecInput{Type: ExecInputResize, Cols: 100, Rows: 30}
+	close(input)
+
+	opts := ExecOptions{Command: []string{"cat"}, ContainerName: "main"}
+	recInput, recOutput, finish, err := uc.recordExec(ctx, opts, 1, input, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = uc.ExecRepo.StreamExec(ctx, opts, recInput, recOutput)
+	finish()
+	close(output)
+
+	// 输出原样转发给调用方
+	var forwarded []ExecOutput
+	for out := range output {
+		forwarded = append(forwarded, out)
+	}
+	if len(forwarded) != 4 || string(forwarded[0].Data) != "hello" || forwarded[3].Type != ExecOutputExit {
+		t.Fatalf("forwarded=%+v", forwarded)
+	}
+
+	if len(sessions.sessions) != 1 {
+		t.Fatalf("sessions=%v", sessions.sessions)
+	}
+	var session *ExecSession
+	for _, s := range sessions.sessions {
+		session = s
+	}
+	if session.UserID != "alice" || session.InstanceID != 1 || session.ContainerName != "main" {
+		t.Fatalf("session=%+v", session)
+	}
+	if session.ExitCode == nil || *session.ExitCode != 2 || session.EndedAt == nil {
+		t.Fatalf("session not finished: %+v", session)
+	}
+	if len(audit.records) != 1 || audit.records[0].LogType != "EXEC" {
+		t.Fatalf("audit=%+v", audit.records)
+	}
+
+	got, recording, err := uc.GetExecSession(ctx, session.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SessionID != session.SessionID {
+		t.Fatalf("session=%+v", got)
+	}
+	var codes []string
+	scanner := bufio.NewScanner(bytes.NewReader(recording))
+	scanner.Scan() // header
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, event[1].(string))
+	}
+	// fakeExecRepo 对每条输入回显一次，再输出 stderr
+	if strings.Join(codes, ",") != "i,r,o,o,o" {
+		t.Fatalf("recorded events=%v", codes)
+	}
+}
+
+func TestResourceUsecase_RecordExecCreateSessionFailure(t *testing.T) {
+	store := &memRecordingStore{recordings: map[string]*nopWriteCloser{}}
+	uc := &ResourceUsecase{
+		AuditRepo:       &fakeAuditRepo{},
+		ExecSessionRepo: &memExecSessionRepo{sessions: map[string]*ExecSession{}, createErr: errors.New("db down")},
+		RecordingStore:  store,
+		log:             log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+
+	_, _, _, err := uc.recordExec(context.Background(), ExecOptions{Command: []string{"sh"}}, 1, make(chan ExecInput), make(chan ExecOutput))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(store.recordings) != 0 || len(store.deleted) != 1 {
+		t.Fatalf("recording not deleted: recordings=%v deleted=%v", store.recordings, store.deleted)
+	}
+}
@@ -0,0 +1,438 @@
+package biz
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrFileTooLarge 传输内容超过大小上限
+	ErrFileTooLarge = errors.New("file exceeds size limit")
+	// ErrIsDirectory 非归档模式下载目录
+	ErrIsDirectory = errors.New("path is a directory, use archive mode")
+	// ErrInvalidPath 容器内路径必须为绝对路径
+	ErrInvalidPath = errors.New("path must be an absolute path")
+	// ErrUploadSizeMismatch 非归档上传的内容长度与声明的 size 不一致
+	ErrUploadSizeMismatch = errors.New("upload length does not match size")
+)
+
+// 文件传输默认值
+const (
+	defaultFileMode      = 0o644
+	fileTransferChunk    = 32 * 1024
+	maxTarStderrCapture  = 4096
+	fileTransferLogType  = "FILE_TRANSFER"
+	fileTransferUpload   = "upload"
+	fileTransferDownload = "download"
+)
+
+// FileTransferError 容器内 tar 命令执行失败
+type FileTransferError struct {
+	ExitCode int32
+	Message  string
+}
+
+func (e *FileTransferError) Error() string {
+	return fmt.Sprintf("file transfer failed (exit code %d): %s", e.ExitCode, e.Message)
+}
+
+// FileTransferOptions 文件传输选项
+type FileTransferOptions struct {
+	Path          string // 容器内绝对路径
+	Archive       bool   // 内容为 tar 归档（目录）
+	Size          int64  // 上传文件大小，非归档上传时必填
+	Mode          uint32 // 上传文件权限，默认 0644
+	PodName       string
+	ContainerName string
+	MaxBytes      int64 // 传输大小上限，0 表示不限制
+}
+
+// FileInfo 下载文件信息
+type FileInfo struct {
+	Name    string
+	Size    int64 // 归档模式下未知，为 0
+	Mode    uint32
+	Archive bool
+}
+
+// UploadFile 通过 exec + tar 将 src 写入容器。
+// 非归档模式下 src 为单个文件内容，写入 opts.Path；归档模式下 src 为 tar 归档，解压到 opts.Path 目录。
+// progress 在每次读取后以累计字节数回调。
+func (uc *ResourceUsecase) UploadFile(ctx context.Context, namespace string, instanceID int64, opts FileTransferOptions, src io.Reader, progress func(int64)) (int64, error) {
+	target, err := cleanContainerPath(opts.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	counter := &countingReader{r: src, limit: opts.MaxBytes, progress: progress}
+	var stdin io.ReadCloser
+	if !opts.Archive {
+		if opts.Size < 0 {
+			return 0, errors.New("size is required")
+		}
+		if opts.MaxBytes > 0 && opts.Size > opts.MaxBytes {
+			return 0, ErrFileTooLarge
+		}
+		stdin = tarSingleFile(counter, path.Base(target), opts.Size, opts.Mode)
+	} else {
+		stdin = pipeReader(counter)
+	}
+
+	// execTar 在 exec 结束后关闭 stdin；读取 src 的协程可能仍阻塞在客户端流上，计数在锁内读取
+	err = uc.execTar(ctx, namespace, instanceID, opts, uploadCommand(target, opts.Archive), stdin, io.Discard)
+	n, readErr := counter.result()
+	if readErr != nil {
+		err = readErr
+	}
+	uc.auditFileTransfer(ctx, instanceID, fileTransferUpload, target, n, err)
+	return n, err
+}
+
+// DownloadFile 通过 exec + tar 从容器读取文件写入 dst。
+// 非归档模式下只允许下载单个普通文件，写入文件内容；归档模式下写入 tar 归档。
+// 写入内容前回调 info。
+func (uc *ResourceUsecase) DownloadFile(ctx context.Context, namespace string, instanceID int64, opts FileTransferOptions, info func(FileInfo) error, dst io.Writer) (int64, error) {
+	target, err := cleanContainerPath(opts.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	limited := &limitedWriter{w: dst, limit: opts.MaxBytes}
+	command := downloadCommand(target)
+
+	if opts.Archive {
+		if err := info(FileInfo{Name: path.Base(target) + ".tar", Archive: true}); err != nil {
+			return 0, err
+		}
+		err = uc.execTar(ctx, namespace, instanceID, opts, command, nil, limited)
+		uc.auditFileTransfer(ctx, instanceID, fileTransferDownload, target, limited.n, err)
+		return limited.n, err
+	}
+
+	// 非归档模式：从 tar 流中取出单个文件
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pr, pw := io.Pipe()
+	execErr := make(chan error, 1)
+	go func() {
+		err := uc.execTar(ctx, namespace, instanceID, opts, command, nil, pw)
+		_ = pw.CloseWithError(err)
+		execErr <- err
+	}()
+
+	err = copySingleFile(tar.NewReader(pr), opts.MaxBytes, info, limited)
+	if err != nil {
+		// 停止 exec 并等待退出，tar 自身的错误优先返回
+		_ = pr.CloseWithError(err)
+		cancel()
+		if e := <-execErr; e != nil && !errors.Is(e, io.ErrClosedPipe) && !errors.Is(e, err) && ctx.Err() == nil {
+			err = e
+		}
+	} else {
+		// 丢弃归档结尾，等待 tar 正常退出
+		_, _ = io.Copy(io.Discard, pr)
+		err = <-execErr
+	}
+
+	uc.auditFileTransfer(ctx, instanceID, fileTransferDownload, target, limited.n, err)
+	return limited.n, err
+}
+
+// UploadCommand 上传时在容器内执行的命令，用于在传输前按 exec 命令策略授权
+func (opts FileTransferOptions) UploadCommand() ([]string, error) {
+	target, err := cleanContainerPath(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	return uploadCommand(target, opts.Archive), nil
+}
+
+// DownloadCommand 下载时在容器内执行的命令，用于在传输前按 exec 命令策略授权
+func (opts FileTransferOptions) DownloadCommand() ([]string, error) {
+	target, err := cleanContainerPath(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	return downloadCommand(target), nil
+}
+
+// uploadCommand 与 kubectl cp 一致依赖容器内的 tar，目标目录不存在时创建。
+// 非归档模式下 target 为文件，解压到其所在目录。
+func uploadCommand(target string, archive bool) []string {
+	dir := target
+	if !archive {
+		dir = path.Dir(target)
+	}
+	return []string{"sh", "-c", `mkdir -p "$1" && exec tar -xmf - -C "$1"`, "sh", dir}
+}
+
+func downloadCommand(target string) []string {
+	return []string{"tar", "cf", "-", "-C", path.Dir(target), path.Base(target)}
+}
+
+// copySingleFile 读取 tar 中的第一个条目，要求为普通文件
+func copySingleFile(tr *tar.Reader, maxBytes int64, info func(FileInfo) error, dst io.Writer) error {
+	hdr, err := tr.Next()
+	if err != nil {
+		return err
+	}
+	switch hdr.Typeflag {
+	case tar.TypeReg:
+	case tar.TypeDir:
+		return ErrIsDirectory
+	default:
+		return fmt.Errorf("unsupported file type %q", string(hdr.Typeflag))
+	}
+	if maxBytes > 0 && hdr.Size > maxBytes {
+		return ErrFileTooLarge
+	}
+
+	if err := info(FileInfo{Name: path.Base(hdr.Name), Size: hdr.Size, Mode: uint32(hdr.Mode)}); err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, tr)
+	return err
+}
+
+// execTar 直接调用 ExecRepo 执行 tar 命令（不录像，二进制内容不适合终端录像）。
+// stdin 为 nil 时不发送输入，否则在 exec 结束后关闭，使阻塞在读取上的输入协程退出；stdout 写入失败时终止 exec。
+func (uc *ResourceUsecase) execTar(ctx context.Context, namespace string, instanceID int64, opts FileTransferOptions, command []string, stdin io.ReadCloser, stdout io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	input := make(chan ExecInput, 4)
+	var inputErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(input)
+		if stdin == nil {
+			return
+		}
+		buf := make([]byte, fileTransferChunk)
+		for {
+			n, err := stdin.Read(buf)
+			if n > 0 {
+				data := make([]byte, n)
+				copy(data, buf[:n])
+				select {
+				case input <- ExecInput{Type: ExecInputStdin, Data: data}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				// exec 结束后关闭 stdin 导致的 ErrClosedPipe 不是输入错误
+				if err != io.EOF && !errors.Is(err, io.ErrClosedPipe) {
+					inputErr = err
+					cancel()
+				}
+				return
+			}
+		}
+	}()
+
+	var (
+		outputErr error
+		execErr   *FileTransferError
+		stderr    bytes.Buffer
+		exitCode  = ExecExitCodeUnknown
+	)
+	output := make(chan ExecOutput, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for out := range output {
+			switch out.Type {
+			case ExecOutputData:
+				if out.Stream == "stderr" {
+					if stderr.Len() < maxTarStderrCapture {
+						stderr.Write(out.Data)
+					}
+					continue
+				}
+				if outputErr != nil {
+					continue
+				}
+				if _, err := stdout.Write(out.Data); err != nil {
+					outputErr = err
+					cancel()
+				}
+			case ExecOutputError:
+				execErr = &FileTransferError{ExitCode: ExecExitCodeUnknown, Message: string(out.Data)}
+			case ExecOutputExit:
+				exitCode = out.ExitCode
+			}
+		}
+	}()
+
+	_ = uc.ExecRepo.StreamExec(ctx, ExecOptions{
+		Namespace:     namespace,
+		InstanceID:    fmt.Sprint(instanceID),
+		PodName:       opts.PodName,
+		ContainerName: opts.ContainerName,
+		Command:       command,
+	}, input, output)
+	close(output)
+	<-done
+	cancel()
+	if stdin != nil {
+		_ = stdin.Close()
+	}
+	wg.Wait()
+
+	switch {
+	case inputErr != nil:
+		return inputErr
+	case outputErr != nil:
+		return outputErr
+	case execErr != nil:
+		return execErr
+	case exitCode != 0:
+		return &FileTransferError{ExitCode: exitCode, Message: strings.TrimSpace(stderr.String())}
+	}
+	return nil
+}
+
+func (uc *ResourceUsecase) auditFileTransfer(ctx context.Context, instanceID int64, direction, target string, n int64, err error) {
+	p, _ := PrincipalFromContext(ctx)
+	payload := map[string]interface{}{
+		"direction": direction,
+		"path":      target,
+		"bytes":     n,
+		"user_id":   p.UserID,
+	}
+	if err != nil {
+		payload["error"] = err.Error()
+	}
+	data, _ := json.Marshal(payload)
+	_ = uc.AuditRepo.CreateAudit(context.WithoutCancel(ctx), AuditInformation{
+		InstanceID: instanceID,
+		LogType:    fileTransferLogType,
+		Message:    fmt.Sprintf("File %s %s", direction, target),
+		DataJson:   data,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// cleanContainerPath 规范化容器内路径，拒绝相对路径与根目录
+func cleanContainerPath(p string) (string, error) {
+	if !path.IsAbs(p) {
+		return "", ErrInvalidPath
+	}
+	cleaned := path.Clean(p)
+	if cleaned == "/" {
+		return "", ErrInvalidPath
+	}
+	return cleaned, nil
+}
+
+// tarSingleFile 将单个文件内容封装为 tar 流，内容长度与 size 不一致时以 ErrUploadSizeMismatch 结束
+func tarSingleFile(src io.Reader, name string, size int64, mode uint32) *io.PipeReader {
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     size,
+			Mode:     int64(mode),
+			ModTime:  time.Now(),
+		})
+		if err == nil {
+			var n int64
+			n, err = io.CopyN(tw, src, size)
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("%w: upload ended after %d of %d bytes", ErrUploadSizeMismatch, n, size)
+			}
+		}
+		if err == nil {
+			// 超出 size 的内容不能静默丢弃，未填写 size（为 0）却发送了内容同样报错
+			var extra int64
+			extra, err = io.Copy(io.Discard, io.LimitReader(src, 1))
+			if err == nil && extra > 0 {
+				err = fmt.Errorf("%w: upload exceeds %d bytes", ErrUploadSizeMismatch, size)
+			}
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// pipeReader 在独立协程中读取 src，关闭返回的 PipeReader 即可让读取方退出，不必等待 src 返回
+func pipeReader(src io.Reader) *io.PipeReader {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := io.Copy(pw, src)
+		_ = pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// countingReader 统计读取字节数并检查大小上限。
+// Read 在封装协程中调用，可能与 result 并发，计数由 mu 保护
+type countingReader struct {
+	r        io.Reader
+	limit    int64
+	progress func(int64)
+
+	mu  sync.Mutex
+	n   int64
+	err error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.mu.Lock()
+	c.n += int64(n)
+	total := c.n
+	if c.limit > 0 && total > c.limit {
+		c.err = ErrFileTooLarge
+		c.mu.Unlock()
+		return n, ErrFileTooLarge
+	}
+	c.mu.Unlock()
+	if n > 0 && c.progress != nil {
+		c.progress(total)
+	}
+	return n, err
+}
+
+// result 返回已读取的字节数与超出上限的错误
+func (c *countingReader) result() (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n, c.err
+}
+
+// limitedWriter 统计写入字节数并检查大小上限
+type limitedWriter struct {
+	w     io.Writer
+	n     int64
+	limit int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.limit > 0 && l.n+int64(len(p)) > l.limit {
+		return 0, ErrFileTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,211 @@
+package biz
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTarExecRepo 记录命令与标准输入，并输出预置的 stdout
+type fakeTarExecRepo struct {
+	ExecRepo
+	command []string
+	stdin   bytes.Buffer
+	stdout  []byte
+}
+
+func (f *fakeTarExecRepo) StreamExec(_ context.Context, opts ExecOptions, input <-chan ExecInput, output chan<- ExecOutput) error {
+	f.command = opts.Command
+	for in := range input {
+		f.stdin.Write(in.Data)
+	}
+	if len(f.stdout) > 0 {
+		output <- ExecOutput{Type: ExecOutputData, Stream: "stdout", Data: f.stdout}
+	}
+	output <- ExecOutput{Type: ExecOutputExit, ExitCode: 0}
+	return nil
+}
+
+func buildTar(t *testing.T, hdr *tar.Header, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResourceUsecase_UploadFile(t *testing.T) {
+	repo := &fakeTarExecRepo{}
+	uc := newTestExecUsecase(repo)
+
+	var progressed int64
+	n, err := uc.UploadFile(context.Background(), "alice", 1, FileTransferOptions{
+		Path: "/data/../app/config.yaml",
+		Size: 5,
+	}, bytes.NewReader([]byte("hello")), func(n int64) { progressed = n })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || progressed != 5 {
+		t.Fatalf("bytes=%d progress=%d want 5", n, progressed)
+	}
+	if dir := repo.command[len(repo.command)-1]; dir != "/app" {
+		t.Fatalf("extract dir=%q want /app", dir)
+	}
+	// 授权的命令必须与实际执行的一致
+	if command, _ := (FileTransferOptions{Path: "/data/../app/config.yaml"}).UploadCommand(); strings.Join(command, " ") != strings.Join(repo.command, " ") {
+		t.Fatalf("authorized command=%q executed=%q", command, repo.command)
+	}
+
+	tr := tar.NewReader(&repo.stdin)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(tr)
+	if hdr.Name != "config.yaml" || hdr.Mode != defaultFileMode || string(content) != "hello" {
+		t.Fatalf("entry=%s mode=%o content=%q", hdr.Name, hdr.Mode, content)
+	}
+}
+
+func TestResourceUsecase_UploadFileLimits(t *testing.T) {
+	uc := newTestExecUsecase(&fakeTarExecRepo{})
+
+	if _, err := uc.UploadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "relative/file"}, bytes.NewReader(nil), nil); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("err=%v want ErrInvalidPath", err)
+	}
+	if _, err := uc.UploadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "/f", Size: 10, MaxBytes: 4}, bytes.NewReader(nil), nil); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("err=%v want ErrFileTooLarge", err)
+	}
+	// 非归档上传的内容长度必须与 size 一致，未填写 size 时不能静默写入空文件
+	for _, tt := range []struct {
+		size    int64
+		content string
+	}{
+		{size: 0, content: "hello"},
+		{size: 3, content: "hello"},
+		{size: 8, content: "hello"},
+	} {
+		if _, err := uc.UploadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "/f", Size: tt.size}, bytes.NewReader([]byte(tt.content)), nil); !errors.Is(err, ErrUploadSizeMismatch) {
+			t.Fatalf("size=%d content=%q: err=%v want ErrUploadSizeMismatch", tt.size, tt.content, err)
+		}
+	}
+	if _, err := uc.UploadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "/empty"}, bytes.NewReader(nil), nil); err != nil {
+		t.Fatalf("empty file: err=%v", err)
+	}
+	// 归档模式的大小在传输过程中检查
+	if _, err := uc.UploadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "/d", Archive: true, MaxBytes: 4}, bytes.NewReader([]byte("0123456789")), nil); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("err=%v want ErrFileTooLarge", err)
+	}
+}
+
+// failingTarExecRepo 不读取输入，tar 直接以非零状态退出
+type failingTarExecRepo struct {
+	ExecRepo
+}
+
+func (f *failingTarExecRepo) StreamExec(_ context.Context, _ ExecOptions, _ <-chan ExecInput, output chan<- ExecOutput) error {
+	output <- ExecOutput{Type: ExecOutputData, Stream: "stderr", Data: []byte("tar: /root: Permission denied")}
+	output <- ExecOutput{Type: ExecOutputExit, ExitCode: 2}
+	return nil
+}
+
+// blockingReader 先返回一段内容，之后阻塞到 release 关闭，模拟客户端未结束的上传流
+type blockingReader struct {
+	first   []byte
+	release chan struct{}
+}
+
+func (b *blockingReader) Read(p []byte) (int, error) {
+	if len(b.first) > 0 {
+		n := copy(p, b.first)
+		b.first = b.first[n:]
+		return n, nil
+	}
+	<-b.release
+	return 0, io.EOF
+}
+
+func TestResourceUsecase_UploadFileExecExitsEarly(t *testing.T) {
+	uc := newTestExecUsecase(&failingTarExecRepo{})
+
+	for _, archive := range []bool{false, true} {
+		src := &blockingReader{first: []byte("hello"), release: make(chan struct{})}
+		done := make(chan error, 1)
+		go func() {
+			_, err := uc.UploadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "/root/f", Size: 10, Archive: archive}, src, nil)
+			done <- err
+		}()
+
+		// tar 失败后立即返回，不等待客户端发送更多内容
+		select {
+		case err := <-done:
+			var transferErr *FileTransferError
+			if !errors.As(err, &transferErr) || transferErr.ExitCode != 2 {
+				t.Fatalf("archive=%v err=%v want exit code 2", archive, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("archive=%v: UploadFile blocked on client stream after tar exited", archive)
+		}
+		close(src.release)
+	}
+}
+
+func TestResourceUsecase_DownloadFile(t *testing.T) {
+	repo := &fakeTarExecRepo{stdout: buildTar(t, &tar.Header{
+		Typeflag: tar.TypeReg, Name: "app.log", Size: 5, Mode: 0o600,
+	}, []byte("hello"))}
+	uc := newTestExecUsecase(repo)
+
+	var info FileInfo
+	var dst bytes.Buffer
+	n, err := uc.DownloadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "/var/log/app.log"},
+		func(i FileInfo) error { info = i; return nil }, &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || dst.String() != "hello" {
+		t.Fatalf("bytes=%d content=%q", n, dst.String())
+	}
+	if info.Name != "app.log" || info.Size != 5 || info.Mode != 0o600 {
+		t.Fatalf("info=%+v", info)
+	}
+	if got := repo.command; len(got) != 6 || got[4] != "/var/log" || got[5] != "app.log" {
+		t.Fatalf("command=%v", got)
+	}
+}
+
+func TestResourceUsecase_DownloadFileRejectsDirectory(t *testing.T) {
+	archive := buildTar(t, &tar.Header{Typeflag: tar.TypeDir, Name: "logs/", Mode: 0o755}, nil)
+
+	uc := newTestExecUsecase(&fakeTarExecRepo{stdout: archive})
+	_, err := uc.DownloadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "/var/logs"},
+		func(FileInfo) error { return nil }, io.Discard)
+	if !errors.Is(err, ErrIsDirectory) {
+		t.Fatalf("err=%v want ErrIsDirectory", err)
+	}
+
+	// 归档模式原样返回 tar
+	var dst bytes.Buffer
+	uc = newTestExecUsecase(&fakeTarExecRepo{stdout: archive})
+	if _, err := uc.DownloadFile(context.Background(), "alice", 1, FileTransferOptions{Path: "/var/logs", Archive: true},
+		func(FileInfo) error { return nil }, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.Bytes(), archive) {
+		t.Fatal("archive content mismatch")
+	}
+}
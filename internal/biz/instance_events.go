@@ -0,0 +1,227 @@
+package biz
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 实例失败诊断代码，对外保持稳定，新增的分类只追加不修改
+const (
+	FailureImagePull             = "IMAGE_PULL_FAILED"      // 镜像不存在、无权限或仓库不可达
+	FailureInsufficientGPU       = "INSUFFICIENT_GPU"       // 集群没有满足条件的 GPU
+	FailureInsufficientResources = "INSUFFICIENT_RESOURCES" // CPU/内存不足
+	FailureUnschedulable         = "UNSCHEDULABLE"          // 其他调度失败，如节点选择器、污点
+	FailureCrashLoop             = "CRASH_LOOP_BACK_OFF"    // 容器反复退出
+	FailureOOMKilled             = "OOM_KILLED"             // 容器内存超限被杀
+	FailureVolumeMount           = "VOLUME_MOUNT_FAILED"    // 存储卷挂载失败
+	FailureQuotaExceeded         = "QUOTA_EXCEEDED"         // 命名空间配额不足
+	FailureCreateFailed          = "CREATE_FAILED"          // 控制器无法创建 Pod
+	FailureProbeFailed           = "PROBE_FAILED"           // 健康检查失败
+	FailureUnknown               = "UNKNOWN_WARNING"        // 未分类的 Warning 事件
+)
+
+// maxStatusMessageLength 持久化的诊断信息最大长度
+const maxStatusMessageLength = 1024
+
+// InstanceEvent 实例相关的 K8s 事件
+type InstanceEvent struct {
+	Type          string // Normal / Warning
+	Reason        string
+	Message       string
+	ObjectKind    string // Deployment / ReplicaSet / Pod
+	ObjectName    string
+	Count         int32
+	FirstSeen     time.Time
+	LastSeen      time.Time
+	FailureReason string // 失败诊断代码，非失败事件为空
+}
+
+// InstanceDiagnosis 实例事件与当前失败诊断
+type InstanceDiagnosis struct {
+	Events        []InstanceEvent // 按最后发生时间倒序
+	StatusReason  string
+	StatusMessage string
+}
+
+// ClassifyEvent 将 K8s 事件归类为失败诊断代码，Normal 事件返回空字符串
+func ClassifyEvent(eventType, reason, message string) string {
+	if eventType != "Warning" {
+		return ""
+	}
+	msg := strings.ToLower(message)
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "ErrImageNeverPull", "InvalidImageName", "InspectFailed":
+		return FailureImagePull
+	case "Failed":
+		if strings.Contains(msg, "image") || strings.Contains(msg, "pull") {
+			return FailureImagePull
+		}
+		return FailureUnknown
+	case "BackOff":
+		if strings.Contains(msg, "pulling image") {
+			return FailureImagePull
+		}
+		return FailureCrashLoop
+	case "FailedScheduling":
+		switch {
+		case strings.Contains(msg, "nvidia.com/gpu"):
+			return FailureInsufficientGPU
+		case strings.Contains(msg, "insufficient cpu"), strings.Contains(msg, "insufficient memory"):
+			return FailureInsufficientResources
+		default:
+			return FailureUnschedulable
+		}
+	case "OOMKilling":
+		return FailureOOMKilled
+	case "FailedMount", "FailedAttachVolume", "FailedMapVolume":
+		return FailureVolumeMount
+	case "FailedCreate":
+		if strings.Contains(msg, "exceeded quota") {
+			return FailureQuotaExceeded
+		}
+		return FailureCreateFailed
+	case "Unhealthy":
+		return FailureProbeFailed
+	}
+	if strings.Contains(msg, "oomkilled") {
+		return FailureOOMKilled
+	}
+	return FailureUnknown
+}
+
+// GetInstanceEvents 汇总实例 Deployment、ReplicaSet、Pod 的事件并诊断失败原因。
+// 诊断结果与实例记录不一致时写回实例状态，供 ListResources 返回。
+func (uc *ResourceUsecase) GetInstanceEvents(ctx context.Context, instanceID int64, warningsOnly bool) (*InstanceDiagnosis, error) {
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if resource == nil {
+		return nil, ErrInstanceNotFound
+	}
+
+	diagnosis, _, err := uc.diagnoseInstance(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if warningsOnly {
+		filtered := diagnosis.Events[:0]
+		for _, ev := range diagnosis.Events {
+			if ev.Type == "Warning" {
+				filtered = append(filtered, ev)
+			}
+		}
+		diagnosis.Events = filtered
+	}
+	return diagnosis, nil
+}
+
+// RefreshStatusReasons 诊断全部运行中的实例并写回变化的失败原因，使未查询过事件的实例也能在 ListResources 中返回原因。
+// 已停止的实例不再诊断，只清除遗留的原因。单个实例诊断或写回失败只记录日志，返回成功写回的实例数。
+func (uc *ResourceUsecase) RefreshStatusReasons(ctx context.Context) (int, error) {
+	resources, err := uc.InstanceSpec.ListResources(ctx, ListResourcesFilter{})
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for i := range resources {
+		if ctx.Err() != nil {
+			return updated, ctx.Err()
+		}
+		r := &resources[i]
+		if r.Type == InstanceStatusStopped {
+			if uc.saveStatusReason(ctx, r, "", "") {
+				updated++
+			}
+			continue
+		}
+		_, written, err := uc.diagnoseInstance(ctx, r)
+		if err != nil {
+			uc.log.WithContext(ctx).Warnf("RefreshStatusReasons: failed to diagnose instance %d: %v", r.InstanceID, err)
+			continue
+		}
+		if written {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// diagnoseInstance 列出实例事件并诊断，诊断结果与实例记录不一致时写回，written 表示写回成功
+func (uc *ResourceUsecase) diagnoseInstance(ctx context.Context, resource *Resource) (diagnosis *InstanceDiagnosis, written bool, err error) {
+	instanceIDStr := strconv.FormatInt(resource.InstanceID, 10)
+	events, err := uc.K8sRepo.ListInstanceEvents(ctx, resource.UserID, instanceIDStr)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range events {
+		events[i].FailureReason = ClassifyEvent(events[i].Type, events[i].Reason, events[i].Message)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastSeen.After(events[j].LastSeen)
+	})
+
+	pods, err := uc.ExecRepo.ListPods(ctx, resource.UserID, instanceIDStr)
+	if err != nil {
+		// 无法判断 Pod 状态时仍按事件诊断
+		uc.log.WithContext(ctx).Warnf("failed to list pods of instance %d: %v", resource.InstanceID, err)
+	}
+	diagnosis = &InstanceDiagnosis{Events: events}
+	diagnosis.StatusReason, diagnosis.StatusMessage = diagnose(events, pods)
+
+	written = uc.saveStatusReason(ctx, resource, diagnosis.StatusReason, diagnosis.StatusMessage)
+	return diagnosis, written, nil
+}
+
+// saveStatusReason 诊断结果与实例记录不一致时写回，返回是否写回成功；写回失败只记录日志
+func (uc *ResourceUsecase) saveStatusReason(ctx context.Context, resource *Resource, reason, message string) bool {
+	if reason == resource.StatusReason && message == resource.StatusMessage {
+		return false
+	}
+	if err := uc.InstanceSpec.UpdateStatusReason(ctx, resource.InstanceID, reason, message); err != nil {
+		uc.log.WithContext(ctx).Warnf("failed to update status reason of instance %d: %v", resource.InstanceID, err)
+		return false
+	}
+	return true
+}
+
+// diagnose 返回最近一次失败事件的诊断代码与信息。
+// 有 Ready Pod 时实例正常；早于最新 Pod 创建时间的事件属于旧 Pod，不再参与诊断。
+func diagnose(events []InstanceEvent, pods []PodInfo) (string, string) {
+	var newest time.Time
+	for _, pod := range pods {
+		if pod.Ready && !pod.Terminating {
+			return "", ""
+		}
+		if pod.CreatedAt.After(newest) {
+			newest = pod.CreatedAt
+		}
+	}
+
+	for _, ev := range events {
+		if ev.FailureReason == "" || ev.LastSeen.Before(newest) {
+			continue
+		}
+		return ev.FailureReason, truncateUTF8(ev.Message, maxStatusMessageLength)
+	}
+	return "", ""
+}
+
+// truncateUTF8 按字节上限截断，不拆分多字节字符；非法 UTF-8 字节替换为 U+FFFD，避免写入 text 列失败
+func truncateUTF8(s string, maxBytes int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if len(s) <= maxBytes {
+		return s
+	}
+	i := maxBytes
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i]
+}
@@ -0,0 +1,174 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestClassifyEvent(t *testing.T) {
+	tests := []struct {
+		eventType string
+		reason    string
+		message   string
+		want      string
+	}{
+		{"Normal", "Scheduled", "Successfully assigned alice/1-abc to node-1", ""},
+		{"Warning", "Failed", `Failed to pull image "pytorch:missing": not found`, FailureImagePull},
+		{"Warning", "BackOff", `Back-off pulling image "pytorch:missing"`, FailureImagePull},
+		{"Warning", "BackOff", "Back-off restarting failed container 1 in pod 1-abc", FailureCrashLoop},
+		{"Warning", "FailedScheduling", "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.", FailureInsufficientGPU},
+		{"Warning", "FailedScheduling", "0/3 nodes are available: 2 Insufficient memory, 1 Insufficient cpu.", FailureInsufficientResources},
+		{"Warning", "FailedScheduling", "0/3 nodes are available: 3 node(s) didn't match Pod's node affinity/selector.", FailureUnschedulable},
+		{"Warning", "FailedCreate", `pods "1-abc" is forbidden: exceeded quota: compute`, FailureQuotaExceeded},
+		{"Warning", "FailedMount", "MountVolume.SetUp failed", FailureVolumeMount},
+		{"Warning", "Unhealthy", "Readiness probe failed", FailureProbeFailed},
+		{"Warning", "OOMKilling", "Memory cgroup out of memory", FailureOOMKilled},
+		{"Warning", "Evicted", "The node was low on resource: ephemeral-storage.", FailureUnknown},
+	}
+	for _, tt := range tests {
+		if got := ClassifyEvent(tt.eventType, tt.reason, tt.message); got != tt.want {
+			t.Errorf("ClassifyEvent(%s, %s)=%q want %q", tt.eventType, tt.reason, got, tt.want)
+		}
+	}
+}
+
+type fakeEventsK8sRepo struct {
+	K8sRepo
+	events []InstanceEvent
+	listed []string
+}
+
+func (f *fakeEventsK8sRepo) ListInstanceEvents(_ context.Context, _ string, instanceID string) ([]InstanceEvent, error) {
+	f.listed = append(f.listed, instanceID)
+	return append([]InstanceEvent(nil), f.events...), nil
+}
+
+type fakePodsExecRepo struct {
+	ExecRepo
+	pods []PodInfo
+}
+
+func (f *fakePodsExecRepo) ListPods(context.Context, string, string) ([]PodInfo, error) {
+	return f.pods, nil
+}
+
+// statusInstanceRepo 记录诊断结果的写回
+type statusInstanceRepo struct {
+	fakeInstanceRepo
+	updates []string
+	failFor map[int64]bool
+}
+
+func (f *statusInstanceRepo) UpdateStatusReason(_ context.Context, instanceID int64, reason, message string) error {
+	if f.failFor[instanceID] {
+		return errors.New("db unavailable")
+	}
+	f.updates = append(f.updates, reason)
+	f.resources[instanceID].StatusReason = reason
+	f.resources[instanceID].StatusMessage = message
+	return nil
+}
+
+func (f *statusInstanceRepo) ListResources(context.Context, ListResourcesFilter) ([]Resource, error) {
+	resources := make([]Resource, 0, len(f.resources))
+	for _, r := range f.resources {
+		resources = append(resources, *r)
+	}
+	return resources, nil
+}
+
+func TestResourceUsecase_GetInstanceEvents(t *testing.T) {
+	now := time.Now()
+	podCreated := now.Add(-10 * time.Minute)
+	events := []InstanceEvent{
+		{Type: "Normal", Reason: "ScalingReplicaSet", LastSeen: now.Add(-11 * time.Minute)},
+		{Type: "Warning", Reason: "FailedScheduling", Message: "Insufficient cpu", LastSeen: now.Add(-20 * time.Minute)},
+		{Type: "Warning", Reason: "Failed", Message: "Failed to pull image", LastSeen: now.Add(-time.Minute)},
+		{Type: "Normal", Reason: "Pulling", LastSeen: now},
+	}
+	repo := &statusInstanceRepo{fakeInstanceRepo: fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}}
+	pods := &fakePodsExecRepo{pods: []PodInfo{{Name: "1-abc", CreatedAt: podCreated}}}
+	uc := &ResourceUsecase{
+		InstanceSpec: repo,
+		K8sRepo:      &fakeEventsK8sRepo{events: events},
+		ExecRepo:     pods,
+		log:          log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+
+	diagnosis, err := uc.GetInstanceEvents(context.Background(), 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnosis.StatusReason != FailureImagePull || len(diagnosis.Events) != 4 || diagnosis.Events[0].Reason != "Pulling" {
+		t.Fatalf("diagnosis=%+v", diagnosis)
+	}
+	if len(repo.updates) != 1 {
+		t.Fatalf("updates=%v want one write back", repo.updates)
+	}
+
+	// 诊断未变化时不重复写回；只返回 Warning 事件
+	diagnosis, _ = uc.GetInstanceEvents(context.Background(), 1, true)
+	if len(repo.updates) != 1 || len(diagnosis.Events) != 2 {
+		t.Fatalf("updates=%v events=%d", repo.updates, len(diagnosis.Events))
+	}
+
+	// Pod Ready 后清除诊断
+	pods.pods[0].Ready = true
+	diagnosis, _ = uc.GetInstanceEvents(context.Background(), 1, false)
+	if diagnosis.StatusReason != "" || repo.resources[1].StatusReason != "" {
+		t.Fatalf("reason=%q stored=%q want cleared", diagnosis.StatusReason, repo.resources[1].StatusReason)
+	}
+}
+
+func TestResourceUsecase_RefreshStatusReasons(t *testing.T) {
+	events := []InstanceEvent{
+		{Type: "Warning", Reason: "FailedScheduling", Message: "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.", LastSeen: time.Now()},
+	}
+	repo := &statusInstanceRepo{fakeInstanceRepo: fakeInstanceRepo{resources: map[int64]*Resource{
+		1: {InstanceID: 1, UserID: "alice"},
+		2: {InstanceID: 2, UserID: "bob", StatusReason: FailureInsufficientGPU, StatusMessage: events[0].Message},
+		3: {InstanceID: 3, UserID: "carol"},
+		4: {InstanceID: 4, UserID: "dave", Type: InstanceStatusStopped, StatusReason: FailureCrashLoop},
+		5: {InstanceID: 5, UserID: "erin", Type: InstanceStatusStopped},
+	}}, failFor: map[int64]bool{3: true}}
+	k8s := &fakeEventsK8sRepo{events: events}
+	uc := &ResourceUsecase{
+		InstanceSpec: repo,
+		K8sRepo:      k8s,
+		ExecRepo:     &fakePodsExecRepo{},
+		log:          log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+
+	// 未查询过事件的实例也写回原因，已是最新的不重复写回，写回失败的不计数；
+	// 已停止的实例不查询事件，只清除遗留的原因
+	updated, err := uc.RefreshStatusReasons(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 2 || len(repo.updates) != 2 || repo.resources[1].StatusReason != FailureInsufficientGPU || repo.resources[4].StatusReason != "" {
+		t.Fatalf("updated=%d updates=%v reasons=%q/%q", updated, repo.updates, repo.resources[1].StatusReason, repo.resources[4].StatusReason)
+	}
+	sort.Strings(k8s.listed)
+	if strings.Join(k8s.listed, ",") != "1,2,3" {
+		t.Fatalf("listed events of %v, stopped instances must be skipped", k8s.listed)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if got := truncateUTF8("镜像拉取失败", 7); got != "镜像" {
+		t.Fatalf("got=%q want 镜像", got)
+	}
+	if got := truncateUTF8("abc", 7); got != "abc" {
+		t.Fatalf("got=%q want abc", got)
+	}
+	if got := truncateUTF8("a\xffb", 7); got != "a\uFFFDb" {
+		t.Fatalf("got=%q want invalid byte replaced", got)
+	}
+}
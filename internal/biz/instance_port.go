@@ -0,0 +1,74 @@
+package biz
+
+import (
+	"context"
+	"strconv"
+)
+
+// 端口后端 Service 的健康状态
+const (
+	PortHealthHealthy        = "HEALTHY"         // 至少一个后端地址就绪
+	PortHealthUnhealthy      = "UNHEALTHY"       // 有后端地址但均未就绪，如容器未监听端口或探针失败
+	PortHealthNoEndpoints    = "NO_ENDPOINTS"    // 没有后端 Pod，如实例仍在调度
+	PortHealthStopped        = "STOPPED"         // 实例已停止，Service 与外部端口保留
+	PortHealthServiceMissing = "SERVICE_MISSING" // Service 不存在，可通过 ReconcileNetwork 修复
+	PortHealthUnknown        = "UNKNOWN"         // 查询集群失败
+)
+
+// PortHealth 端口后端 Service 的实时健康状态，来自 EndpointSlice
+type PortHealth struct {
+	Status            string
+	ReadyEndpoints    int32
+	NotReadyEndpoints int32 // 未就绪或正在终止
+	Message           string
+}
+
+// InstancePort 已暴露的端口及其健康状态
+type InstancePort struct {
+	NetworkBinding
+	Health PortHealth
+}
+
+// ListInstancePorts 列出实例已暴露的端口，并查询后端 Service 的健康状态。
+// 查询集群失败时仍返回端口列表，健康状态为 UNKNOWN。
+func (uc *ResourceUsecase) ListInstancePorts(ctx context.Context, instanceID int64) ([]InstancePort, error) {
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if resource == nil {
+		return nil, ErrInstanceNotFound
+	}
+
+	bindings, err := uc.NetworkRepo.ListNetworkBindings(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bindings) == 0 {
+		return []InstancePort{}, nil
+	}
+
+	uc.refreshAccessURLs(ctx, resource.UserID, bindings)
+
+	health, healthErr := uc.K8sRepo.GetServiceHealth(ctx, resource.UserID, strconv.FormatInt(instanceID, 10))
+	if healthErr != nil {
+		uc.log.WithContext(ctx).Warnf("ListInstancePorts: failed to get service health of instance %d: %v", instanceID, healthErr)
+	}
+
+	ports := make([]InstancePort, 0, len(bindings))
+	for _, b := range bindings {
+		item := InstancePort{NetworkBinding: b}
+		switch h, ok := health[b.ServiceName]; {
+		case !b.Active:
+			item.Health = PortHealth{Status: PortHealthStopped, Message: "instance is stopped"}
+		case healthErr != nil:
+			item.Health = PortHealth{Status: PortHealthUnknown, Message: healthErr.Error()}
+		case !ok:
+			item.Health = PortHealth{Status: PortHealthServiceMissing, Message: "service " + b.ServiceName + " not found"}
+		default:
+			item.Health = h
+		}
+		ports = append(ports, item)
+	}
+	return ports, nil
+}
@@ -0,0 +1,66 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeHealthK8sRepo struct {
+	K8sRepo
+	health map[string]PortHealth
+	err    error
+}
+
+func (f *fakeHealthK8sRepo) GetServiceHealth(context.Context, string, string) (map[string]PortHealth, error) {
+	return f.health, f.err
+}
+
+func TestListInstancePorts(t *testing.T) {
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}
+	network := &fakeNetworkRepo{bindings: []NetworkBinding{
+		{InstanceID: 1, Port: 22, ServiceName: "instance-1-22", Protocol: "TCP", Enabled: true, Active: true},
+		{InstanceID: 1, Port: 80, ServiceName: "instance-1-80", Protocol: "HTTP", Enabled: true, Active: true},
+		{InstanceID: 2, Port: 80, ServiceName: "instance-2-80", Protocol: "HTTP", Enabled: true, Active: true},
+	}}
+	k8s := &fakeHealthK8sRepo{health: map[string]PortHealth{
+		"instance-1-22": {Status: PortHealthHealthy, ReadyEndpoints: 1},
+	}}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	ports, err := uc.ListInstancePorts(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 2 || ports[0].Health.Status != PortHealthHealthy || ports[1].Health.Status != PortHealthServiceMissing {
+		t.Fatalf("ports=%+v", ports)
+	}
+
+	// 实例停止后端口显示为 STOPPED
+	network.bindings[0].Active = false
+	ports, _ = uc.ListInstancePorts(ctx, 1)
+	if ports[0].Health.Status != PortHealthStopped {
+		t.Fatalf("port=%+v", ports[0])
+	}
+	network.bindings[0].Active = true
+
+	// 查询集群失败时仍返回端口列表
+	k8s.err = errors.New("forbidden")
+	ports, err = uc.ListInstancePorts(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range ports {
+		if p.Health.Status != PortHealthUnknown || p.Health.Message != "forbidden" {
+			t.Fatalf("port=%+v", p)
+		}
+	}
+
+	if _, err := uc.ListInstancePorts(ctx, 9); !errors.Is(err, ErrInstanceNotFound) {
+		t.Fatalf("err=%v want ErrInstanceNotFound", err)
+	}
+}
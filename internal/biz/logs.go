@@ -0,0 +1,51 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrPodNotFound 实例没有可用的 Pod 或指定的 Pod/容器不存在
+	ErrPodNotFound = errors.New("pod not found")
+	// ErrLogsUnavailable 请求的日志不存在，例如容器没有上一次运行记录
+	ErrLogsUnavailable = errors.New("logs unavailable")
+)
+
+// LogOptions 容器日志查询选项
+type LogOptions struct {
+	Namespace     string
+	InstanceID    string
+	PodName       string // 为空时选择最新的 Pod
+	ContainerName string // 为空时使用默认容器
+	Follow        bool
+	TailLines     *int64
+	SinceTime     *time.Time
+	Previous      bool // 上一次运行的容器
+	Timestamps    bool
+}
+
+// LogLine 一行容器日志
+type LogLine struct {
+	PodName       string
+	ContainerName string
+	Timestamp     time.Time // Timestamps 为 false 时为零值
+	Line          string
+}
+
+// StreamInstanceLogs 流式读取实例容器日志，每行回调一次 handle，handle 返回错误时停止
+func (uc *ResourceUsecase) StreamInstanceLogs(ctx context.Context, instanceID int64, opts LogOptions, handle func(LogLine) error) error {
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if resource == nil {
+		return ErrInstanceNotFound
+	}
+
+	opts.Namespace = resource.UserID
+	opts.InstanceID = strconv.FormatInt(instanceID, 10)
+	return uc.ExecRepo.StreamLogs(ctx, opts, handle)
+}
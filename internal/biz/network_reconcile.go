@@ -0,0 +1,403 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 网络差异类型，对外保持稳定
+const (
+	NetworkDriftServiceMissing        = "SERVICE_MISSING"          // 绑定记录存在但 Service 缺失
+	NetworkDriftIngressMissing        = "INGRESS_MISSING"          // HTTP 绑定的 Ingress 缺失
+	NetworkDriftConfigMapEntryMissing = "CONFIGMAP_ENTRY_MISSING"  // TCP/UDP 绑定的 ConfigMap 条目缺失
+	NetworkDriftConfigMapMismatch     = "CONFIGMAP_ENTRY_MISMATCH" // ConfigMap 条目指向其他 Service，需人工处理
+	NetworkDriftLBPortMissing         = "LB_PORT_MISSING"          // ingress-nginx Service 缺少外部端口
+	NetworkDriftPortConflict          = "EXTERNAL_PORT_CONFLICT"   // 多条绑定记录占用同一外部端口，需人工处理
+	NetworkDriftOrphanBinding         = "ORPHAN_BINDING"           // 实例已删除但绑定记录仍在
+	NetworkDriftOrphanService         = "ORPHAN_SERVICE"           // Service 没有对应的绑定记录
+	NetworkDriftOrphanIngress         = "ORPHAN_INGRESS"           // Ingress 没有对应的绑定记录
+	NetworkDriftOrphanConfigMapEntry  = "ORPHAN_CONFIGMAP_ENTRY"   // ConfigMap 条目没有对应的绑定记录
+	NetworkDriftOrphanLBPort          = "ORPHAN_LB_PORT"           // ingress-nginx Service 端口没有对应的绑定记录
+	NetworkDriftDisabledPortRouted    = "DISABLED_PORT_ROUTED"     // 已禁用的端口仍有 Ingress 或 ConfigMap 条目
+)
+
+// networkOrphanGrace 新建不久的资源不视为孤儿：openPort 先创建 K8s 资源再写入绑定记录
+const networkOrphanGrace = 2 * time.Minute
+
+// NetworkObject 本服务创建的 Service 或 Ingress（带 managed-by=resource-service 标签）
+type NetworkObject struct {
+	Namespace  string
+	Name       string
+	InstanceID int64 // instance-id 标签，无法解析时为 0
+	CreatedAt  time.Time
+}
+
+// TCPUDPMapping ingress-nginx tcp-services/udp-services ConfigMap 中的一条映射
+// 格式：{ExternalPort}: {Namespace}/{ServiceName}:{ServicePort}
+type TCPUDPMapping struct {
+	Protocol     string
+	ExternalPort uint32
+	Namespace    string
+	ServiceName  string
+	ServicePort  uint32
+	InstanceID   int64 // 从 ServiceName 解析，非实例 Service 时为 0
+}
+
+// LBPort ingress-nginx LoadBalancer Service 上的 TCP/UDP 端口
+type LBPort struct {
+	Protocol string
+	Port     uint32
+}
+
+// NetworkState K8s 中实际存在的端口暴露资源
+type NetworkState struct {
+	Services  []NetworkObject
+	Ingresses []NetworkObject
+	Mappings  []TCPUDPMapping
+	LBPorts   []LBPort // 仅包含外部端口范围内的端口
+}
+
+// NetworkDrift 绑定记录与 K8s 实际状态之间的一处差异
+type NetworkDrift struct {
+	Kind         string
+	InstanceID   int64
+	Namespace    string
+	Name         string // Service / Ingress 名称
+	Port         uint32 // 容器端口
+	ExternalPort uint32
+	Protocol     string
+	Detail       string
+	Repaired     bool
+	RepairError  string
+}
+
+// NetworkReconcileReport 一次核对的结果
+type NetworkReconcileReport struct {
+	CheckedBindings int
+	Drifts          []NetworkDrift
+}
+
+type mappingKey struct {
+	protocol string
+	port     uint32
+}
+
+type bindingKey struct {
+	instanceID int64
+	port       uint32
+}
+
+// ReconcileNetwork 核对 instance_network 记录与 Service、Ingress、tcp/udp-services ConfigMap 以及
+// ingress-nginx Service 端口。instanceID 为 0 时核对全部实例；repair 为 false 时只报告差异。
+func (uc *ResourceUsecase) ReconcileNetwork(ctx context.Context, instanceID int64, repair bool) (*NetworkReconcileReport, error) {
+	// 先读取 K8s 状态再读取绑定记录，配合 networkOrphanGrace 避免把正在开放的端口当作孤儿
+	state, err := uc.K8sRepo.GetNetworkState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make(map[int64]string)
+	var bindings []NetworkBinding
+	if instanceID != 0 {
+		resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+		if err != nil {
+			return nil, err
+		}
+		if resource == nil {
+			return nil, ErrInstanceNotFound
+		}
+		namespaces[instanceID] = resource.UserID
+		bindings, err = uc.NetworkRepo.ListNetworkBindings(ctx, instanceID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		resources, err := uc.InstanceSpec.ListResources(ctx, ListResourcesFilter{})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resources {
+			namespaces[r.InstanceID] = r.UserID
+		}
+		bindings, err = uc.NetworkRepo.ListAllNetworkBindings(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &NetworkReconcileReport{CheckedBindings: len(bindings)}
+	drifts, broken := checkNetworkBindings(bindings, namespaces, state)
+	report.Drifts = append(report.Drifts, drifts...)
+	report.Drifts = append(report.Drifts, findNetworkOrphans(bindings, namespaces, state, instanceID, time.Now())...)
+
+	if repair {
+		uc.repairNetworkDrifts(ctx, report.Drifts, broken, namespaces)
+	}
+
+	if len(report.Drifts) > 0 {
+		uc.log.WithContext(ctx).Warnf("ReconcileNetwork: instance=%d bindings=%d drifts=%d repair=%v", instanceID, report.CheckedBindings, len(report.Drifts), repair)
+	}
+	return report, nil
+}
+
+// checkNetworkBindings 检查每条绑定记录期望的 K8s 资源是否存在，返回差异以及需要重建资源的绑定
+func checkNetworkBindings(bindings []NetworkBinding, namespaces map[int64]string, state *NetworkState) ([]NetworkDrift, map[bindingKey]NetworkBinding) {
+	services := make(map[string]bool, len(state.Services))
+	for _, svc := range state.Services {
+		services[svc.Namespace+"/"+svc.Name] = true
+	}
+	ingresses := make(map[string]bool, len(state.Ingresses))
+	for _, ing := range state.Ingresses {
+		ingresses[ing.Namespace+"/"+ing.Name] = true
+	}
+	mappings := make(map[mappingKey]TCPUDPMapping, len(state.Mappings))
+	for _, m := range state.Mappings {
+		mappings[mappingKey{m.Protocol, m.ExternalPort}] = m
+	}
+	lbPorts := make(map[mappingKey]bool, len(state.LBPorts))
+	for _, p := range state.LBPorts {
+		lbPorts[mappingKey{p.Protocol, p.Port}] = true
+	}
+
+	var drifts []NetworkDrift
+	// broken 记录需要调用 EnsureNetworkBinding 重建资源的绑定
+	broken := make(map[bindingKey]NetworkBinding)
+	claimed := make(map[mappingKey]NetworkBinding)
+	for _, b := range bindings {
+		ns, live := namespaces[b.InstanceID]
+		drift := NetworkDrift{
+			InstanceID: b.InstanceID,
+			Namespace:  ns,
+			Name:       b.ServiceName,
+			Port:       b.Port,
+			Protocol:   b.Protocol,
+		}
+		if b.ExternalPort != nil {
+			drift.ExternalPort = *b.ExternalPort
+		}
+		if !live {
+			drift.Kind = NetworkDriftOrphanBinding
+			drift.Detail = "instance no longer exists"
+			drifts = append(drifts, drift)
+			continue
+		}
+
+		missing := func(kind, name, detail string) {
+			d := drift
+			d.Kind, d.Name, d.Detail = kind, name, detail
+			drifts = append(drifts, d)
+			broken[bindingKey{b.InstanceID, b.Port}] = b
+		}
+
+		if !services[ns+"/"+b.ServiceName] {
+			missing(NetworkDriftServiceMissing, b.ServiceName, "service not found")
+		}
+		if !b.Enabled {
+			// 已禁用的绑定只保留 Service 与外部端口，路由资源应已删除
+			routed := b.IngressName != nil && ingresses[ns+"/"+*b.IngressName]
+			if b.ExternalPort != nil {
+				m, ok := mappings[mappingKey{b.Protocol, *b.ExternalPort}]
+				routed = routed || ok && m.Namespace == ns && m.ServiceName == b.ServiceName
+			}
+			if routed {
+				d := drift
+				d.Kind, d.Detail = NetworkDriftDisabledPortRouted, "disabled port still has routing resources"
+				drifts = append(drifts, d)
+				broken[bindingKey{b.InstanceID, b.Port}] = b
+			}
+			continue
+		}
+		if b.IngressName != nil && !ingresses[ns+"/"+*b.IngressName] {
+			missing(NetworkDriftIngressMissing, *b.IngressName, "ingress not found")
+		}
+		if b.ExternalPort == nil {
+			continue
+		}
+
+		key := mappingKey{b.Protocol, *b.ExternalPort}
+		if other, ok := claimed[key]; ok {
+			d := drift
+			d.Kind = NetworkDriftPortConflict
+			d.Detail = fmt.Sprintf("external port is also bound to instance %d port %d", other.InstanceID, other.Port)
+			drifts = append(drifts, d)
+			continue
+		}
+		claimed[key] = b
+
+		if m, ok := mappings[key]; !ok {
+			missing(NetworkDriftConfigMapEntryMissing, b.ServiceName, "tcp/udp-services entry not found")
+		} else if m.Namespace != ns || m.ServiceName != b.ServiceName || m.ServicePort != b.ServicePort {
+			d := drift
+			d.Kind = NetworkDriftConfigMapMismatch
+			d.Detail = fmt.Sprintf("entry points to %s/%s:%d", m.Namespace, m.ServiceName, m.ServicePort)
+			drifts = append(drifts, d)
+		}
+		if !lbPorts[key] {
+			missing(NetworkDriftLBPortMissing, b.ServiceName, "port not found on ingress-nginx service")
+		}
+	}
+	return drifts, broken
+}
+
+// findNetworkOrphans 查找没有对应绑定记录的 K8s 资源。instanceID 不为 0 时只检查该实例的资源，
+// 无法关联实例的 ingress-nginx Service 端口仅在全量核对时检查。
+func findNetworkOrphans(bindings []NetworkBinding, namespaces map[int64]string, state *NetworkState, instanceID int64, now time.Time) []NetworkDrift {
+	services := make(map[string]bool)
+	ingresses := make(map[string]bool)
+	ports := make(map[mappingKey]bool)
+	for _, b := range bindings {
+		ns, live := namespaces[b.InstanceID]
+		if !live {
+			continue
+		}
+		services[ns+"/"+b.ServiceName] = true
+		// 已禁用绑定残留的路由资源由 findNetworkDrifts 报告，保留的外部端口不视为孤儿
+		if b.IngressName != nil {
+			ingresses[ns+"/"+*b.IngressName] = true
+		}
+		if b.ExternalPort != nil {
+			ports[mappingKey{b.Protocol, *b.ExternalPort}] = true
+		}
+	}
+
+	inScope := func(id int64) bool {
+		return instanceID == 0 || id == instanceID
+	}
+	young := make(map[string]bool)
+	var drifts []NetworkDrift
+	for _, svc := range state.Services {
+		if now.Sub(svc.CreatedAt) < networkOrphanGrace {
+			young[svc.Namespace+"/"+svc.Name] = true
+			continue
+		}
+		if inScope(svc.InstanceID) && !services[svc.Namespace+"/"+svc.Name] {
+			drifts = append(drifts, NetworkDrift{
+				Kind:       NetworkDriftOrphanService,
+				InstanceID: svc.InstanceID,
+				Namespace:  svc.Namespace,
+				Name:       svc.Name,
+				Detail:     "no network binding references this service",
+			})
+		}
+	}
+	for _, ing := range state.Ingresses {
+		if now.Sub(ing.CreatedAt) < networkOrphanGrace {
+			continue
+		}
+		if inScope(ing.InstanceID) && !ingresses[ing.Namespace+"/"+ing.Name] {
+			drifts = append(drifts, NetworkDrift{
+				Kind:       NetworkDriftOrphanIngress,
+				InstanceID: ing.InstanceID,
+				Namespace:  ing.Namespace,
+				Name:       ing.Name,
+				Detail:     "no network binding references this ingress",
+			})
+		}
+	}
+
+	mapped := make(map[mappingKey]bool)
+	for _, m := range state.Mappings {
+		key := mappingKey{m.Protocol, m.ExternalPort}
+		mapped[key] = true
+		// 只处理指向实例 Service 的条目，集群中其他 TCP/UDP 映射保持不变
+		if m.InstanceID == 0 || !inScope(m.InstanceID) || ports[key] || young[m.Namespace+"/"+m.ServiceName] {
+			continue
+		}
+		drifts = append(drifts, NetworkDrift{
+			Kind:         NetworkDriftOrphanConfigMapEntry,
+			InstanceID:   m.InstanceID,
+			Namespace:    m.Namespace,
+			Name:         m.ServiceName,
+			Port:         m.ServicePort,
+			ExternalPort: m.ExternalPort,
+			Protocol:     m.Protocol,
+			Detail:       "no network binding references this external port",
+		})
+	}
+	if instanceID == 0 {
+		for _, p := range state.LBPorts {
+			key := mappingKey{p.Protocol, p.Port}
+			if ports[key] || mapped[key] {
+				continue
+			}
+			drifts = append(drifts, NetworkDrift{
+				Kind:         NetworkDriftOrphanLBPort,
+				ExternalPort: p.Port,
+				Protocol:     p.Protocol,
+				Detail:       "no network binding or tcp/udp-services entry references this port",
+			})
+		}
+	}
+	return drifts
+}
+
+// repairNetworkDrifts 修复可自动处理的差异：缺失的资源按绑定记录重建，孤儿资源与记录直接删除。
+// 端口冲突与 ConfigMap 条目指向错误需要人工处理。
+func (uc *ResourceUsecase) repairNetworkDrifts(ctx context.Context, drifts []NetworkDrift, broken map[bindingKey]NetworkBinding, namespaces map[int64]string) {
+	ensured := make(map[bindingKey]error, len(broken))
+	keys := make([]bindingKey, 0, len(broken))
+	for key := range broken {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].instanceID != keys[j].instanceID {
+			return keys[i].instanceID < keys[j].instanceID
+		}
+		return keys[i].port < keys[j].port
+	})
+	for _, key := range keys {
+		b := broken[key]
+		ensured[key] = uc.K8sRepo.EnsureNetworkBinding(ctx, namespaces[b.InstanceID], b)
+	}
+
+	for i := range drifts {
+		d := &drifts[i]
+		var err error
+		switch d.Kind {
+		case NetworkDriftServiceMissing, NetworkDriftIngressMissing, NetworkDriftConfigMapEntryMissing, NetworkDriftLBPortMissing,
+			NetworkDriftDisabledPortRouted:
+			err = ensured[bindingKey{d.InstanceID, d.Port}]
+		case NetworkDriftOrphanBinding:
+			err = uc.NetworkRepo.DeleteNetworkBinding(ctx, d.InstanceID, d.Port)
+		case NetworkDriftOrphanService:
+			err = uc.K8sRepo.DeleteService(ctx, d.Namespace, d.Name)
+		case NetworkDriftOrphanIngress:
+			err = uc.K8sRepo.DeleteIngress(ctx, d.Namespace, d.Name)
+		case NetworkDriftOrphanConfigMapEntry, NetworkDriftOrphanLBPort:
+			// 同时删除 ConfigMap 条目与 ingress-nginx Service 端口
+			err = uc.K8sRepo.ReleaseTCPUDPPort(ctx, d.Protocol, d.ExternalPort)
+		default:
+			continue
+		}
+		if err != nil {
+			d.RepairError = err.Error()
+			uc.log.WithContext(ctx).Errorf("ReconcileNetwork: failed to repair %s %s/%s: %v", d.Kind, d.Namespace, d.Name, err)
+			continue
+		}
+		d.Repaired = true
+
+		data, _ := json.Marshal(map[string]interface{}{
+			"kind":          d.Kind,
+			"namespace":     d.Namespace,
+			"name":          d.Name,
+			"port":          d.Port,
+			"external_port": d.ExternalPort,
+			"protocol":      d.Protocol,
+		})
+		_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
+			InstanceID: d.InstanceID,
+			LogType:    "NETWORK_REPAIRED",
+			Message:    "Network drift " + d.Kind + " repaired",
+			DataJson:   data,
+			CreatedAt:  time.Now(),
+		})
+	}
+}
+
+// RunAsLeader 多副本部署时通过 K8s Lease 选主，只在持有租约期间执行 run，阻塞到 ctx 取消
+func (uc *ResourceUsecase) RunAsLeader(ctx context.Context, namespace, name string, run func(ctx context.Context)) error {
+	return uc.K8sRepo.RunAsLeader(ctx, namespace, name, run)
+}
@@ -0,0 +1,256 @@
+package biz
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type listInstanceRepo struct {
+	fakeInstanceRepo
+}
+
+func (f *listInstanceRepo) ListResources(context.Context, ListResourcesFilter) ([]Resource, error) {
+	out := make([]Resource, 0, len(f.resources))
+	for _, r := range f.resources {
+		out = append(out, *r)
+	}
+	return out, nil
+}
+
+type fakeNetworkRepo struct {
+	NetworkRepo
+	bindings []NetworkBinding
+	deleted  []NetworkBinding
+}
+
+func (f *fakeNetworkRepo) ListNetworkBindings(_ context.Context, instanceID int64) ([]NetworkBinding, error) {
+	var out []NetworkBinding
+	for _, b := range f.bindings {
+		if b.InstanceID == instanceID {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeNetworkRepo) ListAllNetworkBindings(context.Context) ([]NetworkBinding, error) {
+	return f.bindings, nil
+}
+
+func (f *fakeNetworkRepo) ListNetworkBindingsByInstances(_ context.Context, instanceIDs []int64) ([]NetworkBinding, error) {
+	var out []NetworkBinding
+	for _, id := range instanceIDs {
+		bindings, _ := f.ListNetworkBindings(context.Background(), id)
+		out = append(out, bindings...)
+	}
+	return out, nil
+}
+
+func (f *fakeNetworkRepo) ListCustomDomains(context.Context, int64) ([]CustomDomain, error) {
+	return nil, nil
+}
+
+func (f *fakeNetworkRepo) DeleteNetworkBinding(_ context.Context, instanceID int64, port uint32) error {
+	f.deleted = append(f.deleted, NetworkBinding{InstanceID: instanceID, Port: port})
+	return nil
+}
+
+type fakeNetworkK8sRepo struct {
+	K8sRepo
+	state   *NetworkState
+	ensured []uint32
+	deleted []string
+}
+
+func (f *fakeNetworkK8sRepo) GetNetworkState(context.Context) (*NetworkState, error) {
+	return f.state, nil
+}
+
+func (f *fakeNetworkK8sRepo) EnsureNetworkBinding(_ context.Context, _ string, binding NetworkBinding) error {
+	f.ensured = append(f.ensured, binding.Port)
+	return nil
+}
+
+func (f *fakeNetworkK8sRepo) DeleteService(_ context.Context, namespace, name string) error {
+	f.deleted = append(f.deleted, "service:"+namespace+"/"+name)
+	return nil
+}
+
+func (f *fakeNetworkK8sRepo) DeleteIngress(_ context.Context, namespace, name string) error {
+	f.deleted = append(f.deleted, "ingress:"+namespace+"/"+name)
+	return nil
+}
+
+func (f *fakeNetworkK8sRepo) ReleaseTCPUDPPort(_ context.Context, protocol string, externalPort uint32) error {
+	f.deleted = append(f.deleted, protocol+":"+strconv.FormatUint(uint64(externalPort), 10))
+	return nil
+}
+
+func newTestNetworkUsecase() (*ResourceUsecase, *fakeNetworkRepo, *fakeNetworkK8sRepo, *fakeAuditRepo) {
+	old := time.Now().Add(-time.Hour)
+	ptr := func(v uint32) *uint32 { return &v }
+	ingress := "ingress-1-80"
+
+	network := &fakeNetworkRepo{bindings: []NetworkBinding{
+		{InstanceID: 1, Port: 80, ServiceName: "instance-1-80", ServicePort: 80, IngressName: &ingress, Protocol: "HTTP", Enabled: true},
+		{InstanceID: 1, Port: 22, ServiceName: "instance-1-22", ServicePort: 22, ExternalPort: ptr(30000), Protocol: "TCP", Enabled: true},
+		{InstanceID: 1, Port: 23, ServiceName: "instance-1-23", ServicePort: 23, ExternalPort: ptr(30000), Protocol: "TCP", Enabled: true},
+		{InstanceID: 1, Port: 53, ServiceName: "instance-1-53", ServicePort: 53, ExternalPort: ptr(30001), Protocol: "UDP", Enabled: true},
+		{InstanceID: 2, Port: 80, ServiceName: "instance-2-80", ServicePort: 80, Protocol: "HTTP", Enabled: true},
+	}}
+	k8s := &fakeNetworkK8sRepo{state: &NetworkState{
+		Services: []NetworkObject{
+			{Namespace: "alice", Name: "instance-1-80", InstanceID: 1, CreatedAt: old},
+			{Namespace: "alice", Name: "instance-1-22", InstanceID: 1, CreatedAt: old},
+			{Namespace: "alice", Name: "instance-1-23", InstanceID: 1, CreatedAt: old},
+			{Namespace: "alice", Name: "instance-1-53", InstanceID: 1, CreatedAt: old},
+			{Namespace: "alice", Name: "instance-1-9000", InstanceID: 1, CreatedAt: time.Now()}, // 正在开放的端口
+			{Namespace: "bob", Name: "instance-3-8080", InstanceID: 3, CreatedAt: old},
+		},
+		Ingresses: []NetworkObject{
+			{Namespace: "bob", Name: "ingress-3-8080", InstanceID: 3, CreatedAt: old},
+		},
+		Mappings: []TCPUDPMapping{
+			{Protocol: "TCP", ExternalPort: 30000, Namespace: "alice", ServiceName: "instance-1-22", ServicePort: 22, InstanceID: 1},
+			{Protocol: "UDP", ExternalPort: 30001, Namespace: "kube-system", ServiceName: "dns", ServicePort: 53},
+			{Protocol: "TCP", ExternalPort: 30005, Namespace: "bob", ServiceName: "instance-3-5432", ServicePort: 5432, InstanceID: 3},
+		},
+		LBPorts: []LBPort{{Protocol: "UDP", Port: 30001}, {Protocol: "TCP", Port: 30005}, {Protocol: "TCP", Port: 30009}},
+	}}
+	audit := &fakeAuditRepo{}
+	uc := &ResourceUsecase{
+		InstanceSpec: &listInstanceRepo{fakeInstanceRepo{resources: map[int64]*Resource{
+			1: {InstanceID: 1, UserID: "alice"},
+			3: {InstanceID: 3, UserID: "bob"},
+		}}},
+		AuditRepo:   audit,
+		K8sRepo:     k8s,
+		NetworkRepo: network,
+		log:         log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+	return uc, network, k8s, audit
+}
+
+func driftKinds(drifts []NetworkDrift) map[string]int {
+	kinds := make(map[string]int)
+	for _, d := range drifts {
+		kinds[d.Kind]++
+	}
+	return kinds
+}
+
+func TestResourceUsecase_ReconcileNetwork(t *testing.T) {
+	uc, network, k8s, audit := newTestNetworkUsecase()
+
+	// dry-run 只报告差异
+	report, err := uc.ReconcileNetwork(context.Background(), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		NetworkDriftIngressMissing:       1,
+		NetworkDriftLBPortMissing:        1,
+		NetworkDriftPortConflict:         1,
+		NetworkDriftConfigMapMismatch:    1,
+		NetworkDriftOrphanBinding:        1,
+		NetworkDriftOrphanService:        1,
+		NetworkDriftOrphanIngress:        1,
+		NetworkDriftOrphanConfigMapEntry: 1,
+		NetworkDriftOrphanLBPort:         1,
+	}
+	kinds := driftKinds(report.Drifts)
+	if report.CheckedBindings != 5 || len(kinds) != len(want) {
+		t.Fatalf("checked=%d kinds=%v", report.CheckedBindings, kinds)
+	}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Fatalf("kinds=%v want %v", kinds, want)
+		}
+	}
+	if len(k8s.ensured) != 0 || len(k8s.deleted) != 0 || len(network.deleted) != 0 || len(audit.records) != 0 {
+		t.Fatalf("dry-run changed state: ensured=%v deleted=%v bindings=%v", k8s.ensured, k8s.deleted, network.deleted)
+	}
+
+	// 修复：重建缺失资源、删除孤儿，冲突与指向错误的条目保留给人工处理
+	report, err = uc.ReconcileNetwork(context.Background(), 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k8s.ensured) != 2 || k8s.ensured[0] != 22 || k8s.ensured[1] != 80 {
+		t.Fatalf("ensured=%v want [22 80]", k8s.ensured)
+	}
+	if len(k8s.deleted) != 4 || len(network.deleted) != 1 || network.deleted[0].InstanceID != 2 {
+		t.Fatalf("deleted=%v bindings=%v", k8s.deleted, network.deleted)
+	}
+	for _, d := range report.Drifts {
+		manual := d.Kind == NetworkDriftPortConflict || d.Kind == NetworkDriftConfigMapMismatch
+		if d.Repaired == manual {
+			t.Fatalf("drift %s repaired=%v", d.Kind, d.Repaired)
+		}
+	}
+	if len(audit.records) != 7 || audit.records[0].LogType != "NETWORK_REPAIRED" {
+		t.Fatalf("audit=%+v", audit.records)
+	}
+}
+
+func TestResourceUsecase_ReconcileNetworkInstance(t *testing.T) {
+	uc, _, _, _ := newTestNetworkUsecase()
+
+	// 单实例核对不报告其他实例与无法关联实例的资源
+	report, err := uc.ReconcileNetwork(context.Background(), 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := driftKinds(report.Drifts)
+	if report.CheckedBindings != 4 || len(report.Drifts) != 4 || kinds[NetworkDriftIngressMissing] != 1 || kinds[NetworkDriftPortConflict] != 1 {
+		t.Fatalf("checked=%d kinds=%v", report.CheckedBindings, kinds)
+	}
+
+	if _, err := uc.ReconcileNetwork(context.Background(), 9, false); err != ErrInstanceNotFound {
+		t.Fatalf("err=%v want ErrInstanceNotFound", err)
+	}
+}
+
+func TestResourceUsecase_ReconcileNetworkDisabled(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	ptr := func(v uint32) *uint32 { return &v }
+	ingress := "ingress-1-80"
+
+	network := &fakeNetworkRepo{bindings: []NetworkBinding{
+		{InstanceID: 1, Port: 80, ServiceName: "instance-1-80", ServicePort: 80, IngressName: &ingress, Protocol: "HTTP"},
+		{InstanceID: 1, Port: 22, ServiceName: "instance-1-22", ServicePort: 22, ExternalPort: ptr(30000), Protocol: "TCP"},
+	}}
+	k8s := &fakeNetworkK8sRepo{state: &NetworkState{
+		Services: []NetworkObject{
+			{Namespace: "alice", Name: "instance-1-80", InstanceID: 1, CreatedAt: old},
+			{Namespace: "alice", Name: "instance-1-22", InstanceID: 1, CreatedAt: old},
+		},
+		// 禁用时未删除的 Ingress
+		Ingresses: []NetworkObject{{Namespace: "alice", Name: ingress, InstanceID: 1, CreatedAt: old}},
+		// 禁用的 TCP 端口只保留 ingress-nginx Service 端口
+		LBPorts: []LBPort{{Protocol: "TCP", Port: 30000}},
+	}}
+	uc := &ResourceUsecase{
+		InstanceSpec: &listInstanceRepo{fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}},
+		AuditRepo:    &fakeAuditRepo{},
+		K8sRepo:      k8s,
+		NetworkRepo:  network,
+		log:          log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+
+	report, err := uc.ReconcileNetwork(context.Background(), 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Drifts) != 1 || report.Drifts[0].Kind != NetworkDriftDisabledPortRouted || !report.Drifts[0].Repaired {
+		t.Fatalf("drifts=%+v", report.Drifts)
+	}
+	if len(k8s.ensured) != 1 || k8s.ensured[0] != 80 || len(k8s.deleted) != 0 {
+		t.Fatalf("ensured=%v deleted=%v", k8s.ensured, k8s.deleted)
+	}
+}
@@ -0,0 +1,189 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNetworkRuleNotFound 网络规则不存在或不属于该用户
+	ErrNetworkRuleNotFound = errors.New("network rule not found")
+	// ErrInvalidNetworkRule 网络规则参数不合法
+	ErrInvalidNetworkRule = errors.New("invalid network rule")
+	// ErrNetworkPolicyDisabled 配置关闭了 NetworkPolicy，无法添加网络规则
+	ErrNetworkPolicyDisabled = errors.New("network policies are disabled")
+)
+
+// 网络规则方向
+const (
+	NetworkRuleIngress = "INGRESS" // 允许对端访问本用户的实例
+	NetworkRuleEgress  = "EGRESS"  // 允许本用户的实例访问对端
+)
+
+// NetworkRule 用户命名空间在默认隔离之外额外放开的流量，每条规则对应一个 NetworkPolicy。
+// 用户命名空间默认只允许同命名空间、ingress 控制器（入站）与集群 DNS（出站）的流量。
+type NetworkRule struct {
+	ID          int64
+	UserID      string // 规则所在的用户命名空间
+	Direction   string // INGRESS / EGRESS
+	PeerUserID  string // 对端用户命名空间，与 CIDR 二选一
+	CIDR        string
+	Ports       []uint32 // 为空表示全部端口
+	Protocol    string   // TCP / UDP
+	Description string
+	CreatedAt   time.Time
+}
+
+// Validate 校验并规范化网络规则：方向与协议转大写，协议默认 TCP，CIDR 规范化，端口去重排序
+func (r *NetworkRule) Validate() error {
+	r.Direction = strings.ToUpper(strings.TrimSpace(r.Direction))
+	if r.Direction != NetworkRuleIngress && r.Direction != NetworkRuleEgress {
+		return fmt.Errorf("%w: direction must be INGRESS or EGRESS", ErrInvalidNetworkRule)
+	}
+
+	r.Protocol = strings.ToUpper(strings.TrimSpace(r.Protocol))
+	if r.Protocol == "" {
+		r.Protocol = "TCP"
+	}
+	if r.Protocol != "TCP" && r.Protocol != "UDP" {
+		return fmt.Errorf("%w: protocol must be TCP or UDP", ErrInvalidNetworkRule)
+	}
+
+	r.PeerUserID = strings.TrimSpace(r.PeerUserID)
+	r.CIDR = strings.TrimSpace(r.CIDR)
+	switch {
+	case r.PeerUserID == "" && r.CIDR == "":
+		return fmt.Errorf("%w: one of peer_user_id or cidr is required", ErrInvalidNetworkRule)
+	case r.PeerUserID != "" && r.CIDR != "":
+		return fmt.Errorf("%w: peer_user_id and cidr are mutually exclusive", ErrInvalidNetworkRule)
+	case r.PeerUserID == r.UserID:
+		return fmt.Errorf("%w: traffic within the same user namespace is always allowed", ErrInvalidNetworkRule)
+	case r.CIDR != "":
+		cidr, err := normalizeCIDR(r.CIDR)
+		if err != nil {
+			return fmt.Errorf("%w: invalid CIDR %q", ErrInvalidNetworkRule, r.CIDR)
+		}
+		r.CIDR = cidr
+	}
+
+	seen := make(map[uint32]bool, len(r.Ports))
+	ports := make([]uint32, 0, len(r.Ports))
+	for _, port := range r.Ports {
+		if port == 0 || port > 65535 {
+			return fmt.Errorf("%w: invalid port %d, must be 1-65535", ErrInvalidNetworkRule, port)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	r.Ports = ports
+
+	if len(r.Description) > 255 {
+		return fmt.Errorf("%w: description must be at most 255 bytes", ErrInvalidNetworkRule)
+	}
+	return nil
+}
+
+// CreateNetworkRule 保存规则并在用户命名空间中创建对应的 NetworkPolicy，创建失败时删除记录
+func (uc *ResourceUsecase) CreateNetworkRule(ctx context.Context, rule NetworkRule) (*NetworkRule, error) {
+	uc.log.WithContext(ctx).Infof("CreateNetworkRule: user=%s direction=%s peer=%s cidr=%s ports=%v protocol=%s",
+		rule.UserID, rule.Direction, rule.PeerUserID, rule.CIDR, rule.Ports, rule.Protocol)
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := uc.NetworkRepo.CreateNetworkRule(ctx, &rule); err != nil {
+		return nil, err
+	}
+
+	if err := uc.K8sRepo.ApplyNetworkRule(ctx, rule.UserID, rule); err != nil {
+		if delErr := uc.NetworkRepo.DeleteNetworkRule(ctx, rule.ID); delErr != nil {
+			uc.log.WithContext(ctx).Errorf("failed to roll back network rule %d: %v", rule.ID, delErr)
+		}
+		return nil, err
+	}
+
+	uc.auditNetworkRule(ctx, "NETWORK_RULE_CREATED", rule)
+	return &rule, nil
+}
+
+// ListNetworkRules 查询用户命名空间的网络规则
+func (uc *ResourceUsecase) ListNetworkRules(ctx context.Context, userID string) ([]NetworkRule, error) {
+	return uc.NetworkRepo.ListNetworkRules(ctx, userID)
+}
+
+// DeleteNetworkRule 删除规则对应的 NetworkPolicy 与记录
+func (uc *ResourceUsecase) DeleteNetworkRule(ctx context.Context, userID string, ruleID int64) error {
+	uc.log.WithContext(ctx).Infof("DeleteNetworkRule: user=%s rule=%d", userID, ruleID)
+
+	rule, err := uc.NetworkRepo.GetNetworkRule(ctx, ruleID)
+	if err != nil {
+		return err
+	}
+	if rule == nil || rule.UserID != userID {
+		return ErrNetworkRuleNotFound
+	}
+
+	if err := uc.K8sRepo.DeleteNetworkRule(ctx, rule.UserID, rule.ID); err != nil {
+		return err
+	}
+	if err := uc.NetworkRepo.DeleteNetworkRule(ctx, rule.ID); err != nil {
+		return err
+	}
+
+	uc.auditNetworkRule(ctx, "NETWORK_RULE_DELETED", *rule)
+	return nil
+}
+
+// EnsureNetworkPolicies 为全部有实例的用户命名空间补齐默认隔离策略。
+// 策略原先只在创建实例时创建，此前已存在的命名空间由此补齐（data 层仅在开启 network_policy_backfill 时修改）；单个命名空间失败只记录日志，返回失败数。
+func (uc *ResourceUsecase) EnsureNetworkPolicies(ctx context.Context) (int, error) {
+	resources, err := uc.InstanceSpec.ListResources(ctx, ListResourcesFilter{})
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[string]bool)
+	failed := 0
+	for _, r := range resources {
+		if seen[r.UserID] {
+			continue
+		}
+		seen[r.UserID] = true
+		if ctx.Err() != nil {
+			return failed, ctx.Err()
+		}
+		if err := uc.K8sRepo.EnsureNetworkPolicies(ctx, r.UserID); err != nil {
+			uc.log.WithContext(ctx).Warnf("EnsureNetworkPolicies: namespace=%s: %v", r.UserID, err)
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// auditNetworkRule 网络规则与具体实例无关，审计日志的 instance_id 为 0
+func (uc *ResourceUsecase) auditNetworkRule(ctx context.Context, logType string, rule NetworkRule) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"rule_id":      rule.ID,
+		"user_id":      rule.UserID,
+		"direction":    rule.Direction,
+		"peer_user_id": rule.PeerUserID,
+		"cidr":         rule.CIDR,
+		"ports":        rule.Ports,
+		"protocol":     rule.Protocol,
+	})
+	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
+		LogType:   logType,
+		Message:   fmt.Sprintf("Network rule %d %s for user %s", rule.ID, strings.ToLower(strings.TrimPrefix(logType, "NETWORK_RULE_")), rule.UserID),
+		DataJson:  json.RawMessage(data),
+		CreatedAt: time.Now(),
+	})
+}
@@ -0,0 +1,151 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestNetworkRule_Validate(t *testing.T) {
+	rule := NetworkRule{UserID: "alice", Direction: " egress", CIDR: "203.0.113.5", Ports: []uint32{443, 80, 443}}
+	if err := rule.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Direction != NetworkRuleEgress || rule.Protocol != "TCP" || rule.CIDR != "203.0.113.5/32" || !reflect.DeepEqual(rule.Ports, []uint32{80, 443}) {
+		t.Fatalf("rule=%+v", rule)
+	}
+
+	invalid := []NetworkRule{
+		{UserID: "alice", Direction: "BOTH", PeerUserID: "bob"},
+		{UserID: "alice", Direction: "INGRESS"},
+		{UserID: "alice", Direction: "INGRESS", PeerUserID: "bob", CIDR: "10.0.0.0/8"},
+		{UserID: "alice", Direction: "INGRESS", PeerUserID: "alice"},
+		{UserID: "alice", Direction: "EGRESS", CIDR: "10.0.0.0/33"},
+		{UserID: "alice", Direction: "EGRESS", CIDR: "10.0.0.0/8", Protocol: "ICMP"},
+		{UserID: "alice", Direction: "EGRESS", CIDR: "10.0.0.0/8", Ports: []uint32{70000}},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidNetworkRule) {
+			t.Errorf("rule=%+v err=%v want ErrInvalidNetworkRule", r, err)
+		}
+	}
+}
+
+type fakeRuleNetworkRepo struct {
+	NetworkRepo
+	rules  map[int64]NetworkRule
+	nextID int64
+}
+
+func (f *fakeRuleNetworkRepo) CreateNetworkRule(_ context.Context, rule *NetworkRule) error {
+	f.nextID++
+	rule.ID = f.nextID
+	f.rules[rule.ID] = *rule
+	return nil
+}
+
+func (f *fakeRuleNetworkRepo) GetNetworkRule(_ context.Context, ruleID int64) (*NetworkRule, error) {
+	rule, ok := f.rules[ruleID]
+	if !ok {
+		return nil, nil
+	}
+	return &rule, nil
+}
+
+func (f *fakeRuleNetworkRepo) DeleteNetworkRule(_ context.Context, ruleID int64) error {
+	delete(f.rules, ruleID)
+	return nil
+}
+
+type fakeRuleK8sRepo struct {
+	K8sRepo
+	applied map[int64]string // rule ID -> namespace
+	err     error
+}
+
+func (f *fakeRuleK8sRepo) ApplyNetworkRule(_ context.Context, namespace string, rule NetworkRule) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.applied[rule.ID] = namespace
+	return nil
+}
+
+func (f *fakeRuleK8sRepo) DeleteNetworkRule(_ context.Context, _ string, ruleID int64) error {
+	delete(f.applied, ruleID)
+	return nil
+}
+
+func TestResourceUsecase_NetworkRules(t *testing.T) {
+	network := &fakeRuleNetworkRepo{rules: map[int64]NetworkRule{}}
+	k8s := &fakeRuleK8sRepo{applied: map[int64]string{}}
+	audit := &fakeAuditRepo{}
+	uc := &ResourceUsecase{
+		AuditRepo:   audit,
+		K8sRepo:     k8s,
+		NetworkRepo: network,
+		log:         log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+	ctx := context.Background()
+
+	rule, err := uc.CreateNetworkRule(ctx, NetworkRule{UserID: "alice", Direction: "ingress", PeerUserID: "bob", Ports: []uint32{8080}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k8s.applied[rule.ID] != "alice" || len(audit.records) != 1 || audit.records[0].LogType != "NETWORK_RULE_CREATED" {
+		t.Fatalf("applied=%v audit=%+v", k8s.applied, audit.records)
+	}
+
+	// 其他用户不能删除
+	if err := uc.DeleteNetworkRule(ctx, "bob", rule.ID); !errors.Is(err, ErrNetworkRuleNotFound) {
+		t.Fatalf("err=%v want ErrNetworkRuleNotFound", err)
+	}
+	if err := uc.DeleteNetworkRule(ctx, "alice", rule.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(k8s.applied) != 0 || len(network.rules) != 0 {
+		t.Fatalf("applied=%v rules=%v", k8s.applied, network.rules)
+	}
+
+	// NetworkPolicy 创建失败时不保留记录
+	k8s.err = ErrNetworkPolicyDisabled
+	if _, err := uc.CreateNetworkRule(ctx, NetworkRule{UserID: "alice", Direction: "EGRESS", CIDR: "10.0.0.0/8"}); !errors.Is(err, ErrNetworkPolicyDisabled) {
+		t.Fatalf("err=%v want ErrNetworkPolicyDisabled", err)
+	}
+	if len(network.rules) != 0 {
+		t.Fatalf("rule not rolled back: %v", network.rules)
+	}
+}
+
+type fakePolicyK8sRepo struct {
+	K8sRepo
+	ensured []string
+}
+
+func (f *fakePolicyK8sRepo) EnsureNetworkPolicies(_ context.Context, namespace string) error {
+	f.ensured = append(f.ensured, namespace)
+	if namespace == "bob" {
+		return errors.New("forbidden")
+	}
+	return nil
+}
+
+func TestResourceUsecase_EnsureNetworkPolicies(t *testing.T) {
+	repo := &listInstanceRepo{fakeInstanceRepo{resources: map[int64]*Resource{
+		1: {InstanceID: 1, UserID: "alice"},
+		2: {InstanceID: 2, UserID: "alice"},
+		3: {InstanceID: 3, UserID: "bob"},
+	}}}
+	k8s := &fakePolicyK8sRepo{}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, nil, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+
+	// 每个命名空间只处理一次，单个失败不影响其他命名空间
+	failed, err := uc.EnsureNetworkPolicies(context.Background())
+	if err != nil || failed != 1 || len(k8s.ensured) != 2 {
+		t.Fatalf("failed=%d err=%v ensured=%v", failed, err, k8s.ensured)
+	}
+}
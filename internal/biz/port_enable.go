@@ -0,0 +1,140 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPortProtocolMismatch 重新打开已禁用的端口时协议与原配置不一致
+	ErrPortProtocolMismatch = errors.New("port protocol mismatch")
+	// ErrPortOptionsMismatch 重新打开已禁用的端口时传输协议、域名、路由、TLS 或访问控制与原配置不一致
+	ErrPortOptionsMismatch = errors.New("port options mismatch")
+)
+
+// SetPortEnabled 启用或禁用已开放的端口，返回访问地址。
+// 禁用时删除路由资源（Ingress/HTTPRoute、ConfigMap 条目/TCPRoute/UDPRoute，NODEPORT/LOADBALANCER 清空 Service selector），
+// 保留 Service、外部端口与访问地址；重新启用后地址不变。
+func (uc *ResourceUsecase) SetPortEnabled(ctx context.Context, instanceID int64, port uint32, enabled bool) (string, error) {
+	uc.log.WithContext(ctx).Infof("SetPortEnabled: instanceID=%d port=%d enabled=%v", instanceID, port, enabled)
+
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return "", err
+	}
+	if resource == nil {
+		return "", ErrInstanceNotFound
+	}
+
+	binding, err := uc.NetworkRepo.GetNetworkBinding(ctx, instanceID, port)
+	if err != nil {
+		return "", err
+	}
+	if binding == nil {
+		return "", ErrPortNotOpen
+	}
+	if binding.Enabled == enabled {
+		bindings := []NetworkBinding{*binding}
+		uc.refreshAccessURLs(ctx, resource.UserID, bindings)
+		return bindings[0].AccessURL, nil // 幂等
+	}
+
+	return uc.setPortEnabled(ctx, resource.UserID, binding, enabled)
+}
+
+// setPortEnabled 按目标状态同步 K8s 路由资源后更新绑定记录
+func (uc *ResourceUsecase) setPortEnabled(ctx context.Context, namespace string, binding *NetworkBinding, enabled bool) (string, error) {
+	updated := *binding
+	updated.Enabled = enabled
+	if err := uc.K8sRepo.EnsureNetworkBinding(ctx, namespace, updated); err != nil {
+		return "", err
+	}
+	if err := uc.NetworkRepo.UpdateNetworkBinding(ctx, updated); err != nil {
+		// 回到原状态，失败时留给网络核对处理
+		if rollbackErr := uc.K8sRepo.EnsureNetworkBinding(ctx, namespace, *binding); rollbackErr != nil {
+			uc.log.WithContext(ctx).Errorf("failed to roll back port %d of instance %d: %v", binding.Port, binding.InstanceID, rollbackErr)
+		}
+		return "", err
+	}
+	if updated.IngressName != nil {
+		uc.syncCustomDomains(ctx, namespace, []NetworkBinding{updated})
+	}
+	bindings := []NetworkBinding{updated}
+	uc.refreshAccessURLs(ctx, namespace, bindings)
+	accessURL := bindings[0].AccessURL
+
+	logType, action := "PORT_DISABLED", " disabled"
+	if enabled {
+		logType, action = "PORT_ENABLED", " enabled"
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"port":       binding.Port,
+		"protocol":   binding.Protocol,
+		"access_url": accessURL,
+	})
+	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
+		InstanceID: binding.InstanceID,
+		LogType:    logType,
+		Message:    "Port " + strconv.Itoa(int(binding.Port)) + action,
+		DataJson:   json.RawMessage(data),
+		CreatedAt:  time.Now(),
+	})
+
+	uc.log.WithContext(ctx).Infof("port %d of instance %d%s, access URL: %s", binding.Port, binding.InstanceID, action, accessURL)
+	return accessURL, nil
+}
+
+// checkReopenOptions 检查重新打开已禁用端口的参数与原配置一致。重新打开只恢复原配置，
+// 不一致时返回错误，避免静默丢弃新参数；修改访问控制使用 UpdatePortProtection，其他修改需先关闭端口
+func checkReopenOptions(existing *NetworkBinding, transport string, ingress IngressOptions) error {
+	switch existing.Protocol {
+	case "NODEPORT", "LOADBALANCER":
+		if transport == "" {
+			transport = "TCP"
+		}
+		if transport != existing.Transport {
+			return fmt.Errorf("%w: port %d is disabled with transport %s, close it before reopening with %s", ErrPortOptionsMismatch, existing.Port, existing.Transport, transport)
+		}
+	case "HTTP":
+		if ingress.Routing == "" {
+			ingress.Routing = IngressRoutingPath
+		}
+		if err := ingress.Protection.Validate(); err != nil {
+			return err
+		}
+		current := existing.Ingress
+		if current.Routing == "" {
+			current.Routing = IngressRoutingPath
+		}
+		domain := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(ingress.Domain), "."))
+		if domain != strings.ToLower(current.Domain) || ingress.Routing != current.Routing || ingress.TLS != current.TLS {
+			return fmt.Errorf("%w: port %d is disabled with domain %q, routing %s, tls %v, close it before reopening with different ingress options",
+				ErrPortOptionsMismatch, existing.Port, current.Domain, current.Routing, current.TLS)
+		}
+		if ingress.Protection.BasicAuthPassword != "" || !sameProtection(ingress.Protection, current.Protection) {
+			return fmt.Errorf("%w: port %d is disabled with different protection, reopen it first and then use UpdatePortProtection", ErrPortOptionsMismatch, existing.Port)
+		}
+	}
+	return nil
+}
+
+// sameProtection 比较除密码外的访问控制设置
+func sameProtection(a, b IngressProtection) bool {
+	if a.BasicAuthUser != b.BasicAuthUser || a.RateLimitRPS != b.RateLimitRPS || a.RateLimitRPM != b.RateLimitRPM || a.MaxConnections != b.MaxConnections {
+		return false
+	}
+	if len(a.AllowedCIDRs) != len(b.AllowedCIDRs) {
+		return false
+	}
+	for i := range a.AllowedCIDRs {
+		if a.AllowedCIDRs[i] != b.AllowedCIDRs[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type fakeBindingRepo struct {
+	NetworkRepo
+	bindings map[uint32]NetworkBinding
+}
+
+func (f *fakeBindingRepo) GetNetworkBinding(_ context.Context, _ int64, port uint32) (*NetworkBinding, error) {
+	b, ok := f.bindings[port]
+	if !ok {
+		return nil, nil
+	}
+	return &b, nil
+}
+
+func (f *fakeBindingRepo) UpdateNetworkBinding(_ context.Context, binding NetworkBinding) error {
+	f.bindings[binding.Port] = binding
+	return nil
+}
+
+func (f *fakeBindingRepo) UpdateAccessURL(_ context.Context, binding NetworkBinding, accessURL, externalIP string) error {
+	stored, ok := f.bindings[binding.Port]
+	if !ok {
+		// 与数据库一致：记录不存在时不创建
+		return nil
+	}
+	if stored.AccessURL != binding.AccessURL || stored.ExternalIP != binding.ExternalIP {
+		return nil
+	}
+	stored.AccessURL, stored.ExternalIP = accessURL, externalIP
+	f.bindings[binding.Port] = stored
+	return nil
+}
+
+type fakeEnsureK8sRepo struct {
+	K8sRepo
+	ensured       []NetworkBinding
+	publicAddress string
+	ingressDomain string
+	lbAddresses   map[string]string
+}
+
+func (f *fakeEnsureK8sRepo) GetTCPUDPPublicAddress(context.Context) (string, error) {
+	if f.publicAddress == "" {
+		return "", errors.New("ingress-nginx LoadBalancer has no external IP")
+	}
+	return f.publicAddress, nil
+}
+
+func (f *fakeEnsureK8sRepo) GetIngressDomain() string {
+	return f.ingressDomain
+}
+
+func (f *fakeEnsureK8sRepo) GetLoadBalancerAddress(_ context.Context, _, serviceName string) (string, error) {
+	return f.lbAddresses[serviceName], nil
+}
+
+func (f *fakeEnsureK8sRepo) EnsureNetworkBinding(_ context.Context, _ string, binding NetworkBinding) error {
+	f.ensured = append(f.ensured, binding)
+	return nil
+}
+
+func TestSetPortEnabled(t *testing.T) {
+	externalPort := uint32(30002)
+	network := &fakeBindingRepo{bindings: map[uint32]NetworkBinding{
+		22: {InstanceID: 1, Port: 22, ServiceName: "instance-1-22", ExternalPort: &externalPort, Protocol: "TCP", AccessURL: "203.0.113.10:30002", Enabled: true},
+	}}
+	k8s := &fakeEnsureK8sRepo{publicAddress: "203.0.113.10"}
+	audit := &fakeAuditRepo{}
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}
+	uc := NewResourceUsecase(repo, audit, k8s, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	url, err := uc.SetPortEnabled(ctx, 1, 22, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "203.0.113.10:30002" || network.bindings[22].Enabled || len(k8s.ensured) != 1 || k8s.ensured[0].Enabled {
+		t.Fatalf("url=%s binding=%+v ensured=%+v", url, network.bindings[22], k8s.ensured)
+	}
+	if len(audit.records) != 1 || audit.records[0].LogType != "PORT_DISABLED" {
+		t.Fatalf("audit=%+v", audit.records)
+	}
+
+	// 重复禁用保持幂等
+	if _, err := uc.SetPortEnabled(ctx, 1, 22, false); err != nil || len(k8s.ensured) != 1 {
+		t.Fatalf("err=%v ensured=%d", err, len(k8s.ensured))
+	}
+
+	// 通过 SetInstancePort 重新打开时沿用原外部端口与地址
+	url, err = uc.SetInstancePort(ctx, 1, 22, "TCP", "", true, IngressOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "203.0.113.10:30002" || !network.bindings[22].Enabled || *network.bindings[22].ExternalPort != 30002 {
+		t.Fatalf("url=%s binding=%+v", url, network.bindings[22])
+	}
+
+	// 协议不一致时需要先关闭
+	network.bindings[22] = NetworkBinding{InstanceID: 1, Port: 22, Protocol: "TCP"}
+	if _, err := uc.SetInstancePort(ctx, 1, 22, "HTTP", "", true, IngressOptions{}); !errors.Is(err, ErrPortProtocolMismatch) {
+		t.Fatalf("err=%v want ErrPortProtocolMismatch", err)
+	}
+
+	if _, err := uc.SetPortEnabled(ctx, 1, 8080, true); !errors.Is(err, ErrPortNotOpen) {
+		t.Fatalf("err=%v want ErrPortNotOpen", err)
+	}
+}
+
+func TestSetInstancePort_ReopenOptionsMismatch(t *testing.T) {
+	ingressName := "instance-1-80"
+	stored := NetworkBinding{
+		InstanceID: 1, Port: 80, ServiceName: "instance-1-80", IngressName: &ingressName, Protocol: "HTTP", AccessURL: "http://demo.localtest.me/alice/1/80",
+		Ingress: IngressOptions{Domain: "demo.localtest.me", Routing: IngressRoutingPath, Protection: IngressProtection{AllowedCIDRs: []string{"10.0.0.0/8"}}},
+	}
+	network := &protectionBindingRepo{fakeBindingRepo{bindings: map[uint32]NetworkBinding{80: stored}}}
+	k8s := &fakeEnsureK8sRepo{}
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	// 与原配置不一致时返回错误，不静默丢弃新参数
+	mismatched := []IngressOptions{
+		{Domain: "other.localtest.me", Protection: IngressProtection{AllowedCIDRs: []string{"10.0.0.0/8"}}},
+		{Domain: "demo.localtest.me", Routing: IngressRoutingHost, Protection: IngressProtection{AllowedCIDRs: []string{"10.0.0.0/8"}}},
+		{Domain: "demo.localtest.me", TLS: true, Protection: IngressProtection{AllowedCIDRs: []string{"10.0.0.0/8"}}},
+		{Domain: "demo.localtest.me"},
+		{Domain: "demo.localtest.me", Protection: IngressProtection{BasicAuthUser: "admin", BasicAuthPassword: "secret", AllowedCIDRs: []string{"10.0.0.0/8"}}},
+	}
+	for _, opts := range mismatched {
+		if _, err := uc.SetInstancePort(ctx, 1, 80, "HTTP", "", true, opts); !errors.Is(err, ErrPortOptionsMismatch) {
+			t.Errorf("opts=%+v err=%v want ErrPortOptionsMismatch", opts, err)
+		}
+	}
+	if len(k8s.ensured) != 0 || network.bindings[80].Enabled {
+		t.Fatalf("ensured=%+v binding=%+v", k8s.ensured, network.bindings[80])
+	}
+
+	// 相同配置（单个 IP 规范化后一致）按原配置重新启用
+	opts := IngressOptions{Domain: "demo.localtest.me", Protection: IngressProtection{AllowedCIDRs: []string{"10.1.2.3/8"}}}
+	if _, err := uc.SetInstancePort(ctx, 1, 80, "HTTP", "", true, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !network.bindings[80].Enabled || len(k8s.ensured) != 1 {
+		t.Fatalf("binding=%+v ensured=%+v", network.bindings[80], k8s.ensured)
+	}
+
+	// NODEPORT/LOADBALANCER 的传输协议不同
+	network.bindings[9000] = NetworkBinding{InstanceID: 1, Port: 9000, Protocol: "NODEPORT", Transport: "TCP"}
+	if _, err := uc.SetInstancePort(ctx, 1, 9000, "NODEPORT", "UDP", true, IngressOptions{}); !errors.Is(err, ErrPortOptionsMismatch) {
+		t.Fatalf("err=%v want ErrPortOptionsMismatch", err)
+	}
+}
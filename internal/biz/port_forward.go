@@ -0,0 +1,36 @@
+package biz
+
+import (
+	"context"
+	"io"
+	"strconv"
+)
+
+// PortForwardOptions 端口转发选项
+type PortForwardOptions struct {
+	Namespace  string
+	InstanceID string
+	PodName    string // 为空时选择最新的 Ready Pod
+	Port       int32
+}
+
+// PortForward 建立到实例容器端口的单个 TCP 隧道：src 的数据写入容器端口，容器端口返回的数据写入 dst。
+// 隧道建立后回调 ready，任一方向结束或 ctx 取消时关闭隧道。
+func (uc *ResourceUsecase) PortForward(ctx context.Context, instanceID int64, podName string, port int32, src io.Reader, dst io.Writer, ready func(podName string) error) error {
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if resource == nil {
+		return ErrInstanceNotFound
+	}
+
+	opts := PortForwardOptions{
+		Namespace:  resource.UserID,
+		InstanceID: strconv.FormatInt(instanceID, 10),
+		PodName:    podName,
+		Port:       port,
+	}
+	uc.log.WithContext(ctx).Infof("PortForward: instanceID=%d port=%d", instanceID, port)
+	return uc.ExecRepo.PortForward(ctx, opts, src, dst, ready)
+}
@@ -0,0 +1,34 @@
+package biz
+
+import (
+	"context"
+)
+
+// restoreNetworkBindings 启动实例后按记录核对并修复每个端口的 Service、路由与外部端口，
+// 禁用的端口保持禁用，同时修复已生效的自定义域名。返回修复失败的端口，随后将实例全部端口标记为 active。
+func (uc *ResourceUsecase) restoreNetworkBindings(ctx context.Context, namespace string, instanceID int64) []uint32 {
+	failed := []uint32{}
+
+	bindings, err := uc.NetworkRepo.ListNetworkBindings(ctx, instanceID)
+	if err != nil {
+		uc.log.WithContext(ctx).Warnf("StartInstance: failed to list network bindings of instance %d: %v", instanceID, err)
+		return failed
+	}
+	if len(bindings) == 0 {
+		return failed
+	}
+
+	for _, b := range bindings {
+		if err := uc.K8sRepo.EnsureNetworkBinding(ctx, namespace, b); err != nil {
+			uc.log.WithContext(ctx).Warnf("StartInstance: failed to restore port %d of instance %d: %v", b.Port, instanceID, err)
+			failed = append(failed, b.Port)
+		}
+	}
+
+	uc.syncCustomDomains(ctx, namespace, bindings)
+
+	if err := uc.NetworkRepo.SetNetworkBindingsActive(ctx, instanceID, true); err != nil {
+		uc.log.WithContext(ctx).Warnf("StartInstance: failed to mark network bindings of instance %d active: %v", instanceID, err)
+	}
+	return failed
+}
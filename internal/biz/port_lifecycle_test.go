@@ -0,0 +1,133 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type lifecycleNetworkRepo struct {
+	fakeNetworkRepo
+	batchDeleted []int64
+}
+
+func (f *lifecycleNetworkRepo) SetNetworkBindingsActive(_ context.Context, instanceID int64, active bool) error {
+	for i := range f.bindings {
+		if f.bindings[i].InstanceID == instanceID {
+			f.bindings[i].Active = active
+		}
+	}
+	return nil
+}
+
+func (f *lifecycleNetworkRepo) BatchDeleteNetworkBindings(_ context.Context, instanceID int64) error {
+	f.batchDeleted = append(f.batchDeleted, instanceID)
+	return nil
+}
+
+type lifecycleK8sRepo struct {
+	fakeNetworkK8sRepo
+	ensureErr map[uint32]error
+	calls     []string
+}
+
+func (f *lifecycleK8sRepo) StopInstance(context.Context, string, string) error {
+	f.calls = append(f.calls, "stop")
+	return nil
+}
+
+func (f *lifecycleK8sRepo) StartInstance(context.Context, string, string) error {
+	f.calls = append(f.calls, "start")
+	return nil
+}
+
+func (f *lifecycleK8sRepo) DeleteInstance(context.Context, string, string) error {
+	f.calls = append(f.calls, "delete")
+	return nil
+}
+
+func (f *lifecycleK8sRepo) EnsureNetworkBinding(ctx context.Context, namespace string, binding NetworkBinding) error {
+	if err := f.ensureErr[binding.Port]; err != nil {
+		return err
+	}
+	return f.fakeNetworkK8sRepo.EnsureNetworkBinding(ctx, namespace, binding)
+}
+
+func newTestLifecycleUsecase() (*ResourceUsecase, *lifecycleNetworkRepo, *lifecycleK8sRepo, *fakeAuditRepo) {
+	externalPort := uint32(30002)
+	ingress := "ingress-1-80"
+	network := &lifecycleNetworkRepo{fakeNetworkRepo: fakeNetworkRepo{bindings: []NetworkBinding{
+		{InstanceID: 1, Port: 22, ServiceName: "instance-1-22", ExternalPort: &externalPort, Protocol: "TCP", Enabled: true, Active: true},
+		{InstanceID: 1, Port: 80, ServiceName: "instance-1-80", IngressName: &ingress, Protocol: "HTTP", Enabled: false, Active: true},
+		{InstanceID: 2, Port: 80, ServiceName: "instance-2-80", Protocol: "HTTP", Enabled: true, Active: true},
+	}}}
+	k8s := &lifecycleK8sRepo{}
+	audit := &fakeAuditRepo{}
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}
+	return NewResourceUsecase(repo, audit, k8s, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard)), network, k8s, audit
+}
+
+func TestResourceUsecase_StopStartRestoresPorts(t *testing.T) {
+	uc, network, k8s, audit := newTestLifecycleUsecase()
+	ctx := context.Background()
+
+	if err := uc.StopInstance(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if network.bindings[0].Active || network.bindings[1].Active || !network.bindings[2].Active {
+		t.Fatalf("bindings=%+v", network.bindings)
+	}
+	if len(k8s.deleted) != 0 {
+		t.Fatalf("stop must keep exposure, deleted=%v", k8s.deleted)
+	}
+
+	// 端口 22 修复失败不影响启动，仍记录在审计日志中
+	k8s.ensureErr = map[uint32]error{22: errors.New("configmap conflict")}
+	if err := uc.StartInstance(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(k8s.ensured) != 1 || k8s.ensured[0] != 80 {
+		t.Fatalf("ensured=%v", k8s.ensured)
+	}
+	if !network.bindings[0].Active || !network.bindings[1].Active || network.bindings[1].Enabled {
+		t.Fatalf("bindings=%+v", network.bindings)
+	}
+
+	var data struct {
+		FailedPorts []uint32 `json:"failed_ports"`
+	}
+	last := audit.records[len(audit.records)-1]
+	if err := json.Unmarshal(last.DataJson, &data); err != nil || last.LogType != "START" || len(data.FailedPorts) != 1 || data.FailedPorts[0] != 22 {
+		t.Fatalf("audit=%+v err=%v", last, err)
+	}
+}
+
+func TestResourceUsecase_DeleteInstanceReleasesPorts(t *testing.T) {
+	uc, network, k8s, _ := newTestLifecycleUsecase()
+
+	if err := uc.DeleteInstance(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		"service:alice/instance-1-22": true,
+		"TCP:30002":                   true,
+		"service:alice/instance-1-80": true,
+		"ingress:alice/ingress-1-80":  true,
+	}
+	if len(k8s.deleted) != len(want) {
+		t.Fatalf("deleted=%v", k8s.deleted)
+	}
+	for _, d := range k8s.deleted {
+		if !want[d] {
+			t.Fatalf("unexpected delete %s", d)
+		}
+	}
+	if len(k8s.calls) != 1 || k8s.calls[0] != "delete" || len(network.batchDeleted) != 1 || network.batchDeleted[0] != 1 {
+		t.Fatalf("calls=%v batchDeleted=%v", k8s.calls, network.batchDeleted)
+	}
+}
@@ -0,0 +1,149 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPortNotOpen 端口未开放
+	ErrPortNotOpen = errors.New("port is not open")
+	// ErrProtectionNotSupported 访问控制仅支持通过 Ingress 暴露的 HTTP 端口
+	ErrProtectionNotSupported = errors.New("protection is only supported for HTTP ports")
+	// ErrInvalidProtection 访问控制参数不合法
+	ErrInvalidProtection = errors.New("invalid protection")
+	// ErrBasicAuthPasswordRequired 新启用 basic auth 或更换用户名时必须提供密码
+	ErrBasicAuthPasswordRequired = errors.New("basic_auth_password is required")
+	// ErrPortDisabled 端口已禁用，无法执行需要路由资源的操作
+	ErrPortDisabled = errors.New("port is disabled")
+)
+
+// IngressProtection HTTP 端口的访问控制，由 ingress-nginx 注解实现。
+// 除密码外均随绑定记录持久化，密码仅以 bcrypt 形式保存在 {ingressName}-auth Secret 中。
+type IngressProtection struct {
+	BasicAuthUser     string
+	BasicAuthPassword string   // 只写；为空表示保留 Secret 中的现有密码
+	AllowedCIDRs      []string // 来源 IP 白名单，为空时不限制
+	RateLimitRPS      uint32   // 每个客户端 IP 每秒请求数，0 表示不限制
+	RateLimitRPM      uint32   // 每个客户端 IP 每分钟请求数，0 表示不限制
+	MaxConnections    uint32   // 每个客户端 IP 的并发连接数，0 表示不限制
+}
+
+// Enabled 是否设置了任一访问控制
+func (p IngressProtection) Enabled() bool {
+	return p.BasicAuthUser != "" || len(p.AllowedCIDRs) > 0 || p.RateLimitRPS > 0 || p.RateLimitRPM > 0 || p.MaxConnections > 0
+}
+
+// Validate 校验并规范化访问控制参数，单个 IP 转换为 /32 或 /128
+func (p *IngressProtection) Validate() error {
+	if p.BasicAuthUser == "" && p.BasicAuthPassword != "" {
+		return fmt.Errorf("%w: basic_auth_username is required when basic_auth_password is set", ErrInvalidProtection)
+	}
+	// htpasswd 格式以冒号分隔用户名与密码哈希
+	if strings.ContainsAny(p.BasicAuthUser, ":\r\n") {
+		return fmt.Errorf("%w: basic_auth_username must not contain ':' or line breaks", ErrInvalidProtection)
+	}
+	// bcrypt 只使用前 72 字节
+	if len(p.BasicAuthPassword) > 72 {
+		return fmt.Errorf("%w: basic_auth_password must be at most 72 bytes", ErrInvalidProtection)
+	}
+
+	cidrs := make([]string, 0, len(p.AllowedCIDRs))
+	for _, c := range p.AllowedCIDRs {
+		cidr, err := normalizeCIDR(c)
+		if err != nil {
+			return fmt.Errorf("%w: invalid CIDR %q", ErrInvalidProtection, c)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	p.AllowedCIDRs = cidrs
+	return nil
+}
+
+// normalizeCIDR 规范化 CIDR，单个 IP 转换为 /32 或 /128
+func normalizeCIDR(c string) (string, error) {
+	c = strings.TrimSpace(c)
+	if ip := net.ParseIP(c); ip != nil {
+		if ip.To4() != nil {
+			c += "/32"
+		} else {
+			c += "/128"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(c)
+	if err != nil {
+		return "", err
+	}
+	return ipNet.String(), nil
+}
+
+// UpdatePortProtection 整体替换已开放 HTTP 端口的访问控制，原地更新 Ingress 注解与 basic auth Secret。
+// 已禁用的端口只更新记录，重新启用时按记录重建 Ingress
+func (uc *ResourceUsecase) UpdatePortProtection(ctx context.Context, instanceID int64, port uint32, protection IngressProtection) error {
+	uc.log.WithContext(ctx).Infof("UpdatePortProtection: instanceID=%d port=%d basicAuth=%v cidrs=%v rps=%d rpm=%d conns=%d",
+		instanceID, port, protection.BasicAuthUser != "", protection.AllowedCIDRs, protection.RateLimitRPS, protection.RateLimitRPM, protection.MaxConnections)
+
+	if err := protection.Validate(); err != nil {
+		return err
+	}
+
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if resource == nil {
+		return ErrInstanceNotFound
+	}
+
+	binding, err := uc.NetworkRepo.GetNetworkBinding(ctx, instanceID, port)
+	if err != nil {
+		return err
+	}
+	if binding == nil {
+		return ErrPortNotOpen
+	}
+	if binding.IngressName == nil {
+		return ErrProtectionNotSupported
+	}
+
+	if binding.Enabled {
+		if err := uc.K8sRepo.UpdateIngressProtection(ctx, resource.UserID, *binding.IngressName, protection); err != nil {
+			return err
+		}
+	} else if protection.BasicAuthPassword != "" {
+		// 禁用时 Ingress 已删除，只保存访问控制，重新启用时按记录重建；密码不落库，无法留到重新启用时写入 Secret
+		return fmt.Errorf("%w: enable the port before changing basic auth password", ErrPortDisabled)
+	}
+
+	binding.Ingress.Protection = protection
+	binding.Ingress.Protection.BasicAuthPassword = ""
+	if err := uc.NetworkRepo.UpdateNetworkBinding(ctx, *binding); err != nil {
+		return err
+	}
+	uc.syncCustomDomains(ctx, resource.UserID, []NetworkBinding{*binding})
+
+	// 审计日志不记录密码
+	data, _ := json.Marshal(map[string]interface{}{
+		"port":            port,
+		"basic_auth":      protection.BasicAuthUser != "",
+		"allowed_cidrs":   protection.AllowedCIDRs,
+		"rate_limit_rps":  protection.RateLimitRPS,
+		"rate_limit_rpm":  protection.RateLimitRPM,
+		"max_connections": protection.MaxConnections,
+	})
+	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
+		InstanceID: instanceID,
+		LogType:    "PORT_PROTECTION_UPDATED",
+		Message:    "Port " + strconv.Itoa(int(port)) + " protection updated",
+		DataJson:   json.RawMessage(data),
+		CreatedAt:  time.Now(),
+	})
+
+	return nil
+}
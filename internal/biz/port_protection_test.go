@@ -0,0 +1,110 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestIngressProtection_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        IngressProtection
+		wantCIDRs []string
+		wantErr   bool
+	}{
+		{
+			name:      "normalize",
+			in:        IngressProtection{AllowedCIDRs: []string{"10.1.2.3/8", " 203.0.113.7 ", "2001:db8::1"}},
+			wantCIDRs: []string{"10.0.0.0/8", "203.0.113.7/32", "2001:db8::1/128"},
+		},
+		{
+			name:      "basic_auth",
+			in:        IngressProtection{BasicAuthUser: "admin", BasicAuthPassword: "secret"},
+			wantCIDRs: []string{},
+		},
+		{name: "invalid_cidr", in: IngressProtection{AllowedCIDRs: []string{"10.0.0.0/33"}}, wantErr: true},
+		{name: "colon_in_user", in: IngressProtection{BasicAuthUser: "a:b", BasicAuthPassword: "x"}, wantErr: true},
+		{name: "password_without_user", in: IngressProtection{BasicAuthPassword: "x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.in
+			err := p.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidProtection) {
+					t.Fatalf("err=%v want ErrInvalidProtection", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p.AllowedCIDRs, tt.wantCIDRs) {
+				t.Fatalf("cidrs=%v want %v", p.AllowedCIDRs, tt.wantCIDRs)
+			}
+		})
+	}
+}
+
+type protectionBindingRepo struct {
+	fakeBindingRepo
+}
+
+func (f *protectionBindingRepo) ListCustomDomains(context.Context, int64) ([]CustomDomain, error) {
+	return nil, nil
+}
+
+type fakeProtectionK8sRepo struct {
+	K8sRepo
+	updated []IngressProtection
+}
+
+func (f *fakeProtectionK8sRepo) UpdateIngressProtection(_ context.Context, _, _ string, protection IngressProtection) error {
+	f.updated = append(f.updated, protection)
+	return nil
+}
+
+func TestUpdatePortProtection_DisabledPort(t *testing.T) {
+	ingressName := "instance-1-80"
+	network := &protectionBindingRepo{fakeBindingRepo{bindings: map[uint32]NetworkBinding{
+		80: {InstanceID: 1, Port: 80, Protocol: "HTTP", IngressName: &ingressName, Enabled: false},
+	}}}
+	k8s := &fakeProtectionK8sRepo{}
+	repo := &fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	// Ingress 已随禁用删除：只保存记录，重新启用时按记录重建
+	if err := uc.UpdatePortProtection(ctx, 1, 80, IngressProtection{AllowedCIDRs: []string{"10.0.0.0/8"}, MaxConnections: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(k8s.updated) != 0 {
+		t.Fatalf("ingress must not be updated for a disabled port: %+v", k8s.updated)
+	}
+	if p := network.bindings[80].Ingress.Protection; p.MaxConnections != 5 || !reflect.DeepEqual(p.AllowedCIDRs, []string{"10.0.0.0/8"}) {
+		t.Fatalf("protection=%+v", p)
+	}
+
+	// 密码无法保存到重新启用时
+	err := uc.UpdatePortProtection(ctx, 1, 80, IngressProtection{BasicAuthUser: "admin", BasicAuthPassword: "secret"})
+	if !errors.Is(err, ErrPortDisabled) || network.bindings[80].Ingress.Protection.BasicAuthUser != "" {
+		t.Fatalf("err=%v binding=%+v", err, network.bindings[80])
+	}
+
+	// 已启用的端口原地更新 Ingress
+	b := network.bindings[80]
+	b.Enabled = true
+	network.bindings[80] = b
+	if err := uc.UpdatePortProtection(ctx, 1, 80, IngressProtection{BasicAuthUser: "admin", BasicAuthPassword: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(k8s.updated) != 1 || network.bindings[80].Ingress.Protection.BasicAuthPassword != "" {
+		t.Fatalf("updated=%+v binding=%+v", k8s.updated, network.bindings[80])
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"time"
 
@@ -13,13 +14,19 @@ import (
 
 var ErrInstanceAlreadyExists = errors.New("instance already exists")
 
+// ErrInstanceNotFound 实例不存在
+var ErrInstanceNotFound = errors.New("instance not found")
+
 type ResourceUsecase struct {
-	InstanceSpec InstanceRepo
-	AuditRepo    AuditRepo
-	K8sRepo      K8sRepo
-	NetworkRepo  NetworkRepo
-	ExecRepo     ExecRepo
-	log          *log.Helper
+	InstanceSpec    InstanceRepo
+	AuditRepo       AuditRepo
+	K8sRepo         K8sRepo
+	NetworkRepo     NetworkRepo
+	ExecRepo        ExecRepo
+	ExecSessionRepo ExecSessionRepo
+	RecordingStore  ExecRecordingStore
+	DomainResolver  DomainResolver
+	log             *log.Helper
 }
 
 type AuditInformation struct {
@@ -43,6 +50,11 @@ type InstanceSpec struct {
 	GPU        uint32
 	Image      string
 	ConfigJSON json.RawMessage
+	// 带宽上限（Mbit/s），通过 kubernetes.io/ingress-bandwidth 与 egress-bandwidth Pod 注解生效，0 表示不限制
+	IngressBandwidth uint32
+	EgressBandwidth  uint32
+	// PortMaxConnections 已暴露 HTTP 端口的连接数限制，key 为端口；仅查询时填充
+	PortMaxConnections map[uint32]uint32
 }
 
 type InstanceRepo interface {
@@ -52,27 +64,35 @@ type InstanceRepo interface {
 	GetResource(ctx context.Context, instanceID int64) (*Resource, error)
 	// ListResourceSpecs returns resource specs keyed by instance ID.
 	ListResourceSpecs(ctx context.Context, instanceIDs []int64) (map[int64]InstanceSpec, error)
+	// UpdateStatusReason records the latest failure diagnosis of an instance, empty when healthy.
+	UpdateStatusReason(ctx context.Context, instanceID int64, reason, message string) error
+	// UpdateInstanceSpec persists the updated CPU, memory and bandwidth limits; image and GPU are left unchanged.
+	UpdateInstanceSpec(ctx context.Context, spec InstanceSpec) error
 }
 
 type K8sRepo interface {
 	CreateInstance(ctx context.Context, spec InstanceSpec) error
 
-	// DeleteInstance deletes an instance deployment and its associated resources
+	// DeleteInstance deletes an instance deployment and its associated resources, releasing external ports
 	DeleteInstance(ctx context.Context, namespace, instanceID string) error
 
-	// StopInstance scales the deployment replicas to 0
+	// StopInstance scales the deployment replicas to 0 and marks its ports inactive
 	StopInstance(ctx context.Context, namespace, instanceID string) error
 
-	// StartInstance scales the deployment replicas to 1
+	// StartInstance scales the deployment replicas to 1 and restores the exposure of its ports
 	StartInstance(ctx context.Context, namespace, instanceID string) error
 
 	// UpdateInstance dynamically updates the instance's resource quotas (CPU/Mem/GPU) and container image
 	UpdateInstance(ctx context.Context, spec InstanceSpec) error
 
-	// CreateServiceForTCPUDP creates a ClusterIP Service for TCP/UDP protocols and patches ingress-nginx ConfigMap
+	// CreateServiceForTCPUDP creates a ClusterIP Service for TCP/UDP protocols and exposes it through the exposure backend
 	// Returns: serviceName, externalPort, error
 	CreateServiceForTCPUDP(ctx context.Context, namespace, instanceID string, port uint32, protocol string) (string, uint32, error)
 
+	// CreateDirectService creates a NodePort or LoadBalancer Service that exposes the port without ingress
+	// Returns: serviceName, endpoint, error
+	CreateDirectService(ctx context.Context, namespace, instanceID string, port uint32, serviceType, transport string) (string, DirectEndpoint, error)
+
 	// CreateServiceForHTTP creates a ClusterIP Service for HTTP protocol
 	// Returns: serviceName, error
 	CreateServiceForHTTP(ctx context.Context, namespace, instanceID string, port uint32) (string, error)
@@ -80,38 +100,109 @@ type K8sRepo interface {
 	// DeleteService deletes a Service by name
 	DeleteService(ctx context.Context, namespace, serviceName string) error
 
-	// DeleteTCPUDPConfigMapEntry deletes a TCP/UDP ConfigMap entry
-	DeleteTCPUDPConfigMapEntry(ctx context.Context, protocol string, externalPort uint32) error
+	// ReleaseTCPUDPPort removes the external exposure of a TCP/UDP port from the exposure backend:
+	// the tcp/udp-services ConfigMap entry and ingress-nginx Service port, or the Gateway listener and route
+	ReleaseTCPUDPPort(ctx context.Context, protocol string, externalPort uint32) error
 
 	// CreateIngress creates an Ingress for HTTP access
 	// Returns: ingressName, accessURL, error
-	CreateIngress(ctx context.Context, namespace, instanceID string, port uint32, serviceName, ingressDomain string) (string, string, error)
+	CreateIngress(ctx context.Context, namespace, instanceID string, port uint32, serviceName string, opts IngressOptions) (string, string, error)
 
 	// DeleteIngress deletes an Ingress by name
 	DeleteIngress(ctx context.Context, namespace, ingressName string) error
 
+	// UpdateIngressProtection replaces the access control annotations and basic auth Secret of an Ingress
+	UpdateIngressProtection(ctx context.Context, namespace, ingressName string, protection IngressProtection) error
+
+	// ApplyNetworkRule creates or replaces the NetworkPolicy of an extra network rule in the user namespace
+	ApplyNetworkRule(ctx context.Context, namespace string, rule NetworkRule) error
+
+	// DeleteNetworkRule deletes the NetworkPolicy of a network rule, returns nil if it doesn't exist
+	DeleteNetworkRule(ctx context.Context, namespace string, ruleID int64) error
+
+	// EnsureNetworkPolicies creates or updates the default isolation NetworkPolicies of an existing user namespace,
+	// returns nil without changes if the namespace doesn't exist, network policies are disabled or backfill is not enabled
+	EnsureNetworkPolicies(ctx context.Context, namespace string) error
+
+	// RunAsLeader elects a leader among replicas with the K8s Lease namespace/name and blocks until ctx is done;
+	// run is called while this replica holds the lease and its context is canceled when the lease is lost
+	RunAsLeader(ctx context.Context, namespace, name string, run func(ctx context.Context)) error
+
 	// GetIngressDomain returns the configured ingress domain
 	GetIngressDomain() string
 
-	// GetIngressNginxLBIP returns the ingress-nginx LoadBalancer External IP
-	GetIngressNginxLBIP(ctx context.Context) (string, error)
+	// GetTCPUDPPublicAddress returns the public address of TCP/UDP ports:
+	// the configured public hostname, otherwise the external address of the exposure backend
+	GetTCPUDPPublicAddress(ctx context.Context) (string, error)
+
+	// GetLoadBalancerAddress returns the external address of a LOADBALANCER port's Service, empty if not assigned yet
+	GetLoadBalancerAddress(ctx context.Context, namespace, serviceName string) (string, error)
+
+	// ListInstanceEvents lists events of the instance's Deployment, ReplicaSets and Pods
+	ListInstanceEvents(ctx context.Context, namespace, instanceID string) ([]InstanceEvent, error)
+
+	// GetNetworkState lists managed Services/Ingresses, tcp/udp-services entries and ingress-nginx Service ports
+	GetNetworkState(ctx context.Context) (*NetworkState, error)
+
+	// EnsureNetworkBinding recreates the missing Service, Ingress, ConfigMap entry and ingress-nginx port of a binding.
+	// For a disabled binding it keeps the Service and external port but removes the routing resources.
+	EnsureNetworkBinding(ctx context.Context, namespace string, binding NetworkBinding) error
+
+	// GetServiceHealth returns the endpoint health of the instance's Services keyed by Service name
+	GetServiceHealth(ctx context.Context, namespace, instanceID string) (map[string]PortHealth, error)
+
+	// EnsureCustomDomain creates or updates the host-based HTTPS Ingress of a verified custom domain,
+	// reusing the access control of the port
+	EnsureCustomDomain(ctx context.Context, namespace string, domain CustomDomain, binding NetworkBinding) error
+	// DeleteCustomDomain deletes the Ingress of a custom domain, returns nil if it doesn't exist
+	DeleteCustomDomain(ctx context.Context, namespace string, domain CustomDomain) error
 }
 
 // ExecRepo K8s exec 操作接口
 type ExecRepo interface {
 	// StreamExec 流式执行容器命令
 	StreamExec(ctx context.Context, opts ExecOptions, input <-chan ExecInput, output chan<- ExecOutput) error
+
+	// ListPods 列出实例的 Pod，按创建时间倒序
+	ListPods(ctx context.Context, namespace, instanceID string) ([]PodInfo, error)
+
+	// StreamLogs 流式读取容器日志
+	StreamLogs(ctx context.Context, opts LogOptions, handle func(LogLine) error) error
+
+	// PortForward 建立到容器端口的 TCP 隧道
+	PortForward(ctx context.Context, opts PortForwardOptions, src io.Reader, dst io.Writer, ready func(podName string) error) error
 }
 
 // ExecOptions exec 执行选项
 type ExecOptions struct {
 	Namespace     string
 	InstanceID    string // 实例 ID，用于通过 label 查找 Pod
-	ContainerName string
+	PodName       string // 为空时选择最新的 Ready Pod
+	ContainerName string // 为空时使用默认容器（与实例 ID 同名）
 	Command       []string
 	TTY           bool
 }
 
+// PodInfo 实例 Pod 信息
+type PodInfo struct {
+	Name        string
+	Phase       string
+	Ready       bool
+	Terminating bool // 正在删除，不可作为 exec 目标
+	NodeName    string
+	CreatedAt   time.Time
+	Containers  []ContainerInfo
+}
+
+// ContainerInfo Pod 中的容器信息
+type ContainerInfo struct {
+	Name         string
+	Image        string
+	Ready        bool
+	RestartCount int32
+	State        string // Waiting / Running / Terminated
+}
+
 // ExecInput exec 输入消息
 type ExecInput struct {
 	Type ExecInputType
@@ -133,7 +224,8 @@ type ExecOutput struct {
 	Type     ExecOutputType
 	Stream   string // "stdout" or "stderr"
 	Data     []byte
-	ExitCode int32
+	ExitCode int32             // ExecOutputExit 时有效，ExecExitCodeUnknown 表示进程未启动或状态未知
+	Category ExecErrorCategory // ExecOutputError 时有效
 }
 
 // ExecOutputType exec 输出类型
@@ -145,10 +237,26 @@ const (
 	ExecOutputExit
 )
 
+// ExecExitCodeUnknown 进程未能启动或退出状态未知时的退出码
+const ExecExitCodeUnknown int32 = -1
+
+// ExecErrorCategory exec 错误类别，区分 exec 通道本身的失败与命令的非零退出
+type ExecErrorCategory int
+
+const (
+	ExecErrorUnspecified ExecErrorCategory = iota
+	ExecErrorNotFound                      // Pod 不存在
+	ExecErrorSetup                         // 建立 exec 连接失败
+	ExecErrorTransport                     // exec 流传输失败
+	ExecErrorCanceled                      // 客户端断开或会话被取消
+	ExecErrorLimit                         // 超过空闲时间、最长持续时间或并发会话上限
+)
+
 // NetworkBinding 网络绑定信息
-// 支持两种暴露模式：
+// 支持以下暴露模式：
 //  1. TCP/UDP: 通过 ClusterIP Service + ingress-nginx ConfigMap 暴露，ExternalPort 字段有值
 //  2. HTTP: 通过 ClusterIP Service + Ingress 暴露，IngressName 字段有值
+//  3. NODEPORT/LOADBALANCER: 直接使用对应类型的 Service 暴露，NodePort/ExternalIP 字段有值
 type NetworkBinding struct {
 	InstanceID   int64
 	Port         uint32
@@ -159,28 +267,82 @@ type NetworkBinding struct {
 	Protocol     string  // TCP/UDP/HTTP
 	AccessURL    string
 	Enabled      bool
+	Active       bool           // 实例运行中；停止实例时置为 false，启动实例核对修复后恢复为 true
+	Ingress      IngressOptions // HTTP 模式下的 Ingress 路由与 TLS 选项
+	Transport    string         // NODEPORT/LOADBALANCER 模式的传输协议：TCP/UDP
+	NodePort     *uint32        // NODEPORT/LOADBALANCER 模式分配的节点端口
+	ExternalIP   string         // LOADBALANCER 模式分配的外部 IP 或主机名
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
 
+// DirectEndpoint NODEPORT/LOADBALANCER Service 的访问端点
+type DirectEndpoint struct {
+	NodePort uint32
+	Address  string // NODEPORT 为节点地址，LOADBALANCER 为外部 IP 或主机名，尚未分配时为空
+}
+
+// HTTP 端口的 Ingress 路由方式
+const (
+	IngressRoutingPath = "PATH" // {domain}/{namespace}/{instanceID}/{port}，通过 rewrite-target 去掉前缀
+	IngressRoutingHost = "HOST" // {port}-{instanceID}.{domain}，应用可使用绝对路径
+)
+
+// ErrTLSNotConfigured 请求 HTTPS 但暴露后端未配置证书（cert-manager ClusterIssuer、通配符证书或 Gateway HTTPS listener）
+var ErrTLSNotConfigured = errors.New("TLS is not configured")
+
+// ErrNotSupportedByBackend 当前端口暴露后端不支持请求的功能
+var ErrNotSupportedByBackend = errors.New("not supported by the configured exposure backend")
+
+// IngressOptions HTTP 端口的 Ingress 选项
+type IngressOptions struct {
+	Domain     string
+	Routing    string // PATH / HOST，为空时按 PATH 处理
+	TLS        bool
+	Protection IngressProtection
+}
+
 // NetworkRepo 网络配置仓储接口
 type NetworkRepo interface {
 	CreateNetworkBinding(ctx context.Context, binding NetworkBinding) error
 	UpdateNetworkBinding(ctx context.Context, binding NetworkBinding) error
+	// UpdateAccessURL 只更新 access_url 与 external_ip，记录已被其他请求修改（与 binding 中的旧值不同）时不更新
+	UpdateAccessURL(ctx context.Context, binding NetworkBinding, accessURL, externalIP string) error
 	DeleteNetworkBinding(ctx context.Context, instanceID int64, port uint32) error
 	GetNetworkBinding(ctx context.Context, instanceID int64, port uint32) (*NetworkBinding, error)
 	ListNetworkBindings(ctx context.Context, instanceID int64) ([]NetworkBinding, error)
+	ListAllNetworkBindings(ctx context.Context) ([]NetworkBinding, error)
+	// ListNetworkBindingsByInstances 批量列出多个实例的端口绑定
+	ListNetworkBindingsByInstances(ctx context.Context, instanceIDs []int64) ([]NetworkBinding, error)
 	BatchDeleteNetworkBindings(ctx context.Context, instanceID int64) error
+	// SetNetworkBindingsActive 随实例启停批量更新端口绑定的 active 状态
+	SetNetworkBindingsActive(ctx context.Context, instanceID int64, active bool) error
+	// CreateNetworkRule 保存网络规则，回填 ID 与创建时间
+	CreateNetworkRule(ctx context.Context, rule *NetworkRule) error
+	// GetNetworkRule 不存在时返回 nil
+	GetNetworkRule(ctx context.Context, ruleID int64) (*NetworkRule, error)
+	ListNetworkRules(ctx context.Context, userID string) ([]NetworkRule, error)
+	DeleteNetworkRule(ctx context.Context, ruleID int64) error
+	// CreateCustomDomain 保存自定义域名，回填创建时间
+	CreateCustomDomain(ctx context.Context, domain *CustomDomain) error
+	// GetCustomDomain 不存在时返回 nil
+	GetCustomDomain(ctx context.Context, domain string) (*CustomDomain, error)
+	ListCustomDomains(ctx context.Context, instanceID int64) ([]CustomDomain, error)
+	UpdateCustomDomain(ctx context.Context, domain CustomDomain) error
+	DeleteCustomDomain(ctx context.Context, domain string) error
 }
 
-func NewResourceUsecase(repo InstanceRepo, audit AuditRepo, k8sRepo K8sRepo, networkRepo NetworkRepo, execRepo ExecRepo, logger log.Logger) *ResourceUsecase {
+func NewResourceUsecase(repo InstanceRepo, audit AuditRepo, k8sRepo K8sRepo, networkRepo NetworkRepo, execRepo ExecRepo, sessionRepo ExecSessionRepo, recordingStore ExecRecordingStore, resolver DomainResolver, logger log.Logger) *ResourceUsecase {
 	return &ResourceUsecase{
-		InstanceSpec: repo,
-		AuditRepo:    audit,
-		K8sRepo:      k8sRepo,
-		NetworkRepo:  networkRepo,
-		ExecRepo:     execRepo,
-		log:          log.NewHelper(logger),
+		InstanceSpec:    repo,
+		AuditRepo:       audit,
+		K8sRepo:         k8sRepo,
+		NetworkRepo:     networkRepo,
+		ExecRepo:        execRepo,
+		ExecSessionRepo: sessionRepo,
+		RecordingStore:  recordingStore,
+		DomainResolver:  resolver,
+		log:             log.NewHelper(logger),
 	}
 }
 
@@ -211,14 +373,19 @@ func (uc *ResourceUsecase) CreateInstance(ctx context.Context, spec InstanceSpec
 	return nil
 }
 
+// InstanceStatusStopped is the instance status (Resource.Type) of a stopped instance.
+const InstanceStatusStopped = "STOPPED"
+
 // Resource is a read model for listing resources.
 type Resource struct {
-	InstanceID int64
-	Name       string
-	UserID     string
-	Type       string
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	InstanceID    int64
+	Name          string
+	UserID        string
+	Type          string
+	StatusReason  string // 最近一次失败诊断代码，见 Failure* 常量
+	StatusMessage string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 // ListResourcesFilter defines optional filters for listing resources.
@@ -237,7 +404,28 @@ func (uc *ResourceUsecase) ListResources(ctx context.Context, filter ListResourc
 
 // ListResourceSpecs returns specs for given instance IDs.
 func (uc *ResourceUsecase) ListResourceSpecs(ctx context.Context, instanceIDs []int64) (map[int64]InstanceSpec, error) {
-	return uc.InstanceSpec.ListResourceSpecs(ctx, instanceIDs)
+	specs, err := uc.InstanceSpec.ListResourceSpecs(ctx, instanceIDs)
+	if err != nil || len(specs) == 0 {
+		return specs, err
+	}
+
+	// 连接数限制保存在端口绑定的访问控制中
+	bindings, err := uc.NetworkRepo.ListNetworkBindingsByInstances(ctx, instanceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bindings {
+		spec, ok := specs[b.InstanceID]
+		if !ok || b.Ingress.Protection.MaxConnections == 0 {
+			continue
+		}
+		if spec.PortMaxConnections == nil {
+			spec.PortMaxConnections = make(map[uint32]uint32)
+		}
+		spec.PortMaxConnections[b.Port] = b.Ingress.Protection.MaxConnections
+		specs[b.InstanceID] = spec
+	}
+	return specs, nil
 }
 
 // GetResource returns a single resource by instance ID.
@@ -248,8 +436,9 @@ func (uc *ResourceUsecase) GetResource(ctx context.Context, instanceID int64) (*
 // SetInstancePort sets port exposure for an instance.
 // When open=true, creates Service/Ingress and persists configuration.
 // When open=false, deletes Service/Ingress and removes configuration.
-func (uc *ResourceUsecase) SetInstancePort(ctx context.Context, instanceID int64, port uint32, protocol string, open bool, ingressDomain string) (string, error) {
-	uc.log.WithContext(ctx).Infof("SetInstancePort: instanceID=%d port=%d protocol=%s open=%v", instanceID, port, protocol, open)
+// transport is the TCP/UDP transport of NODEPORT/LOADBALANCER ports, TCP when empty.
+func (uc *ResourceUsecase) SetInstancePort(ctx context.Context, instanceID int64, port uint32, protocol, transport string, open bool, ingress IngressOptions) (string, error) {
+	uc.log.WithContext(ctx).Infof("SetInstancePort: instanceID=%d port=%d protocol=%s transport=%s open=%v", instanceID, port, protocol, transport, open)
 
 	// 1. 获取实例信息（验证实例是否存在，并获取 namespace）
 	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
@@ -265,7 +454,7 @@ func (uc *ResourceUsecase) SetInstancePort(ctx context.Context, instanceID int64
 
 	if open {
 		// 打开端口
-		return uc.openPort(ctx, instanceID, instanceIDStr, namespace, port, protocol, ingressDomain)
+		return uc.openPort(ctx, instanceID, instanceIDStr, namespace, port, protocol, transport, ingress)
 	} else {
 		// 关闭端口
 		return "", uc.closePort(ctx, instanceID, namespace, port)
@@ -273,7 +462,7 @@ func (uc *ResourceUsecase) SetInstancePort(ctx context.Context, instanceID int64
 }
 
 // openPort opens a port for an instance.
-func (uc *ResourceUsecase) openPort(ctx context.Context, instanceID int64, instanceIDStr, namespace string, port uint32, protocol, ingressDomain string) (string, error) {
+func (uc *ResourceUsecase) openPort(ctx context.Context, instanceID int64, instanceIDStr, namespace string, port uint32, protocol, transport string, ingress IngressOptions) (string, error) {
 	// 检查端口是否已经打开
 	existing, err := uc.NetworkRepo.GetNetworkBinding(ctx, instanceID, port)
 	if err != nil {
@@ -281,13 +470,27 @@ func (uc *ResourceUsecase) openPort(ctx context.Context, instanceID int64, insta
 	}
 	if existing != nil && existing.Enabled {
 		uc.log.WithContext(ctx).Infof("port %d already opened, returning existing URL", port)
-		return existing.AccessURL, nil // 幂等：已打开
+		bindings := []NetworkBinding{*existing}
+		uc.refreshAccessURLs(ctx, namespace, bindings)
+		return bindings[0].AccessURL, nil // 幂等：已打开
+	}
+	if existing != nil {
+		// 已禁用的端口按原配置重新启用，外部端口与访问地址不变
+		if existing.Protocol != protocol {
+			return "", fmt.Errorf("%w: port %d is disabled with protocol %s, close it before reopening with %s", ErrPortProtocolMismatch, port, existing.Protocol, protocol)
+		}
+		if err := checkReopenOptions(existing, transport, ingress); err != nil {
+			return "", err
+		}
+		return uc.setPortEnabled(ctx, namespace, existing, true)
 	}
 
 	var accessURL string
 	var serviceName string
 	var externalPort *uint32
 	var ingressName *string
+	var nodePort *uint32
+	var externalIP string
 
 	// 根据协议选择暴露模式
 	switch protocol {
@@ -300,23 +503,34 @@ func (uc *ResourceUsecase) openPort(ctx context.Context, instanceID int64, insta
 		serviceName = svcName
 		externalPort = &allocatedExternalPort
 
-		// 获取 ingress-nginx LoadBalancer IP
-		lbIP, err := uc.K8sRepo.GetIngressNginxLBIP(ctx)
+		// 获取公开地址（配置的主机名或暴露后端的外部地址），尚未分配时访问地址留空，查询时再计算
+		publicAddress, err := uc.K8sRepo.GetTCPUDPPublicAddress(ctx)
 		if err != nil {
-			uc.log.Warnf("failed to get ingress-nginx LB IP: %v, using placeholder", err)
-			lbIP = "<ingress-lb-ip>"
+			uc.log.Warnf("failed to get TCP/UDP public address: %v, access URL will be resolved later", err)
+		} else {
+			accessURL = tcpUDPAccessURL(publicAddress, allocatedExternalPort)
 		}
-		accessURL = fmt.Sprintf("%s:%d", lbIP, allocatedExternalPort)
 
 	case "HTTP":
 		// HTTP 模式：创建 ClusterIP Service + Ingress
+		domain, err := uc.checkIngressDomain(ctx, instanceID, ingress.Domain)
+		if err != nil {
+			return "", err
+		}
+		ingress.Domain = domain
+		if ingress.Routing == "" {
+			ingress.Routing = IngressRoutingPath
+		}
+		if err := ingress.Protection.Validate(); err != nil {
+			return "", err
+		}
 		svcName, err := uc.K8sRepo.CreateServiceForHTTP(ctx, namespace, instanceIDStr, port)
 		if err != nil {
 			return "", err
 		}
 		serviceName = svcName
 
-		ingName, url, err := uc.K8sRepo.CreateIngress(ctx, namespace, instanceIDStr, port, serviceName, ingressDomain)
+		ingName, url, err := uc.K8sRepo.CreateIngress(ctx, namespace, instanceIDStr, port, serviceName, ingress)
 		if err != nil {
 			// 创建 Ingress 失败，回滚 Service
 			_ = uc.K8sRepo.DeleteService(ctx, namespace, serviceName)
@@ -325,8 +539,31 @@ func (uc *ResourceUsecase) openPort(ctx context.Context, instanceID int64, insta
 		ingressName = &ingName
 		accessURL = url
 
+	case "NODEPORT", "LOADBALANCER":
+		// 直接暴露模式：NodePort / LoadBalancer Service（如 MetalLB），不经过 ingress-nginx
+		if transport == "" {
+			transport = "TCP"
+		}
+		svcName, endpoint, err := uc.K8sRepo.CreateDirectService(ctx, namespace, instanceIDStr, port, protocol, transport)
+		if err != nil {
+			return "", err
+		}
+		serviceName = svcName
+		nodePort = &endpoint.NodePort
+
+		if protocol == "NODEPORT" {
+			accessURL = fmt.Sprintf("%s:%d", endpoint.Address, endpoint.NodePort)
+		} else {
+			externalIP = endpoint.Address
+			if externalIP == "" {
+				uc.log.Warnf("LoadBalancer service %s has no external address yet, access URL will be resolved later", serviceName)
+			} else {
+				accessURL = fmt.Sprintf("%s:%d", externalIP, port)
+			}
+		}
+
 	default:
-		return "", errors.New("invalid protocol, must be TCP/UDP/HTTP")
+		return "", errors.New("invalid protocol, must be TCP/UDP/HTTP/NODEPORT/LOADBALANCER")
 	}
 
 	// 持久化网络配置
@@ -340,6 +577,14 @@ func (uc *ResourceUsecase) openPort(ctx context.Context, instanceID int64, insta
 		Protocol:     protocol,
 		AccessURL:    accessURL,
 		Enabled:      true,
+		Active:       true,
+		Transport:    transport,
+		NodePort:     nodePort,
+		ExternalIP:   externalIP,
+	}
+	if ingressName != nil {
+		binding.Ingress = ingress
+		binding.Ingress.Protection.BasicAuthPassword = ""
 	}
 
 	if err := uc.NetworkRepo.CreateNetworkBinding(ctx, binding); err != nil {
@@ -349,7 +594,7 @@ func (uc *ResourceUsecase) openPort(ctx context.Context, instanceID int64, insta
 			_ = uc.K8sRepo.DeleteIngress(ctx, namespace, *ingressName)
 		}
 		if externalPort != nil {
-			_ = uc.K8sRepo.DeleteTCPUDPConfigMapEntry(ctx, protocol, *externalPort)
+			_ = uc.K8sRepo.ReleaseTCPUDPPort(ctx, protocol, *externalPort)
 		}
 		return "", err
 	}
@@ -379,27 +624,9 @@ func (uc *ResourceUsecase) closePort(ctx context.Context, instanceID int64, name
 		return nil // 幂等：已关闭
 	}
 
-	// 删除 K8s Service
-	if err := uc.K8sRepo.DeleteService(ctx, namespace, binding.ServiceName); err != nil {
-		uc.log.Errorf("failed to delete service %s: %v", binding.ServiceName, err)
-		// 继续删除其他资源
-	}
-
-	// 删除 K8s Ingress（如果存在）
-	if binding.IngressName != nil {
-		if err := uc.K8sRepo.DeleteIngress(ctx, namespace, *binding.IngressName); err != nil {
-			uc.log.Errorf("failed to delete ingress %s: %v", *binding.IngressName, err)
-			// 继续删除其他资源
-		}
-	}
-
-	// 删除 TCP/UDP ConfigMap 条目（如果存在）
-	if binding.ExternalPort != nil {
-		if err := uc.K8sRepo.DeleteTCPUDPConfigMapEntry(ctx, binding.Protocol, *binding.ExternalPort); err != nil {
-			uc.log.Errorf("failed to delete ConfigMap entry for port %d: %v", *binding.ExternalPort, err)
-			// 继续删除数据库记录
-		}
-	}
+	// 删除 K8s Service、Ingress 与 TCP/UDP 外部端口，失败时继续删除数据库记录
+	uc.releaseNetworkBinding(ctx, namespace, *binding)
+	uc.releaseCustomDomains(ctx, namespace, instanceID, port)
 
 	// 删除数据库记录
 	if err := uc.NetworkRepo.DeleteNetworkBinding(ctx, instanceID, port); err != nil {
@@ -419,8 +646,29 @@ func (uc *ResourceUsecase) closePort(ctx context.Context, instanceID int64, name
 	return nil
 }
 
+// releaseNetworkBinding 删除端口的 Service（含 NetworkPolicy）、Ingress（含 basic auth Secret）
+// 与 TCP/UDP 外部端口（ConfigMap 条目与 ingress-nginx Service 端口 / Gateway listener）。
+// 单项失败只记录日志，继续释放其余资源。
+func (uc *ResourceUsecase) releaseNetworkBinding(ctx context.Context, namespace string, binding NetworkBinding) {
+	if err := uc.K8sRepo.DeleteService(ctx, namespace, binding.ServiceName); err != nil {
+		uc.log.Errorf("failed to delete service %s: %v", binding.ServiceName, err)
+	}
+
+	if binding.IngressName != nil {
+		if err := uc.K8sRepo.DeleteIngress(ctx, namespace, *binding.IngressName); err != nil {
+			uc.log.Errorf("failed to delete ingress %s: %v", *binding.IngressName, err)
+		}
+	}
+
+	if binding.ExternalPort != nil {
+		if err := uc.K8sRepo.ReleaseTCPUDPPort(ctx, binding.Protocol, *binding.ExternalPort); err != nil {
+			uc.log.Errorf("failed to delete ConfigMap entry for port %d: %v", *binding.ExternalPort, err)
+		}
+	}
+}
+
 // StreamExec 流式执行容器命令
-func (uc *ResourceUsecase) StreamExec(ctx context.Context, namespace string, instanceID int64, command []string, tty bool, containerName string, input <-chan ExecInput, output chan<- ExecOutput) error {
+func (uc *ResourceUsecase) StreamExec(ctx context.Context, namespace string, instanceID int64, command []string, tty bool, podName, containerName string, input <-chan ExecInput, output chan<- ExecOutput) error {
 	if namespace == "" {
 		return errors.New("namespace is required")
 	}
@@ -428,24 +676,40 @@ func (uc *ResourceUsecase) StreamExec(ctx context.Context, namespace string, ins
 	// 构建 exec 选项
 	instanceIDStr := strconv.FormatInt(instanceID, 10)
 
-	// 如果未指定容器名称，使用实例 ID（与 Deployment 定义一致）
-	if containerName == "" {
-		containerName = instanceIDStr
-	}
-
 	opts := ExecOptions{
 		Namespace:     namespace,
 		InstanceID:    instanceIDStr,
+		PodName:       podName,
 		ContainerName: containerName,
 		Command:       command,
 		TTY:           tty,
 	}
 
+	// 记录会话录像与审计日志
+	recInput, recOutput, finish, err := uc.recordExec(ctx, opts, instanceID, input, output)
+	if err != nil {
+		return err
+	}
+	defer finish()
+
 	// 调用 data 层执行
-	return uc.ExecRepo.StreamExec(ctx, opts, input, output)
+	return uc.ExecRepo.StreamExec(ctx, opts, recInput, recOutput)
 }
 
-// DeleteInstance deletes an instance deployment and its associated resources
+// ListInstancePods 列出实例的 Pod 与容器，供 exec 选择目标
+func (uc *ResourceUsecase) ListInstancePods(ctx context.Context, instanceID int64) ([]PodInfo, error) {
+	resource, err := uc.InstanceSpec.GetResource(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if resource == nil {
+		return nil, ErrInstanceNotFound
+	}
+
+	return uc.ExecRepo.ListPods(ctx, resource.UserID, strconv.FormatInt(instanceID, 10))
+}
+
+// DeleteInstance deletes an instance deployment and its associated resources, releasing external ports
 func (uc *ResourceUsecase) DeleteInstance(ctx context.Context, instanceID int64) error {
 	uc.log.WithContext(ctx).Infof("DeleteInstance: instanceID=%d", instanceID)
 
@@ -460,28 +724,42 @@ func (uc *ResourceUsecase) DeleteInstance(ctx context.Context, instanceID int64)
 	instanceIDStr := strconv.FormatInt(instanceID, 10)
 	namespace := resource.UserID
 
-	// We can delete network bindings as part of cleanup
-	if err := uc.NetworkRepo.BatchDeleteNetworkBindings(ctx, instanceID); err != nil {
-		uc.log.WithContext(ctx).Warnf("failed to batch delete network bindings, continuing: %v", err)
+	// 先按端口绑定释放外部端口：ConfigMap 条目、ingress-nginx Service 端口、Gateway listener 与路由
+	// 不在实例命名空间或不带 instance-id 标签，K8sRepo.DeleteInstance 无法清理
+	bindings, err := uc.NetworkRepo.ListNetworkBindings(ctx, instanceID)
+	if err != nil {
+		return err
 	}
+	ports := make([]uint32, 0, len(bindings))
+	for _, b := range bindings {
+		uc.releaseNetworkBinding(ctx, namespace, b)
+		ports = append(ports, b.Port)
+	}
+	uc.releaseCustomDomains(ctx, namespace, instanceID, 0)
 
 	if err := uc.K8sRepo.DeleteInstance(ctx, namespace, instanceIDStr); err != nil {
 		return err
 	}
 
+	// 集群资源删除成功后再删除记录，失败时可重试 DeleteInstance
+	if err := uc.NetworkRepo.BatchDeleteNetworkBindings(ctx, instanceID); err != nil {
+		return err
+	}
+
 	// 记录审计日志
+	data, _ := json.Marshal(map[string]interface{}{"released_ports": ports})
 	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
 		InstanceID: instanceID,
 		LogType:    "DELETE",
 		Message:    "Instance deleted",
-		DataJson:   json.RawMessage(`{}`),
+		DataJson:   data,
 		CreatedAt:  time.Now(),
 	})
 
 	return nil
 }
 
-// StopInstance scales the deployment replicas to 0
+// StopInstance scales the deployment replicas to 0 and marks its ports inactive
 func (uc *ResourceUsecase) StopInstance(ctx context.Context, instanceID int64) error {
 	uc.log.WithContext(ctx).Infof("StopInstance: instanceID=%d", instanceID)
 
@@ -500,6 +778,11 @@ func (uc *ResourceUsecase) StopInstance(ctx context.Context, instanceID int64) e
 		return err
 	}
 
+	// Service 与外部端口保留，实例停止期间端口没有后端
+	if err := uc.NetworkRepo.SetNetworkBindingsActive(ctx, instanceID, false); err != nil {
+		uc.log.WithContext(ctx).Warnf("StopInstance: failed to mark network bindings of instance %d inactive: %v", instanceID, err)
+	}
+
 	// 记录审计日志
 	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
 		InstanceID: instanceID,
@@ -512,7 +795,7 @@ func (uc *ResourceUsecase) StopInstance(ctx context.Context, instanceID int64) e
 	return nil
 }
 
-// StartInstance scales the deployment replicas to 1
+// StartInstance scales the deployment replicas to 1 and restores the exposure of its ports
 func (uc *ResourceUsecase) StartInstance(ctx context.Context, instanceID int64) error {
 	uc.log.WithContext(ctx).Infof("StartInstance: instanceID=%d", instanceID)
 
@@ -531,20 +814,31 @@ func (uc *ResourceUsecase) StartInstance(ctx context.Context, instanceID int64)
 		return err
 	}
 
+	// 核对并修复端口暴露，单个端口失败不影响启动，可稍后通过 ReconcileNetwork 修复
+	failed := uc.restoreNetworkBindings(ctx, namespace, instanceID)
+
 	// 记录审计日志
+	data, _ := json.Marshal(map[string]interface{}{"failed_ports": failed})
 	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
 		InstanceID: instanceID,
 		LogType:    "START",
 		Message:    "Instance started",
-		DataJson:   json.RawMessage(`{}`),
+		DataJson:   data,
 		CreatedAt:  time.Now(),
 	})
 
 	return nil
 }
 
-// UpdateInstance dynamically updates the instance's resource quotas and container image
-func (uc *ResourceUsecase) UpdateInstance(ctx context.Context, instanceID int64, cpu, memory, gpu uint32, image string) error {
+// 未指定 CPU/内存时 K8s 使用的默认配额，与 data 层创建、更新 Deployment 时的回退值一致
+const (
+	defaultInstanceCPU    = 1   // vCPU
+	defaultInstanceMemory = 512 // MiB
+)
+
+// UpdateInstance dynamically updates the instance's resource quotas, container image and bandwidth limits.
+// ingressBandwidth/egressBandwidth 为 nil 时保持当前带宽限制；cpu/memory 为 0 时使用默认配额，保存的规格与实际生效的一致
+func (uc *ResourceUsecase) UpdateInstance(ctx context.Context, instanceID int64, cpu, memory, gpu uint32, image string, ingressBandwidth, egressBandwidth *uint32) error {
 	uc.log.WithContext(ctx).Infof("UpdateInstance: instanceID=%d cpu=%d mem=%d gpu=%d image=%s", instanceID, cpu, memory, gpu, image)
 
 	r, err := uc.InstanceSpec.GetResource(ctx, instanceID)
@@ -555,6 +849,13 @@ func (uc *ResourceUsecase) UpdateInstance(ctx context.Context, instanceID int64,
 		return errors.New("instance not found")
 	}
 
+	if cpu == 0 {
+		cpu = defaultInstanceCPU
+	}
+	if memory == 0 {
+		memory = defaultInstanceMemory
+	}
+
 	spec := InstanceSpec{
 		InstanceID: instanceID,
 		UserID:     r.UserID,
@@ -564,12 +865,33 @@ func (uc *ResourceUsecase) UpdateInstance(ctx context.Context, instanceID int64,
 		Image:      image,
 	}
 
+	// 未指定的带宽限制沿用当前规格
+	if ingressBandwidth == nil || egressBandwidth == nil {
+		specs, err := uc.InstanceSpec.ListResourceSpecs(ctx, []int64{instanceID})
+		if err != nil {
+			return err
+		}
+		current := specs[instanceID]
+		spec.IngressBandwidth = current.IngressBandwidth
+		spec.EgressBandwidth = current.EgressBandwidth
+	}
+	if ingressBandwidth != nil {
+		spec.IngressBandwidth = *ingressBandwidth
+	}
+	if egressBandwidth != nil {
+		spec.EgressBandwidth = *egressBandwidth
+	}
+
 	if err := uc.K8sRepo.UpdateInstance(ctx, spec); err != nil {
 		return err
 	}
+	if err := uc.InstanceSpec.UpdateInstanceSpec(ctx, spec); err != nil {
+		return err
+	}
 
 	// 记录审计日志
-	data := fmt.Sprintf(`{"cpu":%d,"memory":%d,"GPU":%d,"image":"%s"}`, cpu, memory, gpu, image)
+	data := fmt.Sprintf(`{"cpu":%d,"memory":%d,"GPU":%d,"image":"%s","ingress_bandwidth_mbps":%d,"egress_bandwidth_mbps":%d}`,
+		cpu, memory, gpu, image, spec.IngressBandwidth, spec.EgressBandwidth)
 	_ = uc.AuditRepo.CreateAudit(ctx, AuditInformation{
 		InstanceID: instanceID,
 		LogType:    "UPDATE",
@@ -0,0 +1,105 @@
+package biz
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type specInstanceRepo struct {
+	fakeInstanceRepo
+	specs map[int64]InstanceSpec
+}
+
+func (f *specInstanceRepo) ListResourceSpecs(_ context.Context, instanceIDs []int64) (map[int64]InstanceSpec, error) {
+	out := make(map[int64]InstanceSpec)
+	for _, id := range instanceIDs {
+		if spec, ok := f.specs[id]; ok {
+			out[id] = spec
+		}
+	}
+	return out, nil
+}
+
+func (f *specInstanceRepo) UpdateInstanceSpec(_ context.Context, spec InstanceSpec) error {
+	f.specs[spec.InstanceID] = spec
+	return nil
+}
+
+type fakeUpdateK8sRepo struct {
+	K8sRepo
+	updated []InstanceSpec
+}
+
+func (f *fakeUpdateK8sRepo) UpdateInstance(_ context.Context, spec InstanceSpec) error {
+	f.updated = append(f.updated, spec)
+	return nil
+}
+
+func TestUpdateInstance_Bandwidth(t *testing.T) {
+	repo := &specInstanceRepo{
+		fakeInstanceRepo: fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}},
+		specs:            map[int64]InstanceSpec{1: {InstanceID: 1, IngressBandwidth: 100, EgressBandwidth: 20}},
+	}
+	k8s := &fakeUpdateK8sRepo{}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, nil, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+
+	// 未指定的方向保持当前限制
+	egress := uint32(0)
+	if err := uc.UpdateInstance(context.Background(), 1, 2, 1024, 0, "", nil, &egress); err != nil {
+		t.Fatal(err)
+	}
+	got := k8s.updated[0]
+	if got.IngressBandwidth != 100 || got.EgressBandwidth != 0 || got.UserID != "alice" {
+		t.Fatalf("k8s spec=%+v", got)
+	}
+	if saved := repo.specs[1]; saved.IngressBandwidth != 100 || saved.EgressBandwidth != 0 || saved.CPU != 2 {
+		t.Fatalf("saved spec=%+v", saved)
+	}
+}
+
+func TestListResourceSpecs_PortMaxConnections(t *testing.T) {
+	repo := &specInstanceRepo{specs: map[int64]InstanceSpec{
+		1: {InstanceID: 1, EgressBandwidth: 20},
+		2: {InstanceID: 2},
+	}}
+	network := &fakeNetworkRepo{bindings: []NetworkBinding{
+		{InstanceID: 1, Port: 80, Ingress: IngressOptions{Protection: IngressProtection{MaxConnections: 50}}},
+		{InstanceID: 1, Port: 22},
+		{InstanceID: 2, Port: 8080},
+	}}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, nil, network, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+
+	specs, err := uc.ListResourceSpecs(context.Background(), []int64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs[1].EgressBandwidth != 20 || len(specs[1].PortMaxConnections) != 1 || specs[1].PortMaxConnections[80] != 50 {
+		t.Fatalf("spec 1=%+v", specs[1])
+	}
+	if specs[2].PortMaxConnections != nil {
+		t.Fatalf("spec 2=%+v", specs[2])
+	}
+}
+
+func TestUpdateInstance_DefaultQuota(t *testing.T) {
+	repo := &specInstanceRepo{
+		fakeInstanceRepo: fakeInstanceRepo{resources: map[int64]*Resource{1: {InstanceID: 1, UserID: "alice"}}},
+		specs:            map[int64]InstanceSpec{1: {InstanceID: 1, CPU: 4, Memory: 8192}},
+	}
+	k8s := &fakeUpdateK8sRepo{}
+	uc := NewResourceUsecase(repo, &fakeAuditRepo{}, k8s, nil, nil, nil, nil, nil, log.NewStdLogger(io.Discard))
+
+	// 未指定 CPU/内存时保存实际生效的默认配额，而不是 0
+	if err := uc.UpdateInstance(context.Background(), 1, 0, 0, 0, "", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := k8s.updated[0]; got.CPU != defaultInstanceCPU || got.Memory != defaultInstanceMemory {
+		t.Fatalf("k8s spec=%+v", got)
+	}
+	if saved := repo.specs[1]; saved.CPU != defaultInstanceCPU || saved.Memory != defaultInstanceMemory {
+		t.Fatalf("saved spec=%+v", saved)
+	}
+}
@@ -0,0 +1,82 @@
+package biz
+
+import (
+	"context"
+)
+
+// RunCommandOptions 非交互命令执行选项
+type RunCommandOptions struct {
+	Command        []string
+	Stdin          []byte // 写入后关闭标准输入
+	PodName        string
+	ContainerName  string
+	MaxOutputBytes int // stdout/stderr 各自的缓冲上限
+}
+
+// RunCommandResult 非交互命令执行结果
+type RunCommandResult struct {
+	ExitCode        int32
+	Stdout          []byte
+	Stderr          []byte
+	StdoutTruncated bool
+	StderrTruncated bool
+	Error           string            // exec 通道错误
+	ErrorCategory   ExecErrorCategory // exec 通道错误类别
+}
+
+// RunCommand 基于 StreamExec 执行非交互命令，缓冲输出并返回退出码。
+// 超时由调用方通过 ctx 控制；与交互式会话一样记录审计日志与录像。
+func (uc *ResourceUsecase) RunCommand(ctx context.Context, namespace string, instanceID int64, opts RunCommandOptions) (*RunCommandResult, error) {
+	input := make(chan ExecInput, 1)
+	if len(opts.Stdin) > 0 {
+		input <- ExecInput{Type: ExecInputStdin, Data: opts.Stdin}
+	}
+	close(input)
+
+	result := &RunCommandResult{ExitCode: ExecExitCodeUnknown}
+	output := make(chan ExecOutput, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for out := range output {
+			switch out.Type {
+			case ExecOutputData:
+				if out.Stream == "stderr" {
+					result.Stderr, result.StderrTruncated = appendLimited(result.Stderr, out.Data, opts.MaxOutputBytes, result.StderrTruncated)
+				} else {
+					result.Stdout, result.StdoutTruncated = appendLimited(result.Stdout, out.Data, opts.MaxOutputBytes, result.StdoutTruncated)
+				}
+			case ExecOutputError:
+				result.Error = string(out.Data)
+				result.ErrorCategory = out.Category
+			case ExecOutputExit:
+				result.ExitCode = out.ExitCode
+			}
+		}
+	}()
+
+	err := uc.StreamExec(ctx, namespace, instanceID, opts.Command, false, opts.PodName, opts.ContainerName, input, output)
+	close(output)
+	<-done
+
+	// data 层的失败已经写入 result，只有未进入 exec 的错误需要返回
+	if err != nil && result.Error == "" {
+		return nil, err
+	}
+	return result, nil
+}
+
+// appendLimited 追加输出，超过上限的部分丢弃并标记截断
+func appendLimited(buf, data []byte, limit int, truncated bool) ([]byte, bool) {
+	if limit <= 0 {
+		return append(buf, data...), truncated
+	}
+	remaining := limit - len(buf)
+	if remaining <= 0 {
+		return buf, truncated || len(data) > 0
+	}
+	if len(data) > remaining {
+		return append(buf, data[:remaining]...), true
+	}
+	return append(buf, data...), truncated
+}
@@ -0,0 +1,99 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// fakeExecRepo 回显标准输入到 stdout，并输出固定的 stderr 与退出码
+type fakeExecRepo struct {
+	ExecRepo
+	stderr   []byte
+	exitCode int32
+	err      error
+}
+
+func (f *fakeExecRepo) StreamExec(_ context.Context, _ ExecOptions, input <-chan ExecInput, output chan<- ExecOutput) error {
+	if f.err != nil {
+		output <- ExecOutput{Type: ExecOutputError, Data: []byte(f.err.Error()), Category: ExecErrorNotFound}
+		output <- ExecOutput{Type: ExecOutputExit, ExitCode: ExecExitCodeUnknown}
+		return f.err
+	}
+	for in := range input {
+		output <- ExecOutput{Type: ExecOutputData, Stream: "stdout", Data: in.Data}
+	}
+	output <- ExecOutput{Type: ExecOutputData, Stream: "stderr", Data: f.stderr}
+	output <- ExecOutput{Type: ExecOutputExit, ExitCode: f.exitCode}
+	return nil
+}
+
+type fakeExecSessionRepo struct {
+	ExecSessionRepo
+}
+
+func (fakeExecSessionRepo) CreateExecSession(context.Context, ExecSession) error { return nil }
+func (fakeExecSessionRepo) FinishExecSession(context.Context, string, int32, time.Time) error {
+	return nil
+}
+
+type fakeRecordingStore struct {
+	ExecRecordingStore
+}
+
+type nopWriteCloser struct{ bytes.Buffer }
+
+func (*nopWriteCloser) Close() error { return nil }
+
+func (fakeRecordingStore) Create(context.Context, string) (io.WriteCloser, error) {
+	return &nopWriteCloser{}, nil
+}
+
+func newTestExecUsecase(repo ExecRepo) *ResourceUsecase {
+	return &ResourceUsecase{
+		AuditRepo:       &fakeAuditRepo{},
+		ExecRepo:        repo,
+		ExecSessionRepo: fakeExecSessionRepo{},
+		RecordingStore:  fakeRecordingStore{},
+		log:             log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+}
+
+func TestResourceUsecase_RunCommand(t *testing.T) {
+	uc := newTestExecUsecase(&fakeExecRepo{stderr: []byte("warning"), exitCode: 3})
+
+	result, err := uc.RunCommand(context.Background(), "alice", 1, RunCommandOptions{
+		Command:        []string{"cat"},
+		Stdin:          []byte("0123456789"),
+		MaxOutputBytes: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != 3 {
+		t.Fatalf("exit code=%d want 3", result.ExitCode)
+	}
+	if string(result.Stdout) != "0123" || !result.StdoutTruncated {
+		t.Fatalf("stdout=%q truncated=%v want truncated 0123", result.Stdout, result.StdoutTruncated)
+	}
+	if string(result.Stderr) != "warn" || !result.StderrTruncated {
+		t.Fatalf("stderr=%q truncated=%v", result.Stderr, result.StderrTruncated)
+	}
+}
+
+func TestResourceUsecase_RunCommandExecFailure(t *testing.T) {
+	uc := newTestExecUsecase(&fakeExecRepo{err: errors.New("pod not found")})
+
+	result, err := uc.RunCommand(context.Background(), "alice", 1, RunCommandOptions{Command: []string{"ls"}})
+	if err != nil {
+		t.Fatalf("err=%v, exec failures should be reported in the result", err)
+	}
+	if result.ExitCode != ExecExitCodeUnknown || result.ErrorCategory != ExecErrorNotFound || result.Error == "" {
+		t.Fatalf("result=%+v", result)
+	}
+}
@@ -26,6 +26,7 @@ type Bootstrap struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Server        *Server                `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
 	Data          *Data                  `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Auth          *Auth                  `protobuf:"bytes,3,opt,name=auth,proto3" json:"auth,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -74,12 +75,23 @@ func (x *Bootstrap) GetData() *Data {
 	return nil
 }
 
+func (x *Bootstrap) GetAuth() *Auth {
+	if x != nil {
+		return x.Auth
+	}
+	return nil
+}
+
 type Server struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Http          *Server_HTTP           `protobuf:"bytes,1,opt,name=http,proto3" json:"http,omitempty"`
-	Grpc          *Server_GRPC           `protobuf:"bytes,2,opt,name=grpc,proto3" json:"grpc,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state            protoimpl.MessageState   `protogen:"open.v1"`
+	Http             *Server_HTTP             `protobuf:"bytes,1,opt,name=http,proto3" json:"http,omitempty"`
+	Grpc             *Server_GRPC             `protobuf:"bytes,2,opt,name=grpc,proto3" json:"grpc,omitempty"`
+	Exec             *Server_Exec             `protobuf:"bytes,3,opt,name=exec,proto3" json:"exec,omitempty"`
+	FileTransfer     *Server_FileTransfer     `protobuf:"bytes,4,opt,name=file_transfer,json=fileTransfer,proto3" json:"file_transfer,omitempty"`
+	NetworkReconcile *Server_NetworkReconcile `protobuf:"bytes,5,opt,name=network_reconcile,json=networkReconcile,proto3" json:"network_reconcile,omitempty"`
+	InstanceStatus   *Server_InstanceStatus   `protobuf:"bytes,6,opt,name=instance_status,json=instanceStatus,proto3" json:"instance_status,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *Server) Reset() {
@@ -126,12 +138,42 @@ func (x *Server) GetGrpc() *Server_GRPC {
 	return nil
 }
 
+func (x *Server) GetExec() *Server_Exec {
+	if x != nil {
+		return x.Exec
+	}
+	return nil
+}
+
+func (x *Server) GetFileTransfer() *Server_FileTransfer {
+	if x != nil {
+		return x.FileTransfer
+	}
+	return nil
+}
+
+func (x *Server) GetNetworkReconcile() *Server_NetworkReconcile {
+	if x != nil {
+		return x.NetworkReconcile
+	}
+	return nil
+}
+
+func (x *Server) GetInstanceStatus() *Server_InstanceStatus {
+	if x != nil {
+		return x.InstanceStatus
+	}
+	return nil
+}
+
 type Data struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Database      *Data_Database         `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
 	Redis         *Data_Redis            `protobuf:"bytes,2,opt,name=redis,proto3" json:"redis,omitempty"`
 	Rabbitmq      *Data_RabbitMQ         `protobuf:"bytes,3,opt,name=rabbitmq,proto3" json:"rabbitmq,omitempty"`
 	Kubernetes    *Data_Kubernetes       `protobuf:"bytes,4,opt,name=kubernetes,proto3" json:"kubernetes,omitempty"`
+	ExecRecording *Data_ExecRecording    `protobuf:"bytes,5,opt,name=exec_recording,json=execRecording,proto3" json:"exec_recording,omitempty"`
+	CustomDomain  *Data_CustomDomain     `protobuf:"bytes,6,opt,name=custom_domain,json=customDomain,proto3" json:"custom_domain,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -194,6 +236,120 @@ func (x *Data) GetKubernetes() *Data_Kubernetes {
 	return nil
 }
 
+func (x *Data) GetExecRecording() *Data_ExecRecording {
+	if x != nil {
+		return x.ExecRecording
+	}
+	return nil
+}
+
+func (x *Data) GetCustomDomain() *Data_CustomDomain {
+	if x != nil {
+		return x.CustomDomain
+	}
+	return nil
+}
+
+type Auth struct {
+	state                protoimpl.MessageState     `protogen:"open.v1"`
+	Enabled              bool                       `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`                                                        // 是否启用访问控制，关闭时放行所有请求
+	UserHeader           string                     `protobuf:"bytes,2,opt,name=user_header,json=userHeader,proto3" json:"user_header,omitempty"`                                 // 携带用户 ID 的请求头，默认 x-user-id
+	RoleHeader           string                     `protobuf:"bytes,3,opt,name=role_header,json=roleHeader,proto3" json:"role_header,omitempty"`                                 // 携带角色的请求头，默认 x-user-role
+	TrustRoleHeader      bool                       `protobuf:"varint,4,opt,name=trust_role_header,json=trustRoleHeader,proto3" json:"trust_role_header,omitempty"`               // 是否信任网关传入的角色请求头
+	DefaultRole          string                     `protobuf:"bytes,5,opt,name=default_role,json=defaultRole,proto3" json:"default_role,omitempty"`                              // 未绑定角色的用户使用的默认角色，默认 user
+	Roles                []*Auth_Role               `protobuf:"bytes,6,rep,name=roles,proto3" json:"roles,omitempty"`                                                             // 角色定义，与内置角色同名时覆盖内置角色
+	Bindings             []*Auth_Binding            `protobuf:"bytes,7,rep,name=bindings,proto3" json:"bindings,omitempty"`                                                       // 用户与角色的绑定关系
+	InstanceExecPolicies []*Auth_InstanceExecPolicy `protobuf:"bytes,8,rep,name=instance_exec_policies,json=instanceExecPolicies,proto3" json:"instance_exec_policies,omitempty"` // 按实例配置的 exec 命令策略，与角色策略同时生效
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *Auth) Reset() {
+	*x = Auth{}
+	mi := &file_conf_conf_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Auth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Auth) ProtoMessage() {}
+
+func (x *Auth) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Auth.ProtoReflect.Descriptor instead.
+func (*Auth) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Auth) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Auth) GetUserHeader() string {
+	if x != nil {
+		return x.UserHeader
+	}
+	return ""
+}
+
+func (x *Auth) GetRoleHeader() string {
+	if x != nil {
+		return x.RoleHeader
+	}
+	return ""
+}
+
+func (x *Auth) GetTrustRoleHeader() bool {
+	if x != nil {
+		return x.TrustRoleHeader
+	}
+	return false
+}
+
+func (x *Auth) GetDefaultRole() string {
+	if x != nil {
+		return x.DefaultRole
+	}
+	return ""
+}
+
+func (x *Auth) GetRoles() []*Auth_Role {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *Auth) GetBindings() []*Auth_Binding {
+	if x != nil {
+		return x.Bindings
+	}
+	return nil
+}
+
+func (x *Auth) GetInstanceExecPolicies() []*Auth_InstanceExecPolicy {
+	if x != nil {
+		return x.InstanceExecPolicies
+	}
+	return nil
+}
+
 type Server_HTTP struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Network       string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
@@ -205,7 +361,7 @@ type Server_HTTP struct {
 
 func (x *Server_HTTP) Reset() {
 	*x = Server_HTTP{}
-	mi := &file_conf_conf_proto_msgTypes[3]
+	mi := &file_conf_conf_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -217,7 +373,7 @@ func (x *Server_HTTP) String() string {
 func (*Server_HTTP) ProtoMessage() {}
 
 func (x *Server_HTTP) ProtoReflect() protoreflect.Message {
-	mi := &file_conf_conf_proto_msgTypes[3]
+	mi := &file_conf_conf_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -265,7 +421,7 @@ type Server_GRPC struct {
 
 func (x *Server_GRPC) Reset() {
 	*x = Server_GRPC{}
-	mi := &file_conf_conf_proto_msgTypes[4]
+	mi := &file_conf_conf_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -277,7 +433,7 @@ func (x *Server_GRPC) String() string {
 func (*Server_GRPC) ProtoMessage() {}
 
 func (x *Server_GRPC) ProtoReflect() protoreflect.Message {
-	mi := &file_conf_conf_proto_msgTypes[4]
+	mi := &file_conf_conf_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -314,29 +470,35 @@ func (x *Server_GRPC) GetTimeout() *durationpb.Duration {
 	return nil
 }
 
-type Data_Database struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Driver        string                 `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
-	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type Server_Exec struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	IdleTimeout            *durationpb.Duration   `protobuf:"bytes,1,opt,name=idle_timeout,json=idleTimeout,proto3" json:"idle_timeout,omitempty"`                                       // 无输入超时，0 表示不限制
+	MaxDuration            *durationpb.Duration   `protobuf:"bytes,2,opt,name=max_duration,json=maxDuration,proto3" json:"max_duration,omitempty"`                                       // 单个会话最长持续时间，0 表示不限制
+	MaxSessionsPerUser     int32                  `protobuf:"varint,3,opt,name=max_sessions_per_user,json=maxSessionsPerUser,proto3" json:"max_sessions_per_user,omitempty"`             // 每个用户的并发会话上限，0 表示不限制
+	MaxSessionsPerInstance int32                  `protobuf:"varint,4,opt,name=max_sessions_per_instance,json=maxSessionsPerInstance,proto3" json:"max_sessions_per_instance,omitempty"` // 每个实例的并发会话上限，0 表示不限制
+	AllowedOrigins         []string               `protobuf:"bytes,5,rep,name=allowed_origins,json=allowedOrigins,proto3" json:"allowed_origins,omitempty"`                              // WebSocket exec 允许的 Origin，为空时仅允许同源，"*" 表示全部
+	RunTimeout             *durationpb.Duration   `protobuf:"bytes,6,opt,name=run_timeout,json=runTimeout,proto3" json:"run_timeout,omitempty"`                                          // RunCommand 默认超时，默认 30s
+	RunMaxTimeout          *durationpb.Duration   `protobuf:"bytes,7,opt,name=run_max_timeout,json=runMaxTimeout,proto3" json:"run_max_timeout,omitempty"`                               // RunCommand 允许请求的最长超时，默认 10m
+	RunMaxOutputBytes      int64                  `protobuf:"varint,8,opt,name=run_max_output_bytes,json=runMaxOutputBytes,proto3" json:"run_max_output_bytes,omitempty"`                // RunCommand stdout/stderr 各自的缓冲上限，默认 1MiB
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
-func (x *Data_Database) Reset() {
-	*x = Data_Database{}
-	mi := &file_conf_conf_proto_msgTypes[5]
+func (x *Server_Exec) Reset() {
+	*x = Server_Exec{}
+	mi := &file_conf_conf_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Data_Database) String() string {
+func (x *Server_Exec) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Data_Database) ProtoMessage() {}
+func (*Server_Exec) ProtoMessage() {}
 
-func (x *Data_Database) ProtoReflect() protoreflect.Message {
-	mi := &file_conf_conf_proto_msgTypes[5]
+func (x *Server_Exec) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -347,117 +509,90 @@ func (x *Data_Database) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Data_Database.ProtoReflect.Descriptor instead.
-func (*Data_Database) Descriptor() ([]byte, []int) {
-	return file_conf_conf_proto_rawDescGZIP(), []int{2, 0}
+// Deprecated: Use Server_Exec.ProtoReflect.Descriptor instead.
+func (*Server_Exec) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 2}
 }
 
-func (x *Data_Database) GetDriver() string {
+func (x *Server_Exec) GetIdleTimeout() *durationpb.Duration {
 	if x != nil {
-		return x.Driver
+		return x.IdleTimeout
 	}
-	return ""
+	return nil
 }
 
-func (x *Data_Database) GetSource() string {
+func (x *Server_Exec) GetMaxDuration() *durationpb.Duration {
 	if x != nil {
-		return x.Source
+		return x.MaxDuration
 	}
-	return ""
-}
-
-type Data_Redis struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Network       string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
-	Addr          string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
-	ReadTimeout   *durationpb.Duration   `protobuf:"bytes,3,opt,name=read_timeout,json=readTimeout,proto3" json:"read_timeout,omitempty"`
-	WriteTimeout  *durationpb.Duration   `protobuf:"bytes,4,opt,name=write_timeout,json=writeTimeout,proto3" json:"write_timeout,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *Data_Redis) Reset() {
-	*x = Data_Redis{}
-	mi := &file_conf_conf_proto_msgTypes[6]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *Data_Redis) String() string {
-	return protoimpl.X.MessageStringOf(x)
+	return nil
 }
 
-func (*Data_Redis) ProtoMessage() {}
-
-func (x *Data_Redis) ProtoReflect() protoreflect.Message {
-	mi := &file_conf_conf_proto_msgTypes[6]
+func (x *Server_Exec) GetMaxSessionsPerUser() int32 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.MaxSessionsPerUser
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use Data_Redis.ProtoReflect.Descriptor instead.
-func (*Data_Redis) Descriptor() ([]byte, []int) {
-	return file_conf_conf_proto_rawDescGZIP(), []int{2, 1}
+func (x *Server_Exec) GetMaxSessionsPerInstance() int32 {
+	if x != nil {
+		return x.MaxSessionsPerInstance
+	}
+	return 0
 }
 
-func (x *Data_Redis) GetNetwork() string {
+func (x *Server_Exec) GetAllowedOrigins() []string {
 	if x != nil {
-		return x.Network
+		return x.AllowedOrigins
 	}
-	return ""
+	return nil
 }
 
-func (x *Data_Redis) GetAddr() string {
+func (x *Server_Exec) GetRunTimeout() *durationpb.Duration {
 	if x != nil {
-		return x.Addr
+		return x.RunTimeout
 	}
-	return ""
+	return nil
 }
 
-func (x *Data_Redis) GetReadTimeout() *durationpb.Duration {
+func (x *Server_Exec) GetRunMaxTimeout() *durationpb.Duration {
 	if x != nil {
-		return x.ReadTimeout
+		return x.RunMaxTimeout
 	}
 	return nil
 }
 
-func (x *Data_Redis) GetWriteTimeout() *durationpb.Duration {
+func (x *Server_Exec) GetRunMaxOutputBytes() int64 {
 	if x != nil {
-		return x.WriteTimeout
+		return x.RunMaxOutputBytes
 	}
-	return nil
+	return 0
 }
 
-type Data_RabbitMQ struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
-	Queue         string                 `protobuf:"bytes,2,opt,name=queue,proto3" json:"queue,omitempty"`
-	Exchange      string                 `protobuf:"bytes,3,opt,name=exchange,proto3" json:"exchange,omitempty"`
-	RoutingKey    string                 `protobuf:"bytes,4,opt,name=routing_key,json=routingKey,proto3" json:"routing_key,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type Server_FileTransfer struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	MaxUploadBytes   int64                  `protobuf:"varint,1,opt,name=max_upload_bytes,json=maxUploadBytes,proto3" json:"max_upload_bytes,omitempty"`       // 单次上传的大小上限，默认 1GiB
+	MaxDownloadBytes int64                  `protobuf:"varint,2,opt,name=max_download_bytes,json=maxDownloadBytes,proto3" json:"max_download_bytes,omitempty"` // 单次下载的大小上限，默认 1GiB
+	ChunkSize        uint32                 `protobuf:"varint,3,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`                        // 下载分块大小，默认 32KiB
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
-func (x *Data_RabbitMQ) Reset() {
-	*x = Data_RabbitMQ{}
+func (x *Server_FileTransfer) Reset() {
+	*x = Server_FileTransfer{}
 	mi := &file_conf_conf_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Data_RabbitMQ) String() string {
+func (x *Server_FileTransfer) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Data_RabbitMQ) ProtoMessage() {}
+func (*Server_FileTransfer) ProtoMessage() {}
 
-func (x *Data_RabbitMQ) ProtoReflect() protoreflect.Message {
+func (x *Server_FileTransfer) ProtoReflect() protoreflect.Message {
 	mi := &file_conf_conf_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -469,65 +604,55 @@ func (x *Data_RabbitMQ) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Data_RabbitMQ.ProtoReflect.Descriptor instead.
-func (*Data_RabbitMQ) Descriptor() ([]byte, []int) {
-	return file_conf_conf_proto_rawDescGZIP(), []int{2, 2}
-}
-
-func (x *Data_RabbitMQ) GetUrl() string {
-	if x != nil {
-		return x.Url
-	}
-	return ""
+// Deprecated: Use Server_FileTransfer.ProtoReflect.Descriptor instead.
+func (*Server_FileTransfer) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 3}
 }
 
-func (x *Data_RabbitMQ) GetQueue() string {
+func (x *Server_FileTransfer) GetMaxUploadBytes() int64 {
 	if x != nil {
-		return x.Queue
+		return x.MaxUploadBytes
 	}
-	return ""
+	return 0
 }
 
-func (x *Data_RabbitMQ) GetExchange() string {
+func (x *Server_FileTransfer) GetMaxDownloadBytes() int64 {
 	if x != nil {
-		return x.Exchange
+		return x.MaxDownloadBytes
 	}
-	return ""
+	return 0
 }
 
-func (x *Data_RabbitMQ) GetRoutingKey() string {
+func (x *Server_FileTransfer) GetChunkSize() uint32 {
 	if x != nil {
-		return x.RoutingKey
+		return x.ChunkSize
 	}
-	return ""
+	return 0
 }
 
-type Data_Kubernetes struct {
-	state                 protoimpl.MessageState `protogen:"open.v1"`
-	Kubeconfig            string                 `protobuf:"bytes,1,opt,name=kubeconfig,proto3" json:"kubeconfig,omitempty"`
-	IngressDomain         string                 `protobuf:"bytes,2,opt,name=ingress_domain,json=ingressDomain,proto3" json:"ingress_domain,omitempty"`                             // Ingress 默认域名（HTTP 模式）
-	IngressNginxNamespace string                 `protobuf:"bytes,3,opt,name=ingress_nginx_namespace,json=ingressNginxNamespace,proto3" json:"ingress_nginx_namespace,omitempty"`   // ingress-nginx 所在命名空间
-	IngressNginxLbService string                 `protobuf:"bytes,4,opt,name=ingress_nginx_lb_service,json=ingressNginxLbService,proto3" json:"ingress_nginx_lb_service,omitempty"` // ingress-nginx LoadBalancer Service 名称
-	TcpUdpPortRangeStart  uint32                 `protobuf:"varint,5,opt,name=tcp_udp_port_range_start,json=tcpUdpPortRangeStart,proto3" json:"tcp_udp_port_range_start,omitempty"` // TCP/UDP 外部端口范围起始
-	TcpUdpPortRangeEnd    uint32                 `protobuf:"varint,6,opt,name=tcp_udp_port_range_end,json=tcpUdpPortRangeEnd,proto3" json:"tcp_udp_port_range_end,omitempty"`       // TCP/UDP 外部端口范围结束
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+type Server_NetworkReconcile struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Interval       *durationpb.Duration   `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`                                   // 周期核对端口绑定与 K8s 网络资源的间隔，0 表示不启用
+	Repair         bool                   `protobuf:"varint,2,opt,name=repair,proto3" json:"repair,omitempty"`                                      // 是否自动修复差异，false 时仅记录日志
+	LeaseNamespace string                 `protobuf:"bytes,3,opt,name=lease_namespace,json=leaseNamespace,proto3" json:"lease_namespace,omitempty"` // 选主 Lease 所在命名空间，设置后多副本中只有持有 Lease 的副本执行周期核对与修复
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *Data_Kubernetes) Reset() {
-	*x = Data_Kubernetes{}
+func (x *Server_NetworkReconcile) Reset() {
+	*x = Server_NetworkReconcile{}
 	mi := &file_conf_conf_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Data_Kubernetes) String() string {
+func (x *Server_NetworkReconcile) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Data_Kubernetes) ProtoMessage() {}
+func (*Server_NetworkReconcile) ProtoMessage() {}
 
-func (x *Data_Kubernetes) ProtoReflect() protoreflect.Message {
+func (x *Server_NetworkReconcile) ProtoReflect() protoreflect.Message {
 	mi := &file_conf_conf_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -539,51 +664,914 @@ func (x *Data_Kubernetes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Data_Kubernetes.ProtoReflect.Descriptor instead.
-func (*Data_Kubernetes) Descriptor() ([]byte, []int) {
-	return file_conf_conf_proto_rawDescGZIP(), []int{2, 3}
+// Deprecated: Use Server_NetworkReconcile.ProtoReflect.Descriptor instead.
+func (*Server_NetworkReconcile) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 4}
 }
 
-func (x *Data_Kubernetes) GetKubeconfig() string {
+func (x *Server_NetworkReconcile) GetInterval() *durationpb.Duration {
 	if x != nil {
-		return x.Kubeconfig
+		return x.Interval
 	}
-	return ""
+	return nil
 }
 
-func (x *Data_Kubernetes) GetIngressDomain() string {
+func (x *Server_NetworkReconcile) GetRepair() bool {
 	if x != nil {
-		return x.IngressDomain
+		return x.Repair
 	}
-	return ""
+	return false
 }
 
-func (x *Data_Kubernetes) GetIngressNginxNamespace() string {
+func (x *Server_NetworkReconcile) GetLeaseNamespace() string {
 	if x != nil {
-		return x.IngressNginxNamespace
+		return x.LeaseNamespace
 	}
 	return ""
 }
 
-func (x *Data_Kubernetes) GetIngressNginxLbService() string {
-	if x != nil {
-		return x.IngressNginxLbService
-	}
-	return ""
+type Server_InstanceStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Interval      *durationpb.Duration   `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"` // 周期诊断实例事件并写回失败原因的间隔，0 表示不启用
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Data_Kubernetes) GetTcpUdpPortRangeStart() uint32 {
-	if x != nil {
-		return x.TcpUdpPortRangeStart
-	}
-	return 0
+func (x *Server_InstanceStatus) Reset() {
+	*x = Server_InstanceStatus{}
+	mi := &file_conf_conf_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *Data_Kubernetes) GetTcpUdpPortRangeEnd() uint32 {
-	if x != nil {
+func (x *Server_InstanceStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Server_InstanceStatus) ProtoMessage() {}
+
+func (x *Server_InstanceStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Server_InstanceStatus.ProtoReflect.Descriptor instead.
+func (*Server_InstanceStatus) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 5}
+}
+
+func (x *Server_InstanceStatus) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+type Data_Database struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Driver        string                 `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
+	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Database) Reset() {
+	*x = Data_Database{}
+	mi := &file_conf_conf_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Database) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Database) ProtoMessage() {}
+
+func (x *Data_Database) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Database.ProtoReflect.Descriptor instead.
+func (*Data_Database) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 0}
+}
+
+func (x *Data_Database) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+func (x *Data_Database) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type Data_Redis struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Network       string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	Addr          string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	ReadTimeout   *durationpb.Duration   `protobuf:"bytes,3,opt,name=read_timeout,json=readTimeout,proto3" json:"read_timeout,omitempty"`
+	WriteTimeout  *durationpb.Duration   `protobuf:"bytes,4,opt,name=write_timeout,json=writeTimeout,proto3" json:"write_timeout,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Redis) Reset() {
+	*x = Data_Redis{}
+	mi := &file_conf_conf_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Redis) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Redis) ProtoMessage() {}
+
+func (x *Data_Redis) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Redis.ProtoReflect.Descriptor instead.
+func (*Data_Redis) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 1}
+}
+
+func (x *Data_Redis) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *Data_Redis) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *Data_Redis) GetReadTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.ReadTimeout
+	}
+	return nil
+}
+
+func (x *Data_Redis) GetWriteTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.WriteTimeout
+	}
+	return nil
+}
+
+type Data_RabbitMQ struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Queue         string                 `protobuf:"bytes,2,opt,name=queue,proto3" json:"queue,omitempty"`
+	Exchange      string                 `protobuf:"bytes,3,opt,name=exchange,proto3" json:"exchange,omitempty"`
+	RoutingKey    string                 `protobuf:"bytes,4,opt,name=routing_key,json=routingKey,proto3" json:"routing_key,omitempty"`
+	DlxExchange   string                 `protobuf:"bytes,5,opt,name=dlx_exchange,json=dlxExchange,proto3" json:"dlx_exchange,omitempty"` // 死信交换机
+	DlxQueue      string                 `protobuf:"bytes,6,opt,name=dlx_queue,json=dlxQueue,proto3" json:"dlx_queue,omitempty"`          // 死信队列
+	MaxRetries    uint32                 `protobuf:"varint,7,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`   // 最大重试次数
+	MessageTtl    uint32                 `protobuf:"varint,8,opt,name=message_ttl,json=messageTtl,proto3" json:"message_ttl,omitempty"`   // 消息 TTL (毫秒)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_RabbitMQ) Reset() {
+	*x = Data_RabbitMQ{}
+	mi := &file_conf_conf_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_RabbitMQ) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_RabbitMQ) ProtoMessage() {}
+
+func (x *Data_RabbitMQ) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_RabbitMQ.ProtoReflect.Descriptor instead.
+func (*Data_RabbitMQ) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 2}
+}
+
+func (x *Data_RabbitMQ) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Data_RabbitMQ) GetQueue() string {
+	if x != nil {
+		return x.Queue
+	}
+	return ""
+}
+
+func (x *Data_RabbitMQ) GetExchange() string {
+	if x != nil {
+		return x.Exchange
+	}
+	return ""
+}
+
+func (x *Data_RabbitMQ) GetRoutingKey() string {
+	if x != nil {
+		return x.RoutingKey
+	}
+	return ""
+}
+
+func (x *Data_RabbitMQ) GetDlxExchange() string {
+	if x != nil {
+		return x.DlxExchange
+	}
+	return ""
+}
+
+func (x *Data_RabbitMQ) GetDlxQueue() string {
+	if x != nil {
+		return x.DlxQueue
+	}
+	return ""
+}
+
+func (x *Data_RabbitMQ) GetMaxRetries() uint32 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+func (x *Data_RabbitMQ) GetMessageTtl() uint32 {
+	if x != nil {
+		return x.MessageTtl
+	}
+	return 0
+}
+
+type Data_Kubernetes struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	Kubeconfig              string                 `protobuf:"bytes,1,opt,name=kubeconfig,proto3" json:"kubeconfig,omitempty"`
+	IngressDomain           string                 `protobuf:"bytes,2,opt,name=ingress_domain,json=ingressDomain,proto3" json:"ingress_domain,omitempty"`                                     // Ingress 默认域名（HTTP 模式）
+	IngressNginxNamespace   string                 `protobuf:"bytes,3,opt,name=ingress_nginx_namespace,json=ingressNginxNamespace,proto3" json:"ingress_nginx_namespace,omitempty"`           // ingress-nginx 所在命名空间
+	IngressNginxLbService   string                 `protobuf:"bytes,4,opt,name=ingress_nginx_lb_service,json=ingressNginxLbService,proto3" json:"ingress_nginx_lb_service,omitempty"`         // ingress-nginx LoadBalancer Service 名称
+	TcpUdpPortRangeStart    uint32                 `protobuf:"varint,5,opt,name=tcp_udp_port_range_start,json=tcpUdpPortRangeStart,proto3" json:"tcp_udp_port_range_start,omitempty"`         // TCP/UDP 外部端口范围起始
+	TcpUdpPortRangeEnd      uint32                 `protobuf:"varint,6,opt,name=tcp_udp_port_range_end,json=tcpUdpPortRangeEnd,proto3" json:"tcp_udp_port_range_end,omitempty"`               // TCP/UDP 外部端口范围结束
+	TlsClusterIssuer        string                 `protobuf:"bytes,7,opt,name=tls_cluster_issuer,json=tlsClusterIssuer,proto3" json:"tls_cluster_issuer,omitempty"`                          // cert-manager ClusterIssuer，设置后为 HTTPS Ingress 自动签发证书
+	TlsWildcardSecret       string                 `protobuf:"bytes,8,opt,name=tls_wildcard_secret,json=tlsWildcardSecret,proto3" json:"tls_wildcard_secret,omitempty"`                       // 通配符证书 Secret（namespace/name），未配置 ClusterIssuer 时复制到实例命名空间使用
+	ExposureBackend         string                 `protobuf:"bytes,9,opt,name=exposure_backend,json=exposureBackend,proto3" json:"exposure_backend,omitempty"`                               // 端口暴露后端：ingress-nginx（默认）/ gateway-api
+	GatewayApi              *Data_GatewayAPI       `protobuf:"bytes,10,opt,name=gateway_api,json=gatewayApi,proto3" json:"gateway_api,omitempty"`                                             // exposure_backend=gateway-api 时使用的 Gateway
+	NodeAddress             string                 `protobuf:"bytes,11,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`                                          // NODEPORT 模式访问地址中的节点地址，为空时使用节点的 ExternalIP/InternalIP
+	DisableNetworkPolicy    bool                   `protobuf:"varint,12,opt,name=disable_network_policy,json=disableNetworkPolicy,proto3" json:"disable_network_policy,omitempty"`            // 关闭用户命名空间的默认隔离 NetworkPolicy（CNI 不支持 NetworkPolicy 时使用）
+	TcpUdpPublicHost        string                 `protobuf:"bytes,13,opt,name=tcp_udp_public_host,json=tcpUdpPublicHost,proto3" json:"tcp_udp_public_host,omitempty"`                       // TCP/UDP 访问地址中的公开主机名（如 tcp.example.com），为空时使用 ingress-nginx LoadBalancer / Gateway 的地址
+	NetworkPolicyDenyEgress bool                   `protobuf:"varint,14,opt,name=network_policy_deny_egress,json=networkPolicyDenyEgress,proto3" json:"network_policy_deny_egress,omitempty"` // 默认隔离同时拒绝出站流量（只放开同命名空间与集群 DNS），默认只隔离入站
+	NetworkPolicyNodeCidrs  []string               `protobuf:"bytes,15,rep,name=network_policy_node_cidrs,json=networkPolicyNodeCidrs,proto3" json:"network_policy_node_cidrs,omitempty"`     // 允许入站访问实例的节点网段（hostNetwork 的 ingress 控制器、kubelet 等）
+	NetworkPolicyBackfill   bool                   `protobuf:"varint,16,opt,name=network_policy_backfill,json=networkPolicyBackfill,proto3" json:"network_policy_backfill,omitempty"`         // 启动与周期核对时为已有用户命名空间补齐默认隔离策略，默认只在创建实例时创建
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *Data_Kubernetes) Reset() {
+	*x = Data_Kubernetes{}
+	mi := &file_conf_conf_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Kubernetes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Kubernetes) ProtoMessage() {}
+
+func (x *Data_Kubernetes) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Kubernetes.ProtoReflect.Descriptor instead.
+func (*Data_Kubernetes) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 3}
+}
+
+func (x *Data_Kubernetes) GetKubeconfig() string {
+	if x != nil {
+		return x.Kubeconfig
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetIngressDomain() string {
+	if x != nil {
+		return x.IngressDomain
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetIngressNginxNamespace() string {
+	if x != nil {
+		return x.IngressNginxNamespace
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetIngressNginxLbService() string {
+	if x != nil {
+		return x.IngressNginxLbService
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetTcpUdpPortRangeStart() uint32 {
+	if x != nil {
+		return x.TcpUdpPortRangeStart
+	}
+	return 0
+}
+
+func (x *Data_Kubernetes) GetTcpUdpPortRangeEnd() uint32 {
+	if x != nil {
 		return x.TcpUdpPortRangeEnd
 	}
-	return 0
+	return 0
+}
+
+func (x *Data_Kubernetes) GetTlsClusterIssuer() string {
+	if x != nil {
+		return x.TlsClusterIssuer
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetTlsWildcardSecret() string {
+	if x != nil {
+		return x.TlsWildcardSecret
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetExposureBackend() string {
+	if x != nil {
+		return x.ExposureBackend
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetGatewayApi() *Data_GatewayAPI {
+	if x != nil {
+		return x.GatewayApi
+	}
+	return nil
+}
+
+func (x *Data_Kubernetes) GetNodeAddress() string {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetDisableNetworkPolicy() bool {
+	if x != nil {
+		return x.DisableNetworkPolicy
+	}
+	return false
+}
+
+func (x *Data_Kubernetes) GetTcpUdpPublicHost() string {
+	if x != nil {
+		return x.TcpUdpPublicHost
+	}
+	return ""
+}
+
+func (x *Data_Kubernetes) GetNetworkPolicyDenyEgress() bool {
+	if x != nil {
+		return x.NetworkPolicyDenyEgress
+	}
+	return false
+}
+
+func (x *Data_Kubernetes) GetNetworkPolicyNodeCidrs() []string {
+	if x != nil {
+		return x.NetworkPolicyNodeCidrs
+	}
+	return nil
+}
+
+func (x *Data_Kubernetes) GetNetworkPolicyBackfill() bool {
+	if x != nil {
+		return x.NetworkPolicyBackfill
+	}
+	return false
+}
+
+type Data_GatewayAPI struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`                              // Gateway 所在命名空间
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                                        // Gateway 名称，TCP/UDP 外部端口以 listener 形式添加到该 Gateway
+	HttpListener  string                 `protobuf:"bytes,3,opt,name=http_listener,json=httpListener,proto3" json:"http_listener,omitempty"`    // HTTPRoute 挂载的 listener（sectionName），默认 http
+	HttpsListener string                 `protobuf:"bytes,4,opt,name=https_listener,json=httpsListener,proto3" json:"https_listener,omitempty"` // tls=true 时挂载的 HTTPS listener，证书由 Gateway 配置；为空时不支持 HTTPS
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_GatewayAPI) Reset() {
+	*x = Data_GatewayAPI{}
+	mi := &file_conf_conf_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_GatewayAPI) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_GatewayAPI) ProtoMessage() {}
+
+func (x *Data_GatewayAPI) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_GatewayAPI.ProtoReflect.Descriptor instead.
+func (*Data_GatewayAPI) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 4}
+}
+
+func (x *Data_GatewayAPI) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *Data_GatewayAPI) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Data_GatewayAPI) GetHttpListener() string {
+	if x != nil {
+		return x.HttpListener
+	}
+	return ""
+}
+
+func (x *Data_GatewayAPI) GetHttpsListener() string {
+	if x != nil {
+		return x.HttpsListener
+	}
+	return ""
+}
+
+type Data_ExecRecording struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Storage       string                 `protobuf:"bytes,1,opt,name=storage,proto3" json:"storage,omitempty"` // 录像存储类型，目前支持 local（默认）
+	Dir           string                 `protobuf:"bytes,2,opt,name=dir,proto3" json:"dir,omitempty"`         // local 存储目录，默认 data/exec-sessions
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_ExecRecording) Reset() {
+	*x = Data_ExecRecording{}
+	mi := &file_conf_conf_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_ExecRecording) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_ExecRecording) ProtoMessage() {}
+
+func (x *Data_ExecRecording) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_ExecRecording.ProtoReflect.Descriptor instead.
+func (*Data_ExecRecording) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 5}
+}
+
+func (x *Data_ExecRecording) GetStorage() string {
+	if x != nil {
+		return x.Storage
+	}
+	return ""
+}
+
+func (x *Data_ExecRecording) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+type Data_CustomDomain struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DnsServer     string                 `protobuf:"bytes,1,opt,name=dns_server,json=dnsServer,proto3" json:"dns_server,omitempty"` // TXT 验证使用的 DNS 服务器（host:port），为空时使用系统解析器
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_CustomDomain) Reset() {
+	*x = Data_CustomDomain{}
+	mi := &file_conf_conf_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_CustomDomain) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_CustomDomain) ProtoMessage() {}
+
+func (x *Data_CustomDomain) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_CustomDomain.ProtoReflect.Descriptor instead.
+func (*Data_CustomDomain) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 6}
+}
+
+func (x *Data_CustomDomain) GetDnsServer() string {
+	if x != nil {
+		return x.DnsServer
+	}
+	return ""
+}
+
+type Auth_Role struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Operations    []string               `protobuf:"bytes,2,rep,name=operations,proto3" json:"operations,omitempty"`                              // 允许调用的 RPC 方法名，如 StopInstance；"*" 表示全部
+	InstanceScope string                 `protobuf:"bytes,3,opt,name=instance_scope,json=instanceScope,proto3" json:"instance_scope,omitempty"`   // 实例范围：ALL（全部实例）/ OWN（仅本人实例），默认 OWN
+	InstanceIds   []int64                `protobuf:"varint,4,rep,packed,name=instance_ids,json=instanceIds,proto3" json:"instance_ids,omitempty"` // 额外允许访问的实例 ID
+	Exec          *Auth_ExecPolicy       `protobuf:"bytes,5,opt,name=exec,proto3" json:"exec,omitempty"`                                          // 该角色的 exec 命令策略
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Auth_Role) Reset() {
+	*x = Auth_Role{}
+	mi := &file_conf_conf_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Auth_Role) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Auth_Role) ProtoMessage() {}
+
+func (x *Auth_Role) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Auth_Role.ProtoReflect.Descriptor instead.
+func (*Auth_Role) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{3, 0}
+}
+
+func (x *Auth_Role) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Auth_Role) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+func (x *Auth_Role) GetInstanceScope() string {
+	if x != nil {
+		return x.InstanceScope
+	}
+	return ""
+}
+
+func (x *Auth_Role) GetInstanceIds() []int64 {
+	if x != nil {
+		return x.InstanceIds
+	}
+	return nil
+}
+
+func (x *Auth_Role) GetExec() *Auth_ExecPolicy {
+	if x != nil {
+		return x.Exec
+	}
+	return nil
+}
+
+type Auth_Binding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Auth_Binding) Reset() {
+	*x = Auth_Binding{}
+	mi := &file_conf_conf_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Auth_Binding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Auth_Binding) ProtoMessage() {}
+
+func (x *Auth_Binding) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Auth_Binding.ProtoReflect.Descriptor instead.
+func (*Auth_Binding) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{3, 1}
+}
+
+func (x *Auth_Binding) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Auth_Binding) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+type Auth_ExecRule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        []string               `protobuf:"bytes,1,rep,name=prefix,proto3" json:"prefix,omitempty"` // argv 前缀，逐个参数完全匹配，如 [cat, /var/log/app.log]
+	Regex         string                 `protobuf:"bytes,2,opt,name=regex,proto3" json:"regex,omitempty"`   // 匹配以空格拼接的完整命令
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Auth_ExecRule) Reset() {
+	*x = Auth_ExecRule{}
+	mi := &file_conf_conf_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Auth_ExecRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Auth_ExecRule) ProtoMessage() {}
+
+func (x *Auth_ExecRule) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Auth_ExecRule.ProtoReflect.Descriptor instead.
+func (*Auth_ExecRule) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{3, 2}
+}
+
+func (x *Auth_ExecRule) GetPrefix() []string {
+	if x != nil {
+		return x.Prefix
+	}
+	return nil
+}
+
+func (x *Auth_ExecRule) GetRegex() string {
+	if x != nil {
+		return x.Regex
+	}
+	return ""
+}
+
+type Auth_ExecPolicy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allow         []*Auth_ExecRule       `protobuf:"bytes,1,rep,name=allow,proto3" json:"allow,omitempty"`                     // 非空时命令必须命中其中一条规则
+	Deny          []*Auth_ExecRule       `protobuf:"bytes,2,rep,name=deny,proto3" json:"deny,omitempty"`                       // 命中任意一条即拒绝，优先于 allow
+	DenyTty       bool                   `protobuf:"varint,3,opt,name=deny_tty,json=denyTty,proto3" json:"deny_tty,omitempty"` // 禁止交互式 TTY
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Auth_ExecPolicy) Reset() {
+	*x = Auth_ExecPolicy{}
+	mi := &file_conf_conf_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Auth_ExecPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Auth_ExecPolicy) ProtoMessage() {}
+
+func (x *Auth_ExecPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Auth_ExecPolicy.ProtoReflect.Descriptor instead.
+func (*Auth_ExecPolicy) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{3, 3}
+}
+
+func (x *Auth_ExecPolicy) GetAllow() []*Auth_ExecRule {
+	if x != nil {
+		return x.Allow
+	}
+	return nil
+}
+
+func (x *Auth_ExecPolicy) GetDeny() []*Auth_ExecRule {
+	if x != nil {
+		return x.Deny
+	}
+	return nil
+}
+
+func (x *Auth_ExecPolicy) GetDenyTty() bool {
+	if x != nil {
+		return x.DenyTty
+	}
+	return false
+}
+
+type Auth_InstanceExecPolicy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InstanceIds   []int64                `protobuf:"varint,1,rep,packed,name=instance_ids,json=instanceIds,proto3" json:"instance_ids,omitempty"`
+	Policy        *Auth_ExecPolicy       `protobuf:"bytes,2,opt,name=policy,proto3" json:"policy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Auth_InstanceExecPolicy) Reset() {
+	*x = Auth_InstanceExecPolicy{}
+	mi := &file_conf_conf_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Auth_InstanceExecPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Auth_InstanceExecPolicy) ProtoMessage() {}
+
+func (x *Auth_InstanceExecPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Auth_InstanceExecPolicy.ProtoReflect.Descriptor instead.
+func (*Auth_InstanceExecPolicy) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{3, 4}
+}
+
+func (x *Auth_InstanceExecPolicy) GetInstanceIds() []int64 {
+	if x != nil {
+		return x.InstanceIds
+	}
+	return nil
+}
+
+func (x *Auth_InstanceExecPolicy) GetPolicy() *Auth_ExecPolicy {
+	if x != nil {
+		return x.Policy
+	}
+	return nil
 }
 
 var File_conf_conf_proto protoreflect.FileDescriptor
@@ -591,13 +1579,19 @@ var File_conf_conf_proto protoreflect.FileDescriptor
 const file_conf_conf_proto_rawDesc = "" +
 	"\n" +
 	"\x0fconf/conf.proto\x12\n" +
-	"kratos.api\x1a\x1egoogle/protobuf/duration.proto\"]\n" +
+	"kratos.api\x1a\x1egoogle/protobuf/duration.proto\"\x83\x01\n" +
 	"\tBootstrap\x12*\n" +
 	"\x06server\x18\x01 \x01(\v2\x12.kratos.api.ServerR\x06server\x12$\n" +
-	"\x04data\x18\x02 \x01(\v2\x10.kratos.api.DataR\x04data\"\xb8\x02\n" +
+	"\x04data\x18\x02 \x01(\v2\x10.kratos.api.DataR\x04data\x12$\n" +
+	"\x04auth\x18\x03 \x01(\v2\x10.kratos.api.AuthR\x04auth\"\xf3\n" +
+	"\n" +
 	"\x06Server\x12+\n" +
 	"\x04http\x18\x01 \x01(\v2\x17.kratos.api.Server.HTTPR\x04http\x12+\n" +
-	"\x04grpc\x18\x02 \x01(\v2\x17.kratos.api.Server.GRPCR\x04grpc\x1ai\n" +
+	"\x04grpc\x18\x02 \x01(\v2\x17.kratos.api.Server.GRPCR\x04grpc\x12+\n" +
+	"\x04exec\x18\x03 \x01(\v2\x17.kratos.api.Server.ExecR\x04exec\x12D\n" +
+	"\rfile_transfer\x18\x04 \x01(\v2\x1f.kratos.api.Server.FileTransferR\ffileTransfer\x12P\n" +
+	"\x11network_reconcile\x18\x05 \x01(\v2#.kratos.api.Server.NetworkReconcileR\x10networkReconcile\x12J\n" +
+	"\x0finstance_status\x18\x06 \x01(\v2!.kratos.api.Server.InstanceStatusR\x0einstanceStatus\x1ai\n" +
 	"\x04HTTP\x12\x18\n" +
 	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
 	"\x04addr\x18\x02 \x01(\tR\x04addr\x123\n" +
@@ -605,14 +1599,37 @@ const file_conf_conf_proto_rawDesc = "" +
 	"\x04GRPC\x12\x18\n" +
 	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
 	"\x04addr\x18\x02 \x01(\tR\x04addr\x123\n" +
-	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\"\xf5\x06\n" +
+	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\x1a\xc9\x03\n" +
+	"\x04Exec\x12<\n" +
+	"\fidle_timeout\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\vidleTimeout\x12<\n" +
+	"\fmax_duration\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\vmaxDuration\x121\n" +
+	"\x15max_sessions_per_user\x18\x03 \x01(\x05R\x12maxSessionsPerUser\x129\n" +
+	"\x19max_sessions_per_instance\x18\x04 \x01(\x05R\x16maxSessionsPerInstance\x12'\n" +
+	"\x0fallowed_origins\x18\x05 \x03(\tR\x0eallowedOrigins\x12:\n" +
+	"\vrun_timeout\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"runTimeout\x12A\n" +
+	"\x0frun_max_timeout\x18\a \x01(\v2\x19.google.protobuf.DurationR\rrunMaxTimeout\x12/\n" +
+	"\x14run_max_output_bytes\x18\b \x01(\x03R\x11runMaxOutputBytes\x1a\x85\x01\n" +
+	"\fFileTransfer\x12(\n" +
+	"\x10max_upload_bytes\x18\x01 \x01(\x03R\x0emaxUploadBytes\x12,\n" +
+	"\x12max_download_bytes\x18\x02 \x01(\x03R\x10maxDownloadBytes\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x03 \x01(\rR\tchunkSize\x1a\x8a\x01\n" +
+	"\x10NetworkReconcile\x125\n" +
+	"\binterval\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\binterval\x12\x16\n" +
+	"\x06repair\x18\x02 \x01(\bR\x06repair\x12'\n" +
+	"\x0flease_namespace\x18\x03 \x01(\tR\x0eleaseNamespace\x1aG\n" +
+	"\x0eInstanceStatus\x125\n" +
+	"\binterval\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\binterval\"\xfb\x0e\n" +
 	"\x04Data\x125\n" +
 	"\bdatabase\x18\x01 \x01(\v2\x19.kratos.api.Data.DatabaseR\bdatabase\x12,\n" +
 	"\x05redis\x18\x02 \x01(\v2\x16.kratos.api.Data.RedisR\x05redis\x125\n" +
 	"\brabbitmq\x18\x03 \x01(\v2\x19.kratos.api.Data.RabbitMQR\brabbitmq\x12;\n" +
 	"\n" +
 	"kubernetes\x18\x04 \x01(\v2\x1b.kratos.api.Data.KubernetesR\n" +
-	"kubernetes\x1a:\n" +
+	"kubernetes\x12E\n" +
+	"\x0eexec_recording\x18\x05 \x01(\v2\x1e.kratos.api.Data.ExecRecordingR\rexecRecording\x12B\n" +
+	"\rcustom_domain\x18\x06 \x01(\v2\x1d.kratos.api.Data.CustomDomainR\fcustomDomain\x1a:\n" +
 	"\bDatabase\x12\x16\n" +
 	"\x06driver\x18\x01 \x01(\tR\x06driver\x12\x16\n" +
 	"\x06source\x18\x02 \x01(\tR\x06source\x1a\xb3\x01\n" +
@@ -620,13 +1637,19 @@ const file_conf_conf_proto_rawDesc = "" +
 	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
 	"\x04addr\x18\x02 \x01(\tR\x04addr\x12<\n" +
 	"\fread_timeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\vreadTimeout\x12>\n" +
-	"\rwrite_timeout\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\fwriteTimeout\x1ao\n" +
+	"\rwrite_timeout\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\fwriteTimeout\x1a\xf1\x01\n" +
 	"\bRabbitMQ\x12\x10\n" +
 	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
 	"\x05queue\x18\x02 \x01(\tR\x05queue\x12\x1a\n" +
 	"\bexchange\x18\x03 \x01(\tR\bexchange\x12\x1f\n" +
 	"\vrouting_key\x18\x04 \x01(\tR\n" +
-	"routingKey\x1a\xb0\x02\n" +
+	"routingKey\x12!\n" +
+	"\fdlx_exchange\x18\x05 \x01(\tR\vdlxExchange\x12\x1b\n" +
+	"\tdlx_queue\x18\x06 \x01(\tR\bdlxQueue\x12\x1f\n" +
+	"\vmax_retries\x18\a \x01(\rR\n" +
+	"maxRetries\x12\x1f\n" +
+	"\vmessage_ttl\x18\b \x01(\rR\n" +
+	"messageTtl\x1a\xaf\x06\n" +
 	"\n" +
 	"Kubernetes\x12\x1e\n" +
 	"\n" +
@@ -636,7 +1659,64 @@ const file_conf_conf_proto_rawDesc = "" +
 	"\x17ingress_nginx_namespace\x18\x03 \x01(\tR\x15ingressNginxNamespace\x127\n" +
 	"\x18ingress_nginx_lb_service\x18\x04 \x01(\tR\x15ingressNginxLbService\x126\n" +
 	"\x18tcp_udp_port_range_start\x18\x05 \x01(\rR\x14tcpUdpPortRangeStart\x122\n" +
-	"\x16tcp_udp_port_range_end\x18\x06 \x01(\rR\x12tcpUdpPortRangeEndB\x1dZ\x1bresource/internal/conf;confb\x06proto3"
+	"\x16tcp_udp_port_range_end\x18\x06 \x01(\rR\x12tcpUdpPortRangeEnd\x12,\n" +
+	"\x12tls_cluster_issuer\x18\a \x01(\tR\x10tlsClusterIssuer\x12.\n" +
+	"\x13tls_wildcard_secret\x18\b \x01(\tR\x11tlsWildcardSecret\x12)\n" +
+	"\x10exposure_backend\x18\t \x01(\tR\x0fexposureBackend\x12<\n" +
+	"\vgateway_api\x18\n" +
+	" \x01(\v2\x1b.kratos.api.Data.GatewayAPIR\n" +
+	"gatewayApi\x12!\n" +
+	"\fnode_address\x18\v \x01(\tR\vnodeAddress\x124\n" +
+	"\x16disable_network_policy\x18\f \x01(\bR\x14disableNetworkPolicy\x12-\n" +
+	"\x13tcp_udp_public_host\x18\r \x01(\tR\x10tcpUdpPublicHost\x12;\n" +
+	"\x1anetwork_policy_deny_egress\x18\x0e \x01(\bR\x17networkPolicyDenyEgress\x129\n" +
+	"\x19network_policy_node_cidrs\x18\x0f \x03(\tR\x16networkPolicyNodeCidrs\x126\n" +
+	"\x17network_policy_backfill\x18\x10 \x01(\bR\x15networkPolicyBackfill\x1a\x8a\x01\n" +
+	"\n" +
+	"GatewayAPI\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12#\n" +
+	"\rhttp_listener\x18\x03 \x01(\tR\fhttpListener\x12%\n" +
+	"\x0ehttps_listener\x18\x04 \x01(\tR\rhttpsListener\x1a;\n" +
+	"\rExecRecording\x12\x18\n" +
+	"\astorage\x18\x01 \x01(\tR\astorage\x12\x10\n" +
+	"\x03dir\x18\x02 \x01(\tR\x03dir\x1a-\n" +
+	"\fCustomDomain\x12\x1d\n" +
+	"\n" +
+	"dns_server\x18\x01 \x01(\tR\tdnsServer\"\x91\a\n" +
+	"\x04Auth\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x1f\n" +
+	"\vuser_header\x18\x02 \x01(\tR\n" +
+	"userHeader\x12\x1f\n" +
+	"\vrole_header\x18\x03 \x01(\tR\n" +
+	"roleHeader\x12*\n" +
+	"\x11trust_role_header\x18\x04 \x01(\bR\x0ftrustRoleHeader\x12!\n" +
+	"\fdefault_role\x18\x05 \x01(\tR\vdefaultRole\x12+\n" +
+	"\x05roles\x18\x06 \x03(\v2\x15.kratos.api.Auth.RoleR\x05roles\x124\n" +
+	"\bbindings\x18\a \x03(\v2\x18.kratos.api.Auth.BindingR\bbindings\x12Y\n" +
+	"\x16instance_exec_policies\x18\b \x03(\v2#.kratos.api.Auth.InstanceExecPolicyR\x14instanceExecPolicies\x1a\xb5\x01\n" +
+	"\x04Role\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x02 \x03(\tR\n" +
+	"operations\x12%\n" +
+	"\x0einstance_scope\x18\x03 \x01(\tR\rinstanceScope\x12!\n" +
+	"\finstance_ids\x18\x04 \x03(\x03R\vinstanceIds\x12/\n" +
+	"\x04exec\x18\x05 \x01(\v2\x1b.kratos.api.Auth.ExecPolicyR\x04exec\x1a6\n" +
+	"\aBinding\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x1a8\n" +
+	"\bExecRule\x12\x16\n" +
+	"\x06prefix\x18\x01 \x03(\tR\x06prefix\x12\x14\n" +
+	"\x05regex\x18\x02 \x01(\tR\x05regex\x1a\x87\x01\n" +
+	"\n" +
+	"ExecPolicy\x12/\n" +
+	"\x05allow\x18\x01 \x03(\v2\x19.kratos.api.Auth.ExecRuleR\x05allow\x12-\n" +
+	"\x04deny\x18\x02 \x03(\v2\x19.kratos.api.Auth.ExecRuleR\x04deny\x12\x19\n" +
+	"\bdeny_tty\x18\x03 \x01(\bR\adenyTty\x1al\n" +
+	"\x12InstanceExecPolicy\x12!\n" +
+	"\finstance_ids\x18\x01 \x03(\x03R\vinstanceIds\x123\n" +
+	"\x06policy\x18\x02 \x01(\v2\x1b.kratos.api.Auth.ExecPolicyR\x06policyB\x1dZ\x1bresource/internal/conf;confb\x06proto3"
 
 var (
 	file_conf_conf_proto_rawDescOnce sync.Once
@@ -650,37 +1730,71 @@ func file_conf_conf_proto_rawDescGZIP() []byte {
 	return file_conf_conf_proto_rawDescData
 }
 
-var file_conf_conf_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_conf_conf_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
 var file_conf_conf_proto_goTypes = []any{
-	(*Bootstrap)(nil),           // 0: kratos.api.Bootstrap
-	(*Server)(nil),              // 1: kratos.api.Server
-	(*Data)(nil),                // 2: kratos.api.Data
-	(*Server_HTTP)(nil),         // 3: kratos.api.Server.HTTP
-	(*Server_GRPC)(nil),         // 4: kratos.api.Server.GRPC
-	(*Data_Database)(nil),       // 5: kratos.api.Data.Database
-	(*Data_Redis)(nil),          // 6: kratos.api.Data.Redis
-	(*Data_RabbitMQ)(nil),       // 7: kratos.api.Data.RabbitMQ
-	(*Data_Kubernetes)(nil),     // 8: kratos.api.Data.Kubernetes
-	(*durationpb.Duration)(nil), // 9: google.protobuf.Duration
+	(*Bootstrap)(nil),               // 0: kratos.api.Bootstrap
+	(*Server)(nil),                  // 1: kratos.api.Server
+	(*Data)(nil),                    // 2: kratos.api.Data
+	(*Auth)(nil),                    // 3: kratos.api.Auth
+	(*Server_HTTP)(nil),             // 4: kratos.api.Server.HTTP
+	(*Server_GRPC)(nil),             // 5: kratos.api.Server.GRPC
+	(*Server_Exec)(nil),             // 6: kratos.api.Server.Exec
+	(*Server_FileTransfer)(nil),     // 7: kratos.api.Server.FileTransfer
+	(*Server_NetworkReconcile)(nil), // 8: kratos.api.Server.NetworkReconcile
+	(*Server_InstanceStatus)(nil),   // 9: kratos.api.Server.InstanceStatus
+	(*Data_Database)(nil),           // 10: kratos.api.Data.Database
+	(*Data_Redis)(nil),              // 11: kratos.api.Data.Redis
+	(*Data_RabbitMQ)(nil),           // 12: kratos.api.Data.RabbitMQ
+	(*Data_Kubernetes)(nil),         // 13: kratos.api.Data.Kubernetes
+	(*Data_GatewayAPI)(nil),         // 14: kratos.api.Data.GatewayAPI
+	(*Data_ExecRecording)(nil),      // 15: kratos.api.Data.ExecRecording
+	(*Data_CustomDomain)(nil),       // 16: kratos.api.Data.CustomDomain
+	(*Auth_Role)(nil),               // 17: kratos.api.Auth.Role
+	(*Auth_Binding)(nil),            // 18: kratos.api.Auth.Binding
+	(*Auth_ExecRule)(nil),           // 19: kratos.api.Auth.ExecRule
+	(*Auth_ExecPolicy)(nil),         // 20: kratos.api.Auth.ExecPolicy
+	(*Auth_InstanceExecPolicy)(nil), // 21: kratos.api.Auth.InstanceExecPolicy
+	(*durationpb.Duration)(nil),     // 22: google.protobuf.Duration
 }
 var file_conf_conf_proto_depIdxs = []int32{
 	1,  // 0: kratos.api.Bootstrap.server:type_name -> kratos.api.Server
 	2,  // 1: kratos.api.Bootstrap.data:type_name -> kratos.api.Data
-	3,  // 2: kratos.api.Server.http:type_name -> kratos.api.Server.HTTP
-	4,  // 3: kratos.api.Server.grpc:type_name -> kratos.api.Server.GRPC
-	5,  // 4: kratos.api.Data.database:type_name -> kratos.api.Data.Database
-	6,  // 5: kratos.api.Data.redis:type_name -> kratos.api.Data.Redis
-	7,  // 6: kratos.api.Data.rabbitmq:type_name -> kratos.api.Data.RabbitMQ
-	8,  // 7: kratos.api.Data.kubernetes:type_name -> kratos.api.Data.Kubernetes
-	9,  // 8: kratos.api.Server.HTTP.timeout:type_name -> google.protobuf.Duration
-	9,  // 9: kratos.api.Server.GRPC.timeout:type_name -> google.protobuf.Duration
-	9,  // 10: kratos.api.Data.Redis.read_timeout:type_name -> google.protobuf.Duration
-	9,  // 11: kratos.api.Data.Redis.write_timeout:type_name -> google.protobuf.Duration
-	12, // [12:12] is the sub-list for method output_type
-	12, // [12:12] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	3,  // 2: kratos.api.Bootstrap.auth:type_name -> kratos.api.Auth
+	4,  // 3: kratos.api.Server.http:type_name -> kratos.api.Server.HTTP
+	5,  // 4: kratos.api.Server.grpc:type_name -> kratos.api.Server.GRPC
+	6,  // 5: kratos.api.Server.exec:type_name -> kratos.api.Server.Exec
+	7,  // 6: kratos.api.Server.file_transfer:type_name -> kratos.api.Server.FileTransfer
+	8,  // 7: kratos.api.Server.network_reconcile:type_name -> kratos.api.Server.NetworkReconcile
+	9,  // 8: kratos.api.Server.instance_status:type_name -> kratos.api.Server.InstanceStatus
+	10, // 9: kratos.api.Data.database:type_name -> kratos.api.Data.Database
+	11, // 10: kratos.api.Data.redis:type_name -> kratos.api.Data.Redis
+	12, // 11: kratos.api.Data.rabbitmq:type_name -> kratos.api.Data.RabbitMQ
+	13, // 12: kratos.api.Data.kubernetes:type_name -> kratos.api.Data.Kubernetes
+	15, // 13: kratos.api.Data.exec_recording:type_name -> kratos.api.Data.ExecRecording
+	16, // 14: kratos.api.Data.custom_domain:type_name -> kratos.api.Data.CustomDomain
+	17, // 15: kratos.api.Auth.roles:type_name -> kratos.api.Auth.Role
+	18, // 16: kratos.api.Auth.bindings:type_name -> kratos.api.Auth.Binding
+	21, // 17: kratos.api.Auth.instance_exec_policies:type_name -> kratos.api.Auth.InstanceExecPolicy
+	22, // 18: kratos.api.Server.HTTP.timeout:type_name -> google.protobuf.Duration
+	22, // 19: kratos.api.Server.GRPC.timeout:type_name -> google.protobuf.Duration
+	22, // 20: kratos.api.Server.Exec.idle_timeout:type_name -> google.protobuf.Duration
+	22, // 21: kratos.api.Server.Exec.max_duration:type_name -> google.protobuf.Duration
+	22, // 22: kratos.api.Server.Exec.run_timeout:type_name -> google.protobuf.Duration
+	22, // 23: kratos.api.Server.Exec.run_max_timeout:type_name -> google.protobuf.Duration
+	22, // 24: kratos.api.Server.NetworkReconcile.interval:type_name -> google.protobuf.Duration
+	22, // 25: kratos.api.Server.InstanceStatus.interval:type_name -> google.protobuf.Duration
+	22, // 26: kratos.api.Data.Redis.read_timeout:type_name -> google.protobuf.Duration
+	22, // 27: kratos.api.Data.Redis.write_timeout:type_name -> google.protobuf.Duration
+	14, // 28: kratos.api.Data.Kubernetes.gateway_api:type_name -> kratos.api.Data.GatewayAPI
+	20, // 29: kratos.api.Auth.Role.exec:type_name -> kratos.api.Auth.ExecPolicy
+	19, // 30: kratos.api.Auth.ExecPolicy.allow:type_name -> kratos.api.Auth.ExecRule
+	19, // 31: kratos.api.Auth.ExecPolicy.deny:type_name -> kratos.api.Auth.ExecRule
+	20, // 32: kratos.api.Auth.InstanceExecPolicy.policy:type_name -> kratos.api.Auth.ExecPolicy
+	33, // [33:33] is the sub-list for method output_type
+	33, // [33:33] is the sub-list for method input_type
+	33, // [33:33] is the sub-list for extension type_name
+	33, // [33:33] is the sub-list for extension extendee
+	0,  // [0:33] is the sub-list for field type_name
 }
 
 func init() { file_conf_conf_proto_init() }
@@ -694,7 +1808,7 @@ func file_conf_conf_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_conf_conf_proto_rawDesc), len(file_conf_conf_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   22,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
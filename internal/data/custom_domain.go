@@ -0,0 +1,251 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"resource/internal/biz"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"gorm.io/gorm"
+)
+
+// customDomainLabel 自定义域名 Ingress 的标签，网络核对不把它们视为端口的 Ingress
+const customDomainLabel = "custom-domain"
+
+// customDomain 绑定到实例 HTTP 端口的自定义域名表
+type customDomain struct {
+	Domain     string     `gorm:"primaryKey;column:domain;size:253"`
+	InstanceID int64      `gorm:"column:instance_id;not null;index"`
+	Port       uint32     `gorm:"column:port;not null"`
+	Token      string     `gorm:"column:token;size:64;not null"`
+	Status     string     `gorm:"column:status;size:16;not null"` // PENDING / ACTIVE
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	VerifiedAt *time.Time `gorm:"column:verified_at"`
+}
+
+func (customDomain) TableName() string { return "custom_domain" }
+
+// CreateCustomDomain 保存自定义域名，回填创建时间
+func (r *networkRepo) CreateCustomDomain(ctx context.Context, domain *biz.CustomDomain) error {
+	model := &customDomain{
+		Domain:     domain.Domain,
+		InstanceID: domain.InstanceID,
+		Port:       domain.Port,
+		Token:      domain.Token,
+		Status:     domain.Status,
+		CreatedAt:  time.Now(),
+		VerifiedAt: domain.VerifiedAt,
+	}
+
+	if err := r.data.db.WithContext(ctx).Create(model).Error; err != nil {
+		r.log.Errorf("failed to create custom domain: %v", err)
+		return err
+	}
+
+	domain.CreatedAt = model.CreatedAt
+	return nil
+}
+
+// GetCustomDomain 不存在时返回 nil
+func (r *networkRepo) GetCustomDomain(ctx context.Context, domain string) (*biz.CustomDomain, error) {
+	var model customDomain
+	err := r.data.db.WithContext(ctx).Where("domain = ?", domain).First(&model).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.log.Errorf("failed to get custom domain: %v", err)
+		return nil, err
+	}
+
+	d := toCustomDomain(model)
+	return &d, nil
+}
+
+// ListCustomDomains 按端口、域名排序返回实例的自定义域名
+func (r *networkRepo) ListCustomDomains(ctx context.Context, instanceID int64) ([]biz.CustomDomain, error) {
+	var models []customDomain
+	err := r.data.db.WithContext(ctx).Where("instance_id = ?", instanceID).Order("port ASC, domain ASC").Find(&models).Error
+	if err != nil {
+		r.log.Errorf("failed to list custom domains: %v", err)
+		return nil, err
+	}
+
+	domains := make([]biz.CustomDomain, 0, len(models))
+	for _, model := range models {
+		domains = append(domains, toCustomDomain(model))
+	}
+	return domains, nil
+}
+
+// UpdateCustomDomain 更新验证状态
+func (r *networkRepo) UpdateCustomDomain(ctx context.Context, domain biz.CustomDomain) error {
+	result := r.data.db.WithContext(ctx).
+		Model(&customDomain{}).
+		Where("domain = ?", domain.Domain).
+		Updates(map[string]interface{}{
+			"status":      domain.Status,
+			"verified_at": domain.VerifiedAt,
+		})
+
+	if result.Error != nil {
+		r.log.Errorf("failed to update custom domain: %v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteCustomDomain 删除自定义域名记录，不存在时返回 nil
+func (r *networkRepo) DeleteCustomDomain(ctx context.Context, domain string) error {
+	if err := r.data.db.WithContext(ctx).Where("domain = ?", domain).Delete(&customDomain{}).Error; err != nil {
+		r.log.Errorf("failed to delete custom domain: %v", err)
+		return err
+	}
+	return nil
+}
+
+func toCustomDomain(model customDomain) biz.CustomDomain {
+	return biz.CustomDomain{
+		Domain:     model.Domain,
+		InstanceID: model.InstanceID,
+		Port:       model.Port,
+		Token:      model.Token,
+		Status:     model.Status,
+		CreatedAt:  model.CreatedAt,
+		VerifiedAt: model.VerifiedAt,
+	}
+}
+
+// EnsureCustomDomain creates or updates the host-based HTTPS Ingress of a verified custom domain.
+func (r *k8sRepo) EnsureCustomDomain(ctx context.Context, namespace string, domain biz.CustomDomain, binding biz.NetworkBinding) error {
+	return r.exposure.ExposeCustomDomain(ctx, namespace, domain, binding)
+}
+
+// DeleteCustomDomain deletes the Ingress of a custom domain.
+func (r *k8sRepo) DeleteCustomDomain(ctx context.Context, namespace string, domain biz.CustomDomain) error {
+	return r.exposure.UnexposeCustomDomain(ctx, namespace, domain)
+}
+
+// customDomainIngressName 自定义域名 Ingress 名称：domain-{instanceID}-{port}-{域名 FNV-32a 哈希}
+func customDomainIngressName(domain biz.CustomDomain) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(domain.Domain))
+	return fmt.Sprintf("domain-%d-%d-%08x", domain.InstanceID, domain.Port, h.Sum32())
+}
+
+// ensureCustomDomainIngress 创建或更新自定义域名的 Ingress：Host 路由到端口的 Service，
+// 由 cert-manager ClusterIssuer 签发证书（通配符证书不覆盖用户域名），访问控制与端口的 Ingress 一致，
+// basic auth 复用端口 Ingress 的凭据 Secret。
+func (r *k8sRepo) ensureCustomDomainIngress(ctx context.Context, namespace string, domain biz.CustomDomain, binding biz.NetworkBinding) error {
+	if r.tlsClusterIssuer == "" {
+		return fmt.Errorf("%w, custom domains require tls_cluster_issuer", biz.ErrTLSNotConfigured)
+	}
+	if binding.IngressName == nil {
+		return biz.ErrCustomDomainNotSupported
+	}
+
+	name := customDomainIngressName(domain)
+	instanceID := strconv.FormatInt(domain.InstanceID, 10)
+	annotations := map[string]string{
+		"cert-manager.io/cluster-issuer": r.tlsClusterIssuer,
+	}
+	applyProtectionAnnotations(annotations, *binding.IngressName, binding.Ingress.Protection)
+
+	pathType := networkingv1.PathTypePrefix
+	desired := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"instance-id":     instanceID,
+				"managed-by":      "resource-service",
+				customDomainLabel: "true",
+			},
+			Annotations: annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: stringPtr("nginx"),
+			TLS:              []networkingv1.IngressTLS{{Hosts: []string{domain.Domain}, SecretName: name + "-tls"}},
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: domain.Domain,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: binding.ServiceName,
+											Port: networkingv1.ServiceBackendPort{
+												Number: int32(binding.ServicePort),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ingresses := r.client.NetworkingV1().Ingresses(namespace)
+	_, err := ingresses.Create(ctx, desired, metav1.CreateOptions{})
+	if err == nil {
+		r.log.WithContext(ctx).Infof("custom domain ingress %s created for %s", name, domain.Domain)
+		return nil
+	}
+	if !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create custom domain ingress %s: %w", name, err)
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := ingresses.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		existing.Labels = desired.Labels
+		existing.Annotations = desired.Annotations
+		existing.Spec = desired.Spec
+		_, err = ingresses.Update(ctx, existing, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update custom domain ingress %s: %w", name, err)
+	}
+	return nil
+}
+
+// deleteCustomDomainIngress 删除自定义域名的 Ingress 与 cert-manager 签发的证书 Secret，不存在时返回 nil
+func (r *k8sRepo) deleteCustomDomainIngress(ctx context.Context, namespace string, domain biz.CustomDomain) error {
+	name := customDomainIngressName(domain)
+
+	err := r.client.NetworkingV1().Ingresses(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete custom domain ingress %s: %w", name, err)
+	}
+	err = r.client.CoreV1().Secrets(namespace).Delete(ctx, name+"-tls", metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		r.log.Warnf("failed to delete TLS secret %s-tls: %v", name, err)
+	}
+
+	r.log.WithContext(ctx).Infof("custom domain ingress %s deleted", name)
+	return nil
+}
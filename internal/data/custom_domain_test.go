@@ -0,0 +1,87 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"resource/internal/biz"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestK8sRepo_EnsureCustomDomain(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+	ingressName := "ingress-1-80"
+	binding := biz.NetworkBinding{
+		InstanceID:  1,
+		Port:        80,
+		ServiceName: "instance-1-80",
+		ServicePort: 80,
+		IngressName: &ingressName,
+		Protocol:    "HTTP",
+		Enabled:     true,
+		Ingress:     biz.IngressOptions{Protection: biz.IngressProtection{BasicAuthUser: "admin", RateLimitRPS: 5}},
+	}
+	domain := biz.CustomDomain{Domain: "app.example.com", InstanceID: 1, Port: 80, Status: biz.CustomDomainActive}
+
+	// 用户域名不在通配符证书范围内，需要 cert-manager
+	if err := repo.EnsureCustomDomain(ctx, "alice", domain, binding); !errors.Is(err, biz.ErrTLSNotConfigured) {
+		t.Fatalf("err=%v want ErrTLSNotConfigured", err)
+	}
+
+	repo.tlsClusterIssuer = "letsencrypt"
+	if err := repo.EnsureCustomDomain(ctx, "alice", domain, binding); err != nil {
+		t.Fatal(err)
+	}
+	name := customDomainIngressName(domain)
+	ing, err := repo.client.NetworkingV1().Ingresses("alice").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := ing.Spec.Rules[0]
+	if rule.Host != "app.example.com" || rule.HTTP.Paths[0].Path != "/" || rule.HTTP.Paths[0].Backend.Service.Name != "instance-1-80" {
+		t.Fatalf("rule=%+v", rule)
+	}
+	if len(ing.Spec.TLS) != 1 || ing.Spec.TLS[0].Hosts[0] != "app.example.com" || ing.Spec.TLS[0].SecretName != name+"-tls" {
+		t.Fatalf("tls=%+v", ing.Spec.TLS)
+	}
+	if ing.Annotations["cert-manager.io/cluster-issuer"] != "letsencrypt" ||
+		ing.Annotations[annotationAuthSecret] != basicAuthSecretName(ingressName) ||
+		ing.Annotations[annotationLimitRPS] != "5" {
+		t.Fatalf("annotations=%v", ing.Annotations)
+	}
+
+	// 更新端口访问控制后再次同步，覆盖原有注解
+	binding.Ingress.Protection = biz.IngressProtection{}
+	if err := repo.EnsureCustomDomain(ctx, "alice", domain, binding); err != nil {
+		t.Fatal(err)
+	}
+	ing, _ = repo.client.NetworkingV1().Ingresses("alice").Get(ctx, name, metav1.GetOptions{})
+	if _, ok := ing.Annotations[annotationAuthSecret]; ok {
+		t.Fatalf("annotations=%v", ing.Annotations)
+	}
+
+	// 网络核对不把自定义域名 Ingress 视为端口 Ingress
+	state, err := repo.GetNetworkState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range state.Ingresses {
+		if obj.Name == name {
+			t.Fatalf("custom domain ingress listed in network state: %+v", obj)
+		}
+	}
+
+	if err := repo.DeleteCustomDomain(ctx, "alice", domain); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.client.NetworkingV1().Ingresses("alice").Get(ctx, name, metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("err=%v want NotFound", err)
+	}
+	if err := repo.DeleteCustomDomain(ctx, "alice", domain); err != nil {
+		t.Fatalf("delete must be idempotent: %v", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/google/wire"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -26,6 +27,9 @@ var ProviderSet = wire.NewSet(
 	NewResourceRepo,
 	NewNetworkRepo,
 	NewExecRepo,
+	NewExecSessionRepo,
+	NewExecRecordingStore,
+	NewDomainResolver,
 )
 
 // Data .
@@ -65,8 +69,9 @@ func NewData(c *conf.Data, logger log.Logger) (*Data, func(), error) {
 
 // K8sClient 包装 Kubernetes 客户端和配置
 type K8sClient struct {
-	Client *kubernetes.Clientset
-	Config *rest.Config
+	Client  *kubernetes.Clientset
+	Dynamic dynamic.Interface // Gateway API 等 CRD 资源
+	Config  *rest.Config
 }
 
 // NewK8sClient 创建 Kubernetes 客户端
@@ -102,9 +107,14 @@ func NewK8sClient(c *conf.Data, logger log.Logger) (*K8sClient, error) {
 	if err != nil {
 		return nil, err
 	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	return &K8sClient{
-		Client: client,
-		Config: cfg,
+		Client:  client,
+		Dynamic: dynamicClient,
+		Config:  cfg,
 	}, nil
 }
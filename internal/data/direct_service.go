@@ -0,0 +1,174 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// defaultLBAddressTimeout 等待 LoadBalancer 分配外部地址的默认时长，超时后地址留空，由调用方显示为待分配
+const defaultLBAddressTimeout = 15 * time.Second
+
+// directServiceType 把 NODEPORT/LOADBALANCER 协议映射为 Service 类型
+func directServiceType(serviceType string) (corev1.ServiceType, error) {
+	switch serviceType {
+	case "NODEPORT":
+		return corev1.ServiceTypeNodePort, nil
+	case "LOADBALANCER":
+		return corev1.ServiceTypeLoadBalancer, nil
+	default:
+		return "", fmt.Errorf("unsupported direct service type: %s", serviceType)
+	}
+}
+
+// newDirectService builds the NodePort/LoadBalancer Service of a port. nodePort 为 0 时由集群分配。
+func newDirectService(namespace, instanceID, serviceName string, port uint32, serviceType corev1.ServiceType, transport string, nodePort uint32) *corev1.Service {
+	protocol := corev1.ProtocolTCP
+	if transport == "UDP" {
+		protocol = corev1.ProtocolUDP
+	}
+	service := newInstanceService(namespace, instanceID, serviceName, port, protocol)
+	service.Spec.Type = serviceType
+	service.Spec.Ports[0].NodePort = int32(nodePort)
+	return service
+}
+
+// CreateDirectService creates a NodePort or LoadBalancer Service for the port.
+// Returns the service name and the assigned node port with the address clients should connect to.
+func (r *k8sRepo) CreateDirectService(ctx context.Context, namespace, instanceID string, port uint32, serviceType, transport string) (string, biz.DirectEndpoint, error) {
+	serviceName := generateServiceName(instanceID, port)
+
+	svcType, err := directServiceType(serviceType)
+	if err != nil {
+		return "", biz.DirectEndpoint{}, err
+	}
+
+	r.log.WithContext(ctx).Infof("creating %s service %s in namespace %s for port %d/%s", svcType, serviceName, namespace, port, transport)
+
+	service := newDirectService(namespace, instanceID, serviceName, port, svcType, transport, 0)
+	created, err := r.client.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+	if err != nil {
+		r.log.Errorf("failed to create %s service %s: %v", svcType, serviceName, err)
+		return "", biz.DirectEndpoint{}, fmt.Errorf("failed to create service: %w", err)
+	}
+
+	// 默认隔离策略只放开 ingress 控制器，直接暴露的端口需要单独放开集群外的流量
+	if err := r.ensureDirectPortPolicy(ctx, namespace, instanceID, serviceName, port, service.Spec.Ports[0].Protocol); err != nil {
+		_ = r.client.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
+		return "", biz.DirectEndpoint{}, err
+	}
+
+	endpoint := biz.DirectEndpoint{NodePort: uint32(created.Spec.Ports[0].NodePort)}
+
+	if svcType == corev1.ServiceTypeNodePort {
+		endpoint.Address, err = r.getNodeAddress(ctx)
+		if err != nil {
+			// 无法确定访问地址，回滚 Service
+			_ = r.DeleteService(ctx, namespace, serviceName)
+			return "", biz.DirectEndpoint{}, err
+		}
+	} else {
+		endpoint.Address = r.waitLoadBalancerAddress(ctx, created)
+	}
+
+	r.log.WithContext(ctx).Infof("%s service %s created, node port %d, address %q", svcType, serviceName, endpoint.NodePort, endpoint.Address)
+
+	return serviceName, endpoint, nil
+}
+
+// getNodeAddress 返回 NODEPORT 访问地址中的节点地址：
+// 优先使用配置的 node_address，否则取第一个 Ready 节点的 ExternalIP，没有时取 InternalIP
+func (r *k8sRepo) getNodeAddress(ctx context.Context) (string, error) {
+	if r.nodeAddress != "" {
+		return r.nodeAddress, nil
+	}
+
+	nodes, err := r.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var internalIP string
+	for _, node := range nodes.Items {
+		if !isNodeReady(&node) {
+			continue
+		}
+		for _, addr := range node.Status.Addresses {
+			switch addr.Type {
+			case corev1.NodeExternalIP:
+				return addr.Address, nil
+			case corev1.NodeInternalIP:
+				if internalIP == "" {
+					internalIP = addr.Address
+				}
+			}
+		}
+	}
+	if internalIP == "" {
+		return "", fmt.Errorf("no ready node address found, please set data.kubernetes.node_address")
+	}
+	return internalIP, nil
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// waitLoadBalancerAddress 等待 LoadBalancer Service 分配外部 IP 或主机名，超时返回空字符串
+func (r *k8sRepo) waitLoadBalancerAddress(ctx context.Context, service *corev1.Service) string {
+	if address := loadBalancerAddress(service); address != "" {
+		return address
+	}
+	if r.lbAddressTimeout <= 0 {
+		return ""
+	}
+
+	var address string
+	err := wait.PollUntilContextTimeout(ctx, time.Second, r.lbAddressTimeout, true, func(ctx context.Context) (bool, error) {
+		svc, err := r.client.CoreV1().Services(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+		if err != nil {
+			// 临时错误继续等待
+			return false, nil
+		}
+		address = loadBalancerAddress(svc)
+		return address != "", nil
+	})
+	if err != nil {
+		r.log.WithContext(ctx).Warnf("LoadBalancer service %s/%s has no external address after %s", service.Namespace, service.Name, r.lbAddressTimeout)
+		return ""
+	}
+	return address
+}
+
+// GetLoadBalancerAddress returns the external IP or hostname of a LoadBalancer Service, empty if not assigned yet.
+func (r *k8sRepo) GetLoadBalancerAddress(ctx context.Context, namespace, serviceName string) (string, error) {
+	svc, err := r.client.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get service %s/%s: %w", namespace, serviceName, err)
+	}
+	return loadBalancerAddress(svc), nil
+}
+
+// loadBalancerAddress 返回 LoadBalancer Service 的第一个外部 IP 或主机名
+func loadBalancerAddress(service *corev1.Service) string {
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return ingress.IP
+		}
+		if ingress.Hostname != "" {
+			return ingress.Hostname
+		}
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// allocateNodePorts 模拟 apiserver 为 NodePort/LoadBalancer Service 分配节点端口
+func allocateNodePorts(client *fake.Clientset, nodePort int32) {
+	client.PrependReactor("create", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		svc := action.(k8stesting.CreateAction).GetObject().(*corev1.Service)
+		if svc.Spec.Type != corev1.ServiceTypeClusterIP && svc.Spec.Ports[0].NodePort == 0 {
+			svc.Spec.Ports[0].NodePort = nodePort
+		}
+		return false, nil, nil
+	})
+}
+
+func TestCreateDirectService_NodePort(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	client := repo.client.(*fake.Clientset)
+	allocateNodePorts(client, 31022)
+	ctx := context.Background()
+
+	_, err := client.CoreV1().Nodes().Create(ctx, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.5"},
+				{Type: corev1.NodeExternalIP, Address: "198.51.100.5"},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, endpoint, err := repo.CreateDirectService(ctx, "alice", "2", 22, "NODEPORT", "TCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "instance-2-22" || endpoint != (biz.DirectEndpoint{NodePort: 31022, Address: "198.51.100.5"}) {
+		t.Fatalf("name=%s endpoint=%+v", name, endpoint)
+	}
+	svc, err := client.CoreV1().Services("alice").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Spec.Type != corev1.ServiceTypeNodePort || svc.Spec.Ports[0].Protocol != corev1.ProtocolTCP {
+		t.Fatalf("spec=%+v", svc.Spec)
+	}
+	policy, err := client.NetworkingV1().NetworkPolicies("alice").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Spec.Ingress[0].Ports[0].Port.IntVal != 22 || policy.Spec.PodSelector.MatchLabels["instance-id"] != "2" {
+		t.Fatalf("policy=%+v", policy.Spec)
+	}
+
+	// 配置的节点地址优先
+	repo.nodeAddress = "node.example.com"
+	_, endpoint, err = repo.CreateDirectService(ctx, "alice", "2", 53, "NODEPORT", "UDP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.Address != "node.example.com" {
+		t.Fatalf("address=%s", endpoint.Address)
+	}
+
+	// 核对修复：按记录的节点端口重建，Service 与放开端口的 NetworkPolicy 一起删除
+	if err := repo.DeleteService(ctx, "alice", name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NetworkingV1().NetworkPolicies("alice").Get(ctx, name, metav1.GetOptions{}); err == nil {
+		t.Fatal("network policy not deleted with service")
+	}
+	nodePort := uint32(31500)
+	binding := biz.NetworkBinding{InstanceID: 2, Port: 22, ServiceName: name, ServicePort: 22, Protocol: "NODEPORT", Transport: "TCP", NodePort: &nodePort, Enabled: true}
+	if err := repo.EnsureNetworkBinding(ctx, "alice", binding); err != nil {
+		t.Fatal(err)
+	}
+	svc, err = client.CoreV1().Services("alice").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Spec.Type != corev1.ServiceTypeNodePort || svc.Spec.Ports[0].NodePort != 31500 {
+		t.Fatalf("recreated spec=%+v", svc.Spec)
+	}
+	if _, err := client.NetworkingV1().NetworkPolicies("alice").Get(ctx, name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("network policy not recreated: %v", err)
+	}
+}
+
+func TestCreateDirectService_LoadBalancer(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	client := repo.client.(*fake.Clientset)
+	allocateNodePorts(client, 31080)
+	ctx := context.Background()
+
+	// 尚未分配外部地址，不等待时返回空地址
+	_, endpoint, err := repo.CreateDirectService(ctx, "alice", "3", 80, "LOADBALANCER", "TCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint != (biz.DirectEndpoint{NodePort: 31080}) {
+		t.Fatalf("endpoint=%+v", endpoint)
+	}
+
+	// 负载均衡器已分配地址
+	client.PrependReactor("create", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		svc := action.(k8stesting.CreateAction).GetObject().(*corev1.Service)
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}}
+		return false, nil, nil
+	})
+	_, endpoint, err = repo.CreateDirectService(ctx, "alice", "3", 443, "LOADBALANCER", "TCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.Address != "lb.example.com" {
+		t.Fatalf("address=%s", endpoint.Address)
+	}
+
+	if _, _, err := repo.CreateDirectService(ctx, "alice", "3", 8080, "CLUSTERIP", "TCP"); err == nil {
+		t.Fatal("expected error for unsupported service type")
+	}
+}
+
+func TestK8sRepo_PublicAddresses(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+
+	// ingress-nginx LoadBalancer 尚未分配地址
+	if _, err := repo.GetTCPUDPPublicAddress(ctx); err == nil {
+		t.Fatal("expected error without LoadBalancer address")
+	}
+	repo.tcpUDPPublicHost = "tcp.example.com"
+	if address, err := repo.GetTCPUDPPublicAddress(ctx); err != nil || address != "tcp.example.com" {
+		t.Fatalf("address=%q err=%v", address, err)
+	}
+
+	svc := newInstanceService("alice", "1", "instance-1-8080", 8080, corev1.ProtocolTCP)
+	svc.Spec.Type = corev1.ServiceTypeLoadBalancer
+	if _, err := repo.client.CoreV1().Services("alice").Create(ctx, svc, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if address, err := repo.GetLoadBalancerAddress(ctx, "alice", "instance-1-8080"); err != nil || address != "" {
+		t.Fatalf("address=%q err=%v", address, err)
+	}
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}}
+	if _, err := repo.client.CoreV1().Services("alice").UpdateStatus(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if address, err := repo.GetLoadBalancerAddress(ctx, "alice", "instance-1-8080"); err != nil || address != "lb.example.com" {
+		t.Fatalf("address=%q err=%v", address, err)
+	}
+}
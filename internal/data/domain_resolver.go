@@ -0,0 +1,37 @@
+package data
+
+import (
+	"context"
+	"net"
+
+	"resource/internal/biz"
+	"resource/internal/conf"
+)
+
+// dnsResolver 查询自定义域名验证使用的 TXT 记录
+type dnsResolver struct {
+	resolver *net.Resolver
+}
+
+// NewDomainResolver 配置了 custom_domain.dns_server 时直接查询该服务器，
+// 避免集群内 DNS 缓存导致刚添加的记录查询不到；否则使用系统解析器。
+func NewDomainResolver(c *conf.Data) biz.DomainResolver {
+	server := c.GetCustomDomain().GetDnsServer()
+	if server == "" {
+		return &dnsResolver{resolver: net.DefaultResolver}
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &dnsResolver{resolver: &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}}
+}
+
+func (r *dnsResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r.resolver.LookupTXT(ctx, name)
+}
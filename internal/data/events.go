@@ -0,0 +1,100 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ListInstanceEvents 列出实例 Deployment、ReplicaSet 与 Pod 的事件。
+// 已删除的 Pod 通过 ReplicaSet 名称前缀匹配，保留其失败记录。
+func (r *k8sRepo) ListInstanceEvents(ctx context.Context, namespace, instanceID string) ([]biz.InstanceEvent, error) {
+	selector := metav1.ListOptions{LabelSelector: fmt.Sprintf("instance-id=%s", instanceID)}
+
+	objects := map[string]bool{"Deployment/" + instanceID: true}
+	replicaSets, err := r.client.AppsV1().ReplicaSets(namespace).List(ctx, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %w", err)
+	}
+	rsPrefixes := make([]string, 0, len(replicaSets.Items))
+	for _, rs := range replicaSets.Items {
+		objects["ReplicaSet/"+rs.Name] = true
+		rsPrefixes = append(rsPrefixes, rs.Name+"-")
+	}
+	pods, err := r.client.CoreV1().Pods(namespace).List(ctx, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		objects["Pod/"+pod.Name] = true
+	}
+
+	// 实例所在命名空间属于单个用户，一次列出后在内存中过滤
+	eventList, err := r.client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	events := make([]biz.InstanceEvent, 0)
+	for i := range eventList.Items {
+		ev := &eventList.Items[i]
+		obj := ev.InvolvedObject
+		if !objects[obj.Kind+"/"+obj.Name] && !(obj.Kind == "Pod" && hasAnyPrefix(obj.Name, rsPrefixes)) {
+			continue
+		}
+		events = append(events, toInstanceEvent(ev))
+	}
+	return events, nil
+}
+
+func toInstanceEvent(ev *corev1.Event) biz.InstanceEvent {
+	firstSeen := ev.FirstTimestamp.Time
+	if firstSeen.IsZero() {
+		firstSeen = ev.EventTime.Time
+	}
+	if firstSeen.IsZero() {
+		firstSeen = ev.CreationTimestamp.Time
+	}
+
+	lastSeen := ev.LastTimestamp.Time
+	count := ev.Count
+	if ev.Series != nil {
+		if lastSeen.IsZero() {
+			lastSeen = ev.Series.LastObservedTime.Time
+		}
+		if ev.Series.Count > count {
+			count = ev.Series.Count
+		}
+	}
+	if lastSeen.IsZero() {
+		lastSeen = firstSeen
+	}
+	if count == 0 {
+		count = 1
+	}
+
+	return biz.InstanceEvent{
+		Type:       ev.Type,
+		Reason:     ev.Reason,
+		Message:    ev.Message,
+		ObjectKind: ev.InvolvedObject.Kind,
+		ObjectName: ev.InvolvedObject.Name,
+		Count:      count,
+		FirstSeen:  firstSeen,
+		LastSeen:   lastSeen,
+	}
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestK8sRepo_ListInstanceEvents(t *testing.T) {
+	now := time.Now()
+	labels := map[string]string{"app": "instance", "instance-id": "1"}
+	event := func(name, kind, objName string, last time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "alice"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: objName},
+			Type:           "Warning",
+			Reason:         "FailedScheduling",
+			LastTimestamp:  metav1.NewTime(last),
+		}
+	}
+	pod := instancePod()
+	client := fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "1-7d9f", Namespace: "alice", Labels: labels}},
+		&pod,
+		event("e1", "Deployment", "1", now),
+		event("e2", "ReplicaSet", "1-7d9f", now),
+		event("e3", "Pod", "instance-1-abc", now),
+		event("e4", "Pod", "1-7d9f-deleted", now), // 已删除的旧 Pod
+		event("e5", "Pod", "2-other", now),        // 其他实例
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "e6", Namespace: "alice"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "instance-1-abc"},
+			EventTime:      metav1.NewMicroTime(now),
+			Series:         &corev1.EventSeries{Count: 5, LastObservedTime: metav1.NewMicroTime(now.Add(time.Minute))},
+		},
+	)
+	repo := &k8sRepo{client: client, log: log.NewHelper(log.NewStdLogger(io.Discard))}
+
+	events, err := repo.ListInstanceEvents(context.Background(), "alice", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("events=%d want 5: %+v", len(events), events)
+	}
+	for _, ev := range events {
+		if ev.ObjectName == "2-other" {
+			t.Fatal("event of another instance included")
+		}
+		if ev.ObjectName == "instance-1-abc" && ev.Reason == "" && (ev.Count != 5 || !ev.LastSeen.After(now)) {
+			t.Fatalf("series event=%+v", ev)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package data
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"resource/internal/biz"
+	"sort"
+	"sync"
 
 	"github.com/go-kratos/kratos/v2/log"
 	corev1 "k8s.io/api/core/v1"
@@ -13,62 +16,72 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
 )
 
+// executorFactory 为指定 Pod 创建 remotecommand 执行器，测试中可替换
+type executorFactory func(namespace, podName string, opts *corev1.PodExecOptions) (remotecommand.Executor, error)
+
 type execRepo struct {
-	client *kubernetes.Clientset
-	config *rest.Config
-	log    *log.Helper
+	client          kubernetes.Interface
+	newExecutor     executorFactory
+	dialPortForward portForwardDialer
+	log             *log.Helper
 }
 
 // NewExecRepo 创建 exec 仓储实现
 func NewExecRepo(k8sClient *K8sClient, logger log.Logger) biz.ExecRepo {
 	return &execRepo{
-		client: k8sClient.Client,
-		config: k8sClient.Config,
-		log:    log.NewHelper(logger),
+		client:          k8sClient.Client,
+		newExecutor:     spdyExecutorFactory(k8sClient.Client, k8sClient.Config),
+		dialPortForward: spdyPortForwardDialer(k8sClient.Client, k8sClient.Config),
+		log:             log.NewHelper(logger),
+	}
+}
+
+// spdyExecutorFactory 通过 pods/exec 子资源创建 SPDY 执行器
+func spdyExecutorFactory(client kubernetes.Interface, config *rest.Config) executorFactory {
+	return func(namespace, podName string, opts *corev1.PodExecOptions) (remotecommand.Executor, error) {
+		req := client.CoreV1().RESTClient().Post().
+			Resource("pods").
+			Name(podName).
+			Namespace(namespace).
+			SubResource("exec").
+			VersionedParams(opts, scheme.ParameterCodec)
+		return remotecommand.NewSPDYExecutor(config, "POST", req.URL())
 	}
 }
 
 // StreamExec 流式执行容器命令
 func (r *execRepo) StreamExec(ctx context.Context, opts biz.ExecOptions, input <-chan biz.ExecInput, output chan<- biz.ExecOutput) error {
-	// 1. 通过 label selector 查找 Pod
-	podList, err := r.client.CoreV1().Pods(opts.Namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("instance-id=%s,app=instance", opts.InstanceID),
-		Limit:         1,
-	})
+	// 1. 选择目标 Pod 与容器
+	pods, err := r.listPods(ctx, opts.Namespace, opts.InstanceID)
 	if err != nil {
 		r.log.Errorf("failed to list pods: %v", err)
-		return fmt.Errorf("failed to list pods: %w", err)
+		return r.fail(output, biz.ExecErrorSetup, fmt.Errorf("failed to list pods: %w", err))
 	}
-
-	if len(podList.Items) == 0 {
-		r.log.Errorf("no pod found for instance %s in namespace %s", opts.InstanceID, opts.Namespace)
-		return fmt.Errorf("pod not found for instance %s", opts.InstanceID)
+	pod, err := selectExecPod(pods, opts.PodName)
+	if err != nil {
+		r.log.Errorf("no pod available for instance %s in namespace %s: %v", opts.InstanceID, opts.Namespace, err)
+		return r.fail(output, biz.ExecErrorNotFound, err)
+	}
+	container, err := selectExecContainer(pod, opts.ContainerName)
+	if err != nil {
+		return r.fail(output, biz.ExecErrorNotFound, err)
 	}
 
-	podName := podList.Items[0].Name
-
-	// 2. 构建 exec 请求
-	req := r.client.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Name(podName).
-		Namespace(opts.Namespace).
-		SubResource("exec").
-		VersionedParams(&corev1.PodExecOptions{
-			Container: opts.ContainerName,
-			Command:   opts.Command,
-			Stdin:     true,
-			Stdout:    true,
-			Stderr:    true,
-			TTY:       opts.TTY,
-		}, scheme.ParameterCodec)
-
-	// 3. 创建 SPDY 执行器
-	exec, err := remotecommand.NewSPDYExecutor(r.config, "POST", req.URL())
+	// 2-3. 构建 exec 请求并创建执行器
+	exec, err := r.newExecutor(opts.Namespace, pod.Name, &corev1.PodExecOptions{
+		Container: container,
+		Command:   opts.Command,
+		Stdin:     true,
+		Stdout:    true,
+		Stderr:    true,
+		TTY:       opts.TTY,
+	})
 	if err != nil {
 		r.log.Errorf("failed to create SPDY executor: %v", err)
-		return err
+		return r.fail(output, biz.ExecErrorSetup, fmt.Errorf("failed to create executor: %w", err))
 	}
 
 	// 4. 创建流适配器
@@ -85,8 +98,16 @@ func (r *execRepo) StreamExec(ctx context.Context, opts biz.ExecOptions, input <
 	go r.handleInput(input, stdinWriter, sizeQueue)
 
 	// 7. 启动输出处理协程
-	go r.handleOutput(stdoutReader, "stdout", output)
-	go r.handleOutput(stderrReader, "stderr", output)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		r.handleOutput(stdoutReader, "stdout", output)
+	}()
+	go func() {
+		defer wg.Done()
+		r.handleOutput(stderrReader, "stderr", output)
+	}()
 
 	// 8. 执行命令
 	streamOpts := remotecommand.StreamOptions{
@@ -103,25 +124,163 @@ func (r *execRepo) StreamExec(ctx context.Context, opts biz.ExecOptions, input <
 
 	err = exec.StreamWithContext(ctx, streamOpts)
 
-	// 9. 处理执行结果
+	// 关闭流并等待输出全部转发，保证退出信号是最后一条消息
+	_ = stdinReader.Close()
+	_ = stdoutWriter.Close()
+	_ = stderrWriter.Close()
+	wg.Wait()
+
+	// 9. 处理执行结果：命令非零退出不视为错误，返回真实退出码
+	if err == nil {
+		output <- biz.ExecOutput{Type: biz.ExecOutputExit, ExitCode: 0}
+		return nil
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		output <- biz.ExecOutput{Type: biz.ExecOutputExit, ExitCode: int32(exitErr.ExitStatus())}
+		return nil
+	}
+
+	// 10. exec 通道本身失败
+	r.log.Errorf("exec stream error: %v", err)
+	category := biz.ExecErrorTransport
+	if ctx.Err() != nil {
+		category = biz.ExecErrorCanceled
+	}
+	return r.fail(output, category, err)
+}
+
+// ListPods 列出实例的 Pod，按创建时间倒序
+func (r *execRepo) ListPods(ctx context.Context, namespace, instanceID string) ([]biz.PodInfo, error) {
+	pods, err := r.listPods(ctx, namespace, instanceID)
 	if err != nil {
-		r.log.Errorf("exec stream error: %v", err)
-		output <- biz.ExecOutput{
-			Type: biz.ExecOutputError,
-			Data: []byte(err.Error()),
-		}
+		r.log.Errorf("failed to list pods: %v", err)
+		return nil, err
+	}
+
+	infos := make([]biz.PodInfo, 0, len(pods))
+	for i := range pods {
+		infos = append(infos, toPodInfo(&pods[i]))
 	}
+	return infos, nil
+}
 
-	// 10. 发送退出信号
-	exitCode := int32(0)
+// listPods 通过 label selector 查找实例的全部 Pod，按创建时间倒序
+func (r *execRepo) listPods(ctx context.Context, namespace, instanceID string) ([]corev1.Pod, error) {
+	podList, err := r.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("instance-id=%s,app=instance", instanceID),
+	})
 	if err != nil {
-		exitCode = 1
+		return nil, err
+	}
+
+	pods := podList.Items
+	sort.SliceStable(pods, func(i, j int) bool {
+		return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+	})
+	return pods, nil
+}
+
+// selectExecPod 选择 exec 目标 Pod：指定名称时校验其属于实例且未在删除，否则选择最新的 Ready Pod
+func selectExecPod(pods []corev1.Pod, podName string) (*corev1.Pod, error) {
+	if podName != "" {
+		for i := range pods {
+			if pods[i].Name != podName {
+				continue
+			}
+			if pods[i].DeletionTimestamp != nil {
+				return nil, fmt.Errorf("pod %s is terminating", podName)
+			}
+			return &pods[i], nil
+		}
+		return nil, fmt.Errorf("pod %s not found", podName)
+	}
+
+	for i := range pods {
+		if pods[i].DeletionTimestamp == nil && isPodReady(&pods[i]) {
+			return &pods[i], nil
+		}
+	}
+	return nil, errors.New("no ready pod found")
+}
+
+// selectExecContainer 选择 exec 目标容器。未指定时使用默认容器（与实例 ID 同名），
+// 默认容器不存在时使用 Pod 的第一个容器；指定的容器不存在时返回错误
+func selectExecContainer(pod *corev1.Pod, name string) (string, error) {
+	if len(pod.Spec.Containers) == 0 {
+		return "", fmt.Errorf("pod %s has no containers", pod.Name)
+	}
+	if name == "" {
+		name = pod.Labels["instance-id"]
+		for _, c := range pod.Spec.Containers {
+			if c.Name == name {
+				return name, nil
+			}
+		}
+		return pod.Spec.Containers[0].Name, nil
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("container %s not found in pod %s", name, pod.Name)
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func toPodInfo(pod *corev1.Pod) biz.PodInfo {
+	info := biz.PodInfo{
+		Name:        pod.Name,
+		Phase:       string(pod.Status.Phase),
+		Ready:       isPodReady(pod),
+		Terminating: pod.DeletionTimestamp != nil,
+		NodeName:    pod.Spec.NodeName,
+		CreatedAt:   pod.CreationTimestamp.Time,
+		Containers:  make([]biz.ContainerInfo, 0, len(pod.Spec.Containers)),
+	}
+
+	statuses := make(map[string]corev1.ContainerStatus, len(pod.Status.ContainerStatuses))
+	for _, st := range pod.Status.ContainerStatuses {
+		statuses[st.Name] = st
+	}
+	for _, c := range pod.Spec.Containers {
+		container := biz.ContainerInfo{Name: c.Name, Image: c.Image}
+		if st, ok := statuses[c.Name]; ok {
+			container.Ready = st.Ready
+			container.RestartCount = st.RestartCount
+			switch {
+			case st.State.Running != nil:
+				container.State = "Running"
+			case st.State.Terminated != nil:
+				container.State = "Terminated"
+			case st.State.Waiting != nil:
+				container.State = "Waiting"
+			}
+		}
+		info.Containers = append(info.Containers, container)
+	}
+	return info
+}
+
+// fail 发送错误与未知退出码，进程未启动或退出状态未知
+func (r *execRepo) fail(output chan<- biz.ExecOutput, category biz.ExecErrorCategory, err error) error {
+	output <- biz.ExecOutput{
+		Type:     biz.ExecOutputError,
+		Data:     []byte(err.Error()),
+		Category: category,
 	}
 	output <- biz.ExecOutput{
 		Type:     biz.ExecOutputExit,
-		ExitCode: exitCode,
+		ExitCode: biz.ExecExitCodeUnknown,
 	}
-
 	return err
 }
 
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"resource/internal/biz"
+	"resource/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+// execSessionRepo 提供 exec 会话元数据的数据访问能力
+type execSessionRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewExecSessionRepo 创建 exec 会话仓储实现
+func NewExecSessionRepo(data *Data, logger log.Logger) biz.ExecSessionRepo {
+	return &execSessionRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// execSession exec 会话表，录像内容存放在 ExecRecordingStore 中
+type execSession struct {
+	SessionID     string     `gorm:"primaryKey;column:session_id;size:36"`
+	InstanceID    int64      `gorm:"column:instance_id;not null;index"`
+	UserID        string     `gorm:"column:user_id;size:64"`
+	ContainerName string     `gorm:"column:container_name;size:64"`
+	Command       []byte     `gorm:"column:command"` // JSON 数组
+	TTY           bool       `gorm:"column:tty"`
+	ExitCode      *int32     `gorm:"column:exit_code"`
+	StartedAt     time.Time  `gorm:"column:started_at;not null"`
+	EndedAt       *time.Time `gorm:"column:ended_at"`
+}
+
+func (execSession) TableName() string { return "exec_session" }
+
+func (s execSession) toBiz() biz.ExecSession {
+	var command []string
+	_ = json.Unmarshal(s.Command, &command)
+	return biz.ExecSession{
+		SessionID:     s.SessionID,
+		InstanceID:    s.InstanceID,
+		UserID:        s.UserID,
+		ContainerName: s.ContainerName,
+		Command:       command,
+		TTY:           s.TTY,
+		ExitCode:      s.ExitCode,
+		StartedAt:     s.StartedAt,
+		EndedAt:       s.EndedAt,
+	}
+}
+
+// CreateExecSession 创建会话记录
+func (r *execSessionRepo) CreateExecSession(ctx context.Context, session biz.ExecSession) error {
+	command, err := json.Marshal(session.Command)
+	if err != nil {
+		return err
+	}
+	row := &execSession{
+		SessionID:     session.SessionID,
+		InstanceID:    session.InstanceID,
+		UserID:        session.UserID,
+		ContainerName: session.ContainerName,
+		Command:       command,
+		TTY:           session.TTY,
+		StartedAt:     session.StartedAt,
+	}
+	if err := r.data.db.WithContext(ctx).Create(row).Error; err != nil {
+		r.log.Errorf("failed to create exec session: %v", err)
+		return err
+	}
+	return nil
+}
+
+// FinishExecSession 记录会话结束时间与退出码
+func (r *execSessionRepo) FinishExecSession(ctx context.Context, sessionID string, exitCode int32, endedAt time.Time) error {
+	result := r.data.db.WithContext(ctx).
+		Model(&execSession{}).
+		Where("session_id = ?", sessionID).
+		Updates(map[string]interface{}{
+			"exit_code": exitCode,
+			"ended_at":  endedAt,
+		})
+	if result.Error != nil {
+		r.log.Errorf("failed to finish exec session: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetExecSession 获取单个会话
+func (r *execSessionRepo) GetExecSession(ctx context.Context, sessionID string) (*biz.ExecSession, error) {
+	var row execSession
+	err := r.data.db.WithContext(ctx).
+		Where("session_id = ?", sessionID).
+		First(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.log.Errorf("failed to get exec session: %v", err)
+		return nil, err
+	}
+
+	session := row.toBiz()
+	return &session, nil
+}
+
+// ListExecSessions 列出实例的会话，按开始时间倒序
+func (r *execSessionRepo) ListExecSessions(ctx context.Context, instanceID int64) ([]biz.ExecSession, error) {
+	var rows []execSession
+	err := r.data.db.WithContext(ctx).
+		Where("instance_id = ?", instanceID).
+		Order("started_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		r.log.Errorf("failed to list exec sessions: %v", err)
+		return nil, err
+	}
+
+	sessions := make([]biz.ExecSession, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, row.toBiz())
+	}
+	return sessions, nil
+}
+
+// sessionIDPattern 会话 ID 为 UUID，防止路径穿越
+var sessionIDPattern = regexp.MustCompile(`^[0-9a-fA-F-]{36}$`)
+
+// localRecordingStore 将录像以 {session_id}.cast 保存在本地目录
+type localRecordingStore struct {
+	dir string
+}
+
+// NewExecRecordingStore 根据配置创建录像存储，默认使用本地目录
+func NewExecRecordingStore(c *conf.Data, logger log.Logger) (biz.ExecRecordingStore, error) {
+	helper := log.NewHelper(logger)
+
+	storage := c.GetExecRecording().GetStorage()
+	dir := c.GetExecRecording().GetDir()
+	if dir == "" {
+		dir = filepath.Join("data", "exec-sessions")
+	}
+
+	switch storage {
+	case "", "local":
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create exec recording dir %s: %w", dir, err)
+		}
+		helper.Infof("exec recordings stored in %s", dir)
+		return &localRecordingStore{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unsupported exec recording storage %q", storage)
+	}
+}
+
+func (s *localRecordingStore) path(sessionID string) (string, error) {
+	if !sessionIDPattern.MatchString(sessionID) {
+		return "", fmt.Errorf("invalid session id %q", sessionID)
+	}
+	return filepath.Join(s.dir, sessionID+".cast"), nil
+}
+
+// Create 创建录像文件
+func (s *localRecordingStore) Create(_ context.Context, sessionID string) (io.WriteCloser, error) {
+	p, err := s.path(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+}
+
+// Open 打开录像文件
+func (s *localRecordingStore) Open(_ context.Context, sessionID string) (io.ReadCloser, error) {
+	p, err := s.path(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Delete 删除录像文件
+func (s *localRecordingStore) Delete(_ context.Context, sessionID string) error {
+	p, err := s.path(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package data
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"resource/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestLocalRecordingStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recordings")
+	store, err := NewExecRecordingStore(&conf.Data{ExecRecording: &conf.Data_ExecRecording{Dir: dir}}, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id := "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+	w, err := store.Create(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "{\"version\":2}\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一会话不能重复创建，避免覆盖录像
+	if _, err := store.Create(ctx, id); err == nil {
+		t.Fatal("expected error creating existing recording")
+	}
+
+	r, err := store.Open(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil || string(data) != "{\"version\":2}\n" {
+		t.Fatalf("data=%q err=%v", data, err)
+	}
+
+	if err := store.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, id+".cast")); !os.IsNotExist(err) {
+		t.Fatalf("recording not deleted: %v", err)
+	}
+	if err := store.Delete(ctx, id); err != nil {
+		t.Fatalf("deleting missing recording: %v", err)
+	}
+}
+
+func TestLocalRecordingStore_InvalidSessionID(t *testing.T) {
+	store := &localRecordingStore{dir: t.TempDir()}
+	ctx := context.Background()
+
+	for _, id := range []string{"../../etc/passwd", "", "0f8fad5b-d9cb-469f-a165-70867728950e/../x"} {
+		if _, err := store.Create(ctx, id); err == nil {
+			t.Fatalf("Create(%q) expected error", id)
+		}
+		if _, err := store.Open(ctx, id); err == nil {
+			t.Fatalf("Open(%q) expected error", id)
+		}
+		if err := store.Delete(ctx, id); err == nil {
+			t.Fatalf("Delete(%q) expected error", id)
+		}
+	}
+}
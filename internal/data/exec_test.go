@@ -0,0 +1,283 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// fakeExecutor 模拟 remotecommand 执行器
+type fakeExecutor struct {
+	stdout string
+	err    error
+}
+
+func (f *fakeExecutor) Stream(opts remotecommand.StreamOptions) error {
+	return f.StreamWithContext(context.Background(), opts)
+}
+
+func (f *fakeExecutor) StreamWithContext(_ context.Context, opts remotecommand.StreamOptions) error {
+	if f.stdout != "" {
+		_, _ = io.WriteString(opts.Stdout, f.stdout)
+	}
+	return f.err
+}
+
+func newTestExecRepo(executor remotecommand.Executor, factoryErr error, pods ...corev1.Pod) *execRepo {
+	objects := make([]runtime.Object, 0, len(pods))
+	for i := range pods {
+		objects = append(objects, &pods[i])
+	}
+	return &execRepo{
+		client: fake.NewSimpleClientset(objects...),
+		newExecutor: func(string, string, *corev1.PodExecOptions) (remotecommand.Executor, error) {
+			return executor, factoryErr
+		},
+		log: log.NewHelper(log.NewStdLogger(io.Discard)),
+	}
+}
+
+func instancePod() corev1.Pod {
+	return newPod("instance-1-abc", time.Now(), true, false)
+}
+
+func newPod(name string, created time.Time, ready, terminating bool) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "alice",
+			Labels:            map[string]string{"app": "instance", "instance-id": "1"},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "1"}, {Name: "sidecar"}},
+		},
+	}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+	if terminating {
+		now := metav1.NewTime(created.Add(time.Minute))
+		pod.DeletionTimestamp = &now
+	}
+	return pod
+}
+
+func TestExecRepo_StreamExec(t *testing.T) {
+	tests := []struct {
+		name         string
+		pods         []corev1.Pod
+		executor     *fakeExecutor
+		factoryErr   error
+		wantExitCode int32
+		wantCategory biz.ExecErrorCategory // ExecErrorUnspecified 表示不应有错误消息
+		wantErr      bool
+		wantStdout   string
+	}{
+		{
+			name:         "success",
+			pods:         []corev1.Pod{instancePod()},
+			executor:     &fakeExecutor{stdout: "hello\n"},
+			wantExitCode: 0,
+			wantStdout:   "hello\n",
+		},
+		{
+			name:         "command_exit_code",
+			pods:         []corev1.Pod{instancePod()},
+			executor:     &fakeExecutor{err: utilexec.CodeExitError{Err: errors.New("command terminated with exit code 2"), Code: 2}},
+			wantExitCode: 2,
+		},
+		{
+			name:         "pod_not_found",
+			executor:     &fakeExecutor{},
+			wantExitCode: biz.ExecExitCodeUnknown,
+			wantCategory: biz.ExecErrorNotFound,
+			wantErr:      true,
+		},
+		{
+			name:         "executor_setup_failure",
+			pods:         []corev1.Pod{instancePod()},
+			factoryErr:   errors.New("bad config"),
+			wantExitCode: biz.ExecExitCodeUnknown,
+			wantCategory: biz.ExecErrorSetup,
+			wantErr:      true,
+		},
+		{
+			name:         "transport_failure",
+			pods:         []corev1.Pod{instancePod()},
+			executor:     &fakeExecutor{err: errors.New("connection reset by peer")},
+			wantExitCode: biz.ExecExitCodeUnknown,
+			wantCategory: biz.ExecErrorTransport,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestExecRepo(tt.executor, tt.factoryErr, tt.pods...)
+			input := make(chan biz.ExecInput)
+			close(input)
+			output := make(chan biz.ExecOutput, 16)
+
+			err := repo.StreamExec(context.Background(), biz.ExecOptions{
+				Namespace:  "alice",
+				InstanceID: "1",
+				Command:    []string{"sh", "-c", "exit 2"},
+			}, input, output)
+			close(output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err=%v wantErr=%v", err, tt.wantErr)
+			}
+
+			var stdout string
+			var category biz.ExecErrorCategory
+			var last biz.ExecOutput
+			for out := range output {
+				switch out.Type {
+				case biz.ExecOutputData:
+					stdout += string(out.Data)
+				case biz.ExecOutputError:
+					category = out.Category
+				}
+				last = out
+			}
+			if last.Type != biz.ExecOutputExit {
+				t.Fatalf("last message type=%v want exit", last.Type)
+			}
+			if last.ExitCode != tt.wantExitCode {
+				t.Fatalf("exit code=%d want %d", last.ExitCode, tt.wantExitCode)
+			}
+			if category != tt.wantCategory {
+				t.Fatalf("category=%v want %v", category, tt.wantCategory)
+			}
+			if stdout != tt.wantStdout {
+				t.Fatalf("stdout=%q want %q", stdout, tt.wantStdout)
+			}
+		})
+	}
+}
+
+func TestExecRepo_StreamExecCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repo := newTestExecRepo(&fakeExecutor{err: context.Canceled}, nil, instancePod())
+	input := make(chan biz.ExecInput)
+	close(input)
+	output := make(chan biz.ExecOutput, 4)
+
+	// fake clientset 不检查 context，List 仍会成功
+	_ = repo.StreamExec(ctx, biz.ExecOptions{Namespace: "alice", InstanceID: "1"}, input, output)
+	close(output)
+
+	for out := range output {
+		if out.Type == biz.ExecOutputError && out.Category != biz.ExecErrorCanceled {
+			t.Fatalf("category=%v want canceled", out.Category)
+		}
+	}
+}
+
+func TestSelectExecPod(t *testing.T) {
+	now := time.Now()
+	old := newPod("old", now.Add(-time.Hour), true, false)
+	terminating := newPod("terminating", now.Add(-time.Minute), true, true)
+	notReady := newPod("not-ready", now, false, false)
+	// listPods 按创建时间倒序返回
+	pods := []corev1.Pod{notReady, terminating, old}
+
+	tests := []struct {
+		name    string
+		podName string
+		want    string
+		wantErr bool
+	}{
+		{name: "newest_ready_skips_terminating", want: "old"},
+		{name: "explicit_pod", podName: "not-ready", want: "not-ready"},
+		{name: "explicit_terminating_pod", podName: "terminating", wantErr: true},
+		{name: "explicit_unknown_pod", podName: "other", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod, err := selectExecPod(pods, tt.podName)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("pod=%s want error", pod.Name)
+				}
+				return
+			}
+			if err != nil || pod.Name != tt.want {
+				t.Fatalf("pod=%v err=%v want %s", pod, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := selectExecPod([]corev1.Pod{terminating, notReady}, ""); err == nil {
+		t.Fatal("expected error when no ready pod")
+	}
+}
+
+func TestSelectExecContainer(t *testing.T) {
+	pod := instancePod()
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "sidecar", want: "sidecar"},
+		{name: "1", want: "1"},
+		{name: "", want: "1"},
+		{name: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := selectExecContainer(&pod, tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Fatalf("container %q: got=%q err=%v want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	// 默认容器不存在时，未指定使用第一个容器，显式指定实例 ID 则报错
+	pod.Spec.Containers = []corev1.Container{{Name: "app"}, {Name: "sidecar"}}
+	tests = []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "", want: "app"},
+		{name: "1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := selectExecContainer(&pod, tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Fatalf("container %q: got=%q err=%v want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestExecRepo_ListPods(t *testing.T) {
+	now := time.Now()
+	repo := newTestExecRepo(nil, nil, newPod("a", now.Add(-time.Hour), true, false), newPod("b", now, false, true))
+
+	pods, err := repo.ListPods(context.Background(), "alice", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 2 || pods[0].Name != "b" || !pods[0].Terminating || pods[1].Name != "a" || !pods[1].Ready {
+		t.Fatalf("pods=%+v", pods)
+	}
+	if len(pods[1].Containers) != 2 {
+		t.Fatalf("containers=%+v", pods[1].Containers)
+	}
+}
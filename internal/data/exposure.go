@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+
+	"resource/internal/biz"
+)
+
+// 端口暴露后端，对应 data.kubernetes.exposure_backend
+const (
+	exposureBackendIngressNginx = "ingress-nginx" // 默认：Ingress + tcp/udp-services ConfigMap + ingress-nginx Service 端口
+	exposureBackendGatewayAPI   = "gateway-api"   // Gateway API：HTTPRoute/TCPRoute/UDPRoute + Gateway listener
+)
+
+// exposureBackend 把实例的 ClusterIP Service 暴露到集群外。
+// k8sRepo 负责创建/删除 Service，入口资源（Ingress、ConfigMap 条目、Route 等）的操作委托给后端。
+type exposureBackend interface {
+	// ExposeTCPUDP 分配外部端口并把 Service 暴露到该端口
+	ExposeTCPUDP(ctx context.Context, namespace, instanceID, serviceName string, port uint32, protocol string) (uint32, error)
+	// UnexposeTCPUDP 释放外部端口，不存在时返回 nil
+	UnexposeTCPUDP(ctx context.Context, protocol string, externalPort uint32) error
+	// ExposeHTTP 创建 HTTP 入口，返回入口名称与访问地址
+	ExposeHTTP(ctx context.Context, namespace, instanceID string, port uint32, serviceName string, opts biz.IngressOptions) (string, string, error)
+	// UnexposeHTTP 删除 HTTP 入口，不存在时返回 nil
+	UnexposeHTTP(ctx context.Context, namespace, name string) error
+	// UpdateHTTPProtection 原地替换 HTTP 入口的访问控制
+	UpdateHTTPProtection(ctx context.Context, namespace, name string, protection biz.IngressProtection) error
+	// PublicAddress 返回 TCP/UDP 外部端口所在的地址（IP 或主机名）
+	PublicAddress(ctx context.Context) (string, error)
+	// ListExposures 填充网络核对使用的入口资源、外部端口映射与已开放的外部端口
+	ListExposures(ctx context.Context, state *biz.NetworkState) error
+	// EnsureHTTP 按绑定记录重建缺失的 HTTP 入口
+	EnsureHTTP(ctx context.Context, namespace string, binding biz.NetworkBinding) error
+	// EnsureTCPUDP 按绑定记录重建缺失的外部端口映射，不覆盖被其他 Service 占用的端口
+	EnsureTCPUDP(ctx context.Context, namespace string, binding biz.NetworkBinding) error
+	// DisableHTTP 删除禁用端口的 HTTP 入口，保留 basic auth 凭据，不存在时返回 nil
+	DisableHTTP(ctx context.Context, namespace, name string) error
+	// DisableTCPUDP 删除禁用端口的外部端口映射，保留外部端口（ingress-nginx Service 端口 / Gateway listener）不被重新分配
+	DisableTCPUDP(ctx context.Context, namespace string, binding biz.NetworkBinding) error
+	// ExposeCustomDomain 创建或更新已验证自定义域名的 HTTPS 入口
+	ExposeCustomDomain(ctx context.Context, namespace string, domain biz.CustomDomain, binding biz.NetworkBinding) error
+	// UnexposeCustomDomain 删除自定义域名的入口，不存在时返回 nil
+	UnexposeCustomDomain(ctx context.Context, namespace string, domain biz.CustomDomain) error
+	// ControllerNamespace 返回转发流量到实例的控制器所在命名空间，用户命名空间的默认隔离策略放开来自该命名空间的入站流量
+	ControllerNamespace() string
+}
+
+// ingressNginxExposure 基于 ingress-nginx 的暴露后端：
+// HTTP 使用 Ingress，TCP/UDP 修改 tcp-services/udp-services ConfigMap 与 ingress-nginx Service 端口
+type ingressNginxExposure struct {
+	r *k8sRepo
+}
+
+func (e *ingressNginxExposure) ExposeTCPUDP(ctx context.Context, namespace, instanceID, serviceName string, port uint32, protocol string) (uint32, error) {
+	return e.r.exposeTCPUDP(ctx, namespace, serviceName, port, protocol)
+}
+
+func (e *ingressNginxExposure) UnexposeTCPUDP(ctx context.Context, protocol string, externalPort uint32) error {
+	return e.r.deleteTCPUDPConfigMapEntry(ctx, protocol, externalPort)
+}
+
+func (e *ingressNginxExposure) ExposeHTTP(ctx context.Context, namespace, instanceID string, port uint32, serviceName string, opts biz.IngressOptions) (string, string, error) {
+	return e.r.createIngress(ctx, namespace, generateIngressName(instanceID, port), instanceID, port, serviceName, opts)
+}
+
+func (e *ingressNginxExposure) UnexposeHTTP(ctx context.Context, namespace, name string) error {
+	return e.r.deleteIngress(ctx, namespace, name)
+}
+
+func (e *ingressNginxExposure) UpdateHTTPProtection(ctx context.Context, namespace, name string, protection biz.IngressProtection) error {
+	return e.r.updateIngressProtection(ctx, namespace, name, protection)
+}
+
+func (e *ingressNginxExposure) PublicAddress(ctx context.Context) (string, error) {
+	return e.r.getIngressNginxLBIP(ctx)
+}
+
+func (e *ingressNginxExposure) ListExposures(ctx context.Context, state *biz.NetworkState) error {
+	return e.r.listIngressNginxExposures(ctx, state)
+}
+
+func (e *ingressNginxExposure) EnsureHTTP(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	return e.r.ensureIngress(ctx, namespace, binding)
+}
+
+func (e *ingressNginxExposure) EnsureTCPUDP(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	return e.r.ensureTCPUDPConfigMapEntry(ctx, namespace, binding)
+}
+
+func (e *ingressNginxExposure) DisableHTTP(ctx context.Context, namespace, name string) error {
+	return e.r.disableIngress(ctx, namespace, name)
+}
+
+func (e *ingressNginxExposure) DisableTCPUDP(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	return e.r.removeTCPUDPConfigMapEntry(ctx, namespace, binding)
+}
+
+func (e *ingressNginxExposure) ExposeCustomDomain(ctx context.Context, namespace string, domain biz.CustomDomain, binding biz.NetworkBinding) error {
+	return e.r.ensureCustomDomainIngress(ctx, namespace, domain, binding)
+}
+
+func (e *ingressNginxExposure) UnexposeCustomDomain(ctx context.Context, namespace string, domain biz.CustomDomain) error {
+	return e.r.deleteCustomDomainIngress(ctx, namespace, domain)
+}
+
+func (e *ingressNginxExposure) ControllerNamespace() string {
+	return e.r.ingressNginxNamespace
+}
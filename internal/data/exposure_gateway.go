@@ -0,0 +1,529 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"resource/internal/biz"
+	"resource/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// Gateway API 资源，TCPRoute/UDPRoute 属于 experimental channel
+var (
+	gatewayGVR   = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gateways"}
+	httpRouteGVR = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}
+	tcpRouteGVR  = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "tcproutes"}
+	udpRouteGVR  = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "udproutes"}
+)
+
+var errExternalPortExhausted = errors.New("external port pool exhausted")
+
+// gatewayExposure 基于 Gateway API 的暴露后端：
+// HTTP 创建挂载到 HTTP(S) listener 的 HTTPRoute；TCP/UDP 在 Gateway 上为每个外部端口添加一个 listener，
+// 并在实例命名空间创建挂载到该 listener 的 TCPRoute/UDPRoute，不修改集群级 ConfigMap。
+type gatewayExposure struct {
+	client         dynamic.Interface
+	log            *log.Helper
+	namespace      string
+	name           string
+	httpListener   string
+	httpsListener  string
+	portRangeStart uint32
+	portRangeEnd   uint32
+}
+
+func newGatewayExposure(client dynamic.Interface, c *conf.Data_GatewayAPI, portRangeStart, portRangeEnd uint32, logger *log.Helper) (*gatewayExposure, error) {
+	if client == nil {
+		return nil, errors.New("dynamic client is not initialized")
+	}
+	if c.GetNamespace() == "" || c.GetName() == "" {
+		return nil, errors.New("gateway_api.namespace and gateway_api.name are required for gateway-api exposure backend")
+	}
+	httpListener := c.GetHttpListener()
+	if httpListener == "" {
+		httpListener = "http"
+	}
+	return &gatewayExposure{
+		client:         client,
+		log:            logger,
+		namespace:      c.GetNamespace(),
+		name:           c.GetName(),
+		httpListener:   httpListener,
+		httpsListener:  c.GetHttpsListener(),
+		portRangeStart: portRangeStart,
+		portRangeEnd:   portRangeEnd,
+	}, nil
+}
+
+// generateRouteName generates an HTTPRoute name based on instance ID and port.
+// Format: route-{instanceID}-{port}
+func generateRouteName(instanceID string, port uint32) string {
+	return fmt.Sprintf("route-%s-%d", instanceID, port)
+}
+
+// gatewayListenerName TCP/UDP 外部端口对应的 listener 名称，格式：{tcp|udp}-{externalPort}
+func gatewayListenerName(protocol string, externalPort uint32) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(protocol), externalPort)
+}
+
+// tcpUDPRouteResource returns the route resource and kind of the protocol.
+func tcpUDPRouteResource(protocol string) (schema.GroupVersionResource, string, error) {
+	switch protocol {
+	case "TCP":
+		return tcpRouteGVR, "TCPRoute", nil
+	case "UDP":
+		return udpRouteGVR, "UDPRoute", nil
+	default:
+		return schema.GroupVersionResource{}, "", fmt.Errorf("invalid protocol %s, must be TCP or UDP", protocol)
+	}
+}
+
+func (e *gatewayExposure) gateways() dynamic.ResourceInterface {
+	return e.client.Resource(gatewayGVR).Namespace(e.namespace)
+}
+
+// parentRef 挂载到 Gateway 指定 listener 的 parentRefs 条目
+func (e *gatewayExposure) parentRef(listener string) map[string]interface{} {
+	return map[string]interface{}{
+		"group":       gatewayGVR.Group,
+		"kind":        "Gateway",
+		"namespace":   e.namespace,
+		"name":        e.name,
+		"sectionName": listener,
+	}
+}
+
+func (e *gatewayExposure) ExposeTCPUDP(ctx context.Context, namespace, instanceID, serviceName string, port uint32, protocol string) (uint32, error) {
+	if _, _, err := tcpUDPRouteResource(protocol); err != nil {
+		return 0, err
+	}
+
+	// 1. 在 Gateway 上选择未使用的端口并添加 listener，端口占用以 Gateway 为准，重启后不会重复分配
+	var externalPort uint32
+	err := e.updateListeners(ctx, func(listeners []interface{}) ([]interface{}, error) {
+		used := make(map[uint32]bool, len(listeners))
+		for _, l := range listeners {
+			if m, ok := l.(map[string]interface{}); ok {
+				p, _, _ := unstructured.NestedInt64(m, "port")
+				used[uint32(p)] = true
+			}
+		}
+		externalPort = 0
+		for p := e.portRangeStart; p <= e.portRangeEnd; p++ {
+			if !used[p] {
+				externalPort = p
+				break
+			}
+		}
+		if externalPort == 0 {
+			return nil, errExternalPortExhausted
+		}
+		return append(listeners, newGatewayListener(protocol, externalPort)), nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to add gateway listener: %w", err)
+	}
+
+	// 2. 创建挂载到该 listener 的 TCPRoute/UDPRoute
+	if err := e.createTCPUDPRoute(ctx, namespace, instanceID, serviceName, port, protocol, externalPort); err != nil {
+		_ = e.removeListener(ctx, protocol, externalPort)
+		return 0, err
+	}
+
+	e.log.WithContext(ctx).Infof("%s route %s/%s attached to gateway %s/%s on port %d", protocol, namespace, serviceName, e.namespace, e.name, externalPort)
+	return externalPort, nil
+}
+
+// newGatewayListener 允许所有命名空间的 TCPRoute/UDPRoute 挂载的 listener
+func newGatewayListener(protocol string, externalPort uint32) map[string]interface{} {
+	_, kind, _ := tcpUDPRouteResource(protocol)
+	return map[string]interface{}{
+		"name":     gatewayListenerName(protocol, externalPort),
+		"port":     int64(externalPort),
+		"protocol": protocol,
+		"allowedRoutes": map[string]interface{}{
+			"namespaces": map[string]interface{}{"from": "All"},
+			"kinds":      []interface{}{map[string]interface{}{"kind": kind}},
+		},
+	}
+}
+
+// updateListeners 读取 Gateway 的 listeners，按 mutate 的结果更新，冲突时重试
+func (e *gatewayExposure) updateListeners(ctx context.Context, mutate func([]interface{}) ([]interface{}, error)) error {
+	return retry.RetryOnConflict(sharedExposureUpdateBackoff, func() error {
+		gw, err := e.gateways().Get(ctx, e.name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		listeners, _, err := unstructured.NestedSlice(gw.Object, "spec", "listeners")
+		if err != nil {
+			return err
+		}
+		updated, err := mutate(listeners)
+		if err != nil || updated == nil {
+			return err
+		}
+		if err := unstructured.SetNestedSlice(gw.Object, updated, "spec", "listeners"); err != nil {
+			return err
+		}
+		_, err = e.gateways().Update(ctx, gw, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// removeListener 删除外部端口对应的 listener，不存在时返回 nil
+func (e *gatewayExposure) removeListener(ctx context.Context, protocol string, externalPort uint32) error {
+	name := gatewayListenerName(protocol, externalPort)
+	return e.updateListeners(ctx, func(listeners []interface{}) ([]interface{}, error) {
+		kept := make([]interface{}, 0, len(listeners))
+		for _, l := range listeners {
+			if m, ok := l.(map[string]interface{}); ok && m["name"] == name {
+				continue
+			}
+			kept = append(kept, l)
+		}
+		if len(kept) == len(listeners) {
+			return nil, nil
+		}
+		return kept, nil
+	})
+}
+
+func (e *gatewayExposure) createTCPUDPRoute(ctx context.Context, namespace, instanceID, serviceName string, port uint32, protocol string, externalPort uint32) error {
+	gvr, kind, err := tcpUDPRouteResource(protocol)
+	if err != nil {
+		return err
+	}
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": gvr.GroupVersion().String(),
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      serviceName,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				"instance-id":   instanceID,
+				"managed-by":    "resource-service",
+				"external-port": strconv.FormatUint(uint64(externalPort), 10),
+			},
+		},
+		"spec": map[string]interface{}{
+			"parentRefs": []interface{}{e.parentRef(gatewayListenerName(protocol, externalPort))},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"backendRefs": []interface{}{
+						map[string]interface{}{"name": serviceName, "port": int64(port)},
+					},
+				},
+			},
+		},
+	}}
+	if _, err := e.client.Resource(gvr).Namespace(namespace).Create(ctx, route, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create %s %s: %w", kind, serviceName, err)
+	}
+	return nil
+}
+
+func (e *gatewayExposure) UnexposeTCPUDP(ctx context.Context, protocol string, externalPort uint32) error {
+	gvr, kind, err := tcpUDPRouteResource(protocol)
+	if err != nil {
+		return err
+	}
+
+	selector := fmt.Sprintf("managed-by=resource-service,external-port=%d", externalPort)
+	routes, err := e.client.Resource(gvr).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to list %s: %w", kind, err)
+	}
+	if err == nil {
+		for _, route := range routes.Items {
+			err := e.client.Resource(gvr).Namespace(route.GetNamespace()).Delete(ctx, route.GetName(), metav1.DeleteOptions{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %s %s/%s: %w", kind, route.GetNamespace(), route.GetName(), err)
+			}
+		}
+	}
+
+	if err := e.removeListener(ctx, protocol, externalPort); err != nil {
+		return fmt.Errorf("failed to remove gateway listener %s: %w", gatewayListenerName(protocol, externalPort), err)
+	}
+	return nil
+}
+
+func (e *gatewayExposure) ExposeHTTP(ctx context.Context, namespace, instanceID string, port uint32, serviceName string, opts biz.IngressOptions) (string, string, error) {
+	return e.createHTTPRoute(ctx, namespace, generateRouteName(instanceID, port), instanceID, port, serviceName, opts)
+}
+
+// createHTTPRoute creates an HTTPRoute attached to the HTTP(S) listener.
+// Returns the route name and access URL.
+func (e *gatewayExposure) createHTTPRoute(ctx context.Context, namespace, routeName, instanceID string, port uint32, serviceName string, opts biz.IngressOptions) (string, string, error) {
+	// basic auth、来源 IP 白名单与限流不在 Gateway API 标准中
+	if opts.Protection.Enabled() {
+		return "", "", fmt.Errorf("protection: %w", biz.ErrNotSupportedByBackend)
+	}
+	listener := e.httpListener
+	if opts.TLS {
+		if e.httpsListener == "" {
+			return "", "", fmt.Errorf("%w, set gateway_api.https_listener", biz.ErrTLSNotConfigured)
+		}
+		listener = e.httpsListener
+	}
+
+	host, path := ingressHostPath(opts, namespace, instanceID, port)
+	accessURL := generateAccessURL(opts, host, path)
+
+	rule := map[string]interface{}{
+		"matches": []interface{}{
+			map[string]interface{}{"path": map[string]interface{}{"type": "PathPrefix", "value": path}},
+		},
+		"backendRefs": []interface{}{
+			map[string]interface{}{"name": serviceName, "port": int64(port)},
+		},
+	}
+	if opts.Routing != biz.IngressRoutingHost {
+		// Path 模式去掉 /{namespace}/{instanceID}/{port} 前缀后转发
+		rule["filters"] = []interface{}{
+			map[string]interface{}{
+				"type": "URLRewrite",
+				"urlRewrite": map[string]interface{}{
+					"path": map[string]interface{}{"type": "ReplacePrefixMatch", "replacePrefixMatch": "/"},
+				},
+			},
+		}
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": httpRouteGVR.GroupVersion().String(),
+		"kind":       "HTTPRoute",
+		"metadata": map[string]interface{}{
+			"name":      routeName,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				"instance-id": instanceID,
+				"managed-by":  "resource-service",
+			},
+		},
+		"spec": map[string]interface{}{
+			"parentRefs": []interface{}{e.parentRef(listener)},
+			"hostnames":  []interface{}{host},
+			"rules":      []interface{}{rule},
+		},
+	}}
+
+	e.log.WithContext(ctx).Infof("creating HTTPRoute %s in namespace %s for service %s (routing=%s tls=%v)", routeName, namespace, serviceName, opts.Routing, opts.TLS)
+	if _, err := e.client.Resource(httpRouteGVR).Namespace(namespace).Create(ctx, route, metav1.CreateOptions{}); err != nil {
+		return "", "", fmt.Errorf("failed to create HTTPRoute: %w", err)
+	}
+	return routeName, accessURL, nil
+}
+
+func (e *gatewayExposure) UnexposeHTTP(ctx context.Context, namespace, name string) error {
+	err := e.client.Resource(httpRouteGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete HTTPRoute %s: %w", name, err)
+	}
+	return nil
+}
+
+func (e *gatewayExposure) UpdateHTTPProtection(ctx context.Context, namespace, name string, protection biz.IngressProtection) error {
+	if protection.Enabled() {
+		return fmt.Errorf("protection: %w", biz.ErrNotSupportedByBackend)
+	}
+	return nil
+}
+
+// PublicAddress 返回 Gateway status 中的第一个地址
+func (e *gatewayExposure) PublicAddress(ctx context.Context) (string, error) {
+	gw, err := e.gateways().Get(ctx, e.name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get gateway %s/%s: %w", e.namespace, e.name, err)
+	}
+	addresses, _, _ := unstructured.NestedSlice(gw.Object, "status", "addresses")
+	for _, a := range addresses {
+		if m, ok := a.(map[string]interface{}); ok {
+			if value, _ := m["value"].(string); value != "" {
+				return value, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("gateway %s/%s has no address", e.namespace, e.name)
+}
+
+// ListExposures HTTPRoute 视为 Ingress，TCPRoute/UDPRoute 视为端口映射，{tcp|udp}-{port} listener 视为已开放的外部端口
+func (e *gatewayExposure) ListExposures(ctx context.Context, state *biz.NetworkState) error {
+	routes, err := e.client.Resource(httpRouteGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: managedNetworkSelector})
+	if err != nil {
+		return fmt.Errorf("failed to list HTTPRoutes: %w", err)
+	}
+	for _, route := range routes.Items {
+		state.Ingresses = append(state.Ingresses, unstructuredNetworkObject(route))
+	}
+
+	for _, protocol := range []string{"TCP", "UDP"} {
+		gvr, kind, _ := tcpUDPRouteResource(protocol)
+		routes, err := e.client.Resource(gvr).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: managedNetworkSelector})
+		if err != nil {
+			// 未安装 experimental channel 的 CRD
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+		for _, route := range routes.Items {
+			externalPort, err := strconv.ParseUint(route.GetLabels()["external-port"], 10, 32)
+			if err != nil {
+				continue
+			}
+			rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+			serviceName, servicePort := firstBackendRef(rules)
+			state.Mappings = append(state.Mappings, biz.TCPUDPMapping{
+				Protocol:     protocol,
+				ExternalPort: uint32(externalPort),
+				Namespace:    route.GetNamespace(),
+				ServiceName:  serviceName,
+				ServicePort:  servicePort,
+				InstanceID:   unstructuredNetworkObject(route).InstanceID,
+			})
+		}
+	}
+
+	gw, err := e.gateways().Get(ctx, e.name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get gateway %s/%s: %w", e.namespace, e.name, err)
+	}
+	listeners, _, _ := unstructured.NestedSlice(gw.Object, "spec", "listeners")
+	for _, l := range listeners {
+		m, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		protocol, _ := m["protocol"].(string)
+		port, _, _ := unstructured.NestedInt64(m, "port")
+		// 只有本服务添加的 listener 参与核对
+		if (protocol != "TCP" && protocol != "UDP") || m["name"] != gatewayListenerName(protocol, uint32(port)) {
+			continue
+		}
+		if uint32(port) < e.portRangeStart || uint32(port) > e.portRangeEnd {
+			continue
+		}
+		state.LBPorts = append(state.LBPorts, biz.LBPort{Protocol: protocol, Port: uint32(port)})
+	}
+	return nil
+}
+
+func (e *gatewayExposure) EnsureHTTP(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	_, err := e.client.Resource(httpRouteGVR).Namespace(namespace).Get(ctx, *binding.IngressName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		instanceID := strconv.FormatInt(binding.InstanceID, 10)
+		_, _, err = e.createHTTPRoute(ctx, namespace, *binding.IngressName, instanceID, binding.Port, binding.ServiceName, binding.Ingress)
+	}
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// DisableHTTP 网关后端不支持 basic auth，直接删除 HTTPRoute
+func (e *gatewayExposure) DisableHTTP(ctx context.Context, namespace, name string) error {
+	return e.UnexposeHTTP(ctx, namespace, name)
+}
+
+// DisableTCPUDP 只删除 TCPRoute/UDPRoute，保留 listener 占用外部端口
+func (e *gatewayExposure) DisableTCPUDP(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	gvr, kind, err := tcpUDPRouteResource(binding.Protocol)
+	if err != nil {
+		return err
+	}
+	err = e.client.Resource(gvr).Namespace(namespace).Delete(ctx, binding.ServiceName, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %s %s/%s: %w", kind, namespace, binding.ServiceName, err)
+	}
+	return nil
+}
+
+// ExposeCustomDomain 自定义域名的证书需要在 Gateway 上逐个添加 HTTPS listener，暂不支持
+func (e *gatewayExposure) ExposeCustomDomain(context.Context, string, biz.CustomDomain, biz.NetworkBinding) error {
+	return fmt.Errorf("custom domain: %w", biz.ErrNotSupportedByBackend)
+}
+
+func (e *gatewayExposure) UnexposeCustomDomain(context.Context, string, biz.CustomDomain) error {
+	return nil
+}
+
+func (e *gatewayExposure) ControllerNamespace() string {
+	return e.namespace
+}
+
+func (e *gatewayExposure) EnsureTCPUDP(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	externalPort := *binding.ExternalPort
+	listenerName := gatewayListenerName(binding.Protocol, externalPort)
+
+	err := e.updateListeners(ctx, func(listeners []interface{}) ([]interface{}, error) {
+		for _, l := range listeners {
+			m, ok := l.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if m["name"] == listenerName {
+				return nil, nil
+			}
+			if p, _, _ := unstructured.NestedInt64(m, "port"); uint32(p) == externalPort {
+				return nil, fmt.Errorf("external port %d is used by gateway listener %v", externalPort, m["name"])
+			}
+		}
+		return append(listeners, newGatewayListener(binding.Protocol, externalPort)), nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ensure gateway listener %s: %w", listenerName, err)
+	}
+
+	gvr, _, err := tcpUDPRouteResource(binding.Protocol)
+	if err != nil {
+		return err
+	}
+	_, err = e.client.Resource(gvr).Namespace(namespace).Get(ctx, binding.ServiceName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		instanceID := strconv.FormatInt(binding.InstanceID, 10)
+		err = e.createTCPUDPRoute(ctx, namespace, instanceID, binding.ServiceName, binding.ServicePort, binding.Protocol, externalPort)
+	}
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func unstructuredNetworkObject(obj unstructured.Unstructured) biz.NetworkObject {
+	instanceID, _ := strconv.ParseInt(obj.GetLabels()["instance-id"], 10, 64)
+	return biz.NetworkObject{
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		InstanceID: instanceID,
+		CreatedAt:  obj.GetCreationTimestamp().Time,
+	}
+}
+
+// firstBackendRef 返回 rules[0].backendRefs[0] 的 Service 名称与端口
+func firstBackendRef(rules []interface{}) (string, uint32) {
+	if len(rules) == 0 {
+		return "", 0
+	}
+	rule, _ := rules[0].(map[string]interface{})
+	backends, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+	if len(backends) == 0 {
+		return "", 0
+	}
+	backend, _ := backends[0].(map[string]interface{})
+	name, _ := backend["name"].(string)
+	port, _, _ := unstructured.NestedInt64(backend, "port")
+	return name, uint32(port)
+}
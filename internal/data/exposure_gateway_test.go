@@ -0,0 +1,259 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"resource/internal/biz"
+	"resource/internal/conf"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// newTestGatewayK8sRepo 使用 gateway-api 后端的 k8sRepo，Gateway 上已有 http 与管理员配置的 ssh listener
+func newTestGatewayK8sRepo(t *testing.T) (*k8sRepo, *dynamicfake.FakeDynamicClient) {
+	gateway := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]interface{}{"name": "shared", "namespace": "gateway-system"},
+		"spec": map[string]interface{}{
+			"listeners": []interface{}{
+				map[string]interface{}{"name": "http", "port": int64(80), "protocol": "HTTP"},
+				map[string]interface{}{"name": "ssh", "port": int64(30000), "protocol": "TCP"},
+			},
+		},
+		"status": map[string]interface{}{
+			"addresses": []interface{}{map[string]interface{}{"type": "IPAddress", "value": "203.0.113.10"}},
+		},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gatewayGVR:   "GatewayList",
+		httpRouteGVR: "HTTPRouteList",
+		tcpRouteGVR:  "TCPRouteList",
+		udpRouteGVR:  "UDPRouteList",
+	})
+	// 预置对象按 Kind 猜测复数形式（gatewaies），Gateway 需要通过 Create 写入
+	if _, err := client.Resource(gatewayGVR).Namespace("gateway-system").Create(context.Background(), gateway, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newTestNetworkK8sRepo()
+	exposure, err := newGatewayExposure(client, &conf.Data_GatewayAPI{Namespace: "gateway-system", Name: "shared"}, 30000, 30010, repo.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.exposure = exposure
+	return repo, client
+}
+
+func gatewayListenerNames(t *testing.T, client *dynamicfake.FakeDynamicClient) map[string]bool {
+	gw, err := client.Resource(gatewayGVR).Namespace("gateway-system").Get(context.Background(), "shared", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listeners, _, _ := unstructured.NestedSlice(gw.Object, "spec", "listeners")
+	names := map[string]bool{}
+	for _, l := range listeners {
+		names[l.(map[string]interface{})["name"].(string)] = true
+	}
+	return names
+}
+
+func TestGatewayExposure_TCPUDP(t *testing.T) {
+	repo, client := newTestGatewayK8sRepo(t)
+	ctx := context.Background()
+
+	// 30000 已被管理员的 listener 占用
+	serviceName, externalPort, err := repo.CreateServiceForTCPUDP(ctx, "alice", "2", 5432, "TCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if externalPort != 30001 {
+		t.Fatalf("external port=%d want 30001", externalPort)
+	}
+	if !gatewayListenerNames(t, client)["tcp-30001"] {
+		t.Fatal("listener tcp-30001 not added")
+	}
+	route, err := client.Resource(tcpRouteGVR).Namespace("alice").Get(ctx, serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	if parents[0].(map[string]interface{})["sectionName"] != "tcp-30001" {
+		t.Fatalf("parentRefs=%v", parents)
+	}
+
+	addr, err := repo.GetTCPUDPPublicAddress(ctx)
+	if err != nil || addr != "203.0.113.10" {
+		t.Fatalf("addr=%s err=%v", addr, err)
+	}
+
+	state, err := repo.GetNetworkState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Mappings) != 1 || state.Mappings[0] != (biz.TCPUDPMapping{
+		Protocol: "TCP", ExternalPort: 30001, Namespace: "alice", ServiceName: serviceName, ServicePort: 5432, InstanceID: 2,
+	}) {
+		t.Fatalf("mappings=%+v", state.Mappings)
+	}
+	// 管理员的 ssh listener 不参与核对
+	if len(state.LBPorts) != 1 || state.LBPorts[0] != (biz.LBPort{Protocol: "TCP", Port: 30001}) {
+		t.Fatalf("lb ports=%+v", state.LBPorts)
+	}
+
+	if err := repo.ReleaseTCPUDPPort(ctx, "TCP", externalPort); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Resource(tcpRouteGVR).Namespace("alice").Get(ctx, serviceName, metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("route err=%v want NotFound", err)
+	}
+	if names := gatewayListenerNames(t, client); names["tcp-30001"] || !names["ssh"] {
+		t.Fatalf("listeners=%v", names)
+	}
+
+	// 核对修复：按记录重建 listener 与 route
+	ext := uint32(30005)
+	binding := biz.NetworkBinding{InstanceID: 3, Port: 53, ServiceName: "instance-3-53", ServicePort: 53, ExternalPort: &ext, Protocol: "UDP", Enabled: true}
+	if err := repo.EnsureNetworkBinding(ctx, "bob", binding); err != nil {
+		t.Fatal(err)
+	}
+	if !gatewayListenerNames(t, client)["udp-30005"] {
+		t.Fatal("listener udp-30005 not recreated")
+	}
+	if _, err := client.Resource(udpRouteGVR).Namespace("bob").Get(ctx, "instance-3-53", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 禁用端口：删除 route，保留 listener 占用外部端口
+	binding.Enabled = false
+	if err := repo.EnsureNetworkBinding(ctx, "bob", binding); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Resource(udpRouteGVR).Namespace("bob").Get(ctx, "instance-3-53", metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("route err=%v want NotFound", err)
+	}
+	if !gatewayListenerNames(t, client)["udp-30005"] {
+		t.Fatal("listener udp-30005 released by disable")
+	}
+}
+
+func TestGatewayExposure_ConcurrentReplicas(t *testing.T) {
+	repo, client := newTestGatewayK8sRepo(t)
+	enforceResourceVersion(client)
+	ctx := context.Background()
+
+	// 两个副本共享同一 Gateway，端口占用只以 Gateway listeners 为准
+	replica := *repo
+	exposure, err := newGatewayExposure(client, &conf.Data_GatewayAPI{Namespace: "gateway-system", Name: "shared"}, 30000, 30010, repo.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.exposure = exposure
+	repos := []*k8sRepo{repo, &replica}
+
+	const n = 8
+	ports := make([]uint32, n)
+	protocols := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		protocols[i] = "TCP"
+		if i%3 == 0 {
+			protocols[i] = "UDP"
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, ports[i], errs[i] = repos[i%2].CreateServiceForTCPUDP(ctx, "bob", strconv.Itoa(100+i), 7000, protocols[i])
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[uint32]bool{}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("open %d: %v", i, errs[i])
+		}
+		// Gateway 上的 listener 端口不区分协议，30000 已被 ssh listener 占用
+		if seen[ports[i]] || ports[i] == 30000 {
+			t.Fatalf("external port %d allocated twice: %v", ports[i], ports)
+		}
+		seen[ports[i]] = true
+	}
+	names := gatewayListenerNames(t, client)
+	if len(names) != n+2 {
+		t.Fatalf("lost gateway listeners: %v", names)
+	}
+	for i := 0; i < n; i++ {
+		if !names[gatewayListenerName(protocols[i], ports[i])] {
+			t.Fatalf("listener for %s/%d missing: %v", protocols[i], ports[i], names)
+		}
+	}
+
+	// 并发关闭
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repos[i%2].ReleaseTCPUDPPort(ctx, protocols[i], ports[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("close %d: %v", i, err)
+		}
+	}
+	if names := gatewayListenerNames(t, client); len(names) != 2 || !names["http"] || !names["ssh"] {
+		t.Fatalf("unexpected listeners after close: %v", names)
+	}
+}
+
+func TestGatewayExposure_HTTP(t *testing.T) {
+	repo, client := newTestGatewayK8sRepo(t)
+	ctx := context.Background()
+
+	name, url, err := repo.CreateIngress(ctx, "alice", "1", 8080, "instance-1-8080", biz.IngressOptions{Domain: "apps.example.com", Routing: biz.IngressRoutingPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "route-1-8080" || url != "http://apps.example.com/alice/1/8080" {
+		t.Fatalf("name=%s url=%s", name, url)
+	}
+	route, err := client.Resource(httpRouteGVR).Namespace("alice").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	if _, ok := rules[0].(map[string]interface{})["filters"]; !ok {
+		t.Fatalf("path routing must rewrite prefix: %v", rules)
+	}
+	if svc, port := firstBackendRef(rules); svc != "instance-1-8080" || port != 8080 {
+		t.Fatalf("backend=%s:%d", svc, port)
+	}
+
+	_, _, err = repo.CreateIngress(ctx, "alice", "1", 8443, "instance-1-8443", biz.IngressOptions{Domain: "apps.example.com", TLS: true})
+	if !errors.Is(err, biz.ErrTLSNotConfigured) {
+		t.Fatalf("err=%v want ErrTLSNotConfigured", err)
+	}
+	opts := biz.IngressOptions{Domain: "apps.example.com", Protection: biz.IngressProtection{RateLimitRPS: 1}}
+	if _, _, err := repo.CreateIngress(ctx, "alice", "1", 8081, "instance-1-8081", opts); !errors.Is(err, biz.ErrNotSupportedByBackend) {
+		t.Fatalf("err=%v want ErrNotSupportedByBackend", err)
+	}
+
+	if err := repo.DeleteIngress(ctx, "alice", name); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteIngress(ctx, "alice", name); err != nil {
+		t.Fatalf("delete must be idempotent: %v", err)
+	}
+}
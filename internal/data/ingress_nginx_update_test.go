@@ -0,0 +1,149 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeTrackerClient 是 fake clientset 与 fake dynamic client 的公共部分
+type fakeTrackerClient interface {
+	PrependReactor(verb, resource string, reaction k8stesting.ReactionFunc)
+	Tracker() k8stesting.ObjectTracker
+}
+
+// enforceResourceVersion 让 fake client 像 apiserver 一样对 Update 做乐观并发控制：
+// resourceVersion 与当前对象不一致时返回 Conflict，成功时递增 resourceVersion
+func enforceResourceVersion(client fakeTrackerClient) {
+	client.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj, err := meta.Accessor(action.(k8stesting.UpdateAction).GetObject())
+		if err != nil {
+			return false, nil, nil
+		}
+		current, err := client.Tracker().Get(action.GetResource(), action.GetNamespace(), obj.GetName())
+		if err != nil {
+			return false, nil, nil
+		}
+		currentObj, _ := meta.Accessor(current)
+		if obj.GetResourceVersion() != currentObj.GetResourceVersion() {
+			return true, nil, k8serrors.NewConflict(action.GetResource().GroupResource(), obj.GetName(), errors.New("the object has been modified"))
+		}
+		rv, _ := strconv.Atoi(currentObj.GetResourceVersion())
+		obj.SetResourceVersion(strconv.Itoa(rv + 1))
+		return false, nil, nil
+	})
+}
+
+func TestExposeTCPUDP_ConcurrentReplicas(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	client := repo.client.(*fake.Clientset)
+	enforceResourceVersion(client)
+	ctx := context.Background()
+
+	// 两个副本共享同一集群，各自没有本地端口状态
+	replica := *repo
+	replica.exposure = &ingressNginxExposure{r: &replica}
+	repos := []*k8sRepo{repo, &replica}
+
+	const n = 12
+	ports := make([]uint32, n)
+	protocols := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		protocols[i] = "TCP"
+		if i%3 == 0 {
+			protocols[i] = "UDP"
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, ports[i], errs[i] = repos[i%2].CreateServiceForTCPUDP(ctx, "bob", strconv.Itoa(100+i), 7000, protocols[i])
+		}(i)
+	}
+	wg.Wait()
+
+	// 端口只要求在同一协议内唯一：TCP 与 UDP 同时分配时可能得到相同端口号，协议不同不会冲突
+	seen := map[string]bool{}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("open %d: %v", i, errs[i])
+		}
+		key := protocols[i] + "/" + strconv.FormatUint(uint64(ports[i]), 10)
+		if seen[key] {
+			t.Fatalf("external port %s allocated twice: %v", key, ports)
+		}
+		// 30000/30001 已被现有映射占用
+		if ports[i] < 30002 {
+			t.Fatalf("external port %d collides with existing entries", ports[i])
+		}
+		seen[key] = true
+	}
+
+	tcp, _ := client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "tcp-services", metav1.GetOptions{})
+	udp, _ := client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "udp-services", metav1.GetOptions{})
+	if len(tcp.Data)+len(udp.Data) != n+2 {
+		t.Fatalf("lost ConfigMap entries: tcp=%v udp=%v", tcp.Data, udp.Data)
+	}
+	lb, _ := client.CoreV1().Services("ingress-nginx").Get(ctx, "ingress-nginx-controller", metav1.GetOptions{})
+	if len(lb.Spec.Ports) != n+2 {
+		t.Fatalf("lost ingress-nginx Service ports: %+v", lb.Spec.Ports)
+	}
+
+	// 并发关闭
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repos[i%2].ReleaseTCPUDPPort(ctx, protocols[i], ports[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("close %d: %v", i, err)
+		}
+	}
+
+	tcp, _ = client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "tcp-services", metav1.GetOptions{})
+	udp, _ = client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "udp-services", metav1.GetOptions{})
+	if len(tcp.Data) != 2 || len(udp.Data) != 0 || tcp.Data["30001"] != "kube-system/dns:53:PROXY" {
+		t.Fatalf("unexpected entries after close: tcp=%v udp=%v", tcp.Data, udp.Data)
+	}
+	lb, _ = client.CoreV1().Services("ingress-nginx").Get(ctx, "ingress-nginx-controller", metav1.GetOptions{})
+	if len(lb.Spec.Ports) != 2 {
+		t.Fatalf("unexpected ingress-nginx Service ports after close: %+v", lb.Spec.Ports)
+	}
+}
+
+func TestAllocateTCPUDPConfigMapEntry_Exhausted(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	repo.tcpUDPPortRangeEnd = 30002
+	ctx := context.Background()
+
+	port, err := repo.allocateTCPUDPConfigMapEntry(ctx, "tcp-services", "alice/instance-1-80:80")
+	if err != nil || port != 30002 {
+		t.Fatalf("port=%d err=%v", port, err)
+	}
+	if _, err := repo.allocateTCPUDPConfigMapEntry(ctx, "tcp-services", "alice/instance-1-81:81"); err == nil {
+		t.Fatal("expected exhausted pool error")
+	}
+
+	// 重建时不覆盖其他 Service 已占用的条目
+	if err := repo.patchIngressNginxConfigMap(ctx, "tcp-services", "30002", "bob/instance-9-80:80"); err != nil {
+		t.Fatal(err)
+	}
+	cm, _ := repo.client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "tcp-services", metav1.GetOptions{})
+	if cm.Data["30002"] != "alice/instance-1-80:80" {
+		t.Fatalf("entry overwritten: %v", cm.Data)
+	}
+}
@@ -0,0 +1,178 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"resource/internal/biz"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// ingress-nginx 访问控制注解
+const (
+	annotationAuthType        = "nginx.ingress.kubernetes.io/auth-type"
+	annotationAuthSecret      = "nginx.ingress.kubernetes.io/auth-secret"
+	annotationAuthRealm       = "nginx.ingress.kubernetes.io/auth-realm"
+	annotationWhitelistSource = "nginx.ingress.kubernetes.io/whitelist-source-range"
+	annotationLimitRPS        = "nginx.ingress.kubernetes.io/limit-rps"
+	annotationLimitRPM        = "nginx.ingress.kubernetes.io/limit-rpm"
+	annotationLimitConns      = "nginx.ingress.kubernetes.io/limit-connections"
+)
+
+var protectionAnnotations = []string{
+	annotationAuthType,
+	annotationAuthSecret,
+	annotationAuthRealm,
+	annotationWhitelistSource,
+	annotationLimitRPS,
+	annotationLimitRPM,
+	annotationLimitConns,
+}
+
+// basicAuthSecretName basic auth 凭据 Secret 名称，格式：{ingressName}-auth
+func basicAuthSecretName(ingressName string) string {
+	return ingressName + "-auth"
+}
+
+// applyProtectionAnnotations 按访问控制设置替换 Ingress 上的相关注解，其他注解保持不变
+func applyProtectionAnnotations(annotations map[string]string, ingressName string, p biz.IngressProtection) {
+	for _, key := range protectionAnnotations {
+		delete(annotations, key)
+	}
+	if p.BasicAuthUser != "" {
+		annotations[annotationAuthType] = "basic"
+		annotations[annotationAuthSecret] = basicAuthSecretName(ingressName)
+		annotations[annotationAuthRealm] = "Authentication Required"
+	}
+	if len(p.AllowedCIDRs) > 0 {
+		annotations[annotationWhitelistSource] = strings.Join(p.AllowedCIDRs, ",")
+	}
+	if p.RateLimitRPS > 0 {
+		annotations[annotationLimitRPS] = strconv.FormatUint(uint64(p.RateLimitRPS), 10)
+	}
+	if p.RateLimitRPM > 0 {
+		annotations[annotationLimitRPM] = strconv.FormatUint(uint64(p.RateLimitRPM), 10)
+	}
+	if p.MaxConnections > 0 {
+		annotations[annotationLimitConns] = strconv.FormatUint(uint64(p.MaxConnections), 10)
+	}
+}
+
+// ensureBasicAuthSecret 同步 basic auth 凭据 Secret（auth-file 格式："{user}:{bcrypt hash}"）。
+// 未启用 basic auth 时删除 Secret；未提供密码时要求现有 Secret 的用户名一致，沿用原密码。
+func (r *k8sRepo) ensureBasicAuthSecret(ctx context.Context, namespace, instanceID, ingressName string, p biz.IngressProtection) error {
+	secrets := r.client.CoreV1().Secrets(namespace)
+	name := basicAuthSecretName(ingressName)
+
+	if p.BasicAuthUser == "" {
+		return r.deleteBasicAuthSecret(ctx, namespace, ingressName)
+	}
+
+	existing, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		existing = nil
+	case err != nil:
+		return fmt.Errorf("failed to get basic auth secret %s/%s: %w", namespace, name, err)
+	case existing.Labels["managed-by"] != "resource-service":
+		return fmt.Errorf("secret %s/%s already exists and is not managed by resource-service", namespace, name)
+	}
+
+	if p.BasicAuthPassword == "" {
+		if existing == nil || !bytes.HasPrefix(existing.Data["auth"], []byte(p.BasicAuthUser+":")) {
+			return biz.ErrBasicAuthPasswordRequired
+		}
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(p.BasicAuthPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash basic auth password: %w", err)
+	}
+	data := map[string][]byte{"auth": []byte(p.BasicAuthUser + ":" + string(hash) + "\n")}
+
+	if existing == nil {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"instance-id": instanceID,
+					"managed-by":  "resource-service",
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create basic auth secret %s/%s: %w", namespace, name, err)
+		}
+		return nil
+	}
+
+	existing.Data = data
+	if _, err := secrets.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update basic auth secret %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// deleteBasicAuthSecret 删除 Ingress 的 basic auth Secret，不存在时返回 nil
+func (r *k8sRepo) deleteBasicAuthSecret(ctx context.Context, namespace, ingressName string) error {
+	name := basicAuthSecretName(ingressName)
+	err := r.client.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete basic auth secret %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// updateIngressProtection 原地替换 Ingress 的访问控制注解与 basic auth Secret，ingress-nginx 重新加载配置后生效
+func (r *k8sRepo) updateIngressProtection(ctx context.Context, namespace, ingressName string, protection biz.IngressProtection) error {
+	ingresses := r.client.NetworkingV1().Ingresses(namespace)
+
+	ingress, err := ingresses.Get(ctx, ingressName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get ingress %s: %w", ingressName, err)
+	}
+
+	// 先写入凭据再引用，避免注解指向尚不存在的 Secret
+	if protection.BasicAuthUser != "" {
+		if err := r.ensureBasicAuthSecret(ctx, namespace, ingress.Labels["instance-id"], ingressName, protection); err != nil {
+			return err
+		}
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ingress, err := ingresses.Get(ctx, ingressName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if ingress.Annotations == nil {
+			ingress.Annotations = map[string]string{}
+		}
+		applyProtectionAnnotations(ingress.Annotations, ingressName, protection)
+		_, err = ingresses.Update(ctx, ingress, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update ingress %s: %w", ingressName, err)
+	}
+
+	if protection.BasicAuthUser == "" {
+		if err := r.deleteBasicAuthSecret(ctx, namespace, ingressName); err != nil {
+			r.log.Warnf("%v", err)
+		}
+	}
+
+	r.log.WithContext(ctx).Infof("ingress %s/%s protection updated", namespace, ingressName)
+	return nil
+}
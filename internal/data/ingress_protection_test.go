@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"resource/internal/biz"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestK8sRepo_IngressProtection(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+	ingresses := repo.client.NetworkingV1().Ingresses("alice")
+	secrets := repo.client.CoreV1().Secrets("alice")
+
+	opts := biz.IngressOptions{
+		Domain: "apps.example.com",
+		Protection: biz.IngressProtection{
+			BasicAuthUser:     "admin",
+			BasicAuthPassword: "s3cret",
+			AllowedCIDRs:      []string{"10.0.0.0/8", "203.0.113.7/32"},
+			RateLimitRPS:      5,
+		},
+	}
+	name, _, err := repo.CreateIngress(ctx, "alice", "1", 8080, "instance-1-8080", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ing, _ := ingresses.Get(ctx, name, metav1.GetOptions{})
+	want := map[string]string{
+		annotationAuthType:        "basic",
+		annotationAuthSecret:      "ingress-1-8080-auth",
+		annotationWhitelistSource: "10.0.0.0/8,203.0.113.7/32",
+		annotationLimitRPS:        "5",
+	}
+	for k, v := range want {
+		if ing.Annotations[k] != v {
+			t.Fatalf("%s=%q want %q", k, ing.Annotations[k], v)
+		}
+	}
+	secret, err := secrets.Get(ctx, "ingress-1-8080-auth", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, hash, _ := strings.Cut(strings.TrimSpace(string(secret.Data["auth"])), ":")
+	if user != "admin" || bcrypt.CompareHashAndPassword([]byte(hash), []byte("s3cret")) != nil {
+		t.Fatalf("auth=%s", secret.Data["auth"])
+	}
+
+	// 不提供密码时沿用原凭据，其他设置整体替换
+	update := biz.IngressProtection{BasicAuthUser: "admin", RateLimitRPM: 120, MaxConnections: 20}
+	if err := repo.UpdateIngressProtection(ctx, "alice", name, update); err != nil {
+		t.Fatal(err)
+	}
+	ing, _ = ingresses.Get(ctx, name, metav1.GetOptions{})
+	if _, ok := ing.Annotations[annotationWhitelistSource]; ok || ing.Annotations[annotationLimitRPM] != "120" ||
+		ing.Annotations[annotationLimitConns] != "20" || ing.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] != "/" {
+		t.Fatalf("annotations=%v", ing.Annotations)
+	}
+	kept, _ := secrets.Get(ctx, "ingress-1-8080-auth", metav1.GetOptions{})
+	if string(kept.Data["auth"]) != string(secret.Data["auth"]) {
+		t.Fatal("credential changed without new password")
+	}
+
+	// 更换用户名必须提供密码
+	err = repo.UpdateIngressProtection(ctx, "alice", name, biz.IngressProtection{BasicAuthUser: "root"})
+	if !errors.Is(err, biz.ErrBasicAuthPasswordRequired) {
+		t.Fatalf("err=%v want ErrBasicAuthPasswordRequired", err)
+	}
+
+	// 取消全部限制后删除凭据
+	if err := repo.UpdateIngressProtection(ctx, "alice", name, biz.IngressProtection{}); err != nil {
+		t.Fatal(err)
+	}
+	ing, _ = ingresses.Get(ctx, name, metav1.GetOptions{})
+	for _, k := range protectionAnnotations {
+		if _, ok := ing.Annotations[k]; ok {
+			t.Fatalf("annotation %s not removed", k)
+		}
+	}
+	if _, err := secrets.Get(ctx, "ingress-1-8080-auth", metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("secret err=%v want NotFound", err)
+	}
+}
+
+func TestK8sRepo_BasicAuthSecretUnmanaged(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+	own := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "ingress-2-80-auth", Namespace: "bob"}}
+	if _, err := repo.client.CoreV1().Secrets("bob").Create(ctx, own, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	opts := biz.IngressOptions{
+		Domain:     "apps.example.com",
+		Protection: biz.IngressProtection{BasicAuthUser: "admin", BasicAuthPassword: "x"},
+	}
+	if _, _, err := repo.CreateIngress(ctx, "bob", "2", 80, "instance-2-80", opts); err == nil {
+		t.Fatal("expected error for unmanaged secret")
+	}
+	if _, err := repo.client.NetworkingV1().Ingresses("bob").Get(ctx, "ingress-2-80", metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("ingress err=%v want NotFound", err)
+	}
+}
@@ -0,0 +1,76 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ingressTLSSecret 返回 HTTPS Ingress 使用的证书 Secret 名称。
+// 配置了 cert-manager ClusterIssuer 时添加签发注解，证书写入 {ingressName}-tls；
+// 否则把通配符证书复制到实例所在命名空间（Ingress 只能引用同命名空间的 Secret）。
+func (r *k8sRepo) ingressTLSSecret(ctx context.Context, namespace, ingressName string, annotations map[string]string) (string, error) {
+	if r.tlsClusterIssuer != "" {
+		annotations["cert-manager.io/cluster-issuer"] = r.tlsClusterIssuer
+		return ingressName + "-tls", nil
+	}
+	if r.tlsWildcardSecret == "" {
+		return "", fmt.Errorf("%w, set tls_cluster_issuer or tls_wildcard_secret", biz.ErrTLSNotConfigured)
+	}
+
+	sourceNamespace, name, ok := strings.Cut(r.tlsWildcardSecret, "/")
+	if !ok || sourceNamespace == "" || name == "" {
+		return "", fmt.Errorf("invalid tls_wildcard_secret %q, must be namespace/name", r.tlsWildcardSecret)
+	}
+	if sourceNamespace == namespace {
+		return name, nil
+	}
+
+	source, err := r.client.CoreV1().Secrets(sourceNamespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get wildcard TLS secret %s: %w", r.tlsWildcardSecret, err)
+	}
+
+	existing, err := r.client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"managed-by": "resource-service",
+				},
+			},
+			Type: source.Type,
+			Data: source.Data,
+		}
+		if _, err := r.client.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to copy wildcard TLS secret: %w", err)
+		}
+		r.log.WithContext(ctx).Infof("wildcard TLS secret %s copied to namespace %s", r.tlsWildcardSecret, namespace)
+		return name, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get TLS secret %s/%s: %w", namespace, name, err)
+	}
+
+	// 证书续期后同步副本；同名但非本服务创建的 Secret 不覆盖
+	if existing.Labels["managed-by"] != "resource-service" {
+		return "", fmt.Errorf("secret %s/%s already exists and is not managed by resource-service", namespace, name)
+	}
+	if !reflect.DeepEqual(existing.Data, source.Data) {
+		existing.Data = source.Data
+		if _, err := r.client.CoreV1().Secrets(namespace).Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return "", fmt.Errorf("failed to update TLS secret %s/%s: %w", namespace, name, err)
+		}
+		r.log.WithContext(ctx).Infof("TLS secret %s/%s refreshed from %s", namespace, name, r.tlsWildcardSecret)
+	}
+	return name, nil
+}
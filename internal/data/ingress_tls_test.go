@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestK8sRepo_CreateIngressRouting(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        biz.IngressOptions
+		issuer      string
+		wantURL     string
+		wantHost    string
+		wantPath    string
+		wantRewrite bool
+		wantSecret  string
+		wantErr     error
+	}{
+		{
+			name:        "path",
+			opts:        biz.IngressOptions{Domain: "apps.example.com", Routing: biz.IngressRoutingPath},
+			wantURL:     "http://apps.example.com/alice/1/8080",
+			wantHost:    "apps.example.com",
+			wantPath:    "/alice/1/8080",
+			wantRewrite: true,
+		},
+		{
+			name:     "host",
+			opts:     biz.IngressOptions{Domain: "apps.example.com", Routing: biz.IngressRoutingHost},
+			wantURL:  "http://8080-1.apps.example.com",
+			wantHost: "8080-1.apps.example.com",
+			wantPath: "/",
+		},
+		{
+			name:       "host_cert_manager",
+			opts:       biz.IngressOptions{Domain: "apps.example.com", Routing: biz.IngressRoutingHost, TLS: true},
+			issuer:     "letsencrypt",
+			wantURL:    "https://8080-1.apps.example.com",
+			wantHost:   "8080-1.apps.example.com",
+			wantPath:   "/",
+			wantSecret: "ingress-1-8080-tls",
+		},
+		{
+			name:    "tls_not_configured",
+			opts:    biz.IngressOptions{Domain: "apps.example.com", TLS: true},
+			wantErr: biz.ErrTLSNotConfigured,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestNetworkK8sRepo()
+			repo.tlsClusterIssuer = tt.issuer
+
+			name, url, err := repo.CreateIngress(context.Background(), "alice", "1", 8080, "instance-1-8080", tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err=%v want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if url != tt.wantURL {
+				t.Fatalf("url=%s want %s", url, tt.wantURL)
+			}
+
+			ing, err := repo.client.NetworkingV1().Ingresses("alice").Get(context.Background(), name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule := ing.Spec.Rules[0]
+			if rule.Host != tt.wantHost || rule.HTTP.Paths[0].Path != tt.wantPath {
+				t.Fatalf("host=%s path=%s", rule.Host, rule.HTTP.Paths[0].Path)
+			}
+			if _, ok := ing.Annotations["nginx.ingress.kubernetes.io/rewrite-target"]; ok != tt.wantRewrite {
+				t.Fatalf("annotations=%v", ing.Annotations)
+			}
+			if tt.wantSecret == "" {
+				if len(ing.Spec.TLS) != 0 {
+					t.Fatalf("tls=%v want none", ing.Spec.TLS)
+				}
+				return
+			}
+			if len(ing.Spec.TLS) != 1 || ing.Spec.TLS[0].SecretName != tt.wantSecret || ing.Spec.TLS[0].Hosts[0] != tt.wantHost ||
+				ing.Annotations["cert-manager.io/cluster-issuer"] != tt.issuer {
+				t.Fatalf("tls=%v annotations=%v", ing.Spec.TLS, ing.Annotations)
+			}
+		})
+	}
+}
+
+func TestK8sRepo_IngressTLSSecretWildcard(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	repo.tlsWildcardSecret = "ingress-nginx/wildcard-tls"
+	ctx := context.Background()
+	secrets := repo.client.CoreV1().Secrets
+
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "wildcard-tls", Namespace: "ingress-nginx"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{"tls.crt": []byte("v1"), "tls.key": []byte("k")},
+	}
+	if _, err := secrets("ingress-nginx").Create(ctx, source, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 首次使用时复制到实例命名空间
+	name, err := repo.ingressTLSSecret(ctx, "alice", "ingress-1-80", map[string]string{})
+	if err != nil || name != "wildcard-tls" {
+		t.Fatalf("name=%s err=%v", name, err)
+	}
+	copied, err := secrets("alice").Get(ctx, "wildcard-tls", metav1.GetOptions{})
+	if err != nil || copied.Type != corev1.SecretTypeTLS || string(copied.Data["tls.crt"]) != "v1" {
+		t.Fatalf("copied=%v err=%v", copied, err)
+	}
+
+	// 证书续期后刷新副本
+	source.Data["tls.crt"] = []byte("v2")
+	if _, err := secrets("ingress-nginx").Update(ctx, source, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ingressTLSSecret(ctx, "alice", "ingress-1-80", map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	copied, _ = secrets("alice").Get(ctx, "wildcard-tls", metav1.GetOptions{})
+	if string(copied.Data["tls.crt"]) != "v2" {
+		t.Fatalf("tls.crt=%s want v2", copied.Data["tls.crt"])
+	}
+
+	// 用户自己的同名 Secret 不覆盖
+	own := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "wildcard-tls", Namespace: "bob"}}
+	if _, err := secrets("bob").Create(ctx, own, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ingressTLSSecret(ctx, "bob", "ingress-2-80", map[string]string{}); err == nil {
+		t.Fatal("expected error for unmanaged secret")
+	}
+}
@@ -3,10 +3,12 @@ package data
 import (
 	"context"
 	"fmt"
+	"net"
 	"resource/internal/biz"
 	"resource/internal/conf"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	appsv1 "k8s.io/api/apps/v1"
@@ -16,8 +18,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 )
 
 type gpuInfo struct {
@@ -35,6 +39,26 @@ var gpuTypeMap = map[uint32]gpuInfo{
 	6: {nums: 1, name: "nvidia-geforce-rtx4060m"}, // 6 = GeForce RTX 4060 Mobile
 }
 
+// 带宽限制 Pod 注解，由 CNI bandwidth 插件生效
+const (
+	annotationIngressBandwidth = "kubernetes.io/ingress-bandwidth"
+	annotationEgressBandwidth  = "kubernetes.io/egress-bandwidth"
+)
+
+// applyBandwidthAnnotations 按规格设置或清除 Pod 模板上的带宽注解，单位 Mbit/s
+func applyBandwidthAnnotations(annotations map[string]string, spec biz.InstanceSpec) {
+	for key, mbps := range map[string]uint32{
+		annotationIngressBandwidth: spec.IngressBandwidth,
+		annotationEgressBandwidth:  spec.EgressBandwidth,
+	} {
+		if mbps == 0 {
+			delete(annotations, key)
+			continue
+		}
+		annotations[key] = strconv.FormatUint(uint64(mbps), 10) + "M"
+	}
+}
+
 type k8sRepo struct {
 	client                kubernetes.Interface
 	config                *rest.Config
@@ -44,7 +68,16 @@ type k8sRepo struct {
 	ingressNginxLBService string
 	tcpUDPPortRangeStart  uint32
 	tcpUDPPortRangeEnd    uint32
-	nextAvailablePort     uint32 // 简单的端口分配计数器（生产环境需要更复杂的端口池管理）
+	tlsClusterIssuer      string // cert-manager ClusterIssuer
+	tlsWildcardSecret     string // 通配符证书 Secret，格式 namespace/name
+	exposure              exposureBackend
+	nodeAddress           string        // NODEPORT 访问地址中的节点地址，为空时自动探测
+	tcpUDPPublicHost      string        // TCP/UDP 访问地址中的公开主机名，为空时使用暴露后端的地址
+	lbAddressTimeout      time.Duration // 等待 LoadBalancer 分配外部地址的时长
+	disableNetworkPolicy  bool          // 不为用户命名空间创建隔离 NetworkPolicy
+	networkPolicyEgress   bool          // 默认隔离同时拒绝出站流量
+	networkPolicyNodes    []string      // 允许入站访问实例的节点网段
+	networkPolicyBackfill bool          // 为已有用户命名空间补齐默认隔离策略
 }
 
 // NewK8sRepo bootstraps a Kubernetes repo with a shared kubeconfig.
@@ -54,6 +87,8 @@ func NewK8sRepo(c *conf.Data, k8sClient *K8sClient, logger log.Logger) (biz.K8sR
 	// 获取配置
 	var ingressDomain, ingressNginxNamespace, ingressNginxLBService string
 	var tcpUDPPortRangeStart, tcpUDPPortRangeEnd uint32
+	var tlsClusterIssuer, tlsWildcardSecret string
+	var nodeAddress string
 
 	if c.GetKubernetes() != nil {
 		k8sConf := c.GetKubernetes()
@@ -62,6 +97,9 @@ func NewK8sRepo(c *conf.Data, k8sClient *K8sClient, logger log.Logger) (biz.K8sR
 		ingressNginxLBService = k8sConf.GetIngressNginxLbService()
 		tcpUDPPortRangeStart = k8sConf.GetTcpUdpPortRangeStart()
 		tcpUDPPortRangeEnd = k8sConf.GetTcpUdpPortRangeEnd()
+		tlsClusterIssuer = k8sConf.GetTlsClusterIssuer()
+		tlsWildcardSecret = k8sConf.GetTlsWildcardSecret()
+		nodeAddress = k8sConf.GetNodeAddress()
 	}
 
 	// 设置默认值
@@ -81,8 +119,14 @@ func NewK8sRepo(c *conf.Data, k8sClient *K8sClient, logger log.Logger) (biz.K8sR
 	if tcpUDPPortRangeEnd == 0 {
 		tcpUDPPortRangeEnd = 32767
 	}
+	nodeCIDRs := c.GetKubernetes().GetNetworkPolicyNodeCidrs()
+	for _, cidr := range nodeCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("invalid network_policy_node_cidrs %q: %w", cidr, err)
+		}
+	}
 
-	return &k8sRepo{
+	repo := &k8sRepo{
 		client:                k8sClient.Client,
 		config:                k8sClient.Config,
 		log:                   helper,
@@ -91,15 +135,45 @@ func NewK8sRepo(c *conf.Data, k8sClient *K8sClient, logger log.Logger) (biz.K8sR
 		ingressNginxLBService: ingressNginxLBService,
 		tcpUDPPortRangeStart:  tcpUDPPortRangeStart,
 		tcpUDPPortRangeEnd:    tcpUDPPortRangeEnd,
-		nextAvailablePort:     tcpUDPPortRangeStart, // 初始化端口计数器
-	}, nil
+		tlsClusterIssuer:      tlsClusterIssuer,
+		tlsWildcardSecret:     tlsWildcardSecret,
+		nodeAddress:           nodeAddress,
+		lbAddressTimeout:      defaultLBAddressTimeout,
+		disableNetworkPolicy:  c.GetKubernetes().GetDisableNetworkPolicy(),
+		tcpUDPPublicHost:      c.GetKubernetes().GetTcpUdpPublicHost(),
+		networkPolicyEgress:   c.GetKubernetes().GetNetworkPolicyDenyEgress(),
+		networkPolicyNodes:    nodeCIDRs,
+		networkPolicyBackfill: c.GetKubernetes().GetNetworkPolicyBackfill(),
+	}
+
+	// 选择端口暴露后端
+	backend := c.GetKubernetes().GetExposureBackend()
+	if backend == "" {
+		backend = exposureBackendIngressNginx
+	}
+	switch backend {
+	case exposureBackendIngressNginx:
+		repo.exposure = &ingressNginxExposure{r: repo}
+	case exposureBackendGatewayAPI:
+		gateway, err := newGatewayExposure(k8sClient.Dynamic, c.GetKubernetes().GetGatewayApi(), tcpUDPPortRangeStart, tcpUDPPortRangeEnd, helper)
+		if err != nil {
+			return nil, err
+		}
+		repo.exposure = gateway
+	default:
+		return nil, fmt.Errorf("unknown exposure_backend %q, must be %s or %s", backend, exposureBackendIngressNginx, exposureBackendGatewayAPI)
+	}
+	helper.Infof("using %s exposure backend", backend)
+
+	return repo, nil
 }
 
 // ensureNamespace 确保指定的 namespace 存在，如果不存在则创建
+// 同时确保命名空间的默认隔离 NetworkPolicy 存在
 func (r *k8sRepo) ensureNamespace(ctx context.Context, namespace string) error {
 	_, err := r.client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
 	if err == nil {
-		return nil
+		return r.ensureNetworkPolicies(ctx, namespace)
 	}
 
 	// 使用标准 K8s 错误判断
@@ -123,7 +197,7 @@ func (r *k8sRepo) ensureNamespace(ctx context.Context, namespace string) error {
 	}
 
 	r.log.Infof("created namespace %s", namespace)
-	return nil
+	return r.ensureNetworkPolicies(ctx, namespace)
 }
 
 func (r *k8sRepo) CreateInstance(ctx context.Context, spec biz.InstanceSpec) error {
@@ -167,6 +241,8 @@ func (r *k8sRepo) CreateInstance(ctx context.Context, spec biz.InstanceSpec) err
 		"app":         "instance",
 		"user-id":     spec.UserID,
 	}
+	podAnnotations := map[string]string{}
+	applyBandwidthAnnotations(podAnnotations, spec)
 
 	// 使用 Deployment 替代 Pod，提高稳定性
 	deployment := &appsv1.Deployment{
@@ -182,7 +258,8 @@ func (r *k8sRepo) CreateInstance(ctx context.Context, spec biz.InstanceSpec) err
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
+					Labels:      labels,
+					Annotations: podAnnotations,
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -315,6 +392,12 @@ func (r *k8sRepo) UpdateInstance(ctx context.Context, spec biz.InstanceSpec) err
 		deployment.Spec.Template.Spec.NodeSelector = nodeSelector
 	}
 
+	// 带宽注解变化会触发滚动更新
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
+	applyBandwidthAnnotations(deployment.Spec.Template.Annotations, spec)
+
 	_, err = r.client.AppsV1().Deployments(spec.UserID).Update(ctx, deployment, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to update deployment %s: %w", instanceIDStr, err)
@@ -324,7 +407,7 @@ func (r *k8sRepo) UpdateInstance(ctx context.Context, spec biz.InstanceSpec) err
 	return nil
 }
 
-// CreateServiceForTCPUDP creates a ClusterIP Service for TCP/UDP protocols and patches ingress-nginx ConfigMap.
+// CreateServiceForTCPUDP creates a ClusterIP Service for TCP/UDP protocols and exposes it through the exposure backend.
 // Returns the service name and the allocated external port.
 func (r *k8sRepo) CreateServiceForTCPUDP(ctx context.Context, namespace, instanceID string, port uint32, protocol string) (string, uint32, error) {
 	serviceName := generateServiceName(instanceID, port)
@@ -332,42 +415,12 @@ func (r *k8sRepo) CreateServiceForTCPUDP(ctx context.Context, namespace, instanc
 	r.log.WithContext(ctx).Infof("creating ClusterIP service %s in namespace %s for port %d with protocol %s", serviceName, namespace, port, protocol)
 
 	// 验证协议
-	var k8sProtocol corev1.Protocol
-	var configMapName string
-	switch protocol {
-	case "TCP":
-		k8sProtocol = corev1.ProtocolTCP
-		configMapName = "tcp-services"
-	case "UDP":
-		k8sProtocol = corev1.ProtocolUDP
-		configMapName = "udp-services"
-	default:
-		return "", 0, fmt.Errorf("invalid protocol %s, must be TCP or UDP", protocol)
+	if _, err := tcpUDPConfigMapName(protocol); err != nil {
+		return "", 0, err
 	}
 
 	// 1. 创建 ClusterIP Service
-	service := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      serviceName,
-			Namespace: namespace,
-			Labels: map[string]string{
-				"instance-id": instanceID,
-				"managed-by":  "resource-service",
-			},
-		},
-		Spec: corev1.ServiceSpec{
-			Type:     corev1.ServiceTypeClusterIP,
-			Selector: getPodSelector(instanceID),
-			Ports: []corev1.ServicePort{
-				{
-					Name:       fmt.Sprintf("port-%d", port),
-					Protocol:   k8sProtocol,
-					Port:       int32(port),
-					TargetPort: intstr.FromInt32(int32(port)),
-				},
-			},
-		},
-	}
+	service := newInstanceService(namespace, instanceID, serviceName, port, corev1.Protocol(protocol))
 
 	_, err := r.client.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
 	if err != nil {
@@ -377,37 +430,42 @@ func (r *k8sRepo) CreateServiceForTCPUDP(ctx context.Context, namespace, instanc
 
 	r.log.WithContext(ctx).Infof("ClusterIP service %s created successfully", serviceName)
 
-	// 2. 分配外部端口（简单实现：自增）
-	externalPort := r.allocateExternalPort()
-	if externalPort == 0 {
-		// 端口池耗尽，回滚 Service
+	// 2. 分配外部端口并暴露
+	externalPort, err := r.exposure.ExposeTCPUDP(ctx, namespace, instanceID, serviceName, port, protocol)
+	if err != nil {
+		// 暴露失败，回滚 Service
 		_ = r.client.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
-		return "", 0, fmt.Errorf("external port pool exhausted")
+		return "", 0, err
 	}
 
-	// 3. Patch ingress-nginx ConfigMap
-	configMapKey := fmt.Sprintf("%d", externalPort)
-	configMapValue := fmt.Sprintf("%s/%s:%d", namespace, serviceName, port)
+	r.log.WithContext(ctx).Infof("TCP/UDP service %s exposed on external port %d", serviceName, externalPort)
+
+	return serviceName, externalPort, nil
+}
 
-	err = r.patchIngressNginxConfigMap(ctx, configMapName, configMapKey, configMapValue)
+// exposeTCPUDP allocates an external port, patches the ingress-nginx ConfigMap and adds the port to the ingress-nginx Service.
+func (r *k8sRepo) exposeTCPUDP(ctx context.Context, namespace, serviceName string, port uint32, protocol string) (uint32, error) {
+	configMapName, err := tcpUDPConfigMapName(protocol)
 	if err != nil {
-		// ConfigMap patch 失败，回滚 Service
-		_ = r.client.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
-		return "", 0, fmt.Errorf("failed to patch ConfigMap: %w", err)
+		return 0, err
 	}
 
-	// 4. Patch ingress-nginx Service 添加端口
-	err = r.patchIngressNginxServicePort(ctx, externalPort, protocol)
+	// 1. 分配外部端口并写入 ingress-nginx ConfigMap
+	configMapValue := fmt.Sprintf("%s/%s:%d", namespace, serviceName, port)
+	externalPort, err := r.allocateTCPUDPConfigMapEntry(ctx, configMapName, configMapValue)
 	if err != nil {
-		// Service patch 失败，回滚 ConfigMap 和 Service
-		_ = r.DeleteTCPUDPConfigMapEntry(ctx, protocol, externalPort)
-		_ = r.client.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
-		return "", 0, fmt.Errorf("failed to patch ingress-nginx Service: %w", err)
+		return 0, fmt.Errorf("failed to patch ConfigMap: %w", err)
 	}
 
-	r.log.WithContext(ctx).Infof("TCP/UDP service %s exposed on external port %d", serviceName, externalPort)
+	// 2. Patch ingress-nginx Service 添加端口
+	err = r.patchIngressNginxServicePort(ctx, externalPort, protocol)
+	if err != nil {
+		// Service patch 失败，回滚 ConfigMap
+		_ = r.deleteTCPUDPConfigMapEntry(ctx, protocol, externalPort)
+		return 0, fmt.Errorf("failed to patch ingress-nginx Service: %w", err)
+	}
 
-	return serviceName, externalPort, nil
+	return externalPort, nil
 }
 
 // CreateServiceForHTTP creates a ClusterIP Service for HTTP protocol.
@@ -418,7 +476,23 @@ func (r *k8sRepo) CreateServiceForHTTP(ctx context.Context, namespace, instanceI
 	r.log.WithContext(ctx).Infof("creating ClusterIP service %s in namespace %s for port %d", serviceName, namespace, port)
 
 	// 构建 Service 对象
-	service := &corev1.Service{
+	service := newInstanceService(namespace, instanceID, serviceName, port, corev1.ProtocolTCP)
+
+	// 调用 K8s API 创建 Service
+	_, err := r.client.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+	if err != nil {
+		r.log.Errorf("failed to create ClusterIP service %s: %v", serviceName, err)
+		return "", fmt.Errorf("failed to create service: %w", err)
+	}
+
+	r.log.WithContext(ctx).Infof("ClusterIP service %s created successfully", serviceName)
+
+	return serviceName, nil
+}
+
+// newInstanceService builds the ClusterIP Service that exposes a container port of an instance.
+func newInstanceService(namespace, instanceID, serviceName string, port uint32, protocol corev1.Protocol) *corev1.Service {
+	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      serviceName,
 			Namespace: namespace,
@@ -433,24 +507,13 @@ func (r *k8sRepo) CreateServiceForHTTP(ctx context.Context, namespace, instanceI
 			Ports: []corev1.ServicePort{
 				{
 					Name:       fmt.Sprintf("port-%d", port),
-					Protocol:   corev1.ProtocolTCP,
+					Protocol:   protocol,
 					Port:       int32(port),
 					TargetPort: intstr.FromInt32(int32(port)),
 				},
 			},
 		},
 	}
-
-	// 调用 K8s API 创建 Service
-	_, err := r.client.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
-	if err != nil {
-		r.log.Errorf("failed to create ClusterIP service %s: %v", serviceName, err)
-		return "", fmt.Errorf("failed to create service: %w", err)
-	}
-
-	r.log.WithContext(ctx).Infof("ClusterIP service %s created successfully", serviceName)
-
-	return serviceName, nil
 }
 
 // DeleteService deletes a Service by name.
@@ -458,6 +521,11 @@ func (r *k8sRepo) CreateServiceForHTTP(ctx context.Context, namespace, instanceI
 func (r *k8sRepo) DeleteService(ctx context.Context, namespace, serviceName string) error {
 	r.log.WithContext(ctx).Infof("deleting service %s in namespace %s", serviceName, namespace)
 
+	// NODEPORT/LOADBALANCER 端口的 NetworkPolicy 与 Service 同名
+	if err := r.deleteNetworkPolicy(ctx, namespace, serviceName); err != nil {
+		r.log.Warnf("%v", err)
+	}
+
 	err := r.client.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
 	if err != nil {
 		// 使用标准 K8s 错误判断
@@ -477,17 +545,67 @@ func (r *k8sRepo) DeleteService(ctx context.Context, namespace, serviceName stri
 // Ingress 操作
 // ============================================================================
 
-// CreateIngress creates an Ingress for HTTP access.
+// CreateIngress exposes an HTTP Service through the exposure backend (Ingress or HTTPRoute).
+// Returns the ingress/route name and access URL.
+func (r *k8sRepo) CreateIngress(ctx context.Context, namespace, instanceID string, port uint32, serviceName string, opts biz.IngressOptions) (string, string, error) {
+	return r.exposure.ExposeHTTP(ctx, namespace, instanceID, port, serviceName, opts)
+}
+
+// DeleteIngress deletes the Ingress or HTTPRoute of an HTTP port.
+// Returns nil if it doesn't exist (idempotent).
+func (r *k8sRepo) DeleteIngress(ctx context.Context, namespace, ingressName string) error {
+	return r.exposure.UnexposeHTTP(ctx, namespace, ingressName)
+}
+
+// UpdateIngressProtection replaces the access control settings of an HTTP port in place.
+func (r *k8sRepo) UpdateIngressProtection(ctx context.Context, namespace, ingressName string, protection biz.IngressProtection) error {
+	return r.exposure.UpdateHTTPProtection(ctx, namespace, ingressName, protection)
+}
+
+// ReleaseTCPUDPPort removes the external exposure of a TCP/UDP port.
+func (r *k8sRepo) ReleaseTCPUDPPort(ctx context.Context, protocol string, externalPort uint32) error {
+	return r.exposure.UnexposeTCPUDP(ctx, protocol, externalPort)
+}
+
+// GetTCPUDPPublicAddress returns the public address of TCP/UDP ports:
+// the configured tcp_udp_public_host, otherwise the external address of the exposure backend.
+func (r *k8sRepo) GetTCPUDPPublicAddress(ctx context.Context) (string, error) {
+	if r.tcpUDPPublicHost != "" {
+		return r.tcpUDPPublicHost, nil
+	}
+	return r.exposure.PublicAddress(ctx)
+}
+
+// createIngress creates an ingress-nginx Ingress for HTTP access.
 // Returns the ingress name and access URL.
-func (r *k8sRepo) CreateIngress(ctx context.Context, namespace, instanceID string, port uint32, serviceName, ingressDomain string) (string, string, error) {
-	ingressName := generateIngressName(instanceID, port)
-	accessURL := generateAccessURL(ingressDomain, namespace, instanceID, port)
+func (r *k8sRepo) createIngress(ctx context.Context, namespace, ingressName, instanceID string, port uint32, serviceName string, opts biz.IngressOptions) (string, string, error) {
+	host, path := ingressHostPath(opts, namespace, instanceID, port)
+	accessURL := generateAccessURL(opts, host, path)
+
+	r.log.WithContext(ctx).Infof("creating ingress %s in namespace %s for service %s (routing=%s tls=%v)", ingressName, namespace, serviceName, opts.Routing, opts.TLS)
 
-	r.log.WithContext(ctx).Infof("creating ingress %s in namespace %s for service %s", ingressName, namespace, serviceName)
+	annotations := map[string]string{}
+	if opts.Routing != biz.IngressRoutingHost {
+		// Path 模式去掉 /{namespace}/{instanceID}/{port} 前缀后转发
+		annotations["nginx.ingress.kubernetes.io/rewrite-target"] = "/"
+	}
+	applyProtectionAnnotations(annotations, ingressName, opts.Protection)
+	if opts.Protection.BasicAuthUser != "" {
+		if err := r.ensureBasicAuthSecret(ctx, namespace, instanceID, ingressName, opts.Protection); err != nil {
+			return "", "", err
+		}
+	}
+
+	var tls []networkingv1.IngressTLS
+	if opts.TLS {
+		secretName, err := r.ingressTLSSecret(ctx, namespace, ingressName, annotations)
+		if err != nil {
+			return "", "", err
+		}
+		tls = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: secretName}}
+	}
 
-	// 构建 Path
 	pathType := networkingv1.PathTypePrefix
-	path := fmt.Sprintf("/%s/%s/%d", namespace, instanceID, port)
 
 	// 构建 Ingress 对象
 	ingress := &networkingv1.Ingress{
@@ -498,15 +616,14 @@ func (r *k8sRepo) CreateIngress(ctx context.Context, namespace, instanceID strin
 				"instance-id": instanceID,
 				"managed-by":  "resource-service",
 			},
-			Annotations: map[string]string{
-				"nginx.ingress.kubernetes.io/rewrite-target": "/",
-			},
+			Annotations: annotations,
 		},
 		Spec: networkingv1.IngressSpec{
 			IngressClassName: stringPtr("nginx"),
+			TLS:              tls,
 			Rules: []networkingv1.IngressRule{
 				{
-					Host: ingressDomain,
+					Host: host,
 					IngressRuleValue: networkingv1.IngressRuleValue{
 						HTTP: &networkingv1.HTTPIngressRuleValue{
 							Paths: []networkingv1.HTTPIngressPath{
@@ -534,6 +651,10 @@ func (r *k8sRepo) CreateIngress(ctx context.Context, namespace, instanceID strin
 	_, err := r.client.NetworkingV1().Ingresses(namespace).Create(ctx, ingress, metav1.CreateOptions{})
 	if err != nil {
 		r.log.Errorf("failed to create ingress %s: %v", ingressName, err)
+		// 回滚本次写入的凭据；未提供密码时沿用的是已有 Secret，保留
+		if opts.Protection.BasicAuthPassword != "" && !k8serrors.IsAlreadyExists(err) {
+			_ = r.deleteBasicAuthSecret(ctx, namespace, ingressName)
+		}
 		return "", "", fmt.Errorf("failed to create ingress: %w", err)
 	}
 
@@ -542,9 +663,9 @@ func (r *k8sRepo) CreateIngress(ctx context.Context, namespace, instanceID strin
 	return ingressName, accessURL, nil
 }
 
-// DeleteIngress deletes an Ingress by name.
+// deleteIngress deletes an Ingress by name.
 // Returns nil if the ingress doesn't exist (idempotent).
-func (r *k8sRepo) DeleteIngress(ctx context.Context, namespace, ingressName string) error {
+func (r *k8sRepo) deleteIngress(ctx context.Context, namespace, ingressName string) error {
 	r.log.WithContext(ctx).Infof("deleting ingress %s in namespace %s", ingressName, namespace)
 
 	err := r.client.NetworkingV1().Ingresses(namespace).Delete(ctx, ingressName, metav1.DeleteOptions{})
@@ -552,11 +673,15 @@ func (r *k8sRepo) DeleteIngress(ctx context.Context, namespace, ingressName stri
 		// 使用标准 K8s 错误判断
 		if k8serrors.IsNotFound(err) {
 			r.log.WithContext(ctx).Infof("ingress %s not found, already deleted", ingressName)
-			return nil
+			return r.deleteBasicAuthSecret(ctx, namespace, ingressName)
 		}
 		r.log.Errorf("failed to delete ingress %s: %v", ingressName, err)
 		return fmt.Errorf("failed to delete ingress: %w", err)
 	}
+	// basic auth 凭据随 Ingress 一起删除
+	if err := r.deleteBasicAuthSecret(ctx, namespace, ingressName); err != nil {
+		r.log.Warnf("%v", err)
+	}
 
 	r.log.WithContext(ctx).Infof("ingress %s deleted successfully", ingressName)
 	return nil
@@ -578,10 +703,36 @@ func generateIngressName(instanceID string, port uint32) string {
 	return fmt.Sprintf("ingress-%s-%d", instanceID, port)
 }
 
+// ingressHostPath returns the Ingress host and path of the routing mode.
+// Path: {domain} + /{namespace}/{instanceID}/{port}
+// Host: {port}-{instanceID}.{domain} + /
+func ingressHostPath(opts biz.IngressOptions, namespace, instanceID string, port uint32) (string, string) {
+	if opts.Routing == biz.IngressRoutingHost {
+		return fmt.Sprintf("%d-%s.%s", port, instanceID, opts.Domain), "/"
+	}
+	return opts.Domain, fmt.Sprintf("/%s/%s/%d", namespace, instanceID, port)
+}
+
 // generateAccessURL generates the access URL for HTTP Ingress.
-// Format: http(s)://{domain}/{namespace}/{instanceID}/{port}
-func generateAccessURL(ingressDomain, namespace, instanceID string, port uint32) string {
-	return fmt.Sprintf("http://%s/%s/%s/%d", ingressDomain, namespace, instanceID, port)
+// Format: http(s)://{host}{path}, the trailing slash of host routing is omitted
+func generateAccessURL(opts biz.IngressOptions, host, path string) string {
+	scheme := "http"
+	if opts.TLS {
+		scheme = "https"
+	}
+	return scheme + "://" + host + strings.TrimSuffix(path, "/")
+}
+
+// tcpUDPConfigMapName returns the ingress-nginx ConfigMap that holds mappings of the protocol.
+func tcpUDPConfigMapName(protocol string) (string, error) {
+	switch protocol {
+	case "TCP":
+		return "tcp-services", nil
+	case "UDP":
+		return "udp-services", nil
+	default:
+		return "", fmt.Errorf("invalid protocol %s, must be TCP or UDP", protocol)
+	}
 }
 
 // getPodSelector returns the label selector for matching Pods.
@@ -597,8 +748,8 @@ func (r *k8sRepo) GetIngressDomain() string {
 	return r.ingressDomain
 }
 
-// GetIngressNginxLBIP returns the ingress-nginx LoadBalancer External IP.
-func (r *k8sRepo) GetIngressNginxLBIP(ctx context.Context) (string, error) {
+// getIngressNginxLBIP returns the ingress-nginx LoadBalancer External IP.
+func (r *k8sRepo) getIngressNginxLBIP(ctx context.Context) (string, error) {
 	svc, err := r.client.CoreV1().Services(r.ingressNginxNamespace).Get(ctx, r.ingressNginxLBService, metav1.GetOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to get ingress-nginx service: %w", err)
@@ -617,38 +768,129 @@ func (r *k8sRepo) GetIngressNginxLBIP(ctx context.Context) (string, error) {
 	return "", fmt.Errorf("ingress-nginx LoadBalancer has no external IP")
 }
 
-// allocateExternalPort allocates an external port from the port pool.
-// 简单实现：自增分配，不考虑回收和重用。
-func (r *k8sRepo) allocateExternalPort() uint32 {
-	if r.nextAvailablePort > r.tcpUDPPortRangeEnd {
-		r.log.Errorf("external port pool exhausted: %d > %d", r.nextAvailablePort, r.tcpUDPPortRangeEnd)
-		return 0
+// sharedExposureUpdateBackoff 修改共享的 ingress-nginx ConfigMap/Service 或 Gateway listeners 时的冲突重试策略。
+// 多个副本同时开放端口时每轮至少一个写入成功，重试次数需覆盖并发开放的端口数。
+var sharedExposureUpdateBackoff = wait.Backoff{
+	Steps:    20,
+	Duration: 10 * time.Millisecond,
+	Factor:   1.5,
+	Jitter:   0.5,
+	Cap:      time.Second,
+}
+
+// updateIngressNginxConfigMap 读取 ConfigMap 后调用 mutate 修改 Data 并写回，resourceVersion 冲突时重新读取重试。
+// mutate 返回 false 时不写回。写入依赖 apiserver 的乐观并发控制，因此在多个副本之间也是串行的。
+func (r *k8sRepo) updateIngressNginxConfigMap(ctx context.Context, configMapName string, mutate func(data map[string]string) (bool, error)) error {
+	configMaps := r.client.CoreV1().ConfigMaps(r.ingressNginxNamespace)
+	return retry.RetryOnConflict(sharedExposureUpdateBackoff, func() error {
+		cm, err := configMaps.Get(ctx, configMapName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		changed, err := mutate(cm.Data)
+		if err != nil || !changed {
+			return err
+		}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// updateIngressNginxService 读取 ingress-nginx Service 后调用 mutate 修改并写回，resourceVersion 冲突时重新读取重试。
+// mutate 返回 false 时不写回。
+func (r *k8sRepo) updateIngressNginxService(ctx context.Context, mutate func(svc *corev1.Service) bool) error {
+	services := r.client.CoreV1().Services(r.ingressNginxNamespace)
+	return retry.RetryOnConflict(sharedExposureUpdateBackoff, func() error {
+		svc, err := services.Get(ctx, r.ingressNginxLBService, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !mutate(svc) {
+			return nil
+		}
+		_, err = services.Update(ctx, svc, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// allocateTCPUDPConfigMapEntry 在端口范围内选择一个空闲的外部端口并写入 ConfigMap 条目，返回分配的端口。
+// 空闲端口从 tcp-services/udp-services 与 ingress-nginx Service 的现有端口中计算，选择与写入在同一次冲突重试中完成，
+// 多个副本或服务重启后都不会重复分配。TCP 与 UDP 同时分配时可能得到相同端口号，协议不同不会冲突。
+func (r *k8sRepo) allocateTCPUDPConfigMapEntry(ctx context.Context, configMapName, value string) (uint32, error) {
+	var externalPort uint32
+	err := r.updateIngressNginxConfigMap(ctx, configMapName, func(data map[string]string) (bool, error) {
+		used, err := r.usedIngressNginxPorts(ctx, configMapName)
+		if err != nil {
+			return false, err
+		}
+		for key := range data {
+			if p, err := strconv.ParseUint(key, 10, 32); err == nil {
+				used[uint32(p)] = true
+			}
+		}
+
+		for port := r.tcpUDPPortRangeStart; port <= r.tcpUDPPortRangeEnd; port++ {
+			if !used[port] {
+				externalPort = port
+				data[strconv.FormatUint(uint64(port), 10)] = value
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("external port pool exhausted: %d-%d", r.tcpUDPPortRangeStart, r.tcpUDPPortRangeEnd)
+	})
+	if err != nil {
+		return 0, err
 	}
-	port := r.nextAvailablePort
-	r.nextAvailablePort++
-	return port
+	return externalPort, nil
 }
 
-// patchIngressNginxConfigMap patches the ingress-nginx tcp-services or udp-services ConfigMap.
-func (r *k8sRepo) patchIngressNginxConfigMap(ctx context.Context, configMapName, key, value string) error {
-	r.log.WithContext(ctx).Infof("patching ConfigMap %s/%s: %s=%s", r.ingressNginxNamespace, configMapName, key, value)
+// usedIngressNginxPorts 返回 ingress-nginx Service 上的端口与另一协议 ConfigMap 中已映射的端口
+func (r *k8sRepo) usedIngressNginxPorts(ctx context.Context, configMapName string) (map[uint32]bool, error) {
+	used := map[uint32]bool{}
 
-	// 获取 ConfigMap
-	cm, err := r.client.CoreV1().ConfigMaps(r.ingressNginxNamespace).Get(ctx, configMapName, metav1.GetOptions{})
+	svc, err := r.client.CoreV1().Services(r.ingressNginxNamespace).Get(ctx, r.ingressNginxLBService, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to get ConfigMap %s: %w", configMapName, err)
+		return nil, fmt.Errorf("failed to get ingress-nginx Service: %w", err)
+	}
+	for _, p := range svc.Spec.Ports {
+		used[uint32(p.Port)] = true
 	}
 
-	// 初始化 Data 字段（如果为空）
-	if cm.Data == nil {
-		cm.Data = make(map[string]string)
+	for _, name := range []string{"tcp-services", "udp-services"} {
+		if name == configMapName {
+			continue
+		}
+		cm, err := r.client.CoreV1().ConfigMaps(r.ingressNginxNamespace).Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ConfigMap %s: %w", name, err)
+		}
+		for key := range cm.Data {
+			if p, err := strconv.ParseUint(key, 10, 32); err == nil {
+				used[uint32(p)] = true
+			}
+		}
 	}
+	return used, nil
+}
 
-	// 添加或更新条目
-	cm.Data[key] = value
+// patchIngressNginxConfigMap adds an entry to the ingress-nginx tcp-services or udp-services ConfigMap.
+// 已存在的条目保持不变，避免覆盖其他 Service 的映射。
+func (r *k8sRepo) patchIngressNginxConfigMap(ctx context.Context, configMapName, key, value string) error {
+	r.log.WithContext(ctx).Infof("patching ConfigMap %s/%s: %s=%s", r.ingressNginxNamespace, configMapName, key, value)
 
-	// 更新 ConfigMap
-	_, err = r.client.CoreV1().ConfigMaps(r.ingressNginxNamespace).Update(ctx, cm, metav1.UpdateOptions{})
+	err := r.updateIngressNginxConfigMap(ctx, configMapName, func(data map[string]string) (bool, error) {
+		if _, ok := data[key]; ok {
+			return false, nil
+		}
+		data[key] = value
+		return true, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update ConfigMap %s: %w", configMapName, err)
 	}
@@ -661,22 +903,6 @@ func (r *k8sRepo) patchIngressNginxConfigMap(ctx context.Context, configMapName,
 func (r *k8sRepo) patchIngressNginxServicePort(ctx context.Context, externalPort uint32, protocol string) error {
 	r.log.WithContext(ctx).Infof("patching ingress-nginx Service to add port %d (%s)", externalPort, protocol)
 
-	// 获取 ingress-nginx Service
-	svc, err := r.client.CoreV1().Services(r.ingressNginxNamespace).Get(ctx, r.ingressNginxLBService, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to get ingress-nginx Service: %w", err)
-	}
-
-	// 检查端口是否已存在
-	portName := fmt.Sprintf("%s-%d", strings.ToLower(protocol), externalPort)
-	for _, p := range svc.Spec.Ports {
-		if p.Port == int32(externalPort) && string(p.Protocol) == protocol {
-			r.log.WithContext(ctx).Infof("port %d already exists in ingress-nginx Service", externalPort)
-			return nil
-		}
-	}
-
-	// 添加新端口
 	var k8sProtocol corev1.Protocol
 	if protocol == "TCP" {
 		k8sProtocol = corev1.ProtocolTCP
@@ -684,17 +910,22 @@ func (r *k8sRepo) patchIngressNginxServicePort(ctx context.Context, externalPort
 		k8sProtocol = corev1.ProtocolUDP
 	}
 
-	newPort := corev1.ServicePort{
-		Name:       portName,
-		Protocol:   k8sProtocol,
-		Port:       int32(externalPort),
-		TargetPort: intstr.FromInt32(int32(externalPort)),
-	}
-
-	svc.Spec.Ports = append(svc.Spec.Ports, newPort)
-
-	// 更新 Service
-	_, err = r.client.CoreV1().Services(r.ingressNginxNamespace).Update(ctx, svc, metav1.UpdateOptions{})
+	err := r.updateIngressNginxService(ctx, func(svc *corev1.Service) bool {
+		// 检查端口是否已存在
+		for _, p := range svc.Spec.Ports {
+			if p.Port == int32(externalPort) && p.Protocol == k8sProtocol {
+				r.log.WithContext(ctx).Infof("port %d already exists in ingress-nginx Service", externalPort)
+				return false
+			}
+		}
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       fmt.Sprintf("%s-%d", strings.ToLower(protocol), externalPort),
+			Protocol:   k8sProtocol,
+			Port:       int32(externalPort),
+			TargetPort: intstr.FromInt32(int32(externalPort)),
+		})
+		return true
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update ingress-nginx Service: %w", err)
 	}
@@ -703,42 +934,30 @@ func (r *k8sRepo) patchIngressNginxServicePort(ctx context.Context, externalPort
 	return nil
 }
 
-// DeleteTCPUDPConfigMapEntry deletes a TCP/UDP ConfigMap entry and removes the port from ingress-nginx Service.
-func (r *k8sRepo) DeleteTCPUDPConfigMapEntry(ctx context.Context, protocol string, externalPort uint32) error {
-	var configMapName string
-	switch protocol {
-	case "TCP":
-		configMapName = "tcp-services"
-	case "UDP":
-		configMapName = "udp-services"
-	default:
-		return fmt.Errorf("invalid protocol %s, must be TCP or UDP", protocol)
+// deleteTCPUDPConfigMapEntry deletes a TCP/UDP ConfigMap entry and removes the port from ingress-nginx Service.
+func (r *k8sRepo) deleteTCPUDPConfigMapEntry(ctx context.Context, protocol string, externalPort uint32) error {
+	configMapName, err := tcpUDPConfigMapName(protocol)
+	if err != nil {
+		return err
 	}
 
 	r.log.WithContext(ctx).Infof("deleting ConfigMap entry %s/%s: %d", r.ingressNginxNamespace, configMapName, externalPort)
 
 	// 1. 删除 ConfigMap 条目
-	cm, err := r.client.CoreV1().ConfigMaps(r.ingressNginxNamespace).Get(ctx, configMapName, metav1.GetOptions{})
+	key := strconv.FormatUint(uint64(externalPort), 10)
+	err = r.updateIngressNginxConfigMap(ctx, configMapName, func(data map[string]string) (bool, error) {
+		if _, ok := data[key]; !ok {
+			return false, nil
+		}
+		delete(data, key)
+		return true, nil
+	})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			r.log.WithContext(ctx).Infof("ConfigMap %s not found, already deleted", configMapName)
 			return nil
 		}
-		return fmt.Errorf("failed to get ConfigMap %s: %w", configMapName, err)
-	}
-
-	// 删除条目
-	if cm.Data != nil {
-		key := fmt.Sprintf("%d", externalPort)
-		delete(cm.Data, key)
-
-		// 更新 ConfigMap
-		_, err = r.client.CoreV1().ConfigMaps(r.ingressNginxNamespace).Update(ctx, cm, metav1.UpdateOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to update ConfigMap %s: %w", configMapName, err)
-		}
-
-		r.log.WithContext(ctx).Infof("ConfigMap entry deleted successfully")
+		return fmt.Errorf("failed to update ConfigMap %s: %w", configMapName, err)
 	}
 
 	// 2. 从 ingress-nginx Service 删除端口
@@ -755,32 +974,22 @@ func (r *k8sRepo) DeleteTCPUDPConfigMapEntry(ctx context.Context, protocol strin
 func (r *k8sRepo) removeIngressNginxServicePort(ctx context.Context, externalPort uint32, protocol string) error {
 	r.log.WithContext(ctx).Infof("removing port %d (%s) from ingress-nginx Service", externalPort, protocol)
 
-	// 获取 ingress-nginx Service
-	svc, err := r.client.CoreV1().Services(r.ingressNginxNamespace).Get(ctx, r.ingressNginxLBService, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to get ingress-nginx Service: %w", err)
-	}
-
-	// 查找并删除端口
-	newPorts := []corev1.ServicePort{}
-	found := false
-	for _, p := range svc.Spec.Ports {
-		if p.Port == int32(externalPort) && string(p.Protocol) == protocol {
-			found = true
-			r.log.WithContext(ctx).Infof("found port %d to remove", externalPort)
-			continue // 跳过这个端口，不添加到 newPorts
+	err := r.updateIngressNginxService(ctx, func(svc *corev1.Service) bool {
+		// 查找并删除端口
+		newPorts := make([]corev1.ServicePort, 0, len(svc.Spec.Ports))
+		for _, p := range svc.Spec.Ports {
+			if p.Port == int32(externalPort) && string(p.Protocol) == protocol {
+				continue // 跳过这个端口，不添加到 newPorts
+			}
+			newPorts = append(newPorts, p)
 		}
-		newPorts = append(newPorts, p)
-	}
-
-	if !found {
-		r.log.WithContext(ctx).Infof("port %d not found in ingress-nginx Service", externalPort)
-		return nil
-	}
-
-	// 更新 Service
-	svc.Spec.Ports = newPorts
-	_, err = r.client.CoreV1().Services(r.ingressNginxNamespace).Update(ctx, svc, metav1.UpdateOptions{})
+		if len(newPorts) == len(svc.Spec.Ports) {
+			r.log.WithContext(ctx).Infof("port %d not found in ingress-nginx Service", externalPort)
+			return false
+		}
+		svc.Spec.Ports = newPorts
+		return true
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update ingress-nginx Service: %w", err)
 	}
@@ -0,0 +1,44 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"resource/internal/biz"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestK8sRepo_BandwidthAnnotations(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+
+	spec := biz.InstanceSpec{InstanceID: 7, UserID: "carol", CPU: 1, Memory: 512, Image: "ubuntu:22.04", IngressBandwidth: 100, EgressBandwidth: 20}
+	if err := repo.CreateInstance(ctx, spec); err != nil {
+		t.Fatal(err)
+	}
+	deployment, err := repo.client.AppsV1().Deployments("carol").Get(ctx, "7", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotations := deployment.Spec.Template.Annotations
+	if annotations[annotationIngressBandwidth] != "100M" || annotations[annotationEgressBandwidth] != "20M" {
+		t.Fatalf("annotations=%v", annotations)
+	}
+
+	// 0 表示取消限制，其他注解保持不变
+	deployment.Spec.Template.Annotations["example.com/keep"] = "yes"
+	if _, err := repo.client.AppsV1().Deployments("carol").Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	spec.IngressBandwidth = 0
+	spec.EgressBandwidth = 50
+	if err := repo.UpdateInstance(ctx, spec); err != nil {
+		t.Fatal(err)
+	}
+	deployment, _ = repo.client.AppsV1().Deployments("carol").Get(ctx, "7", metav1.GetOptions{})
+	annotations = deployment.Spec.Template.Annotations
+	if _, ok := annotations[annotationIngressBandwidth]; ok || annotations[annotationEgressBandwidth] != "50M" || annotations["example.com/keep"] != "yes" {
+		t.Fatalf("annotations=%v", annotations)
+	}
+}
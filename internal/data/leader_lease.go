@@ -0,0 +1,62 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// 选主租约参数：持有者失联后最多 leaderLeaseDuration 由其他副本接管
+const (
+	leaderLeaseDuration = 30 * time.Second
+	leaderRenewDeadline = 20 * time.Second
+	leaderRetryPeriod   = 5 * time.Second
+)
+
+// RunAsLeader 通过 namespace/name 的 Lease 在副本间选主，阻塞到 ctx 取消。
+// 持有租约期间执行 run，失去租约时取消 run 的 ctx 并重新参与选举；ctx 取消时主动释放租约。
+func (r *k8sRepo) RunAsLeader(ctx context.Context, namespace, name string, run func(ctx context.Context)) error {
+	if r.client == nil {
+		return fmt.Errorf("k8s client is not initialized")
+	}
+	hostname, _ := os.Hostname()
+	identity := hostname + "_" + rand.String(8)
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Client:     r.client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaderLeaseDuration,
+		RenewDeadline:   leaderRenewDeadline,
+		RetryPeriod:     leaderRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				r.log.WithContext(ctx).Infof("acquired lease %s/%s as %s", namespace, name, identity)
+				run(ctx)
+			},
+			OnStoppedLeading: func() {
+				r.log.Infof("stopped leading lease %s/%s as %s", namespace, name, identity)
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leader elector: %w", err)
+	}
+
+	// Run 在失去租约后返回，ctx 未取消时继续参与选举
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunAsLeader_SingleLeader(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	replica := *repo
+
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.RunAsLeader(leaderCtx, "resource", "reconcile", func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+		})
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first replica did not acquire the lease")
+	}
+
+	// 租约被持有时另一副本不执行
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	ran := false
+	if err := replica.RunAsLeader(ctx, "resource", "reconcile", func(context.Context) { ran = true }); err != nil {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Fatal("second replica ran while the lease was held")
+	}
+
+	// 停止时释放租约
+	stopLeader()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	lease, err := repo.client.CoordinationV1().Leases("resource").Get(context.Background(), "reconcile", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		t.Fatalf("lease still held by %s", *lease.Spec.HolderIdentity)
+	}
+}
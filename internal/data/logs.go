@@ -0,0 +1,122 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StreamLogs 通过 pods/log 子资源读取容器日志，按行回调
+func (r *execRepo) StreamLogs(ctx context.Context, opts biz.LogOptions, handle func(biz.LogLine) error) error {
+	pods, err := r.listPods(ctx, opts.Namespace, opts.InstanceID)
+	if err != nil {
+		r.log.Errorf("failed to list pods: %v", err)
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	pod, err := selectLogPod(pods, opts.PodName)
+	if err != nil {
+		return err
+	}
+	container, err := selectExecContainer(pod, opts.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w: %v", biz.ErrPodNotFound, err)
+	}
+
+	logOpts := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     opts.Follow,
+		TailLines:  opts.TailLines,
+		Previous:   opts.Previous,
+		Timestamps: opts.Timestamps,
+	}
+	if opts.SinceTime != nil {
+		since := metav1.NewTime(*opts.SinceTime)
+		logOpts.SinceTime = &since
+	}
+
+	stream, err := r.client.CoreV1().Pods(opts.Namespace).GetLogs(pod.Name, logOpts).Stream(ctx)
+	if err != nil {
+		if apierrors.IsBadRequest(err) || apierrors.IsNotFound(err) {
+			// 例如 previous 请求的容器尚未重启过，或容器还未启动
+			return fmt.Errorf("%w: %v", biz.ErrLogsUnavailable, err)
+		}
+		r.log.Errorf("failed to stream logs of pod %s: %v", pod.Name, err)
+		return err
+	}
+	defer stream.Close()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			entry := biz.LogLine{
+				PodName:       pod.Name,
+				ContainerName: container,
+				Line:          strings.TrimSuffix(line, "\n"),
+			}
+			if opts.Timestamps {
+				entry.Timestamp, entry.Line = splitLogTimestamp(entry.Line)
+			}
+			if herr := handle(entry); herr != nil {
+				return herr
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// selectLogPod 选择日志来源 Pod：指定名称时校验其属于实例；否则依次选择最新的 Ready Pod、
+// 最新的未删除 Pod（如 CrashLoopBackOff 中的 Pod）、最新的 Pod
+func selectLogPod(pods []corev1.Pod, podName string) (*corev1.Pod, error) {
+	if podName != "" {
+		for i := range pods {
+			if pods[i].Name == podName {
+				return &pods[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", biz.ErrPodNotFound, podName)
+	}
+	if len(pods) == 0 {
+		return nil, biz.ErrPodNotFound
+	}
+
+	for i := range pods {
+		if pods[i].DeletionTimestamp == nil && isPodReady(&pods[i]) {
+			return &pods[i], nil
+		}
+	}
+	for i := range pods {
+		if pods[i].DeletionTimestamp == nil {
+			return &pods[i], nil
+		}
+	}
+	return &pods[0], nil
+}
+
+// splitLogTimestamp 拆分 kubelet 添加的 RFC3339 时间戳前缀
+func splitLogTimestamp(line string) (time.Time, string) {
+	prefix, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		prefix, rest = line, ""
+	}
+	ts, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return time.Time{}, line
+	}
+	return ts, rest
+}
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestExecRepo_StreamLogs(t *testing.T) {
+	repo := newTestExecRepo(nil, nil, instancePod())
+
+	var lines []biz.LogLine
+	err := repo.StreamLogs(context.Background(), biz.LogOptions{Namespace: "alice", InstanceID: "1"}, func(l biz.LogLine) error {
+		lines = append(lines, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fake clientset 的日志内容固定为 "fake logs"
+	if len(lines) != 1 || lines[0].Line != "fake logs" || lines[0].PodName != "instance-1-abc" || lines[0].ContainerName != "1" {
+		t.Fatalf("lines=%+v", lines)
+	}
+
+	err = repo.StreamLogs(context.Background(), biz.LogOptions{Namespace: "alice", InstanceID: "1", ContainerName: "missing"}, func(biz.LogLine) error { return nil })
+	if !errors.Is(err, biz.ErrPodNotFound) {
+		t.Fatalf("err=%v want ErrPodNotFound", err)
+	}
+}
+
+func TestSelectLogPod(t *testing.T) {
+	now := time.Now()
+	ready := newPod("ready", now.Add(-time.Hour), true, false)
+	crashing := newPod("crashing", now, false, false)
+	terminating := newPod("terminating", now.Add(time.Minute), true, true)
+
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		podName string
+		want    string
+		wantErr bool
+	}{
+		{name: "prefer_ready", pods: []corev1.Pod{terminating, crashing, ready}, want: "ready"},
+		{name: "fallback_not_ready", pods: []corev1.Pod{terminating, crashing}, want: "crashing"},
+		{name: "fallback_terminating", pods: []corev1.Pod{terminating}, want: "terminating"},
+		{name: "explicit_terminating_pod", pods: []corev1.Pod{terminating, ready}, podName: "terminating", want: "terminating"},
+		{name: "explicit_unknown_pod", pods: []corev1.Pod{ready}, podName: "other", wantErr: true},
+		{name: "no_pods", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod, err := selectLogPod(tt.pods, tt.podName)
+			if tt.wantErr {
+				if !errors.Is(err, biz.ErrPodNotFound) {
+					t.Fatalf("err=%v want ErrPodNotFound", err)
+				}
+				return
+			}
+			if err != nil || pod.Name != tt.want {
+				t.Fatalf("pod=%v err=%v want %s", pod, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLogTimestamp(t *testing.T) {
+	ts, line := splitLogTimestamp("2025-01-02T03:04:05.123456789Z server started")
+	if line != "server started" || ts.Nanosecond() != 123456789 || ts.Year() != 2025 {
+		t.Fatalf("ts=%v line=%q", ts, line)
+	}
+
+	ts, line = splitLogTimestamp("no timestamp here")
+	if !ts.IsZero() || line != "no timestamp here" {
+		t.Fatalf("ts=%v line=%q", ts, line)
+	}
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"resource/internal/biz"
@@ -25,37 +26,92 @@ func NewNetworkRepo(data *Data, logger log.Logger) biz.NetworkRepo {
 }
 
 // instanceNetwork 实例网络配置表，记录端口暴露信息
-// 支持两种暴露模式：
+// 支持以下暴露模式：
 //  1. TCP/UDP: 通过 ClusterIP Service + ingress-nginx ConfigMap 暴露，使用 ExternalPort
 //  2. HTTP: 通过 ClusterIP Service + Ingress 暴露，使用 IngressName
+//  3. NODEPORT/LOADBALANCER: 通过对应类型的 Service 暴露，使用 NodePort/ExternalIP
 type instanceNetwork struct {
-	InstanceID   int64     `gorm:"primaryKey;column:instance_id;not null"` // 实例ID
-	Port         uint32    `gorm:"primaryKey;column:port;not null"`        // 容器端口 (targetPort)
-	ServiceName  string    `gorm:"column:service_name;size:64;not null"`   // K8s Service 名称
-	ServicePort  uint32    `gorm:"column:service_port;not null"`           // Service 暴露的端口
-	ExternalPort *uint32   `gorm:"column:external_port"`                   // TCP/UDP 模式的外部端口（ConfigMap key）
-	IngressName  *string   `gorm:"column:ingress_name;size:64"`            // HTTP 模式的 Ingress 名称
-	Protocol     string    `gorm:"column:protocol;default:'HTTP'"`         // TCP/UDP/HTTP
-	AccessURL    string    `gorm:"column:access_url;not null"`             // 最终访问地址
-	Enabled      bool      `gorm:"column:enabled;default:true"`            // 是否启用
-	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt    time.Time `gorm:"column:updated_at;autoUpdateTime"`
+	InstanceID    int64     `gorm:"primaryKey;column:instance_id;not null"` // 实例ID
+	Port          uint32    `gorm:"primaryKey;column:port;not null"`        // 容器端口 (targetPort)
+	ServiceName   string    `gorm:"column:service_name;size:64;not null"`   // K8s Service 名称
+	ServicePort   uint32    `gorm:"column:service_port;not null"`           // Service 暴露的端口
+	ExternalPort  *uint32   `gorm:"column:external_port"`                   // TCP/UDP 模式的外部端口（ConfigMap key）
+	IngressName   *string   `gorm:"column:ingress_name;size:64"`            // HTTP 模式的 Ingress 名称
+	Protocol      string    `gorm:"column:protocol;default:'HTTP'"`         // TCP/UDP/HTTP/NODEPORT/LOADBALANCER
+	AccessURL     string    `gorm:"column:access_url;not null"`             // 最终访问地址
+	Enabled       bool      `gorm:"column:enabled;default:true"`            // 是否启用
+	Active        bool      `gorm:"column:active;default:true"`             // 实例是否运行中，随实例启停更新
+	IngressDomain string    `gorm:"column:ingress_domain;size:255"`         // HTTP 模式的 Ingress 域名
+	Routing       string    `gorm:"column:routing;size:8"`                  // HTTP 模式的路由方式：PATH/HOST
+	TLS           bool      `gorm:"column:tls;default:false"`               // HTTP 模式是否启用 HTTPS
+	Protection    []byte    `gorm:"column:protection"`                      // HTTP 模式的访问控制（JSON，不含密码）
+	Transport     string    `gorm:"column:transport;size:8"`                // NODEPORT/LOADBALANCER 模式的传输协议：TCP/UDP
+	NodePort      *uint32   `gorm:"column:node_port"`                       // NODEPORT/LOADBALANCER 模式分配的节点端口
+	ExternalIP    string    `gorm:"column:external_ip;size:255"`            // LOADBALANCER 模式分配的外部 IP 或主机名
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;autoUpdateTime"`
 }
 
 func (instanceNetwork) TableName() string { return "instance_network" }
 
+// ingressProtection instance_network.protection 列的 JSON 结构，basic auth 密码只保存在 Secret 中
+type ingressProtection struct {
+	BasicAuthUser  string   `json:"basic_auth_username,omitempty"`
+	AllowedCIDRs   []string `json:"allowed_cidrs,omitempty"`
+	RateLimitRPS   uint32   `json:"rate_limit_rps,omitempty"`
+	RateLimitRPM   uint32   `json:"rate_limit_rpm,omitempty"`
+	MaxConnections uint32   `json:"max_connections,omitempty"`
+}
+
+// marshalProtection 未设置访问控制时返回 nil
+func marshalProtection(p biz.IngressProtection) []byte {
+	if !p.Enabled() {
+		return nil
+	}
+	data, _ := json.Marshal(ingressProtection{
+		BasicAuthUser:  p.BasicAuthUser,
+		AllowedCIDRs:   p.AllowedCIDRs,
+		RateLimitRPS:   p.RateLimitRPS,
+		RateLimitRPM:   p.RateLimitRPM,
+		MaxConnections: p.MaxConnections,
+	})
+	return data
+}
+
+func unmarshalProtection(data []byte) biz.IngressProtection {
+	var p ingressProtection
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &p)
+	}
+	return biz.IngressProtection{
+		BasicAuthUser:  p.BasicAuthUser,
+		AllowedCIDRs:   p.AllowedCIDRs,
+		RateLimitRPS:   p.RateLimitRPS,
+		RateLimitRPM:   p.RateLimitRPM,
+		MaxConnections: p.MaxConnections,
+	}
+}
+
 // CreateNetworkBinding 创建端口绑定记录
 func (r *networkRepo) CreateNetworkBinding(ctx context.Context, binding biz.NetworkBinding) error {
 	network := &instanceNetwork{
-		InstanceID:   binding.InstanceID,
-		Port:         binding.Port,
-		ServiceName:  binding.ServiceName,
-		ServicePort:  binding.ServicePort,
-		ExternalPort: binding.ExternalPort,
-		IngressName:  binding.IngressName,
-		Protocol:     binding.Protocol,
-		AccessURL:    binding.AccessURL,
-		Enabled:      binding.Enabled,
+		InstanceID:    binding.InstanceID,
+		Port:          binding.Port,
+		ServiceName:   binding.ServiceName,
+		ServicePort:   binding.ServicePort,
+		ExternalPort:  binding.ExternalPort,
+		IngressName:   binding.IngressName,
+		Protocol:      binding.Protocol,
+		AccessURL:     binding.AccessURL,
+		Enabled:       binding.Enabled,
+		Active:        binding.Active,
+		IngressDomain: binding.Ingress.Domain,
+		Routing:       binding.Ingress.Routing,
+		TLS:           binding.Ingress.TLS,
+		Protection:    marshalProtection(binding.Ingress.Protection),
+		Transport:     binding.Transport,
+		NodePort:      binding.NodePort,
+		ExternalIP:    binding.ExternalIP,
 	}
 
 	if err := r.data.db.WithContext(ctx).Create(network).Error; err != nil {
@@ -66,17 +122,24 @@ func (r *networkRepo) CreateNetworkBinding(ctx context.Context, binding biz.Netw
 	return nil
 }
 
-// UpdateNetworkBinding 更新端口绑定记录
+// UpdateNetworkBinding 更新端口绑定记录，active 由 SetNetworkBindingsActive 维护
 func (r *networkRepo) UpdateNetworkBinding(ctx context.Context, binding biz.NetworkBinding) error {
 	updates := map[string]interface{}{
-		"service_name":  binding.ServiceName,
-		"service_port":  binding.ServicePort,
-		"external_port": binding.ExternalPort,
-		"ingress_name":  binding.IngressName,
-		"protocol":      binding.Protocol,
-		"access_url":    binding.AccessURL,
-		"enabled":       binding.Enabled,
-		"updated_at":    time.Now(),
+		"service_name":   binding.ServiceName,
+		"service_port":   binding.ServicePort,
+		"external_port":  binding.ExternalPort,
+		"ingress_name":   binding.IngressName,
+		"protocol":       binding.Protocol,
+		"access_url":     binding.AccessURL,
+		"enabled":        binding.Enabled,
+		"ingress_domain": binding.Ingress.Domain,
+		"routing":        binding.Ingress.Routing,
+		"tls":            binding.Ingress.TLS,
+		"protection":     marshalProtection(binding.Ingress.Protection),
+		"transport":      binding.Transport,
+		"node_port":      binding.NodePort,
+		"external_ip":    binding.ExternalIP,
+		"updated_at":     time.Now(),
 	}
 
 	result := r.data.db.WithContext(ctx).
@@ -96,6 +159,26 @@ func (r *networkRepo) UpdateNetworkBinding(ctx context.Context, binding biz.Netw
 	return nil
 }
 
+// UpdateAccessURL 只更新访问地址与外部地址，以旧值为条件，避免覆盖并发修改的其他字段
+func (r *networkRepo) UpdateAccessURL(ctx context.Context, binding biz.NetworkBinding, accessURL, externalIP string) error {
+	result := r.data.db.WithContext(ctx).
+		Model(&instanceNetwork{}).
+		Where("instance_id = ? AND port = ? AND access_url = ? AND external_ip = ?",
+			binding.InstanceID, binding.Port, binding.AccessURL, binding.ExternalIP).
+		Updates(map[string]interface{}{
+			"access_url":  accessURL,
+			"external_ip": externalIP,
+			"updated_at":  time.Now(),
+		})
+
+	if result.Error != nil {
+		r.log.Errorf("failed to update access url: %v", result.Error)
+		return result.Error
+	}
+
+	return nil
+}
+
 // DeleteNetworkBinding 删除端口绑定记录（物理删除）
 func (r *networkRepo) DeleteNetworkBinding(ctx context.Context, instanceID int64, port uint32) error {
 	result := r.data.db.WithContext(ctx).
@@ -129,19 +212,8 @@ func (r *networkRepo) GetNetworkBinding(ctx context.Context, instanceID int64, p
 		return nil, err
 	}
 
-	return &biz.NetworkBinding{
-		InstanceID:   network.InstanceID,
-		Port:         network.Port,
-		ServiceName:  network.ServiceName,
-		ServicePort:  network.ServicePort,
-		ExternalPort: network.ExternalPort,
-		IngressName:  network.IngressName,
-		Protocol:     network.Protocol,
-		AccessURL:    network.AccessURL,
-		Enabled:      network.Enabled,
-		CreatedAt:    network.CreatedAt,
-		UpdatedAt:    network.UpdatedAt,
-	}, nil
+	binding := toNetworkBindings([]instanceNetwork{network})[0]
+	return &binding, nil
 }
 
 // ListNetworkBindings 列出实例的所有端口绑定
@@ -157,6 +229,45 @@ func (r *networkRepo) ListNetworkBindings(ctx context.Context, instanceID int64)
 		return nil, err
 	}
 
+	return toNetworkBindings(networks), nil
+}
+
+// ListNetworkBindingsByInstances 批量列出多个实例的端口绑定
+func (r *networkRepo) ListNetworkBindingsByInstances(ctx context.Context, instanceIDs []int64) ([]biz.NetworkBinding, error) {
+	if len(instanceIDs) == 0 {
+		return nil, nil
+	}
+
+	var networks []instanceNetwork
+	err := r.data.db.WithContext(ctx).
+		Where("instance_id IN ?", instanceIDs).
+		Order("instance_id ASC, port ASC").
+		Find(&networks).Error
+
+	if err != nil {
+		r.log.Errorf("failed to list network bindings: %v", err)
+		return nil, err
+	}
+
+	return toNetworkBindings(networks), nil
+}
+
+// ListAllNetworkBindings 列出全部实例的端口绑定，供网络核对使用
+func (r *networkRepo) ListAllNetworkBindings(ctx context.Context) ([]biz.NetworkBinding, error) {
+	var networks []instanceNetwork
+	err := r.data.db.WithContext(ctx).
+		Order("instance_id ASC, port ASC").
+		Find(&networks).Error
+
+	if err != nil {
+		r.log.Errorf("failed to list all network bindings: %v", err)
+		return nil, err
+	}
+
+	return toNetworkBindings(networks), nil
+}
+
+func toNetworkBindings(networks []instanceNetwork) []biz.NetworkBinding {
 	bindings := make([]biz.NetworkBinding, 0, len(networks))
 	for _, network := range networks {
 		bindings = append(bindings, biz.NetworkBinding{
@@ -169,12 +280,21 @@ func (r *networkRepo) ListNetworkBindings(ctx context.Context, instanceID int64)
 			Protocol:     network.Protocol,
 			AccessURL:    network.AccessURL,
 			Enabled:      network.Enabled,
-			CreatedAt:    network.CreatedAt,
-			UpdatedAt:    network.UpdatedAt,
+			Active:       network.Active,
+			Ingress: biz.IngressOptions{
+				Domain:     network.IngressDomain,
+				Routing:    network.Routing,
+				TLS:        network.TLS,
+				Protection: unmarshalProtection(network.Protection),
+			},
+			Transport:  network.Transport,
+			NodePort:   network.NodePort,
+			ExternalIP: network.ExternalIP,
+			CreatedAt:  network.CreatedAt,
+			UpdatedAt:  network.UpdatedAt,
 		})
 	}
-
-	return bindings, nil
+	return bindings
 }
 
 // BatchDeleteNetworkBindings 批量删除实例的所有端口绑定
@@ -190,3 +310,18 @@ func (r *networkRepo) BatchDeleteNetworkBindings(ctx context.Context, instanceID
 
 	return nil
 }
+
+// SetNetworkBindingsActive 批量更新实例所有端口绑定的 active 状态
+func (r *networkRepo) SetNetworkBindingsActive(ctx context.Context, instanceID int64, active bool) error {
+	result := r.data.db.WithContext(ctx).
+		Model(&instanceNetwork{}).
+		Where("instance_id = ?", instanceID).
+		Updates(map[string]interface{}{"active": active, "updated_at": time.Now()})
+
+	if result.Error != nil {
+		r.log.Errorf("failed to set network bindings active: %v", result.Error)
+		return result.Error
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/retry"
+)
+
+// 用户命名空间的默认 NetworkPolicy：拒绝全部流量，再放开同命名空间、ingress 控制器与集群 DNS
+const (
+	networkPolicyDefaultDeny       = "default-deny"
+	networkPolicySameNamespace     = "allow-same-namespace"
+	networkPolicyIngressController = "allow-ingress-controller"
+	networkPolicyDNS               = "allow-dns"
+)
+
+// namespaceNameLabel Kubernetes 自动为每个命名空间添加的名称标签
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// networkRulePolicyName 网络规则对应的 NetworkPolicy 名称，格式：rule-{ruleID}
+func networkRulePolicyName(ruleID int64) string {
+	return "rule-" + strconv.FormatInt(ruleID, 10)
+}
+
+func newNetworkPolicy(namespace, name string, spec networkingv1.NetworkPolicySpec) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"managed-by": "resource-service",
+			},
+		},
+		Spec: spec,
+	}
+}
+
+func namespacePeer(namespace string) networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: namespace}},
+	}
+}
+
+func policyPort(protocol corev1.Protocol, port uint32) networkingv1.NetworkPolicyPort {
+	p := intstr.FromInt32(int32(port))
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &p}
+}
+
+// baselineNetworkPolicies 用户命名空间的默认隔离策略，controllerNamespace 为暴露后端的控制器所在命名空间。
+// 默认只隔离入站流量；denyEgress 时同时拒绝出站，只放开同命名空间与集群 DNS。
+// nodeCIDRs 为节点网段，hostNetwork 的控制器与 kubelet 以节点地址访问实例，不属于任何命名空间
+func baselineNetworkPolicies(namespace, controllerNamespace string, denyEgress bool, nodeCIDRs []string) []*networkingv1.NetworkPolicy {
+	all := metav1.LabelSelector{}
+	types := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	if denyEgress {
+		types = append(types, networkingv1.PolicyTypeEgress)
+	}
+	samePods := []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}
+
+	sameNamespace := networkingv1.NetworkPolicySpec{
+		PodSelector: all,
+		PolicyTypes: types,
+		Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: samePods}},
+	}
+	if denyEgress {
+		sameNamespace.Egress = []networkingv1.NetworkPolicyEgressRule{{To: samePods}}
+	}
+
+	controllerPeers := []networkingv1.NetworkPolicyPeer{namespacePeer(controllerNamespace)}
+	for _, cidr := range nodeCIDRs {
+		controllerPeers = append(controllerPeers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+	}
+
+	policies := []*networkingv1.NetworkPolicy{
+		newNetworkPolicy(namespace, networkPolicyDefaultDeny, networkingv1.NetworkPolicySpec{
+			PodSelector: all,
+			PolicyTypes: types,
+		}),
+		newNetworkPolicy(namespace, networkPolicySameNamespace, sameNamespace),
+		newNetworkPolicy(namespace, networkPolicyIngressController, networkingv1.NetworkPolicySpec{
+			PodSelector: all,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: controllerPeers}},
+		}),
+	}
+	if denyEgress {
+		policies = append(policies, newNetworkPolicy(namespace, networkPolicyDNS, networkingv1.NetworkPolicySpec{
+			PodSelector: all,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				To:    []networkingv1.NetworkPolicyPeer{namespacePeer(metav1.NamespaceSystem)},
+				Ports: []networkingv1.NetworkPolicyPort{policyPort(corev1.ProtocolUDP, 53), policyPort(corev1.ProtocolTCP, 53)},
+			}},
+		}))
+	}
+	return policies
+}
+
+// newNetworkRulePolicy 把网络规则转换为 NetworkPolicy，作用于命名空间内全部 Pod
+func newNetworkRulePolicy(namespace string, rule biz.NetworkRule) *networkingv1.NetworkPolicy {
+	var peer networkingv1.NetworkPolicyPeer
+	if rule.PeerUserID != "" {
+		peer = namespacePeer(rule.PeerUserID)
+	} else {
+		peer = networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: rule.CIDR}}
+	}
+
+	protocol := corev1.ProtocolTCP
+	if rule.Protocol == "UDP" {
+		protocol = corev1.ProtocolUDP
+	}
+	var ports []networkingv1.NetworkPolicyPort
+	for _, port := range rule.Ports {
+		ports = append(ports, policyPort(protocol, port))
+	}
+
+	spec := networkingv1.NetworkPolicySpec{PodSelector: metav1.LabelSelector{}}
+	if rule.Direction == biz.NetworkRuleEgress {
+		spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}
+		spec.Egress = []networkingv1.NetworkPolicyEgressRule{{To: []networkingv1.NetworkPolicyPeer{peer}, Ports: ports}}
+	} else {
+		spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+		spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{peer}, Ports: ports}}
+	}
+
+	policy := newNetworkPolicy(namespace, networkRulePolicyName(rule.ID), spec)
+	policy.Labels["network-rule-id"] = strconv.FormatInt(rule.ID, 10)
+	return policy
+}
+
+// newDirectPortPolicy 允许集群外访问 NODEPORT/LOADBALANCER 端口，名称与 Service 相同
+func newDirectPortPolicy(namespace, instanceID, serviceName string, port uint32, protocol corev1.Protocol) *networkingv1.NetworkPolicy {
+	policy := newNetworkPolicy(namespace, serviceName, networkingv1.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{MatchLabels: getPodSelector(instanceID)},
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		Ingress: []networkingv1.NetworkPolicyIngressRule{{
+			From: []networkingv1.NetworkPolicyPeer{
+				{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}},
+				{IPBlock: &networkingv1.IPBlock{CIDR: "::/0"}},
+			},
+			Ports: []networkingv1.NetworkPolicyPort{policyPort(protocol, port)},
+		}},
+	})
+	policy.Labels["instance-id"] = instanceID
+	return policy
+}
+
+// applyNetworkPolicy 创建 NetworkPolicy，已存在时按期望状态更新；不覆盖非本服务管理的同名策略
+func (r *k8sRepo) applyNetworkPolicy(ctx context.Context, policy *networkingv1.NetworkPolicy) error {
+	policies := r.client.NetworkingV1().NetworkPolicies(policy.Namespace)
+
+	_, err := policies.Create(ctx, policy, metav1.CreateOptions{})
+	if err == nil {
+		r.log.WithContext(ctx).Infof("network policy %s/%s created", policy.Namespace, policy.Name)
+		return nil
+	}
+	if !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create network policy %s/%s: %w", policy.Namespace, policy.Name, err)
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := policies.Get(ctx, policy.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if existing.Labels["managed-by"] != "resource-service" {
+			return fmt.Errorf("network policy already exists and is not managed by resource-service")
+		}
+		if equality.Semantic.DeepEqual(existing.Spec, policy.Spec) && equality.Semantic.DeepEqual(existing.Labels, policy.Labels) {
+			return nil
+		}
+		existing.Labels = policy.Labels
+		existing.Spec = policy.Spec
+		_, err = policies.Update(ctx, existing, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update network policy %s/%s: %w", policy.Namespace, policy.Name, err)
+	}
+	return nil
+}
+
+// deleteNetworkPolicy 删除 NetworkPolicy，不存在时返回 nil
+func (r *k8sRepo) deleteNetworkPolicy(ctx context.Context, namespace, name string) error {
+	err := r.client.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete network policy %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// ensureNetworkPolicies 确保用户命名空间的默认隔离策略存在且与配置一致
+func (r *k8sRepo) ensureNetworkPolicies(ctx context.Context, namespace string) error {
+	if r.disableNetworkPolicy {
+		return nil
+	}
+	for _, policy := range baselineNetworkPolicies(namespace, r.exposure.ControllerNamespace(), r.networkPolicyEgress, r.networkPolicyNodes) {
+		if err := r.applyNetworkPolicy(ctx, policy); err != nil {
+			return err
+		}
+	}
+	if !r.networkPolicyEgress {
+		// 任何选中 Pod 的 Egress 策略都会隔离出站流量，关闭出站隔离后删除此前创建的 DNS 策略
+		existing, err := r.client.NetworkingV1().NetworkPolicies(namespace).Get(ctx, networkPolicyDNS, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get network policy %s/%s: %w", namespace, networkPolicyDNS, err)
+		}
+		if existing.Labels["managed-by"] == "resource-service" {
+			return r.deleteNetworkPolicy(ctx, namespace, networkPolicyDNS)
+		}
+	}
+	return nil
+}
+
+// EnsureNetworkPolicies ensures the default isolation policies of an existing user namespace when
+// network_policy_backfill is enabled. Returns nil if the namespace doesn't exist, it is created with
+// its policies on the next instance creation.
+func (r *k8sRepo) EnsureNetworkPolicies(ctx context.Context, namespace string) error {
+	if r.disableNetworkPolicy || !r.networkPolicyBackfill {
+		return nil
+	}
+	_, err := r.client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.ensureNetworkPolicies(ctx, namespace)
+}
+
+// ensureDirectPortPolicy 为 NODEPORT/LOADBALANCER 端口放开集群外的入站流量
+func (r *k8sRepo) ensureDirectPortPolicy(ctx context.Context, namespace, instanceID, serviceName string, port uint32, protocol corev1.Protocol) error {
+	if r.disableNetworkPolicy {
+		return nil
+	}
+	return r.applyNetworkPolicy(ctx, newDirectPortPolicy(namespace, instanceID, serviceName, port, protocol))
+}
+
+// ApplyNetworkRule creates or replaces the NetworkPolicy of a network rule, creating the user namespace if needed.
+func (r *k8sRepo) ApplyNetworkRule(ctx context.Context, namespace string, rule biz.NetworkRule) error {
+	// 未启用默认隔离时，单独的规则策略反而会隔离命名空间
+	if r.disableNetworkPolicy {
+		return biz.ErrNetworkPolicyDisabled
+	}
+	// 未启用出站隔离时出站不受限制，单独的 Egress 策略反而会把命名空间的出站限制为该对端
+	if rule.Direction == biz.NetworkRuleEgress && !r.networkPolicyEgress {
+		return fmt.Errorf("%w: egress is not isolated, EGRESS rules require network_policy_deny_egress", biz.ErrInvalidNetworkRule)
+	}
+	if err := r.ensureNamespace(ctx, namespace); err != nil {
+		return err
+	}
+	return r.applyNetworkPolicy(ctx, newNetworkRulePolicy(namespace, rule))
+}
+
+// DeleteNetworkRule deletes the NetworkPolicy of a network rule.
+// Returns nil if it doesn't exist (idempotent).
+func (r *k8sRepo) DeleteNetworkRule(ctx context.Context, namespace string, ruleID int64) error {
+	return r.deleteNetworkPolicy(ctx, namespace, networkRulePolicyName(ruleID))
+}
@@ -0,0 +1,163 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEnsureNamespace_NetworkPolicies(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	repo.networkPolicyNodes = []string{"10.0.0.0/16"}
+	ctx := context.Background()
+
+	// 新建与已存在的命名空间都要补齐默认策略
+	for _, ns := range []string{"carol", "carol"} {
+		if err := repo.ensureNamespace(ctx, ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := listPolicyNames(t, repo, "carol")
+	for _, name := range []string{networkPolicyDefaultDeny, networkPolicySameNamespace, networkPolicyIngressController} {
+		if !names[name] {
+			t.Errorf("missing network policy %s: %v", name, names)
+		}
+	}
+	// 默认只隔离入站，不影响实例访问外网
+	if names[networkPolicyDNS] {
+		t.Errorf("allow-dns must not be created without egress isolation: %v", names)
+	}
+	deny, _ := repo.client.NetworkingV1().NetworkPolicies("carol").Get(ctx, networkPolicyDefaultDeny, metav1.GetOptions{})
+	if len(deny.Spec.PolicyTypes) != 1 || deny.Spec.PolicyTypes[0] != networkingv1.PolicyTypeIngress {
+		t.Fatalf("default deny policy types=%v", deny.Spec.PolicyTypes)
+	}
+
+	controller, _ := repo.client.NetworkingV1().NetworkPolicies("carol").Get(ctx, networkPolicyIngressController, metav1.GetOptions{})
+	from := controller.Spec.Ingress[0].From
+	if from[0].NamespaceSelector.MatchLabels[namespaceNameLabel] != "ingress-nginx" || len(from) != 2 || from[1].IPBlock.CIDR != "10.0.0.0/16" {
+		t.Fatalf("ingress controller policy=%+v", controller.Spec)
+	}
+
+	// 启用出站隔离后拒绝出站并放开 DNS，关闭后删除 DNS 策略
+	repo.networkPolicyEgress = true
+	if err := repo.ensureNamespace(ctx, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	deny, _ = repo.client.NetworkingV1().NetworkPolicies("carol").Get(ctx, networkPolicyDefaultDeny, metav1.GetOptions{})
+	if len(deny.Spec.PolicyTypes) != 2 || !listPolicyNames(t, repo, "carol")[networkPolicyDNS] {
+		t.Fatalf("default deny policy types=%v", deny.Spec.PolicyTypes)
+	}
+	repo.networkPolicyEgress = false
+	if err := repo.ensureNamespace(ctx, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if listPolicyNames(t, repo, "carol")[networkPolicyDNS] {
+		t.Fatal("allow-dns must be removed when egress isolation is turned off")
+	}
+}
+
+func listPolicyNames(t *testing.T, repo *k8sRepo, namespace string) map[string]bool {
+	t.Helper()
+	list, err := repo.client.NetworkingV1().NetworkPolicies(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, p := range list.Items {
+		names[p.Name] = true
+	}
+	return names
+}
+
+func TestApplyNetworkRule(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+
+	rule := biz.NetworkRule{ID: 7, UserID: "alice", Direction: biz.NetworkRuleEgress, CIDR: "10.0.0.0/8", Ports: []uint32{5432}, Protocol: "TCP"}
+	// 未启用出站隔离时拒绝 EGRESS 规则
+	if err := repo.ApplyNetworkRule(ctx, "alice", rule); !errors.Is(err, biz.ErrInvalidNetworkRule) {
+		t.Fatalf("err=%v want ErrInvalidNetworkRule", err)
+	}
+
+	repo.networkPolicyEgress = true
+	if err := repo.ApplyNetworkRule(ctx, "alice", rule); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := repo.client.NetworkingV1().NetworkPolicies("alice").Get(ctx, "rule-7", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Spec.Ingress) != 0 || policy.Spec.Egress[0].To[0].IPBlock.CIDR != "10.0.0.0/8" || policy.Spec.Egress[0].Ports[0].Port.IntVal != 5432 {
+		t.Fatalf("spec=%+v", policy.Spec)
+	}
+
+	// 重复应用按新内容更新
+	rule.Direction = biz.NetworkRuleIngress
+	rule.CIDR = ""
+	rule.PeerUserID = "bob"
+	if err := repo.ApplyNetworkRule(ctx, "alice", rule); err != nil {
+		t.Fatal(err)
+	}
+	policy, _ = repo.client.NetworkingV1().NetworkPolicies("alice").Get(ctx, "rule-7", metav1.GetOptions{})
+	if len(policy.Spec.Egress) != 0 || policy.Spec.Ingress[0].From[0].NamespaceSelector.MatchLabels[namespaceNameLabel] != "bob" {
+		t.Fatalf("updated spec=%+v", policy.Spec)
+	}
+
+	if err := repo.DeleteNetworkRule(ctx, "alice", 7); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.client.NetworkingV1().NetworkPolicies("alice").Get(ctx, "rule-7", metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("err=%v want NotFound", err)
+	}
+	if err := repo.DeleteNetworkRule(ctx, "alice", 7); err != nil {
+		t.Fatalf("delete must be idempotent: %v", err)
+	}
+
+	repo.disableNetworkPolicy = true
+	if err := repo.ApplyNetworkRule(ctx, "alice", rule); !errors.Is(err, biz.ErrNetworkPolicyDisabled) {
+		t.Fatalf("err=%v want ErrNetworkPolicyDisabled", err)
+	}
+}
+
+func TestEnsureNetworkPolicies_ExistingNamespace(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+	if _, err := repo.client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "erin"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未启用 network_policy_backfill 时不修改已有命名空间
+	if err := repo.EnsureNetworkPolicies(ctx, "erin"); err != nil {
+		t.Fatal(err)
+	}
+	if names := listPolicyNames(t, repo, "erin"); len(names) != 0 {
+		t.Fatalf("policies created without backfill: %v", names)
+	}
+
+	repo.networkPolicyBackfill = true
+	// 不存在的命名空间不创建
+	if err := repo.EnsureNetworkPolicies(ctx, "dave"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.client.CoreV1().Namespaces().Get(ctx, "dave", metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("namespace must not be created, err=%v", err)
+	}
+
+	// 早于默认隔离创建的命名空间补齐策略
+	if _, err := repo.client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dave"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.EnsureNetworkPolicies(ctx, "dave"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.client.NetworkingV1().NetworkPolicies("dave").Get(ctx, networkPolicyDefaultDeny, metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,237 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// managedNetworkSelector 本服务为端口暴露创建的 Service 与 Ingress
+const managedNetworkSelector = "managed-by=resource-service,instance-id"
+
+// GetNetworkState 列出所有命名空间中本服务创建的 Service，以及暴露后端的入口资源：
+// ingress-nginx 为 Ingress、tcp/udp-services 条目与 ingress-nginx Service 上外部端口范围内的端口
+func (r *k8sRepo) GetNetworkState(ctx context.Context) (*biz.NetworkState, error) {
+	state := &biz.NetworkState{}
+
+	services, err := r.client.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: managedNetworkSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	for _, svc := range services.Items {
+		state.Services = append(state.Services, toNetworkObject(svc.ObjectMeta))
+	}
+
+	if err := r.exposure.ListExposures(ctx, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// listIngressNginxExposures 列出 Ingress、tcp/udp-services 条目与 ingress-nginx Service 上外部端口范围内的端口
+func (r *k8sRepo) listIngressNginxExposures(ctx context.Context, state *biz.NetworkState) error {
+	// 自定义域名的 Ingress 不对应端口绑定，不参与核对
+	ingresses, err := r.client.NetworkingV1().Ingresses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: managedNetworkSelector + ",!" + customDomainLabel})
+	if err != nil {
+		return fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	for _, ing := range ingresses.Items {
+		state.Ingresses = append(state.Ingresses, toNetworkObject(ing.ObjectMeta))
+	}
+
+	for _, protocol := range []string{"TCP", "UDP"} {
+		configMapName, _ := tcpUDPConfigMapName(protocol)
+		cm, err := r.client.CoreV1().ConfigMaps(r.ingressNginxNamespace).Get(ctx, configMapName, metav1.GetOptions{})
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get ConfigMap %s: %w", configMapName, err)
+		}
+		for key, value := range cm.Data {
+			if m, ok := parseTCPUDPMapping(protocol, key, value); ok {
+				state.Mappings = append(state.Mappings, m)
+			}
+		}
+	}
+
+	lb, err := r.client.CoreV1().Services(r.ingressNginxNamespace).Get(ctx, r.ingressNginxLBService, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get ingress-nginx Service: %w", err)
+	}
+	if err == nil {
+		for _, p := range lb.Spec.Ports {
+			// 80/443 等端口范围外的端口不由本服务管理
+			port := uint32(p.Port)
+			if port < r.tcpUDPPortRangeStart || port > r.tcpUDPPortRangeEnd {
+				continue
+			}
+			if p.Protocol != corev1.ProtocolTCP && p.Protocol != corev1.ProtocolUDP {
+				continue
+			}
+			state.LBPorts = append(state.LBPorts, biz.LBPort{Protocol: string(p.Protocol), Port: port})
+		}
+	}
+
+	return nil
+}
+
+// EnsureNetworkBinding 按绑定记录重建缺失的 Service 与暴露后端的入口资源，已禁用的绑定删除路由资源。
+// 已存在的资源保持不变，被其他 Service 占用的外部端口不会被覆盖。
+func (r *k8sRepo) EnsureNetworkBinding(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	instanceID := strconv.FormatInt(binding.InstanceID, 10)
+
+	_, err := r.client.CoreV1().Services(namespace).Get(ctx, binding.ServiceName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		var service *corev1.Service
+		switch binding.Protocol {
+		case "NODEPORT", "LOADBALANCER":
+			// 沿用记录中的节点端口，保持访问地址不变
+			svcType, _ := directServiceType(binding.Protocol)
+			var nodePort uint32
+			if binding.NodePort != nil {
+				nodePort = *binding.NodePort
+			}
+			service = newDirectService(namespace, instanceID, binding.ServiceName, binding.ServicePort, svcType, binding.Transport, nodePort)
+		case "UDP":
+			service = newInstanceService(namespace, instanceID, binding.ServiceName, binding.ServicePort, corev1.ProtocolUDP)
+		default:
+			service = newInstanceService(namespace, instanceID, binding.ServiceName, binding.ServicePort, corev1.ProtocolTCP)
+		}
+		_, err = r.client.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+		if err == nil {
+			r.log.WithContext(ctx).Infof("recreated service %s/%s", namespace, binding.ServiceName)
+		}
+	}
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to ensure service %s: %w", binding.ServiceName, err)
+	}
+
+	if !binding.Enabled {
+		// 已禁用的绑定只保留 Service 与外部端口，删除残留的路由资源
+		return r.disableRouting(ctx, namespace, binding)
+	}
+
+	if binding.Protocol == "NODEPORT" || binding.Protocol == "LOADBALANCER" {
+		// 重新启用时恢复禁用时清空的 selector
+		if err := r.setServiceSelector(ctx, namespace, binding.ServiceName, getPodSelector(instanceID)); err != nil {
+			return err
+		}
+		protocol := corev1.ProtocolTCP
+		if binding.Transport == "UDP" {
+			protocol = corev1.ProtocolUDP
+		}
+		if err := r.ensureDirectPortPolicy(ctx, namespace, instanceID, binding.ServiceName, binding.ServicePort, protocol); err != nil {
+			return err
+		}
+	}
+
+	if binding.IngressName != nil {
+		if binding.Ingress.Domain == "" {
+			// 早期记录未保存域名
+			binding.Ingress.Domain = r.ingressDomain
+		}
+		if err := r.exposure.EnsureHTTP(ctx, namespace, binding); err != nil {
+			return fmt.Errorf("failed to ensure ingress %s: %w", *binding.IngressName, err)
+		}
+	}
+
+	if binding.ExternalPort != nil {
+		if err := r.exposure.EnsureTCPUDP(ctx, namespace, binding); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureIngress 按绑定记录重建缺失的 Ingress
+func (r *k8sRepo) ensureIngress(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	_, err := r.client.NetworkingV1().Ingresses(namespace).Get(ctx, *binding.IngressName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		instanceID := strconv.FormatInt(binding.InstanceID, 10)
+		_, _, err = r.createIngress(ctx, namespace, *binding.IngressName, instanceID, binding.Port, binding.ServiceName, binding.Ingress)
+	}
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// ensureTCPUDPConfigMapEntry 按绑定记录重建缺失的 ConfigMap 条目与 ingress-nginx 端口，不覆盖已有条目
+func (r *k8sRepo) ensureTCPUDPConfigMapEntry(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	configMapName, err := tcpUDPConfigMapName(binding.Protocol)
+	if err != nil {
+		return err
+	}
+	key := strconv.FormatUint(uint64(*binding.ExternalPort), 10)
+	value := fmt.Sprintf("%s/%s:%d", namespace, binding.ServiceName, binding.ServicePort)
+	if err := r.patchIngressNginxConfigMap(ctx, configMapName, key, value); err != nil {
+		return err
+	}
+	return r.patchIngressNginxServicePort(ctx, *binding.ExternalPort, binding.Protocol)
+}
+
+func toNetworkObject(meta metav1.ObjectMeta) biz.NetworkObject {
+	instanceID, _ := strconv.ParseInt(meta.Labels["instance-id"], 10, 64)
+	return biz.NetworkObject{
+		Namespace:  meta.Namespace,
+		Name:       meta.Name,
+		InstanceID: instanceID,
+		CreatedAt:  meta.CreationTimestamp.Time,
+	}
+}
+
+// parseTCPUDPMapping 解析 ingress-nginx 的 "{namespace}/{service}:{port}[:PROXY[:PROXY]]" 映射
+func parseTCPUDPMapping(protocol, key, value string) (biz.TCPUDPMapping, bool) {
+	externalPort, err := strconv.ParseUint(key, 10, 32)
+	if err != nil {
+		return biz.TCPUDPMapping{}, false
+	}
+	namespace, rest, ok := strings.Cut(value, "/")
+	if !ok {
+		return biz.TCPUDPMapping{}, false
+	}
+	serviceName, rest, ok := strings.Cut(rest, ":")
+	if !ok {
+		return biz.TCPUDPMapping{}, false
+	}
+	portStr, _, _ := strings.Cut(rest, ":")
+	servicePort, err := strconv.ParseUint(portStr, 10, 32)
+	if err != nil {
+		return biz.TCPUDPMapping{}, false
+	}
+
+	return biz.TCPUDPMapping{
+		Protocol:     protocol,
+		ExternalPort: uint32(externalPort),
+		Namespace:    namespace,
+		ServiceName:  serviceName,
+		ServicePort:  uint32(servicePort),
+		InstanceID:   parseServiceInstanceID(serviceName, uint32(servicePort)),
+	}, true
+}
+
+// parseServiceInstanceID 从 generateServiceName 生成的名称中解析实例 ID，不符合格式时返回 0
+func parseServiceInstanceID(serviceName string, port uint32) int64 {
+	rest, ok := strings.CutPrefix(serviceName, "instance-")
+	if !ok {
+		return 0
+	}
+	idStr, ok := strings.CutSuffix(rest, fmt.Sprintf("-%d", port))
+	if !ok {
+		return 0
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestNetworkK8sRepo() *k8sRepo {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tcp-services", Namespace: "ingress-nginx"}, Data: map[string]string{
+			"30000": "alice/instance-1-22:22",
+			"30001": "kube-system/dns:53:PROXY", // 集群管理员配置的映射
+		}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "udp-services", Namespace: "ingress-nginx"}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingress-nginx-controller", Namespace: "ingress-nginx"},
+			Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+				{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80},
+				{Name: "tcp-30000", Protocol: corev1.ProtocolTCP, Port: 30000},
+			}},
+		},
+		newInstanceService("alice", "1", "instance-1-22", 22, corev1.ProtocolTCP),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "alice"}},
+	)
+	repo := &k8sRepo{
+		client:                client,
+		log:                   log.NewHelper(log.NewStdLogger(io.Discard)),
+		ingressDomain:         "demo.localtest.me",
+		ingressNginxNamespace: "ingress-nginx",
+		ingressNginxLBService: "ingress-nginx-controller",
+		tcpUDPPortRangeStart:  30000,
+		tcpUDPPortRangeEnd:    32767,
+	}
+	repo.exposure = &ingressNginxExposure{r: repo}
+	return repo
+}
+
+func TestK8sRepo_GetNetworkState(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+
+	state, err := repo.GetNetworkState(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Services) != 1 || state.Services[0].Name != "instance-1-22" || state.Services[0].InstanceID != 1 {
+		t.Fatalf("services=%+v", state.Services)
+	}
+	if len(state.Mappings) != 2 {
+		t.Fatalf("mappings=%+v", state.Mappings)
+	}
+	for _, m := range state.Mappings {
+		if m.ExternalPort == 30000 && (m.InstanceID != 1 || m.ServicePort != 22) {
+			t.Fatalf("mapping=%+v", m)
+		}
+		if m.ExternalPort == 30001 && (m.InstanceID != 0 || m.ServiceName != "dns" || m.ServicePort != 53) {
+			t.Fatalf("mapping=%+v", m)
+		}
+	}
+	// 端口范围外的 80 不属于本服务管理
+	if len(state.LBPorts) != 1 || state.LBPorts[0] != (biz.LBPort{Protocol: "TCP", Port: 30000}) {
+		t.Fatalf("lb ports=%+v", state.LBPorts)
+	}
+}
+
+func TestK8sRepo_EnsureNetworkBinding(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+	ptr := func(v uint32) *uint32 { return &v }
+	ingress := "ingress-1-80"
+
+	bindings := []biz.NetworkBinding{
+		{InstanceID: 1, Port: 80, ServiceName: "instance-1-80", ServicePort: 80, IngressName: &ingress, Protocol: "HTTP", Enabled: true},
+		{InstanceID: 1, Port: 53, ServiceName: "instance-1-53", ServicePort: 53, ExternalPort: ptr(30002), Protocol: "UDP", Enabled: true},
+		// 30001 已被其他映射占用，不覆盖
+		{InstanceID: 1, Port: 23, ServiceName: "instance-1-23", ServicePort: 23, ExternalPort: ptr(30001), Protocol: "TCP", Enabled: true},
+	}
+	for _, b := range bindings {
+		if err := repo.EnsureNetworkBinding(ctx, "alice", b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc, err := repo.client.CoreV1().Services("alice").Get(ctx, "instance-1-53", metav1.GetOptions{})
+	if err != nil || svc.Spec.Ports[0].Protocol != corev1.ProtocolUDP || svc.Labels["managed-by"] != "resource-service" {
+		t.Fatalf("svc=%v err=%v", svc, err)
+	}
+	if _, err := repo.client.NetworkingV1().Ingresses("alice").Get(ctx, ingress, metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	udp, _ := repo.client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "udp-services", metav1.GetOptions{})
+	if udp.Data["30002"] != "alice/instance-1-53:53" {
+		t.Fatalf("udp-services=%v", udp.Data)
+	}
+	tcp, _ := repo.client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "tcp-services", metav1.GetOptions{})
+	if tcp.Data["30001"] != "kube-system/dns:53:PROXY" {
+		t.Fatalf("tcp-services=%v", tcp.Data)
+	}
+
+	state, err := repo.GetNetworkState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Services) != 4 || len(state.Ingresses) != 1 || len(state.LBPorts) != 3 {
+		t.Fatalf("state=%+v", state)
+	}
+
+	// 重复调用保持幂等
+	if err := repo.EnsureNetworkBinding(ctx, "alice", bindings[0]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseServiceInstanceID(t *testing.T) {
+	tests := []struct {
+		name string
+		port uint32
+		want int64
+	}{
+		{name: "instance-12-8080", port: 8080, want: 12},
+		{name: "instance-12-8080", port: 80, want: 0},
+		{name: "instance-abc-80", port: 80, want: 0},
+		{name: "dns", port: 53, want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseServiceInstanceID(tt.name, tt.port); got != tt.want {
+			t.Errorf("parseServiceInstanceID(%q, %d)=%d want %d", tt.name, tt.port, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"resource/internal/biz"
+
+	"gorm.io/gorm"
+)
+
+// networkRule 用户命名空间的额外网络规则表，每条规则对应命名空间中的一个 NetworkPolicy（rule-{id}）
+type networkRule struct {
+	ID          int64     `gorm:"primaryKey;column:id"`
+	UserID      string    `gorm:"column:user_id;size:64;index"`
+	Direction   string    `gorm:"column:direction;size:8"`     // INGRESS / EGRESS
+	PeerUserID  string    `gorm:"column:peer_user_id;size:64"` // 对端用户命名空间，与 CIDR 二选一
+	CIDR        string    `gorm:"column:cidr;size:64"`
+	Ports       []byte    `gorm:"column:ports"` // JSON 数组，为空表示全部端口
+	Protocol    string    `gorm:"column:protocol;size:8"`
+	Description string    `gorm:"column:description;size:255"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+}
+
+func (networkRule) TableName() string { return "network_rule" }
+
+// CreateNetworkRule 保存网络规则，回填 ID 与创建时间
+func (r *networkRepo) CreateNetworkRule(ctx context.Context, rule *biz.NetworkRule) error {
+	ports, _ := json.Marshal(rule.Ports)
+	model := &networkRule{
+		UserID:      rule.UserID,
+		Direction:   rule.Direction,
+		PeerUserID:  rule.PeerUserID,
+		CIDR:        rule.CIDR,
+		Ports:       ports,
+		Protocol:    rule.Protocol,
+		Description: rule.Description,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := r.data.db.WithContext(ctx).Create(model).Error; err != nil {
+		r.log.Errorf("failed to create network rule: %v", err)
+		return err
+	}
+
+	rule.ID = model.ID
+	rule.CreatedAt = model.CreatedAt
+	return nil
+}
+
+// GetNetworkRule 不存在时返回 nil
+func (r *networkRepo) GetNetworkRule(ctx context.Context, ruleID int64) (*biz.NetworkRule, error) {
+	var model networkRule
+	err := r.data.db.WithContext(ctx).Where("id = ?", ruleID).First(&model).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.log.Errorf("failed to get network rule: %v", err)
+		return nil, err
+	}
+
+	rule := toNetworkRule(model)
+	return &rule, nil
+}
+
+// ListNetworkRules 按创建顺序返回用户的网络规则
+func (r *networkRepo) ListNetworkRules(ctx context.Context, userID string) ([]biz.NetworkRule, error) {
+	var models []networkRule
+	err := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&models).Error
+	if err != nil {
+		r.log.Errorf("failed to list network rules: %v", err)
+		return nil, err
+	}
+
+	rules := make([]biz.NetworkRule, 0, len(models))
+	for _, model := range models {
+		rules = append(rules, toNetworkRule(model))
+	}
+	return rules, nil
+}
+
+// DeleteNetworkRule 删除网络规则记录，不存在时返回 nil
+func (r *networkRepo) DeleteNetworkRule(ctx context.Context, ruleID int64) error {
+	if err := r.data.db.WithContext(ctx).Where("id = ?", ruleID).Delete(&networkRule{}).Error; err != nil {
+		r.log.Errorf("failed to delete network rule: %v", err)
+		return err
+	}
+	return nil
+}
+
+func toNetworkRule(model networkRule) biz.NetworkRule {
+	var ports []uint32
+	if len(model.Ports) > 0 {
+		_ = json.Unmarshal(model.Ports, &ports)
+	}
+	return biz.NetworkRule{
+		ID:          model.ID,
+		UserID:      model.UserID,
+		Direction:   model.Direction,
+		PeerUserID:  model.PeerUserID,
+		CIDR:        model.CIDR,
+		Ports:       ports,
+		Protocol:    model.Protocol,
+		Description: model.Description,
+		CreatedAt:   model.CreatedAt,
+	}
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"resource/internal/biz"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// disableRouting 删除已禁用端口的路由资源，保留 Service、外部端口与访问地址：
+//   - HTTP：删除 Ingress/HTTPRoute，保留 basic auth 凭据
+//   - TCP/UDP：删除 ConfigMap 条目/TCPRoute/UDPRoute，保留 ingress-nginx Service 端口或 Gateway listener
+//   - NODEPORT/LOADBALANCER：清空 Service selector，保留节点端口与外部地址
+func (r *k8sRepo) disableRouting(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	switch {
+	case binding.IngressName != nil:
+		if err := r.exposure.DisableHTTP(ctx, namespace, *binding.IngressName); err != nil {
+			return err
+		}
+	case binding.ExternalPort != nil:
+		if err := r.exposure.DisableTCPUDP(ctx, namespace, binding); err != nil {
+			return err
+		}
+	case binding.Protocol == "NODEPORT" || binding.Protocol == "LOADBALANCER":
+		if err := r.setServiceSelector(ctx, namespace, binding.ServiceName, nil); err != nil {
+			return err
+		}
+	}
+	r.log.WithContext(ctx).Infof("routing of port %d (%s) of instance %d disabled", binding.Port, binding.Protocol, binding.InstanceID)
+	return nil
+}
+
+// disableIngress 删除 Ingress，basic auth Secret 保留到端口关闭，重新启用时沿用原凭据
+func (r *k8sRepo) disableIngress(ctx context.Context, namespace, ingressName string) error {
+	err := r.client.NetworkingV1().Ingresses(namespace).Delete(ctx, ingressName, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ingress %s: %w", ingressName, err)
+	}
+	return nil
+}
+
+// removeTCPUDPConfigMapEntry 只删除指向该绑定 Service 的 ConfigMap 条目，ingress-nginx Service 端口保留，
+// 分配外部端口时会跳过该端口
+func (r *k8sRepo) removeTCPUDPConfigMapEntry(ctx context.Context, namespace string, binding biz.NetworkBinding) error {
+	configMapName, err := tcpUDPConfigMapName(binding.Protocol)
+	if err != nil {
+		return err
+	}
+	key := strconv.FormatUint(uint64(*binding.ExternalPort), 10)
+	value := fmt.Sprintf("%s/%s:%d", namespace, binding.ServiceName, binding.ServicePort)
+
+	err = r.updateIngressNginxConfigMap(ctx, configMapName, func(data map[string]string) (bool, error) {
+		if data[key] != value {
+			return false, nil
+		}
+		delete(data, key)
+		return true, nil
+	})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to update ConfigMap %s: %w", configMapName, err)
+	}
+	return nil
+}
+
+// setServiceSelector 替换 Service 的 selector，nil 表示不选择任何 Pod
+func (r *k8sRepo) setServiceSelector(ctx context.Context, namespace, serviceName string, selector map[string]string) error {
+	services := r.client.CoreV1().Services(namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		svc, err := services.Get(ctx, serviceName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if len(svc.Spec.Selector) == 0 && len(selector) == 0 || reflect.DeepEqual(svc.Spec.Selector, selector) {
+			return nil
+		}
+		svc.Spec.Selector = selector
+		_, err = services.Update(ctx, svc, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update selector of service %s: %w", serviceName, err)
+	}
+	return nil
+}
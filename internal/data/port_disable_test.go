@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEnsureNetworkBinding_DisableTCP(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+
+	serviceName, externalPort, err := repo.CreateServiceForTCPUDP(ctx, "alice", "1", 3306, "TCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	binding := biz.NetworkBinding{InstanceID: 1, Port: 3306, ServiceName: serviceName, ServicePort: 3306, ExternalPort: &externalPort, Protocol: "TCP"}
+	if err := repo.EnsureNetworkBinding(ctx, "alice", binding); err != nil {
+		t.Fatal(err)
+	}
+
+	key := "30002"
+	tcp, _ := repo.client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "tcp-services", metav1.GetOptions{})
+	if _, ok := tcp.Data[key]; ok || externalPort != 30002 {
+		t.Fatalf("port=%d tcp-services=%v", externalPort, tcp.Data)
+	}
+	if _, err := repo.client.CoreV1().Services("alice").Get(ctx, serviceName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("service deleted: %v", err)
+	}
+
+	// 外部端口仍在 ingress-nginx Service 上，不会分配给其他端口
+	_, next, err := repo.CreateServiceForTCPUDP(ctx, "bob", "2", 22, "TCP")
+	if err != nil || next == externalPort {
+		t.Fatalf("next=%d err=%v", next, err)
+	}
+
+	binding.Enabled = true
+	if err := repo.EnsureNetworkBinding(ctx, "alice", binding); err != nil {
+		t.Fatal(err)
+	}
+	tcp, _ = repo.client.CoreV1().ConfigMaps("ingress-nginx").Get(ctx, "tcp-services", metav1.GetOptions{})
+	if tcp.Data[key] != "alice/instance-1-3306:3306" {
+		t.Fatalf("tcp-services=%v", tcp.Data)
+	}
+}
+
+func TestEnsureNetworkBinding_DisableHTTPKeepsCredentials(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+
+	opts := biz.IngressOptions{Domain: "apps.example.com", Protection: biz.IngressProtection{BasicAuthUser: "admin", BasicAuthPassword: "s3cret"}}
+	name, _, err := repo.CreateIngress(ctx, "alice", "1", 8080, "instance-1-8080", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Protection.BasicAuthPassword = ""
+	binding := biz.NetworkBinding{InstanceID: 1, Port: 8080, ServiceName: "instance-1-8080", ServicePort: 8080, IngressName: &name, Protocol: "HTTP", Ingress: opts}
+	if err := repo.EnsureNetworkBinding(ctx, "alice", binding); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.client.NetworkingV1().Ingresses("alice").Get(ctx, name, metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Fatalf("ingress err=%v want NotFound", err)
+	}
+	if _, err := repo.client.CoreV1().Secrets("alice").Get(ctx, basicAuthSecretName(name), metav1.GetOptions{}); err != nil {
+		t.Fatalf("basic auth secret deleted: %v", err)
+	}
+
+	// 重新启用时沿用原凭据，无需再次提供密码
+	binding.Enabled = true
+	if err := repo.EnsureNetworkBinding(ctx, "alice", binding); err != nil {
+		t.Fatal(err)
+	}
+	ing, err := repo.client.NetworkingV1().Ingresses("alice").Get(ctx, name, metav1.GetOptions{})
+	if err != nil || ing.Annotations[annotationAuthType] != "basic" {
+		t.Fatalf("ingress=%v err=%v", ing, err)
+	}
+}
+
+func TestEnsureNetworkBinding_DisableNodePort(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	allocateNodePorts(repo.client.(*fake.Clientset), 31022)
+	repo.nodeAddress = "node.example.com"
+	ctx := context.Background()
+
+	name, endpoint, err := repo.CreateDirectService(ctx, "alice", "2", 22, "NODEPORT", "TCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	binding := biz.NetworkBinding{InstanceID: 2, Port: 22, ServiceName: name, ServicePort: 22, Protocol: "NODEPORT", Transport: "TCP", NodePort: &endpoint.NodePort}
+	if err := repo.EnsureNetworkBinding(ctx, "alice", binding); err != nil {
+		t.Fatal(err)
+	}
+	svc, _ := repo.client.CoreV1().Services("alice").Get(ctx, name, metav1.GetOptions{})
+	if len(svc.Spec.Selector) != 0 || svc.Spec.Ports[0].NodePort != 31022 {
+		t.Fatalf("disabled spec=%+v", svc.Spec)
+	}
+
+	binding.Enabled = true
+	if err := repo.EnsureNetworkBinding(ctx, "alice", binding); err != nil {
+		t.Fatal(err)
+	}
+	svc, _ = repo.client.CoreV1().Services("alice").Get(ctx, name, metav1.GetOptions{})
+	if svc.Spec.Selector["instance-id"] != "2" || svc.Spec.Type != corev1.ServiceTypeNodePort {
+		t.Fatalf("enabled spec=%+v", svc.Spec)
+	}
+}
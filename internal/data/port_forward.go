@@ -0,0 +1,131 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwardDialer 与 Pod 建立 port-forward 连接，测试中可替换
+type portForwardDialer func(namespace, podName string) (httpstream.Connection, error)
+
+// spdyPortForwardDialer 通过 pods/portforward 子资源建立 SPDY 连接
+func spdyPortForwardDialer(client kubernetes.Interface, config *rest.Config) portForwardDialer {
+	return func(namespace, podName string) (httpstream.Connection, error) {
+		transport, upgrader, err := spdy.RoundTripperFor(config)
+		if err != nil {
+			return nil, err
+		}
+		req := client.CoreV1().RESTClient().Post().
+			Resource("pods").
+			Name(podName).
+			Namespace(namespace).
+			SubResource("portforward")
+		dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+		conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+		return conn, err
+	}
+}
+
+// PortForward 建立到容器端口的单个 TCP 隧道，流程与 kubectl port-forward 处理一个本地连接相同：
+// 先创建 error 流，再创建 data 流并双向复制
+func (r *execRepo) PortForward(ctx context.Context, opts biz.PortForwardOptions, src io.Reader, dst io.Writer, ready func(podName string) error) error {
+	pods, err := r.listPods(ctx, opts.Namespace, opts.InstanceID)
+	if err != nil {
+		r.log.Errorf("failed to list pods: %v", err)
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	pod, err := selectExecPod(pods, opts.PodName)
+	if err != nil {
+		return fmt.Errorf("%w: %v", biz.ErrPodNotFound, err)
+	}
+
+	conn, err := r.dialPortForward(opts.Namespace, pod.Name)
+	if err != nil {
+		r.log.Errorf("failed to dial port-forward to pod %s: %v", pod.Name, err)
+		return fmt.Errorf("failed to connect to pod %s: %w", pod.Name, err)
+	}
+	defer conn.Close()
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(int(opts.Port)))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("failed to create error stream: %w", err)
+	}
+	// 不向 error 流写入
+	_ = errorStream.Close()
+
+	errorChan := make(chan error, 1)
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errorChan <- fmt.Errorf("failed to read error stream: %w", err)
+		case len(message) > 0:
+			errorChan <- fmt.Errorf("port-forward to port %d failed: %s", opts.Port, message)
+		}
+		close(errorChan)
+	}()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("failed to create data stream: %w", err)
+	}
+
+	if err := ready(pod.Name); err != nil {
+		_ = dataStream.Reset()
+		return err
+	}
+
+	remoteDone := make(chan struct{})
+	localErr := make(chan error, 1)
+	go func() {
+		defer close(remoteDone)
+		_, _ = io.Copy(dst, dataStream)
+	}()
+	go func() {
+		_, err := io.Copy(dataStream, src)
+		// 客户端不再发送数据时半关闭，容器端仍可继续返回数据
+		_ = dataStream.Close()
+		localErr <- err
+	}()
+
+	select {
+	case <-remoteDone:
+	case err := <-localErr:
+		if err == nil {
+			// 客户端正常结束发送，等待容器端返回剩余数据后关闭
+			select {
+			case <-remoteDone:
+			case <-ctx.Done():
+			}
+		}
+	case <-ctx.Done():
+	}
+
+	// 先丢弃未发送的数据，否则 error 流可能被阻塞
+	_ = dataStream.Reset()
+	select {
+	case err = <-errorChan:
+	case <-ctx.Done():
+	}
+	_ = conn.Close()
+	// 保证返回后不再写入 dst
+	<-remoteDone
+	return err
+}
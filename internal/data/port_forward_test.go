@@ -0,0 +1,108 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+)
+
+// fakeStream data 流回显写入的数据，error 流返回预置的错误信息
+type fakeStream struct {
+	headers http.Header
+	r       io.Reader
+	pr      *io.PipeReader
+	pw      *io.PipeWriter
+}
+
+func (s *fakeStream) Read(p []byte) (int, error) {
+	if s.r != nil {
+		return s.r.Read(p)
+	}
+	return s.pr.Read(p)
+}
+func (s *fakeStream) Write(p []byte) (int, error) { return s.pw.Write(p) }
+func (s *fakeStream) Close() error {
+	if s.pw != nil {
+		return s.pw.Close()
+	}
+	return nil
+}
+func (s *fakeStream) Reset() error {
+	if s.pr != nil {
+		return s.pr.Close()
+	}
+	return nil
+}
+func (s *fakeStream) Headers() http.Header { return s.headers }
+func (s *fakeStream) Identifier() uint32   { return 0 }
+
+type fakeStreamConn struct {
+	errMessage string
+	port       string
+}
+
+func (c *fakeStreamConn) CreateStream(headers http.Header) (httpstream.Stream, error) {
+	c.port = headers.Get(corev1.PortHeader)
+	s := &fakeStream{headers: headers.Clone()}
+	if headers.Get(corev1.StreamType) == corev1.StreamTypeError {
+		s.r = strings.NewReader(c.errMessage)
+	} else {
+		s.pr, s.pw = io.Pipe()
+	}
+	return s, nil
+}
+func (c *fakeStreamConn) Close() error                       { return nil }
+func (c *fakeStreamConn) CloseChan() <-chan bool             { return nil }
+func (c *fakeStreamConn) SetIdleTimeout(time.Duration)       {}
+func (c *fakeStreamConn) RemoveStreams(...httpstream.Stream) {}
+
+func TestExecRepo_PortForward(t *testing.T) {
+	conn := &fakeStreamConn{}
+	repo := newTestExecRepo(nil, nil, instancePod())
+	repo.dialPortForward = func(namespace, podName string) (httpstream.Connection, error) {
+		return conn, nil
+	}
+
+	var readyPod string
+	var dst bytes.Buffer
+	err := repo.PortForward(context.Background(), biz.PortForwardOptions{Namespace: "alice", InstanceID: "1", Port: 8888},
+		strings.NewReader("ping"), &dst, func(podName string) error {
+			readyPod = podName
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readyPod != "instance-1-abc" || conn.port != "8888" || dst.String() != "ping" {
+		t.Fatalf("pod=%s port=%s echoed=%q", readyPod, conn.port, dst.String())
+	}
+}
+
+func TestExecRepo_PortForwardErrors(t *testing.T) {
+	repo := newTestExecRepo(nil, nil, instancePod())
+	repo.dialPortForward = func(string, string) (httpstream.Connection, error) {
+		return &fakeStreamConn{errMessage: "connection refused"}, nil
+	}
+	err := repo.PortForward(context.Background(), biz.PortForwardOptions{Namespace: "alice", InstanceID: "1", Port: 9},
+		strings.NewReader(""), io.Discard, func(string) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("err=%v want remote error", err)
+	}
+
+	repo = newTestExecRepo(nil, nil)
+	err = repo.PortForward(context.Background(), biz.PortForwardOptions{Namespace: "alice", InstanceID: "1", Port: 9},
+		strings.NewReader(""), io.Discard, func(string) error { return nil })
+	if !errors.Is(err, biz.ErrPodNotFound) {
+		t.Fatalf("err=%v want ErrPodNotFound", err)
+	}
+}
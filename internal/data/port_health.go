@@ -0,0 +1,73 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"resource/internal/biz"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetServiceHealth 按 EndpointSlice 统计实例各 Service 的后端地址，key 为 Service 名称。
+// 不存在的 Service 不出现在结果中。
+func (r *k8sRepo) GetServiceHealth(ctx context.Context, namespace, instanceID string) (map[string]biz.PortHealth, error) {
+	services, err := r.client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("instance-id=%s", instanceID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	out := make(map[string]biz.PortHealth, len(services.Items))
+	if len(services.Items) == 0 {
+		return out, nil
+	}
+	names := make([]string, 0, len(services.Items))
+	for _, svc := range services.Items {
+		names = append(names, svc.Name)
+	}
+
+	slices, err := r.client.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s in (%s)", discoveryv1.LabelServiceName, strings.Join(names, ",")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list endpoint slices: %w", err)
+	}
+
+	counts := make(map[string]*biz.PortHealth, len(names))
+	for _, name := range names {
+		counts[name] = &biz.PortHealth{}
+	}
+	for _, slice := range slices.Items {
+		h, ok := counts[slice.Labels[discoveryv1.LabelServiceName]]
+		if !ok {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			// Ready 为空时按就绪处理
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				h.ReadyEndpoints++
+			} else {
+				h.NotReadyEndpoints++
+			}
+		}
+	}
+
+	for name, h := range counts {
+		switch {
+		case h.ReadyEndpoints > 0:
+			h.Status = biz.PortHealthHealthy
+		case h.NotReadyEndpoints > 0:
+			h.Status = biz.PortHealthUnhealthy
+			h.Message = "no ready endpoints"
+		default:
+			h.Status = biz.PortHealthNoEndpoints
+			h.Message = "no pods selected by service"
+		}
+		out[name] = *h
+	}
+	return out, nil
+}
@@ -0,0 +1,81 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"resource/internal/biz"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestEndpointSlice(name, serviceName string, ready ...bool) *discoveryv1.EndpointSlice {
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "alice",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: serviceName},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+	}
+	for i := range ready {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{"10.1.0.1"},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready[i]},
+		})
+	}
+	return slice
+}
+
+func TestK8sRepo_GetServiceHealth(t *testing.T) {
+	repo := newTestNetworkK8sRepo()
+	ctx := context.Background()
+	services := repo.client.CoreV1().Services("alice")
+	slices := repo.client.DiscoveryV1().EndpointSlices("alice")
+
+	for _, svc := range []*corev1.Service{
+		newInstanceService("alice", "1", "instance-1-80", 80, corev1.ProtocolTCP),
+		newInstanceService("alice", "1", "instance-1-8080", 8080, corev1.ProtocolTCP),
+		newInstanceService("alice", "2", "instance-2-80", 80, corev1.ProtocolTCP),
+	} {
+		if _, err := services.Create(ctx, svc, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, slice := range []*discoveryv1.EndpointSlice{
+		newTestEndpointSlice("instance-1-22-a", "instance-1-22", true),
+		newTestEndpointSlice("instance-1-22-b", "instance-1-22", false),
+		newTestEndpointSlice("instance-1-80-a", "instance-1-80", false, false),
+		newTestEndpointSlice("instance-2-80-a", "instance-2-80", true),
+	} {
+		if _, err := slices.Create(ctx, slice, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	health, err := repo.GetServiceHealth(ctx, "alice", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]biz.PortHealth{
+		"instance-1-22":   {Status: biz.PortHealthHealthy, ReadyEndpoints: 1, NotReadyEndpoints: 1},
+		"instance-1-80":   {Status: biz.PortHealthUnhealthy, NotReadyEndpoints: 2, Message: "no ready endpoints"},
+		"instance-1-8080": {Status: biz.PortHealthNoEndpoints, Message: "no pods selected by service"},
+	}
+	if len(health) != len(want) {
+		t.Fatalf("health=%+v", health)
+	}
+	for name, w := range want {
+		if health[name] != w {
+			t.Fatalf("%s=%+v want %+v", name, health[name], w)
+		}
+	}
+
+	// 没有 Service 的实例返回空结果
+	health, err = repo.GetServiceHealth(ctx, "alice", "3")
+	if err != nil || len(health) != 0 {
+		t.Fatalf("health=%+v err=%v", health, err)
+	}
+}
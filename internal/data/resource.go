@@ -30,13 +30,15 @@ func NewResourceRepo(data *Data, logger log.Logger) biz.InstanceRepo {
 }
 
 type instance struct {
-	InstanceID int64      `gorm:"primaryKey;column:instance_id"` // 雪花 ID: [UserID:24][TS:36][Seq:4]
-	UserID     string     `gorm:"column:user_id;type:uuid;index"`
-	Name       string     `gorm:"column:name"`
-	Status     string     `gorm:"column:status"` // 核心状态机字段
-	CreatedAt  time.Time  `gorm:"column:created_at"`
-	UpdatedAt  time.Time  `gorm:"column:updated_at"`
-	DeletedAt  *time.Time `gorm:"column:deleted_at"`
+	InstanceID    int64      `gorm:"primaryKey;column:instance_id"` // 雪花 ID: [UserID:24][TS:36][Seq:4]
+	UserID        string     `gorm:"column:user_id;type:uuid;index"`
+	Name          string     `gorm:"column:name"`
+	Status        string     `gorm:"column:status"`         // 核心状态机字段
+	StatusReason  string     `gorm:"column:status_reason"`  // 最近一次失败诊断代码
+	StatusMessage string     `gorm:"column:status_message"` // 失败诊断对应的事件信息
+	CreatedAt     time.Time  `gorm:"column:created_at"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at"`
+	DeletedAt     *time.Time `gorm:"column:deleted_at"`
 }
 
 func (instance) TableName() string { return "instance" }
@@ -49,6 +51,9 @@ type instanceSpec struct {
 	GPU        *uint32         `gorm:"column:gpu"` // 可为空，取值代表不同 GPU 类型
 	Image      string          `gorm:"column:image"`
 	ConfigJSON json.RawMessage `gorm:"column:config_json"`
+	// 带宽上限（Mbit/s），0 表示不限制
+	IngressBandwidth uint32 `gorm:"column:ingress_bandwidth_mbps;not null;default:0"`
+	EgressBandwidth  uint32 `gorm:"column:egress_bandwidth_mbps;not null;default:0"`
 }
 
 func (instanceSpec) TableName() string { return "instance_spec" }
@@ -79,12 +84,14 @@ func (r *resourceRepo) ListResources(ctx context.Context, filter biz.ListResourc
 	out := make([]biz.Resource, 0, len(rows))
 	for _, row := range rows {
 		out = append(out, biz.Resource{
-			InstanceID: row.InstanceID,
-			Name:       row.Name,
-			UserID:     row.UserID,
-			Type:       row.Status,
-			CreatedAt:  row.CreatedAt,
-			UpdatedAt:  row.UpdatedAt,
+			InstanceID:    row.InstanceID,
+			Name:          row.Name,
+			UserID:        row.UserID,
+			Type:          row.Status,
+			StatusReason:  row.StatusReason,
+			StatusMessage: row.StatusMessage,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
 		})
 	}
 	return out, nil
@@ -117,6 +124,9 @@ func (r *resourceRepo) ListResourceSpecs(ctx context.Context, instanceIDs []int6
 			GPU:        gpu,
 			Image:      row.Image,
 			ConfigJSON: append([]byte(nil), row.ConfigJSON...),
+
+			IngressBandwidth: row.IngressBandwidth,
+			EgressBandwidth:  row.EgressBandwidth,
 		}
 	}
 	return out, nil
@@ -130,6 +140,9 @@ func (r *resourceRepo) CreateInstance(ctx context.Context, spec biz.InstanceSpec
 		Memory:     spec.Memory,
 		Image:      spec.Image,
 		ConfigJSON: append([]byte(nil), spec.ConfigJSON...),
+
+		IngressBandwidth: spec.IngressBandwidth,
+		EgressBandwidth:  spec.EgressBandwidth,
 	}
 	instance := &instance{
 		InstanceID: spec.InstanceID,
@@ -163,6 +176,20 @@ func (r *resourceRepo) CreateInstance(ctx context.Context, spec biz.InstanceSpec
 	return err
 }
 
+// UpdateInstanceSpec 只更新 CPU、内存与带宽限制；gpu 列可为空，镜像与 GPU 保持原值
+func (r *resourceRepo) UpdateInstanceSpec(ctx context.Context, spec biz.InstanceSpec) error {
+	updates := map[string]interface{}{
+		"cpu":                    spec.CPU,
+		"memory":                 spec.Memory,
+		"ingress_bandwidth_mbps": spec.IngressBandwidth,
+		"egress_bandwidth_mbps":  spec.EgressBandwidth,
+	}
+	return r.data.db.WithContext(ctx).
+		Model(&instanceSpec{}).
+		Where("instance_id = ?", spec.InstanceID).
+		Updates(updates).Error
+}
+
 // GetResource returns a single resource by instance ID
 func (r *resourceRepo) GetResource(ctx context.Context, instanceID int64) (*biz.Resource, error) {
 	var row instance
@@ -177,11 +204,24 @@ func (r *resourceRepo) GetResource(ctx context.Context, instanceID int64) (*biz.
 	}
 
 	return &biz.Resource{
-		InstanceID: row.InstanceID,
-		Name:       row.Name,
-		UserID:     row.UserID,
-		Type:       row.Status,
-		CreatedAt:  row.CreatedAt,
-		UpdatedAt:  row.UpdatedAt,
+		InstanceID:    row.InstanceID,
+		Name:          row.Name,
+		UserID:        row.UserID,
+		Type:          row.Status,
+		StatusReason:  row.StatusReason,
+		StatusMessage: row.StatusMessage,
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
 	}, nil
 }
+
+// UpdateStatusReason 记录实例的失败诊断，不修改 updated_at
+func (r *resourceRepo) UpdateStatusReason(ctx context.Context, instanceID int64, reason, message string) error {
+	return r.data.db.WithContext(ctx).
+		Model(&instance{}).
+		Where("instance_id = ?", instanceID).
+		UpdateColumns(map[string]interface{}{
+			"status_reason":  reason,
+			"status_message": message,
+		}).Error
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	kgrpc "github.com/go-kratos/kratos/v2/transport/grpc"
+	"google.golang.org/grpc"
+)
+
+// instanceRequest 带实例 ID 的请求
+type instanceRequest interface {
+	GetInstanceId() int64
+}
+
+// Authorization 在 ResourceService 方法之前执行基于角色的访问控制。
+// 调用方身份写入 context，供 service 层使用。
+func Authorization(authz *biz.AuthzUsecase) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+
+			p := principalFromTransport(authz, tr)
+			var instanceID int64
+			if r, ok := req.(instanceRequest); ok {
+				instanceID = r.GetInstanceId()
+			}
+			if err := authz.Authorize(ctx, p, tr.Operation(), instanceID); err != nil {
+				return nil, toAuthzError(err)
+			}
+
+			return handler(biz.NewPrincipalContext(ctx, p), req)
+		}
+	}
+}
+
+// StreamAuthentication 解析流式 RPC 的调用方身份。
+// 流式请求的实例 ID 在首条消息中，授权由 service 层在收到首条消息后完成。
+func StreamAuthentication(authz *biz.AuthzUsecase) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		tr, ok := transport.FromServerContext(ctx)
+		if !ok {
+			return handler(srv, ss)
+		}
+
+		p := principalFromTransport(authz, tr)
+		return handler(srv, kgrpc.NewWrappedStream(biz.NewPrincipalContext(ctx, p), ss))
+	}
+}
+
+func principalFromTransport(authz *biz.AuthzUsecase, tr transport.Transporter) biz.Principal {
+	header := tr.RequestHeader()
+	return authz.ResolvePrincipal(header.Get(authz.UserHeader()), header.Get(authz.RoleHeader()))
+}
+
+func toAuthzError(err error) error {
+	if errors.Is(err, biz.ErrPermissionDenied) {
+		return errors.New(403, "PERMISSION_DENIED", err.Error())
+	}
+	return errors.New(500, "INTERNAL_ERROR", "authorization failed: "+err.Error())
+}
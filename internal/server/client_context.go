@@ -0,0 +1,24 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"resource/internal/service"
+
+	"google.golang.org/grpc"
+)
+
+// ClientContextInterceptor 在 kratos 的请求超时之前保存客户端 context，须作为原生 grpc 拦截器注册
+func ClientContextInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(service.NewClientContext(ctx), req)
+	}
+}
+
+// ClientContextFilter HTTP 版本的 ClientContextInterceptor
+func ClientContextFilter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(service.NewClientContext(r.Context())))
+	})
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+	"resource/internal/conf"
+	"resource/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// ExecWebSocketPath WebSocket exec 网关路径
+const ExecWebSocketPath = "/v1/instances/{instance_id}/exec"
+
+// WebSocket exec 帧协议：
+//
+//	建立连接：GET /v1/instances/{instance_id}/exec?command=sh&command=-c&command=ls&tty=true&container=xxx&pod=xxx
+//	客户端 → 服务端：
+//	  二进制帧：标准输入原始数据
+//	  文本帧：{"type":"stdin","data":"ls\r"} 或 {"type":"resize","rows":24,"cols":80}
+//	服务端 → 客户端：
+//	  二进制帧：首字节为流类型（1 = stdout，2 = stderr），其后为输出数据
+//	  文本帧：{"type":"error","message":"...","category":"TRANSPORT"} 或 {"type":"exit","code":0}
+const (
+	wsStreamStdout byte = 1
+	wsStreamStderr byte = 2
+)
+
+// wsFrame WebSocket 文本帧
+type wsFrame struct {
+	Type     string `json:"type"`
+	Data     string `json:"data,omitempty"`
+	Rows     uint32 `json:"rows,omitempty"`
+	Cols     uint32 `json:"cols,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Category string `json:"category,omitempty"`
+	Code     *int32 `json:"code,omitempty"`
+	Pod      string `json:"pod,omitempty"`
+	Port     uint32 `json:"port,omitempty"`
+}
+
+// NewExecWebSocketHandler 创建 WebSocket exec 网关，会话与 gRPC ExecContainer 共用同一处理逻辑
+func NewExecWebSocketHandler(c *conf.Server, resource *service.ResourceService, authz *biz.AuthzUsecase, logger log.Logger) http.Handler {
+	helper := log.NewHelper(logger)
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  8192,
+		WriteBufferSize: 8192,
+		CheckOrigin:     checkOrigin(c.GetExec().GetAllowedOrigins()),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		init, err := execInitFromRequest(r)
+		if err != nil {
+			se := errors.FromError(err)
+			http.Error(w, se.Message, int(se.Code))
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade 已写入错误响应
+			helper.Warnf("websocket upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		// HTTP 服务的请求超时不适用于长连接，连接断开时取消会话
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		defer cancel()
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			ctx = biz.NewPrincipalContext(ctx, principalFromTransport(authz, tr))
+		}
+
+		stream := &wsExecStream{ctx: ctx, cancel: cancel, conn: conn, init: init}
+		err = resource.Exec(stream)
+		stream.close(err)
+	})
+}
+
+// execInitFromRequest 从路径与查询参数构造 ExecInit
+func execInitFromRequest(r *http.Request) (*v1.ExecInit, error) {
+	instanceID, err := strconv.ParseInt(mux.Vars(r)["instance_id"], 10, 64)
+	if err != nil || instanceID <= 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "invalid instance_id")
+	}
+	query := r.URL.Query()
+	init := &v1.ExecInit{
+		InstanceId: instanceID,
+		Command:    query["command"],
+		Tty:        true,
+	}
+	if tty := query.Get("tty"); tty != "" {
+		if init.Tty, err = strconv.ParseBool(tty); err != nil {
+			return nil, errors.New(400, "INVALID_ARGUMENT", "invalid tty")
+		}
+	}
+	if container := query.Get("container"); container != "" {
+		init.ContainerName = &container
+	}
+	if pod := query.Get("pod"); pod != "" {
+		init.PodName = &pod
+	}
+	return init, nil
+}
+
+// checkOrigin 未配置时仅允许同源，"*" 允许全部
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	if len(allowed) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(allowed))
+	for _, origin := range allowed {
+		set[origin] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || set["*"] || set[origin] {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && u.Host == r.Host
+	}
+}
+
+// wsExecStream 将 WebSocket 连接适配为 service.ExecStream
+type wsExecStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	conn   *websocket.Conn
+	init   *v1.ExecInit
+
+	mu sync.Mutex // 保护并发写
+}
+
+func (s *wsExecStream) Context() context.Context {
+	return s.ctx
+}
+
+// Recv 首次返回由请求参数构造的 ExecInit，之后读取客户端帧
+func (s *wsExecStream) Recv() (*v1.ExecRequest, error) {
+	if s.init != nil {
+		init := s.init
+		s.init = nil
+		return &v1.ExecRequest{Message: &v1.ExecRequest_Init{Init: init}}, nil
+	}
+
+	for {
+		typ, data, err := s.conn.ReadMessage()
+		if err != nil {
+			// 客户端断开，结束会话
+			s.cancel()
+			return nil, err
+		}
+
+		switch typ {
+		case websocket.BinaryMessage:
+			return &v1.ExecRequest{Message: &v1.ExecRequest_Input{Input: &v1.ExecInput{Data: data}}}, nil
+		case websocket.TextMessage:
+			var frame wsFrame
+			if err := json.Unmarshal(data, &frame); err != nil {
+				continue
+			}
+			switch frame.Type {
+			case "stdin":
+				return &v1.ExecRequest{Message: &v1.ExecRequest_Input{Input: &v1.ExecInput{Data: []byte(frame.Data)}}}, nil
+			case "resize":
+				return &v1.ExecRequest{Message: &v1.ExecRequest_Resize{Resize: &v1.ExecResize{Rows: frame.Rows, Cols: frame.Cols}}}, nil
+			}
+		}
+	}
+}
+
+// Send 将 ExecResponse 编码为 WebSocket 帧
+func (s *wsExecStream) Send(resp *v1.ExecResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg := resp.Message.(type) {
+	case *v1.ExecResponse_Output:
+		stream := wsStreamStdout
+		if msg.Output.Stream == v1.ExecOutput_STDERR {
+			stream = wsStreamStderr
+		}
+		frame := make([]byte, 0, len(msg.Output.Data)+1)
+		frame = append(frame, stream)
+		frame = append(frame, msg.Output.Data...)
+		return s.conn.WriteMessage(websocket.BinaryMessage, frame)
+	case *v1.ExecResponse_Error:
+		return s.conn.WriteJSON(wsFrame{
+			Type:     "error",
+			Message:  msg.Error.Message,
+			Category: msg.Error.Category.String(),
+		})
+	case *v1.ExecResponse_Exit:
+		code := msg.Exit.Code
+		return s.conn.WriteJSON(wsFrame{Type: "exit", Code: &code})
+	}
+	return nil
+}
+
+// close 发送关闭帧，会话建立前的错误作为关闭原因返回给客户端
+func (s *wsExecStream) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.conn.WriteMessage(websocket.CloseMessage, wsCloseMessage(err))
+}
+
+// wsCloseMessage 将会话错误映射为关闭帧
+func wsCloseMessage(err error) []byte {
+	code, reason := websocket.CloseNormalClosure, ""
+	if err != nil {
+		se := errors.FromError(err)
+		reason = se.Message
+		switch se.Code {
+		case 400:
+			code = websocket.CloseUnsupportedData
+		case 403, 429:
+			code = websocket.ClosePolicyViolation
+		default:
+			code = websocket.CloseInternalServerErr
+		}
+	}
+	// 关闭原因最长 123 字节
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	return websocket.FormatCloseMessage(code, reason)
+}
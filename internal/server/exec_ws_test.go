@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "resource/api/resource/v1"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+func TestExecInitFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+		wantTTY bool
+	}{
+		{name: "defaults_to_tty", target: "/v1/instances/7/exec?command=sh", wantTTY: true},
+		{name: "tty_disabled", target: "/v1/instances/7/exec?command=ls&command=-la&tty=false&container=app"},
+		{name: "invalid_instance", target: "/v1/instances/abc/exec?command=sh", wantErr: true},
+		{name: "invalid_tty", target: "/v1/instances/7/exec?command=sh&tty=maybe", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var init *v1.ExecInit
+			var err error
+			router := mux.NewRouter()
+			router.HandleFunc(ExecWebSocketPath, func(_ http.ResponseWriter, r *http.Request) {
+				init, err = execInitFromRequest(r)
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if init.InstanceId != 7 || init.Tty != tt.wantTTY || len(init.Command) == 0 {
+				t.Fatalf("init=%v", init)
+			}
+		})
+	}
+}
+
+func TestWsExecStream(t *testing.T) {
+	done := make(chan struct{})
+	var received []*v1.ExecRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream := &wsExecStream{ctx: ctx, cancel: cancel, conn: conn, init: &v1.ExecInit{InstanceId: 1}}
+		for i := 0; i < 4; i++ {
+			req, err := stream.Recv()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			received = append(received, req)
+		}
+		_ = stream.Send(&v1.ExecResponse{Message: &v1.ExecResponse_Output{Output: &v1.ExecOutput{Stream: v1.ExecOutput_STDERR, Data: []byte("oops")}}})
+		_ = stream.Send(&v1.ExecResponse{Message: &v1.ExecResponse_Exit{Exit: &v1.ExecExit{Code: 3}}})
+		stream.close(nil)
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_ = conn.WriteMessage(websocket.BinaryMessage, []byte("ls\r"))
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"resize","rows":40,"cols":120}`))
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"stdin","data":"exit\r"}`))
+
+	typ, data, err := conn.ReadMessage()
+	if err != nil || typ != websocket.BinaryMessage || data[0] != wsStreamStderr || string(data[1:]) != "oops" {
+		t.Fatalf("output frame typ=%d data=%q err=%v", typ, data, err)
+	}
+	var exit wsFrame
+	if err := conn.ReadJSON(&exit); err != nil || exit.Type != "exit" || exit.Code == nil || *exit.Code != 3 {
+		t.Fatalf("exit frame=%+v err=%v", exit, err)
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("err=%v want normal closure", err)
+	}
+	<-done
+
+	if received[0].GetInit().GetInstanceId() != 1 {
+		t.Fatalf("first message=%v want init", received[0])
+	}
+	if string(received[1].GetInput().GetData()) != "ls\r" {
+		t.Fatalf("binary frame=%v want stdin", received[1])
+	}
+	if r := received[2].GetResize(); r.GetRows() != 40 || r.GetCols() != 120 {
+		t.Fatalf("resize=%v", received[2])
+	}
+	if string(received[3].GetInput().GetData()) != "exit\r" {
+		t.Fatalf("text stdin=%v", received[3])
+	}
+}
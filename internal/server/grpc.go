@@ -2,20 +2,26 @@ package server
 
 import (
 	resourcev1 "resource/api/resource/v1"
+	"resource/internal/biz"
 	"resource/internal/conf"
 	"resource/internal/service"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/middleware/recovery"
 	"github.com/go-kratos/kratos/v2/transport/grpc"
+	ggrpc "google.golang.org/grpc"
 )
 
 // NewGRPCServer new a gRPC server.
-func NewGRPCServer(c *conf.Server, resource *service.ResourceService, logger log.Logger) *grpc.Server {
+func NewGRPCServer(c *conf.Server, resource *service.ResourceService, authz *biz.AuthzUsecase, logger log.Logger) *grpc.Server {
 	var opts = []grpc.ServerOption{
 		grpc.Middleware(
 			recovery.Recovery(),
+			Authorization(authz),
 		),
+		grpc.StreamInterceptor(StreamAuthentication(authz)),
+		// 在 kratos 附加请求超时之前保存客户端 context
+		grpc.Options(ggrpc.UnaryInterceptor(ClientContextInterceptor())),
 	}
 	if c.Grpc.Network != "" {
 		opts = append(opts, grpc.Network(c.Grpc.Network))
@@ -2,6 +2,7 @@ package server
 
 import (
 	resourcev1 "resource/api/resource/v1"
+	"resource/internal/biz"
 	"resource/internal/conf"
 	"resource/internal/service"
 
@@ -11,11 +12,14 @@ import (
 )
 
 // NewHTTPServer new an HTTP server.
-func NewHTTPServer(c *conf.Server, resource *service.ResourceService, logger log.Logger) *http.Server {
+func NewHTTPServer(c *conf.Server, resource *service.ResourceService, authz *biz.AuthzUsecase, logger log.Logger) *http.Server {
 	var opts = []http.ServerOption{
 		http.Middleware(
 			recovery.Recovery(),
+			Authorization(authz),
 		),
+		// 在 kratos 附加请求超时之前保存客户端 context
+		http.Filter(ClientContextFilter),
 	}
 	if c.Http.Network != "" {
 		opts = append(opts, http.Network(c.Http.Network))
@@ -28,5 +32,8 @@ func NewHTTPServer(c *conf.Server, resource *service.ResourceService, logger log
 	}
 	srv := http.NewServer(opts...)
 	resourcev1.RegisterResourceServiceHTTPServer(srv, resource)
+	srv.Handle(ExecWebSocketPath, NewExecWebSocketHandler(c, resource, authz, logger))
+	srv.Handle(InstanceLogsPath, NewInstanceLogsHandler(resource, authz, logger))
+	srv.Handle(PortForwardWebSocketPath, NewPortForwardWebSocketHandler(c, resource, authz, logger))
 	return srv
 }
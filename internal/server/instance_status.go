@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"resource/internal/biz"
+	"resource/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+var _ transport.Server = (*InstanceStatusServer)(nil)
+
+// InstanceStatusServer 周期性诊断实例事件，写回 ListResources 返回的失败原因
+type InstanceStatusServer struct {
+	uc       *biz.ResourceUsecase
+	interval time.Duration
+	log      *log.Helper
+
+	stop chan struct{}
+}
+
+// NewInstanceStatusServer new an instance status server.
+func NewInstanceStatusServer(c *conf.Server, uc *biz.ResourceUsecase, logger log.Logger) *InstanceStatusServer {
+	s := &InstanceStatusServer{
+		uc:   uc,
+		log:  log.NewHelper(logger),
+		stop: make(chan struct{}),
+	}
+	if c.GetInstanceStatus().GetInterval() != nil {
+		s.interval = c.GetInstanceStatus().GetInterval().AsDuration()
+	}
+	return s
+}
+
+// Start 按配置的间隔执行诊断，直到 Stop 或 context 取消；间隔为 0 时不执行
+func (s *InstanceStatusServer) Start(ctx context.Context) error {
+	if s.interval <= 0 {
+		s.log.Info("instance status refresh is disabled")
+		select {
+		case <-ctx.Done():
+		case <-s.stop:
+		}
+		return nil
+	}
+
+	s.log.Infof("starting instance status refresh, interval=%s", s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stop:
+			return nil
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+// Stop 停止周期诊断
+func (s *InstanceStatusServer) Stop(context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	return nil
+}
+
+func (s *InstanceStatusServer) refresh(ctx context.Context) {
+	// 单次诊断不超过一个周期，避免与下一次重叠
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	updated, err := s.uc.RefreshStatusReasons(ctx)
+	if err != nil {
+		s.log.Errorf("instance status refresh failed: %v", err)
+		return
+	}
+	if updated > 0 {
+		s.log.Infof("instance status refresh updated %d instances", updated)
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+	"resource/internal/service"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/encoding/json"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// InstanceLogsPath 容器日志 SSE 端点路径
+const InstanceLogsPath = "/v1/instances/{instance_id}/logs"
+
+// 容器日志 SSE 协议：
+//
+//	建立连接：GET /v1/instances/{instance_id}/logs?follow=true&tail_lines=100&since_time=2025-01-01T00:00:00Z&previous=false&timestamps=true&container=xxx&pod=xxx
+//	event: log    data: InstanceLogLine（JSON）
+//	event: error  data: {"code":500,"reason":"...","message":"..."}，输出开始后的错误
+//	输出开始前的错误（参数错误、权限不足、实例不存在等）直接返回对应的 HTTP 状态码
+//
+// 空闲时定期发送注释行，用于保持代理连接并检测客户端断开
+const sseKeepaliveInterval = 15 * time.Second
+
+// NewInstanceLogsHandler 创建容器日志 SSE 端点，与 gRPC GetInstanceLogs 共用同一处理逻辑
+func NewInstanceLogsHandler(resource *service.ResourceService, authz *biz.AuthzUsecase, logger log.Logger) http.Handler {
+	helper := log.NewHelper(logger)
+	codec := encoding.GetCodec(json.Name)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := logsRequestFromHTTP(r)
+		if err != nil {
+			se := errors.FromError(err)
+			http.Error(w, se.Message, int(se.Code))
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		// HTTP 服务的请求超时不适用于 follow，客户端断开由写入失败检测
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		defer cancel()
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			ctx = biz.NewPrincipalContext(ctx, principalFromTransport(authz, tr))
+		}
+
+		stream := &sseLogStream{ctx: ctx, cancel: cancel, w: w, flusher: flusher, codec: codec}
+		go stream.keepalive(sseKeepaliveInterval)
+		err = resource.StreamLogs(req, stream)
+		if err != nil {
+			helper.Debugf("log stream of instance %d ended: %v", req.InstanceId, err)
+		}
+		stream.close(err)
+	})
+}
+
+// logsRequestFromHTTP 从路径与查询参数构造 GetInstanceLogsReq
+func logsRequestFromHTTP(r *http.Request) (*v1.GetInstanceLogsReq, error) {
+	instanceID, err := strconv.ParseInt(mux.Vars(r)["instance_id"], 10, 64)
+	if err != nil || instanceID <= 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "invalid instance_id")
+	}
+	query := r.URL.Query()
+	req := &v1.GetInstanceLogsReq{InstanceId: instanceID}
+
+	for name, target := range map[string]*bool{
+		"follow":     &req.Follow,
+		"previous":   &req.Previous,
+		"timestamps": &req.Timestamps,
+	} {
+		if value := query.Get(name); value != "" {
+			if *target, err = strconv.ParseBool(value); err != nil {
+				return nil, errors.New(400, "INVALID_ARGUMENT", "invalid "+name)
+			}
+		}
+	}
+	if value := query.Get("tail_lines"); value != "" {
+		tail, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New(400, "INVALID_ARGUMENT", "invalid tail_lines")
+		}
+		req.TailLines = &tail
+	}
+	if value := query.Get("since_time"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New(400, "INVALID_ARGUMENT", "since_time must be RFC3339")
+		}
+		req.SinceTime = timestamppb.New(since)
+	}
+	if container := query.Get("container"); container != "" {
+		req.ContainerName = &container
+	}
+	if pod := query.Get("pod"); pod != "" {
+		req.PodName = &pod
+	}
+	return req, nil
+}
+
+// sseLogStream 将 HTTP 响应适配为 service.LogStream，首次写入时才发送响应头
+type sseLogStream struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	w       http.ResponseWriter
+	flusher http.Flusher
+	codec   encoding.Codec
+
+	mu      sync.Mutex // 保护并发写
+	started bool
+	closed  bool
+}
+
+func (s *sseLogStream) Context() context.Context {
+	return s.ctx
+}
+
+// Send 写入一条 log 事件
+func (s *sseLogStream) Send(line *v1.InstanceLogLine) error {
+	data, err := s.codec.Marshal(line)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(fmt.Sprintf("event: log\ndata: %s\n\n", data))
+}
+
+// keepalive 定期写入注释行，写入失败说明客户端已断开
+func (s *sseLogStream) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if !s.closed {
+				_ = s.write(": keepalive\n\n")
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// write 写入并立即刷新，调用方持有锁
+func (s *sseLogStream) write(payload string) error {
+	if !s.started {
+		header := s.w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	if _, err := fmt.Fprint(s.w, payload); err != nil {
+		s.cancel()
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// close 结束输出：尚未开始时以 HTTP 状态码返回错误，否则发送 error 事件
+func (s *sseLogStream) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.cancel()
+
+	if err == nil {
+		if !s.started {
+			_ = s.write(": end\n\n")
+		}
+		return
+	}
+	se := errors.FromError(err)
+	if !s.started {
+		http.Error(s.w, se.Message, int(se.Code))
+		return
+	}
+	data, _ := s.codec.Marshal(map[string]interface{}{
+		"code":    se.Code,
+		"reason":  se.Reason,
+		"message": se.Message,
+	})
+	_, _ = fmt.Fprintf(s.w, "event: error\ndata: %s\n\n", data)
+	s.flusher.Flush()
+}
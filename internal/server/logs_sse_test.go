@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "resource/api/resource/v1"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/encoding/json"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+func TestLogsRequestFromHTTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{name: "all_options", target: "/v1/instances/7/logs?follow=true&tail_lines=50&since_time=2025-01-01T00:00:00Z&previous=true&timestamps=1&container=app&pod=p"},
+		{name: "invalid_follow", target: "/v1/instances/7/logs?follow=maybe", wantErr: true},
+		{name: "invalid_tail_lines", target: "/v1/instances/7/logs?tail_lines=ten", wantErr: true},
+		{name: "invalid_since_time", target: "/v1/instances/7/logs?since_time=yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *v1.GetInstanceLogsReq
+			var err error
+			router := mux.NewRouter()
+			router.HandleFunc(InstanceLogsPath, func(_ http.ResponseWriter, r *http.Request) {
+				req, err = logsRequestFromHTTP(r)
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.InstanceId != 7 || !req.Follow || !req.Previous || !req.Timestamps || req.GetTailLines() != 50 ||
+				req.SinceTime.AsTime().Year() != 2025 || req.GetContainerName() != "app" || req.GetPodName() != "p" {
+				t.Fatalf("req=%v", req)
+			}
+		})
+	}
+}
+
+func TestSseLogStream(t *testing.T) {
+	newStream := func(w *httptest.ResponseRecorder) *sseLogStream {
+		ctx, cancel := context.WithCancel(context.Background())
+		return &sseLogStream{ctx: ctx, cancel: cancel, w: w, flusher: w, codec: encoding.GetCodec(json.Name)}
+	}
+
+	// 输出开始后的错误以 error 事件返回
+	w := httptest.NewRecorder()
+	stream := newStream(w)
+	if err := stream.Send(&v1.InstanceLogLine{PodName: "p", Line: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	stream.close(errors.New(500, "INTERNAL_ERROR", "stream broken"))
+	body := w.Body.String()
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("code=%d header=%v", w.Code, w.Header())
+	}
+	if !strings.Contains(body, "event: log\ndata: {") || !strings.Contains(body, `"line":"hello"`) ||
+		!strings.Contains(body, "event: error\ndata: {") || !strings.Contains(body, "stream broken") {
+		t.Fatalf("body=%q", body)
+	}
+	if stream.ctx.Err() == nil {
+		t.Fatal("context should be canceled after close")
+	}
+
+	// 输出开始前的错误直接返回 HTTP 状态码
+	w = httptest.NewRecorder()
+	newStream(w).close(errors.New(404, "NOT_FOUND", "instance not found"))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "instance not found") {
+		t.Fatalf("code=%d body=%q", w.Code, w.Body.String())
+	}
+}
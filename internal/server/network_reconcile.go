@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"resource/internal/biz"
+	"resource/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+var _ transport.Server = (*NetworkReconcileServer)(nil)
+
+// NetworkReconcileServer 周期性核对端口绑定与 K8s 网络资源，并补全尚未分配的访问地址。
+// 配置 lease_namespace 时只有持有 Lease 的副本执行周期核对，否则每个副本都会执行。
+type NetworkReconcileServer struct {
+	uc             *biz.ResourceUsecase
+	interval       time.Duration
+	repair         bool
+	leaseNamespace string
+	log            *log.Helper
+
+	stop chan struct{}
+}
+
+// NewNetworkReconcileServer new a network reconcile server.
+func NewNetworkReconcileServer(c *conf.Server, uc *biz.ResourceUsecase, logger log.Logger) *NetworkReconcileServer {
+	s := &NetworkReconcileServer{
+		uc:   uc,
+		log:  log.NewHelper(logger),
+		stop: make(chan struct{}),
+	}
+	if r := c.GetNetworkReconcile(); r != nil {
+		if r.GetInterval() != nil {
+			s.interval = r.GetInterval().AsDuration()
+		}
+		s.repair = r.GetRepair()
+		s.leaseNamespace = r.GetLeaseNamespace()
+	}
+	return s
+}
+
+// networkPolicyBackfillTimeout 启动时补齐默认隔离策略的超时
+const networkPolicyBackfillTimeout = 5 * time.Minute
+
+// networkReconcileLeaseName 周期核对选主使用的 Lease 名称
+const networkReconcileLeaseName = "resource-network-reconcile"
+
+// Start 启动时先为已有用户命名空间补齐默认隔离策略（需开启 network_policy_backfill），再按配置的间隔执行核对，直到 Stop 或 context 取消；
+// 间隔为 0 时不执行周期核对。配置 lease_namespace 时先参与选主，只在持有 Lease 期间执行周期核对
+func (s *NetworkReconcileServer) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.stop:
+			cancel()
+		}
+	}()
+
+	backfillCtx, backfillCancel := context.WithTimeout(ctx, networkPolicyBackfillTimeout)
+	s.ensureNetworkPolicies(backfillCtx)
+	backfillCancel()
+
+	if s.interval <= 0 {
+		s.log.Info("network reconcile is disabled")
+		<-ctx.Done()
+		return nil
+	}
+
+	if s.leaseNamespace == "" {
+		if s.repair {
+			// 多个副本同时修复会争抢同一外部端口与共享资源
+			s.log.Warn("network reconcile repair is enabled without lease_namespace, run a single replica or configure server.network_reconcile.lease_namespace")
+		}
+		s.run(ctx)
+		return nil
+	}
+	s.log.Infof("network reconcile waits for lease %s/%s", s.leaseNamespace, networkReconcileLeaseName)
+	return s.uc.RunAsLeader(ctx, s.leaseNamespace, networkReconcileLeaseName, s.run)
+}
+
+// run 按配置的间隔执行核对，直到 ctx 取消
+func (s *NetworkReconcileServer) run(ctx context.Context) {
+	s.log.Infof("starting network reconcile, interval=%s repair=%v", s.interval, s.repair)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reconcile(ctx)
+		}
+	}
+}
+
+// Stop 停止周期核对
+func (s *NetworkReconcileServer) Stop(context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	return nil
+}
+
+func (s *NetworkReconcileServer) reconcile(ctx context.Context) {
+	// 单次核对不超过一个周期，避免与下一次重叠
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	s.ensureNetworkPolicies(ctx)
+
+	// 补全开放后才分配的访问地址，不依赖用户查询
+	if updated, err := s.uc.RefreshAccessURLs(ctx); err != nil {
+		s.log.Errorf("refresh access URLs failed: %v", err)
+	} else if updated > 0 {
+		s.log.Infof("refreshed %d access URLs", updated)
+	}
+
+	report, err := s.uc.ReconcileNetwork(ctx, 0, s.repair)
+	if err != nil {
+		s.log.Errorf("network reconcile failed: %v", err)
+		return
+	}
+	for _, d := range report.Drifts {
+		s.log.Warnf("network drift: kind=%s instance=%d namespace=%s name=%s port=%d external_port=%d protocol=%s detail=%s repaired=%v repair_error=%s",
+			d.Kind, d.InstanceID, d.Namespace, d.Name, d.Port, d.ExternalPort, d.Protocol, d.Detail, d.Repaired, d.RepairError)
+	}
+}
+
+// ensureNetworkPolicies 补齐用户命名空间的默认隔离策略，与 repair 无关；未开启 network_policy_backfill 时不修改命名空间
+func (s *NetworkReconcileServer) ensureNetworkPolicies(ctx context.Context) {
+	failed, err := s.uc.EnsureNetworkPolicies(ctx)
+	if err != nil {
+		s.log.Errorf("ensure network policies failed: %v", err)
+		return
+	}
+	if failed > 0 {
+		s.log.Warnf("failed to ensure network policies of %d namespaces", failed)
+	}
+}
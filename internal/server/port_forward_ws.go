@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+	"resource/internal/conf"
+	"resource/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// PortForwardWebSocketPath WebSocket 端口转发网关路径
+const PortForwardWebSocketPath = "/v1/instances/{instance_id}/port-forward"
+
+// WebSocket 端口转发帧协议（每个连接对应一个 TCP 连接）：
+//
+//	建立连接：GET /v1/instances/{instance_id}/port-forward?port=8888&pod=xxx
+//	客户端 → 服务端：二进制帧，发往容器端口的数据
+//	服务端 → 客户端：
+//	  文本帧：{"type":"ready","pod":"xxx","port":8888}，隧道建立后发送一次
+//	  二进制帧：容器端口返回的数据
+//
+// 隧道建立前的错误通过关闭帧的原因返回。
+
+// NewPortForwardWebSocketHandler 创建 WebSocket 端口转发网关，与 gRPC PortForward 共用同一处理逻辑
+func NewPortForwardWebSocketHandler(c *conf.Server, resource *service.ResourceService, authz *biz.AuthzUsecase, logger log.Logger) http.Handler {
+	helper := log.NewHelper(logger)
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  32 * 1024,
+		WriteBufferSize: 32 * 1024,
+		CheckOrigin:     checkOrigin(c.GetExec().GetAllowedOrigins()),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		init, err := portForwardInitFromRequest(r)
+		if err != nil {
+			se := errors.FromError(err)
+			http.Error(w, se.Message, int(se.Code))
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			helper.Warnf("websocket upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		// HTTP 服务的请求超时不适用于长连接，连接断开时关闭隧道
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		defer cancel()
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			ctx = biz.NewPrincipalContext(ctx, principalFromTransport(authz, tr))
+		}
+
+		stream := &wsPortForwardStream{ctx: ctx, cancel: cancel, conn: conn, init: init}
+		err = resource.Forward(stream)
+		stream.close(err)
+	})
+}
+
+// portForwardInitFromRequest 从路径与查询参数构造 PortForwardInit
+func portForwardInitFromRequest(r *http.Request) (*v1.PortForwardInit, error) {
+	instanceID, err := strconv.ParseInt(mux.Vars(r)["instance_id"], 10, 64)
+	if err != nil || instanceID <= 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "invalid instance_id")
+	}
+	query := r.URL.Query()
+	port, err := strconv.ParseUint(query.Get("port"), 10, 16)
+	if err != nil || port == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "invalid port")
+	}
+	init := &v1.PortForwardInit{InstanceId: instanceID, Port: uint32(port)}
+	if pod := query.Get("pod"); pod != "" {
+		init.PodName = &pod
+	}
+	return init, nil
+}
+
+// wsPortForwardStream 将 WebSocket 连接适配为 service.PortForwardStream
+type wsPortForwardStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	conn   *websocket.Conn
+	init   *v1.PortForwardInit
+
+	mu sync.Mutex // 保护并发写
+}
+
+func (s *wsPortForwardStream) Context() context.Context {
+	return s.ctx
+}
+
+// Recv 首次返回由请求参数构造的 PortForwardInit，之后读取客户端二进制帧
+func (s *wsPortForwardStream) Recv() (*v1.PortForwardRequest, error) {
+	if s.init != nil {
+		init := s.init
+		s.init = nil
+		return &v1.PortForwardRequest{Message: &v1.PortForwardRequest_Init{Init: init}}, nil
+	}
+
+	for {
+		typ, data, err := s.conn.ReadMessage()
+		if err != nil {
+			// 客户端断开，关闭隧道
+			s.cancel()
+			return nil, err
+		}
+		if typ == websocket.BinaryMessage {
+			return &v1.PortForwardRequest{Message: &v1.PortForwardRequest_Data{Data: data}}, nil
+		}
+	}
+}
+
+// Send 将 PortForwardResponse 编码为 WebSocket 帧
+func (s *wsPortForwardStream) Send(resp *v1.PortForwardResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg := resp.Message.(type) {
+	case *v1.PortForwardResponse_Ready:
+		return s.conn.WriteJSON(wsFrame{Type: "ready", Pod: msg.Ready.PodName, Port: msg.Ready.Port})
+	case *v1.PortForwardResponse_Data:
+		return s.conn.WriteMessage(websocket.BinaryMessage, msg.Data)
+	}
+	return nil
+}
+
+// close 发送关闭帧，隧道建立前的错误作为关闭原因返回给客户端
+func (s *wsPortForwardStream) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.conn.WriteMessage(websocket.CloseMessage, wsCloseMessage(err))
+}
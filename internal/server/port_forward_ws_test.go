@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "resource/api/resource/v1"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPortForwardInitFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{name: "port_and_pod", target: "/v1/instances/7/port-forward?port=8888&pod=p"},
+		{name: "missing_port", target: "/v1/instances/7/port-forward", wantErr: true},
+		{name: "port_out_of_range", target: "/v1/instances/7/port-forward?port=70000", wantErr: true},
+		{name: "invalid_instance", target: "/v1/instances/x/port-forward?port=80", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var init *v1.PortForwardInit
+			var err error
+			router := mux.NewRouter()
+			router.HandleFunc(PortForwardWebSocketPath, func(_ http.ResponseWriter, r *http.Request) {
+				init, err = portForwardInitFromRequest(r)
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if init.InstanceId != 7 || init.Port != 8888 || init.GetPodName() != "p" {
+				t.Fatalf("init=%v", init)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+)
+
+type clientContextKey struct{}
+
+// NewClientContext 保存未附加服务端请求超时的 context，需在 transport 的超时之前调用。
+// 长时间运行的调用（如 RunCommand）不受服务端超时限制，但仍随客户端断开而取消。
+func NewClientContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, ctx)
+}
+
+// withClientCancel 返回保留 ctx 的值但不受服务端超时限制的 context，客户端断开时取消。
+// 未经 NewClientContext 时沿用 ctx 的取消。
+func withClientCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	client, ok := ctx.Value(clientContextKey{}).(context.Context)
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(client, cancel)
+	return detached, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AddCustomDomain 为 HTTP 端口添加自定义域名，返回验证所有权需要添加的 TXT 记录
+func (s *ResourceService) AddCustomDomain(ctx context.Context, req *v1.AddCustomDomainReq) (*v1.AddCustomDomainReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if req.Port == 0 || req.Port > 65535 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "invalid port number, must be 1-65535")
+	}
+
+	domain, err := s.uc.AddCustomDomain(ctx, req.InstanceId, req.Port, req.Domain)
+	if err != nil {
+		return nil, customDomainError("failed to add custom domain", err)
+	}
+	return &v1.AddCustomDomainReply{Domain: toCustomDomainReply(*domain)}, nil
+}
+
+// VerifyCustomDomain 验证自定义域名的 TXT 记录，通过后创建 HTTPS Ingress
+func (s *ResourceService) VerifyCustomDomain(ctx context.Context, req *v1.VerifyCustomDomainReq) (*v1.VerifyCustomDomainReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+
+	domain, err := s.uc.VerifyCustomDomain(ctx, req.InstanceId, req.Domain)
+	if err != nil {
+		return nil, customDomainError("failed to verify custom domain", err)
+	}
+	return &v1.VerifyCustomDomainReply{Domain: toCustomDomainReply(*domain)}, nil
+}
+
+// ListCustomDomains 列出实例的自定义域名
+func (s *ResourceService) ListCustomDomains(ctx context.Context, req *v1.ListCustomDomainsReq) (*v1.ListCustomDomainsReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+
+	domains, err := s.uc.ListCustomDomains(ctx, req.InstanceId)
+	if err != nil {
+		return nil, customDomainError("failed to list custom domains", err)
+	}
+
+	reply := &v1.ListCustomDomainsReply{Domains: make([]*v1.CustomDomain, 0, len(domains))}
+	for _, d := range domains {
+		reply.Domains = append(reply.Domains, toCustomDomainReply(d))
+	}
+	return reply, nil
+}
+
+// DeleteCustomDomain 删除自定义域名及其 Ingress
+func (s *ResourceService) DeleteCustomDomain(ctx context.Context, req *v1.DeleteCustomDomainReq) (*v1.DeleteCustomDomainReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+
+	if err := s.uc.DeleteCustomDomain(ctx, req.InstanceId, req.Domain); err != nil {
+		return nil, customDomainError("failed to delete custom domain", err)
+	}
+	return &v1.DeleteCustomDomainReply{Success: true}, nil
+}
+
+func customDomainError(message string, err error) error {
+	switch {
+	case errors.Is(err, biz.ErrInstanceNotFound):
+		return errors.New(404, "NOT_FOUND", "instance not found")
+	case errors.Is(err, biz.ErrPortNotOpen):
+		return errors.New(404, "PORT_NOT_OPEN", "port is not open")
+	case errors.Is(err, biz.ErrCustomDomainNotFound):
+		return errors.New(404, "DOMAIN_NOT_FOUND", "custom domain not found")
+	case errors.Is(err, biz.ErrDomainTaken):
+		return errors.New(409, "DOMAIN_TAKEN", err.Error())
+	case errors.Is(err, biz.ErrDomainVerificationFailed):
+		return errors.New(412, "DOMAIN_VERIFICATION_FAILED", err.Error())
+	case errors.Is(err, biz.ErrInvalidDomain),
+		errors.Is(err, biz.ErrCustomDomainNotSupported),
+		errors.Is(err, biz.ErrNotSupportedByBackend),
+		errors.Is(err, biz.ErrTLSNotConfigured):
+		return errors.New(400, "INVALID_ARGUMENT", err.Error())
+	}
+	return errors.New(500, "INTERNAL_ERROR", message+": "+err.Error())
+}
+
+func toCustomDomainReply(d biz.CustomDomain) *v1.CustomDomain {
+	item := &v1.CustomDomain{
+		Domain:         d.Domain,
+		Port:           d.Port,
+		Status:         d.Status,
+		TxtRecordName:  d.TXTRecordName(),
+		TxtRecordValue: d.TXTRecordValue(),
+		AccessUrl:      d.AccessURL(),
+	}
+	if !d.CreatedAt.IsZero() {
+		item.CreatedAt = timestamppb.New(d.CreatedAt)
+	}
+	if d.VerifiedAt != nil {
+		item.VerifiedAt = timestamppb.New(*d.VerifiedAt)
+	}
+	return item
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GetInstanceEvents returns Kubernetes events of an instance together with the diagnosed failure reason.
+func (s *ResourceService) GetInstanceEvents(ctx context.Context, req *v1.GetInstanceEventsReq) (*v1.GetInstanceEventsReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+
+	diagnosis, err := s.uc.GetInstanceEvents(ctx, req.InstanceId, req.WarningsOnly)
+	if err != nil {
+		if errors.Is(err, biz.ErrInstanceNotFound) {
+			return nil, errors.New(404, "NOT_FOUND", "instance not found")
+		}
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to list events: "+err.Error())
+	}
+
+	reply := &v1.GetInstanceEventsReply{
+		Events:        make([]*v1.InstanceEvent, 0, len(diagnosis.Events)),
+		StatusReason:  diagnosis.StatusReason,
+		StatusMessage: diagnosis.StatusMessage,
+	}
+	for _, ev := range diagnosis.Events {
+		reply.Events = append(reply.Events, &v1.InstanceEvent{
+			Type:          ev.Type,
+			Reason:        ev.Reason,
+			Message:       ev.Message,
+			ObjectKind:    ev.ObjectKind,
+			ObjectName:    ev.ObjectName,
+			Count:         ev.Count,
+			FirstSeen:     timestamppb.New(ev.FirstSeen),
+			LastSeen:      timestamppb.New(ev.LastSeen),
+			FailureReason: ev.FailureReason,
+		})
+	}
+	return reply, nil
+}
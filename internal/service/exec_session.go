@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListExecSessions lists recorded exec sessions of an instance.
+func (s *ResourceService) ListExecSessions(ctx context.Context, req *v1.ListExecSessionsReq) (*v1.ListExecSessionsReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+
+	sessions, err := s.uc.ListExecSessions(ctx, req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &v1.ListExecSessionsReply{
+		Sessions: make([]*v1.ExecSession, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		reply.Sessions = append(reply.Sessions, toExecSessionProto(session))
+	}
+	return reply, nil
+}
+
+// GetExecSession returns an exec session with its asciicast v2 recording.
+func (s *ResourceService) GetExecSession(ctx context.Context, req *v1.GetExecSessionReq) (*v1.GetExecSessionReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if req.SessionId == "" {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "session_id is required")
+	}
+
+	session, recording, err := s.uc.GetExecSession(ctx, req.SessionId)
+	if err != nil {
+		if errors.Is(err, biz.ErrExecSessionNotFound) {
+			return nil, errors.New(404, "NOT_FOUND", "exec session not found")
+		}
+		return nil, err
+	}
+	// 访问控制按 instance_id 校验，会话必须属于该实例
+	if session.InstanceID != req.InstanceId {
+		return nil, errors.New(404, "NOT_FOUND", "exec session not found")
+	}
+
+	return &v1.GetExecSessionReply{
+		Session:   toExecSessionProto(*session),
+		Recording: recording,
+	}, nil
+}
+
+func toExecSessionProto(session biz.ExecSession) *v1.ExecSession {
+	out := &v1.ExecSession{
+		SessionId:     session.SessionID,
+		InstanceId:    session.InstanceID,
+		UserId:        session.UserID,
+		ContainerName: session.ContainerName,
+		Command:       session.Command,
+		Tty:           session.TTY,
+		ExitCode:      session.ExitCode,
+		StartedAt:     timestamppb.New(session.StartedAt),
+	}
+	if session.EndedAt != nil {
+		out.EndedAt = timestamppb.New(*session.EndedAt)
+	}
+	return out
+}
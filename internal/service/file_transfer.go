@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// 每传输约 1MiB 发送一次进度
+const fileProgressInterval = 1 << 20
+
+// UploadFile 上传文件或目录（tar 归档）到实例容器，首条消息必须为 UploadFileInit
+func (s *ResourceService) UploadFile(stream v1.ResourceService_UploadFileServer) error {
+	ctx := stream.Context()
+
+	req, err := stream.Recv()
+	if err != nil {
+		return errors.New(500, "STREAM_ERROR", "failed to receive init message: "+err.Error())
+	}
+	init := req.GetInit()
+	if init == nil {
+		return errors.New(400, "INVALID_ARGUMENT", "first message must be UploadFileInit")
+	}
+	if init.InstanceId == 0 {
+		return errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if init.Path == "" {
+		return errors.New(400, "INVALID_ARGUMENT", "path is required")
+	}
+
+	opts := biz.FileTransferOptions{
+		Path:          init.Path,
+		Archive:       init.Archive,
+		Size:          init.Size,
+		Mode:          init.Mode,
+		PodName:       init.GetPodName(),
+		ContainerName: init.GetContainerName(),
+		MaxBytes:      s.limiter.MaxUploadBytes(),
+	}
+	command, err := opts.UploadCommand()
+	if err != nil {
+		return errors.New(400, "INVALID_ARGUMENT", err.Error())
+	}
+
+	resource, lease, execCtx, err := s.acquireFileTransfer(ctx, "UploadFile", init.InstanceId, command)
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	total := init.Size
+	if init.Archive {
+		total = 0
+	}
+
+	// 进度在读取协程中发送，结束后不再发送
+	var mu sync.Mutex
+	finished := false
+	var reported int64
+	progress := func(n int64) {
+		lease.Touch()
+		if n-reported < fileProgressInterval {
+			return
+		}
+		reported = n
+		mu.Lock()
+		defer mu.Unlock()
+		if !finished {
+			_ = stream.Send(&v1.UploadFileResponse{
+				Message: &v1.UploadFileResponse_Progress{
+					Progress: &v1.FileTransferProgress{Bytes: n, TotalBytes: total},
+				},
+			})
+		}
+	}
+
+	n, err := s.uc.UploadFile(execCtx, resource.UserID, init.InstanceId, opts, &uploadReader{stream: stream}, progress)
+
+	mu.Lock()
+	defer mu.Unlock()
+	finished = true
+	if err != nil {
+		return toFileTransferError(err, lease)
+	}
+	return stream.Send(&v1.UploadFileResponse{
+		Message: &v1.UploadFileResponse_Result{
+			Result: &v1.UploadFileResult{Bytes: n},
+		},
+	})
+}
+
+// DownloadFile 从实例容器下载文件或目录（tar 归档），首条消息为 DownloadFileInfo
+func (s *ResourceService) DownloadFile(req *v1.DownloadFileReq, stream v1.ResourceService_DownloadFileServer) error {
+	ctx := stream.Context()
+
+	if req.InstanceId == 0 {
+		return errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if req.Path == "" {
+		return errors.New(400, "INVALID_ARGUMENT", "path is required")
+	}
+
+	opts := biz.FileTransferOptions{
+		Path:          req.Path,
+		Archive:       req.Archive,
+		PodName:       req.GetPodName(),
+		ContainerName: req.GetContainerName(),
+		MaxBytes:      s.limiter.MaxDownloadBytes(),
+	}
+	command, err := opts.DownloadCommand()
+	if err != nil {
+		return errors.New(400, "INVALID_ARGUMENT", err.Error())
+	}
+
+	resource, lease, execCtx, err := s.acquireFileTransfer(ctx, "DownloadFile", req.InstanceId, command)
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	w := &downloadWriter{stream: stream, lease: lease, chunkSize: s.limiter.FileChunkSize()}
+	n, err := s.uc.DownloadFile(execCtx, resource.UserID, req.InstanceId, opts, func(info biz.FileInfo) error {
+		w.total = info.Size
+		return stream.Send(&v1.DownloadFileResponse{
+			Message: &v1.DownloadFileResponse_Info{
+				Info: &v1.DownloadFileInfo{
+					Name:    info.Name,
+					Size:    info.Size,
+					Mode:    info.Mode,
+					Archive: info.Archive,
+				},
+			},
+		})
+	}, w)
+	if err != nil {
+		return toFileTransferError(err, lease)
+	}
+
+	// 最后一条进度即传输完成
+	return stream.Send(&v1.DownloadFileResponse{
+		Message: &v1.DownloadFileResponse_Progress{
+			Progress: &v1.FileTransferProgress{Bytes: n, TotalBytes: w.total},
+		},
+	})
+}
+
+// acquireFileTransfer 文件传输的访问控制、实例查询与会话名额，与 exec 会话共用并发上限。
+// 文件传输在容器内执行 tar，command 按 exec 命令策略授权。
+func (s *ResourceService) acquireFileTransfer(ctx context.Context, operation string, instanceID int64, command []string) (*biz.Resource, *biz.ExecLease, context.Context, error) {
+	p, _ := biz.PrincipalFromContext(ctx)
+	err := s.authz.Authorize(ctx, p, operation, instanceID)
+	if err == nil {
+		err = s.authz.AuthorizeExec(ctx, p, instanceID, command, false)
+	}
+	if err != nil {
+		if errors.Is(err, biz.ErrPermissionDenied) {
+			return nil, nil, nil, errors.New(403, "PERMISSION_DENIED", err.Error())
+		}
+		return nil, nil, nil, errors.New(500, "INTERNAL_ERROR", "authorization failed: "+err.Error())
+	}
+
+	resource, err := s.uc.GetResource(ctx, instanceID)
+	if err != nil {
+		return nil, nil, nil, errors.New(500, "INTERNAL_ERROR", "failed to query instance: "+err.Error())
+	}
+	if resource == nil {
+		return nil, nil, nil, errors.New(404, "NOT_FOUND", "instance not found")
+	}
+	if resource.UserID == "" {
+		return nil, nil, nil, errors.New(500, "INTERNAL_ERROR", "instance namespace is empty")
+	}
+
+	userID := p.UserID
+	if userID == "" {
+		userID = resource.UserID
+	}
+	lease, execCtx, err := s.limiter.Acquire(ctx, userID, instanceID)
+	if err != nil {
+		return nil, nil, nil, errors.New(429, "TOO_MANY_SESSIONS", err.Error())
+	}
+	return resource, lease, execCtx, nil
+}
+
+// toFileTransferError 将 biz 层文件传输错误映射为 API 错误
+func toFileTransferError(err error, lease *biz.ExecLease) error {
+	var transferErr *biz.FileTransferError
+	switch {
+	case lease.Reason() != "":
+		return errors.New(408, "SESSION_TIMEOUT", lease.Reason())
+	case errors.Is(err, biz.ErrFileTooLarge):
+		return errors.New(413, "FILE_TOO_LARGE", err.Error())
+	case errors.Is(err, biz.ErrIsDirectory), errors.Is(err, biz.ErrInvalidPath), errors.Is(err, biz.ErrUploadSizeMismatch):
+		return errors.New(400, "INVALID_ARGUMENT", err.Error())
+	case errors.As(err, &transferErr):
+		return errors.New(400, "FILE_TRANSFER_FAILED", err.Error())
+	default:
+		return errors.New(500, "INTERNAL_ERROR", "file transfer failed: "+err.Error())
+	}
+}
+
+// uploadReader 将上传流中的 chunk 消息适配为 io.Reader，客户端关闭发送端即结束
+type uploadReader struct {
+	stream v1.ResourceService_UploadFileServer
+	buf    []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = req.GetChunk()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// downloadWriter 将下载内容按 chunkSize 拆分为 chunk 消息，并定期发送进度
+type downloadWriter struct {
+	stream    v1.ResourceService_DownloadFileServer
+	lease     *biz.ExecLease
+	chunkSize int
+	total     int64
+	written   int64
+	reported  int64
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		size := min(len(p), w.chunkSize)
+		if err := w.stream.Send(&v1.DownloadFileResponse{
+			Message: &v1.DownloadFileResponse_Chunk{Chunk: p[:size]},
+		}); err != nil {
+			return n, err
+		}
+		w.lease.Touch()
+		n += size
+		w.written += int64(size)
+		p = p[size:]
+	}
+
+	if w.written-w.reported >= fileProgressInterval {
+		w.reported = w.written
+		if err := w.stream.Send(&v1.DownloadFileResponse{
+			Message: &v1.DownloadFileResponse_Progress{
+				Progress: &v1.FileTransferProgress{Bytes: w.written, TotalBytes: w.total},
+			},
+		}); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListInstancePorts lists exposed ports of an instance with the live health of their backing Services.
+func (s *ResourceService) ListInstancePorts(ctx context.Context, req *v1.ListInstancePortsReq) (*v1.ListInstancePortsReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+
+	ports, err := s.uc.ListInstancePorts(ctx, req.InstanceId)
+	if err != nil {
+		if errors.Is(err, biz.ErrInstanceNotFound) {
+			return nil, errors.New(404, "NOT_FOUND", "instance not found")
+		}
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to list instance ports: "+err.Error())
+	}
+
+	reply := &v1.ListInstancePortsReply{
+		Ports: make([]*v1.InstancePort, 0, len(ports)),
+	}
+	for _, p := range ports {
+		reply.Ports = append(reply.Ports, toInstancePortReply(p))
+	}
+	return reply, nil
+}
+
+func toInstancePortReply(p biz.InstancePort) *v1.InstancePort {
+	item := &v1.InstancePort{
+		Port:        p.Port,
+		Protocol:    p.Protocol,
+		Transport:   p.Transport,
+		AccessUrl:   p.AccessURL,
+		Enabled:     p.Enabled,
+		Active:      p.Active,
+		ServiceName: p.ServiceName,
+		Health: &v1.PortHealth{
+			Status:            p.Health.Status,
+			ReadyEndpoints:    p.Health.ReadyEndpoints,
+			NotReadyEndpoints: p.Health.NotReadyEndpoints,
+			Message:           p.Health.Message,
+		},
+	}
+	switch {
+	case p.ExternalPort != nil:
+		item.ExternalPort = *p.ExternalPort
+	case p.NodePort != nil:
+		item.ExternalPort = *p.NodePort
+	}
+	if !p.CreatedAt.IsZero() {
+		item.CreatedAt = timestamppb.New(p.CreatedAt)
+	}
+	return item
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// LogStream 日志输出流，gRPC 服务端流与 HTTP SSE 各自实现
+type LogStream interface {
+	Context() context.Context
+	Send(*v1.InstanceLogLine) error
+}
+
+// GetInstanceLogs 容器日志服务端流处理
+func (s *ResourceService) GetInstanceLogs(req *v1.GetInstanceLogsReq, stream v1.ResourceService_GetInstanceLogsServer) error {
+	return s.StreamLogs(req, stream)
+}
+
+// StreamLogs 读取实例容器日志写入 stream，follow 为 true 时持续输出直到客户端断开
+func (s *ResourceService) StreamLogs(req *v1.GetInstanceLogsReq, stream LogStream) error {
+	ctx := stream.Context()
+
+	if req == nil || req.InstanceId == 0 {
+		return errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if req.TailLines != nil && req.GetTailLines() < 0 {
+		return errors.New(400, "INVALID_ARGUMENT", "tail_lines must not be negative")
+	}
+
+	// 流式请求不经过鉴权中间件，在此校验
+	p, _ := biz.PrincipalFromContext(ctx)
+	if err := s.authz.Authorize(ctx, p, "GetInstanceLogs", req.InstanceId); err != nil {
+		if errors.Is(err, biz.ErrPermissionDenied) {
+			return errors.New(403, "PERMISSION_DENIED", err.Error())
+		}
+		return errors.New(500, "INTERNAL_ERROR", "authorization failed: "+err.Error())
+	}
+
+	opts := biz.LogOptions{
+		PodName:       req.GetPodName(),
+		ContainerName: req.GetContainerName(),
+		Follow:        req.Follow,
+		TailLines:     req.TailLines,
+		Previous:      req.Previous,
+		Timestamps:    req.Timestamps,
+	}
+	if req.SinceTime != nil {
+		since := req.SinceTime.AsTime()
+		opts.SinceTime = &since
+	}
+
+	err := s.uc.StreamInstanceLogs(ctx, req.InstanceId, opts, func(line biz.LogLine) error {
+		reply := &v1.InstanceLogLine{
+			PodName:       line.PodName,
+			ContainerName: line.ContainerName,
+			Line:          line.Line,
+		}
+		if !line.Timestamp.IsZero() {
+			reply.Timestamp = timestamppb.New(line.Timestamp)
+		}
+		return stream.Send(reply)
+	})
+	switch {
+	case err == nil, ctx.Err() != nil:
+		// 客户端断开时正常结束
+		return nil
+	case errors.Is(err, biz.ErrInstanceNotFound):
+		return errors.New(404, "NOT_FOUND", "instance not found")
+	case errors.Is(err, biz.ErrPodNotFound):
+		return errors.New(404, "POD_NOT_FOUND", err.Error())
+	case errors.Is(err, biz.ErrLogsUnavailable):
+		return errors.New(400, "LOGS_UNAVAILABLE", err.Error())
+	default:
+		return errors.New(500, "INTERNAL_ERROR", "failed to stream logs: "+err.Error())
+	}
+}
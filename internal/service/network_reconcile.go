@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ReconcileNetwork compares network bindings with Kubernetes resources and optionally repairs the drifts.
+func (s *ResourceService) ReconcileNetwork(ctx context.Context, req *v1.ReconcileNetworkReq) (*v1.ReconcileNetworkReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	// 全量核对涉及所有用户的资源，仅限可访问全部实例的角色
+	if req.InstanceId == 0 {
+		if p, ok := biz.PrincipalFromContext(ctx); ok {
+			if _, scoped := s.authz.ScopedUserID(p); scoped {
+				return nil, errors.New(403, "PERMISSION_DENIED", "instance_id is required for role "+p.Role)
+			}
+		}
+	}
+
+	report, err := s.uc.ReconcileNetwork(ctx, req.InstanceId, req.Repair)
+	if err != nil {
+		if errors.Is(err, biz.ErrInstanceNotFound) {
+			return nil, errors.New(404, "NOT_FOUND", "instance not found")
+		}
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to reconcile network: "+err.Error())
+	}
+
+	reply := &v1.ReconcileNetworkReply{
+		CheckedBindings: int32(report.CheckedBindings),
+		Drifts:          make([]*v1.NetworkDrift, 0, len(report.Drifts)),
+	}
+	for _, d := range report.Drifts {
+		reply.Drifts = append(reply.Drifts, &v1.NetworkDrift{
+			Kind:         d.Kind,
+			InstanceId:   d.InstanceID,
+			Namespace:    d.Namespace,
+			Name:         d.Name,
+			Port:         d.Port,
+			ExternalPort: d.ExternalPort,
+			Protocol:     d.Protocol,
+			Detail:       d.Detail,
+			Repaired:     d.Repaired,
+			RepairError:  d.RepairError,
+		})
+	}
+	return reply, nil
+}
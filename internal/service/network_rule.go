@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateNetworkRule 为用户命名空间添加网络规则
+func (s *ResourceService) CreateNetworkRule(ctx context.Context, req *v1.CreateNetworkRuleReq) (*v1.CreateNetworkRuleReply, error) {
+	if req == nil || req.Rule == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "rule is required")
+	}
+	if err := s.checkNetworkRuleUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	rule, err := s.uc.CreateNetworkRule(ctx, biz.NetworkRule{
+		UserID:      req.UserId,
+		Direction:   req.Rule.Direction,
+		PeerUserID:  req.Rule.PeerUserId,
+		CIDR:        req.Rule.Cidr,
+		Ports:       req.Rule.Ports,
+		Protocol:    req.Rule.Protocol,
+		Description: req.Rule.Description,
+	})
+	if err != nil {
+		if errors.Is(err, biz.ErrInvalidNetworkRule) || errors.Is(err, biz.ErrNetworkPolicyDisabled) {
+			return nil, errors.New(400, "INVALID_ARGUMENT", err.Error())
+		}
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to create network rule: "+err.Error())
+	}
+	return &v1.CreateNetworkRuleReply{Rule: toNetworkRuleReply(*rule)}, nil
+}
+
+// ListNetworkRules 查询用户命名空间的网络规则
+func (s *ResourceService) ListNetworkRules(ctx context.Context, req *v1.ListNetworkRulesReq) (*v1.ListNetworkRulesReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if err := s.checkNetworkRuleUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.uc.ListNetworkRules(ctx, req.UserId)
+	if err != nil {
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to list network rules: "+err.Error())
+	}
+
+	reply := &v1.ListNetworkRulesReply{Rules: make([]*v1.NetworkRule, 0, len(rules))}
+	for _, rule := range rules {
+		reply.Rules = append(reply.Rules, toNetworkRuleReply(rule))
+	}
+	return reply, nil
+}
+
+// DeleteNetworkRule 删除用户命名空间的网络规则
+func (s *ResourceService) DeleteNetworkRule(ctx context.Context, req *v1.DeleteNetworkRuleReq) (*v1.DeleteNetworkRuleReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if err := s.checkNetworkRuleUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	if req.RuleId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "rule_id is required")
+	}
+
+	if err := s.uc.DeleteNetworkRule(ctx, req.UserId, req.RuleId); err != nil {
+		if errors.Is(err, biz.ErrNetworkRuleNotFound) {
+			return nil, errors.New(404, "NOT_FOUND", "network rule not found")
+		}
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to delete network rule: "+err.Error())
+	}
+	return &v1.DeleteNetworkRuleReply{Success: true}, nil
+}
+
+// checkNetworkRuleUser 网络规则按用户命名空间管理，仅能访问本人实例的角色只能管理本人的规则
+func (s *ResourceService) checkNetworkRuleUser(ctx context.Context, userID string) error {
+	if userID == "" {
+		return errors.New(400, "INVALID_ARGUMENT", "user_id is required")
+	}
+	if p, ok := biz.PrincipalFromContext(ctx); ok {
+		if scopedUserID, scoped := s.authz.ScopedUserID(p); scoped && scopedUserID != userID {
+			return errors.New(403, "PERMISSION_DENIED", "network rules of other users are out of scope for role "+p.Role)
+		}
+	}
+	return nil
+}
+
+func toNetworkRuleReply(rule biz.NetworkRule) *v1.NetworkRule {
+	return &v1.NetworkRule{
+		RuleId:      rule.ID,
+		UserId:      rule.UserID,
+		Direction:   rule.Direction,
+		PeerUserId:  rule.PeerUserID,
+		Cidr:        rule.CIDR,
+		Ports:       rule.Ports,
+		Protocol:    rule.Protocol,
+		Description: rule.Description,
+		CreatedAt:   timestamppb.New(rule.CreatedAt),
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListInstancePods lists pods and containers of an instance that can be used as exec targets.
+func (s *ResourceService) ListInstancePods(ctx context.Context, req *v1.ListInstancePodsReq) (*v1.ListInstancePodsReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+
+	pods, err := s.uc.ListInstancePods(ctx, req.InstanceId)
+	if err != nil {
+		if errors.Is(err, biz.ErrInstanceNotFound) {
+			return nil, errors.New(404, "NOT_FOUND", "instance not found")
+		}
+		return nil, err
+	}
+
+	reply := &v1.ListInstancePodsReply{
+		Pods: make([]*v1.InstancePod, 0, len(pods)),
+	}
+	for _, pod := range pods {
+		item := &v1.InstancePod{
+			Name:        pod.Name,
+			Phase:       pod.Phase,
+			Ready:       pod.Ready,
+			Terminating: pod.Terminating,
+			NodeName:    pod.NodeName,
+			CreatedAt:   timestamppb.New(pod.CreatedAt),
+			Containers:  make([]*v1.InstanceContainer, 0, len(pod.Containers)),
+		}
+		for _, c := range pod.Containers {
+			item.Containers = append(item.Containers, &v1.InstanceContainer{
+				Name:         c.Name,
+				Image:        c.Image,
+				Ready:        c.Ready,
+				RestartCount: c.RestartCount,
+				State:        c.State,
+			})
+		}
+		reply.Pods = append(reply.Pods, item)
+	}
+	return reply, nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// SetPortEnabled 启用或禁用已开放的端口，外部端口与访问地址保持不变
+func (s *ResourceService) SetPortEnabled(ctx context.Context, req *v1.SetPortEnabledReq) (*v1.SetPortEnabledReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if req.Port == 0 || req.Port > 65535 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "invalid port number, must be 1-65535")
+	}
+
+	accessURL, err := s.uc.SetPortEnabled(ctx, req.InstanceId, req.Port, req.Enabled)
+	if err != nil {
+		switch {
+		case errors.Is(err, biz.ErrInstanceNotFound):
+			return nil, errors.New(404, "NOT_FOUND", "instance not found")
+		case errors.Is(err, biz.ErrPortNotOpen):
+			return nil, errors.New(404, "PORT_NOT_OPEN", "port is not open")
+		}
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to update port: "+err.Error())
+	}
+	return &v1.SetPortEnabledReply{Success: true, AccessUrl: accessURL}, nil
+}
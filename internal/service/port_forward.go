@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// PortForwardStream 端口转发的双向消息流，gRPC 流与 WebSocket 连接各自实现
+type PortForwardStream interface {
+	Context() context.Context
+	Recv() (*v1.PortForwardRequest, error)
+	Send(*v1.PortForwardResponse) error
+}
+
+// PortForward 端口转发双向流处理
+func (s *ResourceService) PortForward(stream v1.ResourceService_PortForwardServer) error {
+	return s.Forward(stream)
+}
+
+// Forward 处理一个端口转发隧道，首条消息必须为 PortForwardInit。
+// 隧道只对调用方可见，不创建 Service 或 Ingress。
+func (s *ResourceService) Forward(stream PortForwardStream) error {
+	ctx := stream.Context()
+
+	req, err := stream.Recv()
+	if err != nil {
+		return errors.New(500, "STREAM_ERROR", "failed to receive init message: "+err.Error())
+	}
+	init := req.GetInit()
+	if init == nil {
+		return errors.New(400, "INVALID_ARGUMENT", "first message must be PortForwardInit")
+	}
+	if init.InstanceId == 0 {
+		return errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if init.Port == 0 || init.Port > 65535 {
+		return errors.New(400, "INVALID_ARGUMENT", "port must be between 1 and 65535")
+	}
+
+	p, _ := biz.PrincipalFromContext(ctx)
+	if err := s.authz.Authorize(ctx, p, "PortForward", init.InstanceId); err != nil {
+		if errors.Is(err, biz.ErrPermissionDenied) {
+			return errors.New(403, "PERMISSION_DENIED", err.Error())
+		}
+		return errors.New(500, "INTERNAL_ERROR", "authorization failed: "+err.Error())
+	}
+
+	resource, err := s.uc.GetResource(ctx, init.InstanceId)
+	if err != nil {
+		return errors.New(500, "INTERNAL_ERROR", "failed to query instance: "+err.Error())
+	}
+	if resource == nil {
+		return errors.New(404, "NOT_FOUND", "instance not found")
+	}
+
+	// 与 exec 会话共用并发上限、空闲超时与最长持续时间，任一方向有数据即视为活动
+	userID := p.UserID
+	if userID == "" {
+		userID = resource.UserID
+	}
+	lease, forwardCtx, err := s.limiter.Acquire(ctx, userID, init.InstanceId)
+	if err != nil {
+		return errors.New(429, "TOO_MANY_SESSIONS", err.Error())
+	}
+	defer lease.Release()
+
+	ready := func(podName string) error {
+		return stream.Send(&v1.PortForwardResponse{
+			Message: &v1.PortForwardResponse_Ready{
+				Ready: &v1.PortForwardReady{PodName: podName, Port: init.Port},
+			},
+		})
+	}
+	err = s.uc.PortForward(forwardCtx, init.InstanceId, init.GetPodName(), int32(init.Port),
+		&portForwardReader{stream: stream, lease: lease}, &portForwardWriter{stream: stream, lease: lease}, ready)
+	switch {
+	case lease.Reason() != "":
+		return errors.New(408, "SESSION_TIMEOUT", lease.Reason())
+	case err == nil:
+		return nil
+	case errors.Is(err, biz.ErrInstanceNotFound):
+		return errors.New(404, "NOT_FOUND", "instance not found")
+	case errors.Is(err, biz.ErrPodNotFound):
+		return errors.New(404, "POD_NOT_FOUND", err.Error())
+	default:
+		return errors.New(502, "PORT_FORWARD_FAILED", err.Error())
+	}
+}
+
+// portForwardReader 将请求流中的 data 消息适配为 io.Reader，客户端关闭发送端即结束
+type portForwardReader struct {
+	stream PortForwardStream
+	lease  *biz.ExecLease
+	buf    []byte
+}
+
+func (r *portForwardReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = req.GetData()
+		r.lease.Touch()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// portForwardWriter 将容器端口返回的数据作为 data 消息发送
+type portForwardWriter struct {
+	stream PortForwardStream
+	lease  *biz.ExecLease
+}
+
+func (w *portForwardWriter) Write(p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+	if err := w.stream.Send(&v1.PortForwardResponse{
+		Message: &v1.PortForwardResponse_Data{Data: data},
+	}); err != nil {
+		return 0, err
+	}
+	w.lease.Touch()
+	return len(p), nil
+}
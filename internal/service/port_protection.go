@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// UpdatePortProtection 更新已开放 HTTP 端口的访问控制
+func (s *ResourceService) UpdatePortProtection(ctx context.Context, req *v1.UpdatePortProtectionReq) (*v1.UpdatePortProtectionReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if req.Port == 0 || req.Port > 65535 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "invalid port number, must be 1-65535")
+	}
+
+	err := s.uc.UpdatePortProtection(ctx, req.InstanceId, req.Port, toBizProtection(req.Protection))
+	if err != nil {
+		switch {
+		case errors.Is(err, biz.ErrInstanceNotFound):
+			return nil, errors.New(404, "NOT_FOUND", "instance not found")
+		case errors.Is(err, biz.ErrPortNotOpen):
+			return nil, errors.New(404, "PORT_NOT_OPEN", "port is not open")
+		case errors.Is(err, biz.ErrProtectionNotSupported),
+			errors.Is(err, biz.ErrNotSupportedByBackend),
+			errors.Is(err, biz.ErrInvalidProtection),
+			errors.Is(err, biz.ErrBasicAuthPasswordRequired),
+			errors.Is(err, biz.ErrPortDisabled):
+			return nil, errors.New(400, "INVALID_ARGUMENT", err.Error())
+		}
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to update port protection: "+err.Error())
+	}
+	return &v1.UpdatePortProtectionReply{Success: true}, nil
+}
+
+func toBizProtection(p *v1.IngressProtection) biz.IngressProtection {
+	if p == nil {
+		return biz.IngressProtection{}
+	}
+	return biz.IngressProtection{
+		BasicAuthUser:     p.BasicAuthUsername,
+		BasicAuthPassword: p.BasicAuthPassword,
+		AllowedCIDRs:      p.AllowedCidrs,
+		RateLimitRPS:      p.RateLimitRps,
+		RateLimitRPM:      p.RateLimitRpm,
+		MaxConnections:    p.MaxConnections,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	v1 "resource/api/resource/v1"
 	"resource/internal/biz"
 	"strconv"
+	"strings"
 
 	"github.com/go-kratos/kratos/v2/errors"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -18,7 +19,9 @@ import (
 type ResourceService struct {
 	v1.UnimplementedResourceServiceServer
 
-	uc *biz.ResourceUsecase
+	uc      *biz.ResourceUsecase
+	authz   *biz.AuthzUsecase
+	limiter *biz.ExecLimiter
 }
 
 //const Event_Type = map[string]string{
@@ -35,8 +38,8 @@ type ResourceService struct {
 //}
 
 // NewResourceService new a resource service.
-func NewResourceService(uc *biz.ResourceUsecase) *ResourceService {
-	return &ResourceService{uc: uc}
+func NewResourceService(uc *biz.ResourceUsecase, authz *biz.AuthzUsecase, limiter *biz.ExecLimiter) *ResourceService {
+	return &ResourceService{uc: uc, authz: authz, limiter: limiter}
 }
 
 // ConsumeMqMessage implements resource.ResourceServiceServer.
@@ -64,6 +67,9 @@ func (s *ResourceService) ConsumeMqMessage(ctx context.Context, in []byte) error
 			GPU:        event.Spec.Gpu,
 			Image:      event.Spec.Image,
 			ConfigJSON: nil,
+
+			IngressBandwidth: event.Spec.IngressBandwidthMbps,
+			EgressBandwidth:  event.Spec.EgressBandwidthMbps,
 		}
 		return s.uc.CreateInstance(ctx, spec)
 
@@ -112,6 +118,13 @@ func (s *ResourceService) ListResources(ctx context.Context, in *v1.ListResource
 		filter.End = &end
 	}
 
+	// 仅能访问本人实例的角色，强制按本人 user_id 过滤
+	if p, ok := biz.PrincipalFromContext(ctx); ok {
+		if userID, scoped := s.authz.ScopedUserID(p); scoped {
+			filter.UserID = &userID
+		}
+	}
+
 	resources, err := s.uc.ListResources(ctx, filter)
 	if err != nil {
 		return nil, err
@@ -126,12 +139,14 @@ func (s *ResourceService) ListResources(ctx context.Context, in *v1.ListResource
 	for _, resource := range resources {
 		instanceIDs = append(instanceIDs, resource.InstanceID)
 		item := &v1.Resource{
-			InstanceId: resource.InstanceID,
-			Name:       resource.Name,
-			UserId:     resource.UserID,
-			Type:       resource.Type,
-			CreatedAt:  timestamppb.New(resource.CreatedAt),
-			UpdatedAt:  timestamppb.New(resource.UpdatedAt),
+			InstanceId:    resource.InstanceID,
+			Name:          resource.Name,
+			UserId:        resource.UserID,
+			Type:          resource.Type,
+			StatusReason:  resource.StatusReason,
+			StatusMessage: resource.StatusMessage,
+			CreatedAt:     timestamppb.New(resource.CreatedAt),
+			UpdatedAt:     timestamppb.New(resource.UpdatedAt),
 		}
 		if mask != nil && len(mask.GetPaths()) > 0 {
 			item, err = applyResourceFieldMask(item, mask)
@@ -164,6 +179,10 @@ func (s *ResourceService) ListResources(ctx context.Context, in *v1.ListResource
 				Gpu:          spec.GPU,
 				Image:        spec.Image,
 				CustomConfig: customConfig,
+
+				IngressBandwidthMbps: spec.IngressBandwidth,
+				EgressBandwidthMbps:  spec.EgressBandwidth,
+				PortMaxConnections:   spec.PortMaxConnections,
 			}
 		}
 	}
@@ -194,6 +213,10 @@ func applyResourceFieldMask(in *v1.Resource, mask *fieldmaskpb.FieldMask) (*v1.R
 			out.CreatedAt = in.CreatedAt
 		case "updated_at", "updatedAt":
 			out.UpdatedAt = in.UpdatedAt
+		case "status_reason", "statusReason":
+			out.StatusReason = in.StatusReason
+		case "status_message", "statusMessage":
+			out.StatusMessage = in.StatusMessage
 		default:
 			return nil, errors.New(400, "INVALID_FIELD_MASK", "unknown field mask path: "+path)
 		}
@@ -239,9 +262,26 @@ func (s *ResourceService) SetInstancePort(ctx context.Context, req *v1.SetInstan
 		}
 
 		// 验证协议
-		if protocol != "TCP" && protocol != "UDP" && protocol != "HTTP" {
+		switch protocol {
+		case "TCP", "UDP", "HTTP", "NODEPORT", "LOADBALANCER":
+		default:
+			result.Success = false
+			result.Error = "invalid protocol, must be TCP, UDP, HTTP, NODEPORT or LOADBALANCER"
+			results = append(results, result)
+			continue
+		}
+
+		// 传输协议仅对 NODEPORT/LOADBALANCER 生效
+		transport := strings.ToUpper(config.Transport)
+		if transport != "" && transport != "TCP" && transport != "UDP" {
+			result.Success = false
+			result.Error = "invalid transport, must be TCP or UDP"
+			results = append(results, result)
+			continue
+		}
+		if transport != "" && protocol != "NODEPORT" && protocol != "LOADBALANCER" {
 			result.Success = false
-			result.Error = "invalid protocol, must be TCP, UDP or HTTP"
+			result.Error = "transport is only supported for NODEPORT and LOADBALANCER protocols"
 			results = append(results, result)
 			continue
 		}
@@ -254,8 +294,29 @@ func (s *ResourceService) SetInstancePort(ctx context.Context, req *v1.SetInstan
 			continue
 		}
 
+		// 路由方式与 TLS 仅对 HTTP 生效
+		routing := strings.ToUpper(config.Routing)
+		if routing != "" && routing != biz.IngressRoutingPath && routing != biz.IngressRoutingHost {
+			result.Success = false
+			result.Error = "invalid routing, must be PATH or HOST"
+			results = append(results, result)
+			continue
+		}
+		if protocol != "HTTP" && (routing != "" || config.Tls || config.Protection != nil) {
+			result.Success = false
+			result.Error = "routing, tls and protection are only supported for HTTP protocol"
+			results = append(results, result)
+			continue
+		}
+
 		// 调用业务逻辑
-		url, err := s.uc.SetInstancePort(ctx, req.InstanceId, config.Port, protocol, req.Open, config.IngressDomain)
+		ingress := biz.IngressOptions{
+			Domain:     config.IngressDomain,
+			Routing:    routing,
+			TLS:        config.Tls,
+			Protection: toBizProtection(config.Protection),
+		}
+		url, err := s.uc.SetInstancePort(ctx, req.InstanceId, config.Port, protocol, transport, req.Open, ingress)
 		if err != nil {
 			result.Success = false
 			result.Error = err.Error()
@@ -286,8 +347,21 @@ func (s *ResourceService) SetInstancePort(ctx context.Context, req *v1.SetInstan
 	}, nil
 }
 
+// ExecStream exec 会话的双向消息流，gRPC 流与 WebSocket 连接各自实现
+type ExecStream interface {
+	Context() context.Context
+	Recv() (*v1.ExecRequest, error)
+	Send(*v1.ExecResponse) error
+}
+
 // ExecContainer 容器 Exec 双向流处理
 func (s *ResourceService) ExecContainer(stream v1.ResourceService_ExecContainerServer) error {
+	return s.Exec(stream)
+}
+
+// Exec 处理一个 exec 会话，首条消息必须为 ExecInit。
+// 访问控制、命令策略与会话限制对 gRPC 和 WebSocket 一致生效。
+func (s *ResourceService) Exec(stream ExecStream) error {
 	ctx := stream.Context()
 
 	// 1. 接收初始化消息
@@ -309,7 +383,21 @@ func (s *ResourceService) ExecContainer(stream v1.ResourceService_ExecContainerS
 		return errors.New(400, "INVALID_ARGUMENT", "command is required")
 	}
 
-	// 3. 查询实例信息获取 namespace (user_id)
+	// 3. 访问控制（流式请求的实例 ID 在首条消息中，无法在中间件中校验）
+	p, _ := biz.PrincipalFromContext(ctx)
+	err = s.authz.Authorize(ctx, p, "ExecContainer", init.InstanceId)
+	if err == nil {
+		// 命令策略需在启动 pod exec 之前校验
+		err = s.authz.AuthorizeExec(ctx, p, init.InstanceId, init.Command, init.Tty)
+	}
+	if err != nil {
+		if errors.Is(err, biz.ErrPermissionDenied) {
+			return errors.New(403, "PERMISSION_DENIED", err.Error())
+		}
+		return errors.New(500, "INTERNAL_ERROR", "authorization failed: "+err.Error())
+	}
+
+	// 4. 查询实例信息获取 namespace (user_id)
 	resource, err := s.uc.GetResource(ctx, init.InstanceId)
 	if err != nil {
 		return errors.New(500, "INTERNAL_ERROR", "failed to query instance: "+err.Error())
@@ -323,11 +411,30 @@ func (s *ResourceService) ExecContainer(stream v1.ResourceService_ExecContainerS
 		return errors.New(500, "INTERNAL_ERROR", "instance namespace is empty")
 	}
 
-	// 4. 创建输入输出通道
+	// 5. 并发会话数限制，空闲超时与最长持续时间由 lease 计时
+	userID := p.UserID
+	if userID == "" {
+		userID = namespace
+	}
+	lease, execCtx, err := s.limiter.Acquire(ctx, userID, init.InstanceId)
+	if err != nil {
+		_ = stream.Send(&v1.ExecResponse{
+			Message: &v1.ExecResponse_Error{
+				Error: &v1.ExecError{
+					Message:  err.Error(),
+					Category: v1.ExecError_LIMIT,
+				},
+			},
+		})
+		return errors.New(429, "TOO_MANY_SESSIONS", err.Error())
+	}
+	defer lease.Release()
+
+	// 6. 创建输入输出通道
 	inputChan := make(chan biz.ExecInput, 10)
 	outputChan := make(chan biz.ExecOutput, 10)
 
-	// 5. 启动输入处理协程（gRPC → channel）
+	// 7. 启动输入处理协程（gRPC → channel）
 	go func() {
 		defer close(inputChan)
 		for {
@@ -337,25 +444,37 @@ func (s *ResourceService) ExecContainer(stream v1.ResourceService_ExecContainerS
 				return
 			}
 
+			var in biz.ExecInput
 			switch msg := req.Message.(type) {
 			case *v1.ExecRequest_Input:
-				inputChan <- biz.ExecInput{
+				lease.Touch()
+				in = biz.ExecInput{
 					Type: biz.ExecInputStdin,
 					Data: msg.Input.Data,
 				}
 			case *v1.ExecRequest_Resize:
-				inputChan <- biz.ExecInput{
+				in = biz.ExecInput{
 					Type: biz.ExecInputResize,
 					Rows: msg.Resize.Rows,
 					Cols: msg.Resize.Cols,
 				}
+			default:
+				continue
+			}
+
+			select {
+			case inputChan <- in:
+			case <-execCtx.Done():
+				// 会话已结束，不再转发输入
+				return
 			}
 		}
 	}()
 
-	// 6. 启动输出处理协程（channel → gRPC）
+	// 8. 启动输出处理协程（channel → gRPC）
 	errChan := make(chan error, 1)
 	go func() {
+		reasonSent := false
 		for out := range outputChan {
 			var resp *v1.ExecResponse
 
@@ -374,12 +493,33 @@ func (s *ResourceService) ExecContainer(stream v1.ResourceService_ExecContainerS
 					},
 				}
 			case biz.ExecOutputError:
+				message, category := string(out.Data), out.Category
+				if reason := lease.Reason(); reason != "" && category == biz.ExecErrorCanceled {
+					// 因空闲或超时被终止，告知客户端原因
+					message, category = reason, biz.ExecErrorLimit
+					reasonSent = true
+				}
 				resp = &v1.ExecResponse{
 					Message: &v1.ExecResponse_Error{
-						Error: &v1.ExecError{Message: string(out.Data)},
+						Error: &v1.ExecError{
+							Message:  message,
+							Category: toExecErrorCategory(category),
+						},
 					},
 				}
 			case biz.ExecOutputExit:
+				if reason := lease.Reason(); reason != "" && !reasonSent {
+					// 执行端未报告取消时，仍须在 Exit 之前告知终止原因
+					if err := stream.Send(&v1.ExecResponse{
+						Message: &v1.ExecResponse_Error{
+							Error: &v1.ExecError{Message: reason, Category: v1.ExecError_LIMIT},
+						},
+					}); err != nil {
+						errChan <- err
+						return
+					}
+					reasonSent = true
+				}
 				resp = &v1.ExecResponse{
 					Message: &v1.ExecResponse_Exit{
 						Exit: &v1.ExecExit{Code: out.ExitCode},
@@ -395,19 +535,36 @@ func (s *ResourceService) ExecContainer(stream v1.ResourceService_ExecContainerS
 		errChan <- nil
 	}()
 
-	// 7. 调用业务逻辑执行命令
+	// 9. 调用业务逻辑执行命令
 	containerName := ""
 	if init.ContainerName != nil {
 		containerName = *init.ContainerName
 	}
 
-	_ = s.uc.StreamExec(ctx, namespace, init.InstanceId, init.Command, init.Tty, containerName, inputChan, outputChan)
+	_ = s.uc.StreamExec(execCtx, namespace, init.InstanceId, init.Command, init.Tty, init.GetPodName(), containerName, inputChan, outputChan)
 	close(outputChan)
 
-	// 8. 等待输出协程完成
+	// 10. 等待输出协程完成
 	return <-errChan
 }
 
+func toExecErrorCategory(category biz.ExecErrorCategory) v1.ExecError_Category {
+	switch category {
+	case biz.ExecErrorNotFound:
+		return v1.ExecError_NOT_FOUND
+	case biz.ExecErrorSetup:
+		return v1.ExecError_SETUP
+	case biz.ExecErrorTransport:
+		return v1.ExecError_TRANSPORT
+	case biz.ExecErrorCanceled:
+		return v1.ExecError_CANCELED
+	case biz.ExecErrorLimit:
+		return v1.ExecError_LIMIT
+	default:
+		return v1.ExecError_CATEGORY_UNSPECIFIED
+	}
+}
+
 // DeleteInstance deletes an instance deployment and its associated resources.
 func (s *ResourceService) DeleteInstance(ctx context.Context, req *v1.DeleteInstanceReq) (*v1.DeleteInstanceReply, error) {
 	if req == nil {
@@ -465,7 +622,7 @@ func (s *ResourceService) UpdateInstance(ctx context.Context, req *v1.UpdateInst
 		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
 	}
 
-	err := s.uc.UpdateInstance(ctx, req.InstanceId, req.Cpu, req.Memory, req.Gpu, req.Image)
+	err := s.uc.UpdateInstance(ctx, req.InstanceId, req.Cpu, req.Memory, req.Gpu, req.Image, req.IngressBandwidthMbps, req.EgressBandwidthMbps)
 	if err != nil {
 		return &v1.UpdateInstanceReply{Success: false}, err
 	}
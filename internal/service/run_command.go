@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	v1 "resource/api/resource/v1"
+	"resource/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// RunCommand runs a non-interactive command in an instance and returns its buffered output and exit code.
+func (s *ResourceService) RunCommand(ctx context.Context, req *v1.RunCommandReq) (*v1.RunCommandReply, error) {
+	if req == nil {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "request is required")
+	}
+	if req.InstanceId == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "instance_id is required")
+	}
+	if len(req.Command) == 0 {
+		return nil, errors.New(400, "INVALID_ARGUMENT", "command is required")
+	}
+
+	// 命令策略与交互式 exec 一致，RunCommand 不分配 TTY
+	p, _ := biz.PrincipalFromContext(ctx)
+	if err := s.authz.AuthorizeExec(ctx, p, req.InstanceId, req.Command, false); err != nil {
+		if errors.Is(err, biz.ErrPermissionDenied) {
+			return nil, errors.New(403, "PERMISSION_DENIED", err.Error())
+		}
+		return nil, errors.New(500, "INTERNAL_ERROR", "authorization failed: "+err.Error())
+	}
+
+	resource, err := s.uc.GetResource(ctx, req.InstanceId)
+	if err != nil {
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to query instance: "+err.Error())
+	}
+	if resource == nil {
+		return nil, errors.New(404, "NOT_FOUND", "instance not found")
+	}
+
+	// 与交互式会话共用并发上限
+	userID := p.UserID
+	if userID == "" {
+		userID = resource.UserID
+	}
+	lease, _, err := s.limiter.Acquire(ctx, userID, req.InstanceId)
+	if err != nil {
+		return nil, errors.New(429, "TOO_MANY_SESSIONS", err.Error())
+	}
+	defer lease.Release()
+
+	// 使用 RunCommand 自身的超时，不受 server 请求超时限制；客户端断开时立即终止命令并释放名额
+	clientCtx, cancelClient := withClientCancel(ctx)
+	defer cancelClient()
+	timeout := s.limiter.RunTimeout(time.Duration(req.TimeoutSeconds) * time.Second)
+	runCtx, cancel := context.WithTimeout(clientCtx, timeout)
+	defer cancel()
+
+	result, err := s.uc.RunCommand(runCtx, resource.UserID, req.InstanceId, biz.RunCommandOptions{
+		Command:        req.Command,
+		Stdin:          req.Stdin,
+		PodName:        req.GetPodName(),
+		ContainerName:  req.GetContainerName(),
+		MaxOutputBytes: s.limiter.RunMaxOutputBytes(),
+	})
+	if err != nil {
+		return nil, errors.New(500, "INTERNAL_ERROR", "failed to run command: "+err.Error())
+	}
+
+	reply := &v1.RunCommandReply{
+		ExitCode:        result.ExitCode,
+		Stdout:          result.Stdout,
+		Stderr:          result.Stderr,
+		StdoutTruncated: result.StdoutTruncated,
+		StderrTruncated: result.StderrTruncated,
+		TimedOut:        errors.Is(runCtx.Err(), context.DeadlineExceeded),
+		Error:           result.Error,
+		ErrorCategory:   toExecErrorCategory(result.ErrorCategory),
+	}
+	if reply.TimedOut {
+		reply.Error = "command timed out after " + timeout.String()
+	}
+	return reply, nil
+}